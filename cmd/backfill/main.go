@@ -0,0 +1,134 @@
+// cmd/backfill/main.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/app"
+	"github.com/drerr0r/tgparserbot/internal/media"
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/parser"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+)
+
+// readyTimeout сколько ждать подключения к Telegram, включая вход через API
+const readyTimeout = 2 * time.Minute
+
+// parseDate разбирает дату "2006-01-02" в местном времени или время в RFC3339
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, time.Local); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("неверная дата %q, ожидается 2006-01-02 или RFC3339", value)
+	}
+	return t, nil
+}
+
+func main() {
+	ruleID := flag.Int64("rule", 0, "ID правила, канал которого импортируется")
+	from := flag.String("from", "", "начало периода: 2006-01-02 или RFC3339")
+	to := flag.String("to", "", "конец периода, не включая: 2006-01-02 или RFC3339 (по умолчанию сейчас)")
+	mode := flag.String("mode", string(models.BackfillArchive), "что делать с постами: publish, queue или archive")
+	resume := flag.Int64("resume", 0, "ID прерванного импорта, который нужно продолжить")
+	flag.Parse()
+
+	a, err := app.New("configs/config.yaml")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	defer a.Close()
+
+	cfg := a.Config
+	db := a.DB
+	sugar := a.Logger
+
+	ruleRepo := storage.NewRuleRepository(db)
+	postRepo := storage.NewPostRepository(db)
+	backfillRepo := storage.NewBackfillRepository(db)
+
+	ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer cancel()
+
+	// Новый импорт создается сразу, чтобы его можно было продолжить по ID
+	jobID := *resume
+	if jobID == 0 {
+		job := &models.BackfillJob{
+			RuleID:    *ruleID,
+			Mode:      models.BackfillMode(*mode),
+			To:        time.Now(),
+			CreatedBy: "cli",
+		}
+		if job.From, err = parseDate(*from); err != nil {
+			sugar.Fatalf("❌ %v", err)
+		}
+		if *to != "" {
+			if job.To, err = parseDate(*to); err != nil {
+				sugar.Fatalf("❌ %v", err)
+			}
+		}
+		if err := job.Validate(); err != nil {
+			sugar.Fatalf("❌ Неверные параметры импорта: %v", err)
+		}
+
+		rule, err := ruleRepo.GetByID(ctx, job.RuleID)
+		if err != nil {
+			sugar.Fatalf("❌ Ошибка получения правила: %v", err)
+		}
+		if rule == nil {
+			sugar.Fatalf("❌ Правило %d не найдено", job.RuleID)
+		}
+
+		if err := backfillRepo.Create(ctx, job); err != nil {
+			sugar.Fatalf("❌ %v", err)
+		}
+		jobID = job.ID
+		sugar.Infof("📚 Создан импорт %d, продолжить после остановки: -resume %d", job.ID, job.ID)
+	}
+
+	// Медиа скачивается в общий кэш, публикуют воркеры процесса парсера
+	mediaCache, err := media.NewCache(cfg.Media.CacheDir, int64(cfg.Media.MaxFileSizeMB)*1024*1024)
+	if err != nil {
+		sugar.Fatalf("❌ Ошибка инициализации кэша медиа: %v", err)
+	}
+
+	// Пул аккаунтов Telegram, публикаторы нужны только для карточек модерации
+	accountPool, err := a.NewAccountPool()
+	if err != nil {
+		sugar.Fatalf("❌ %v", err)
+	}
+	cursorRepo := storage.NewCursorRepository(db)
+	telegramParser := parser.NewTelegramParser(db, ruleRepo, postRepo, cursorRepo, storage.NewParserStatusRepository(db), a.MultiPublisher(), accountPool, mediaCache, cfg.Dedup, sugar)
+	backfiller := parser.NewBackfiller(telegramParser, backfillRepo, sugar)
+
+	if err := accountPool.Start(ctx); err != nil {
+		sugar.Fatalf("❌ Ошибка запуска аккаунтов Telegram: %v", err)
+	}
+	deadline := time.Now().Add(readyTimeout)
+	for !accountPool.Ready() {
+		if ctx.Err() != nil || time.Now().After(deadline) {
+			sugar.Fatal("❌ Не удалось подключиться к Telegram")
+		}
+		time.Sleep(time.Second)
+	}
+
+	job, err := backfiller.Claim(ctx, jobID)
+	if err != nil {
+		sugar.Fatalf("❌ %v", err)
+	}
+	if job == nil {
+		sugar.Fatalf("❌ Импорт %d не найден, завершен или уже выполняется", jobID)
+	}
+
+	if err := backfiller.Execute(ctx, job); err != nil {
+		sugar.Fatalf("❌ %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+// cmd/tgparserbot/main.go
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/drerr0r/tgparserbot/internal/app"
+)
+
+const usage = `Использование: tgparserbot [-config путь] команда
+
+Команды:
+  serve    web API и интерфейс
+  parse    парсер каналов и импорт истории
+  publish  очередь публикации, планировщик и модерация
+  all      все компоненты в одном процессе
+
+Флаги:
+`
+
+func main() {
+	configPath := flag.String("config", "configs/config.yaml", "путь к файлу конфигурации")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command := flag.Arg(0)
+	if command != "serve" && command != "parse" && command != "publish" && command != "all" {
+		fmt.Fprintf(os.Stderr, "❌ Неизвестная команда %q\n\n", command)
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	a, err := app.New(*configPath)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "❌ %v\n", err)
+		os.Exit(1)
+	}
+	defer a.Close()
+
+	// Миграции применяет процесс с web API
+	if command == "serve" || command == "all" {
+		if err := a.Migrate(); err != nil {
+			a.Logger.Errorf("❌ Критическая ошибка: %v", err)
+			a.Close()
+			os.Exit(1)
+		}
+	}
+
+	var components []app.Component
+	switch command {
+	case "serve":
+		components = []app.Component{a.Serve}
+	case "parse":
+		components = []app.Component{a.Parse}
+	case "publish":
+		components = []app.Component{a.Publish}
+	case "all":
+		components = []app.Component{a.Serve, a.Parse, a.Publish}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	a.Logger.Infof("🚀 Запуск tgparserbot: %s", command)
+	if err := a.Run(ctx, components...); err != nil {
+		a.Logger.Errorf("❌ %v", err)
+		stop()
+		a.Close()
+		os.Exit(1)
+	}
+	a.Logger.Info("👋 Работа завершена")
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+// accountRequest тело запроса добавления аккаунта
+type accountRequest struct {
+	Phone       string `json:"phone"`
+	SessionFile string `json:"session_file"`
+}
+
+// accountUpdateRequest тело запроса изменения аккаунта
+type accountUpdateRequest struct {
+	IsActive bool `json:"is_active"`
+}
+
+// GetAccounts возвращает аккаунты Telegram пула парсера
+func (h *Handlers) GetAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.accountRepo.List(r.Context())
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения аккаунтов: %v", err)
+		return
+	}
+
+	if accounts == nil {
+		accounts = []*models.TelegramAccount{}
+	}
+
+	h.sendJSON(w, http.StatusOK, accounts)
+}
+
+// CreateAccount добавляет аккаунт в пул. Парсер запустит его клиент при
+// следующей сверке аккаунтов.
+func (h *Handlers) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	var req accountRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный формат JSON: %v", err)
+		return
+	}
+
+	account := &models.TelegramAccount{
+		Phone:       strings.TrimSpace(req.Phone),
+		SessionFile: strings.TrimSpace(req.SessionFile),
+		IsActive:    true,
+	}
+	if account.SessionFile == "" {
+		account.SessionFile = "tg_session_" + strings.TrimPrefix(account.Phone, "+")
+	}
+
+	if err := account.Validate(); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Ошибка валидации: %v", err)
+		return
+	}
+
+	if err := h.accountRepo.Create(r.Context(), account); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка добавления аккаунта: %v", err)
+		return
+	}
+
+	h.logger.Infof("➕ Аккаунт Telegram %s добавлен в пул", account.Phone)
+	h.sendJSON(w, http.StatusCreated, account)
+}
+
+// UpdateAccount включает или выключает аккаунт. Каналы выключенного
+// аккаунта переходят к другим аккаунтам пула.
+func (h *Handlers) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный ID аккаунта: %v", err)
+		return
+	}
+
+	var req accountUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный формат JSON: %v", err)
+		return
+	}
+
+	ok, err := h.accountRepo.SetActive(ctx, id, req.IsActive)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка изменения аккаунта: %v", err)
+		return
+	}
+	if !ok {
+		h.sendError(w, http.StatusNotFound, "Аккаунт %d не найден", id)
+		return
+	}
+
+	account, err := h.accountRepo.GetByID(ctx, id)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения аккаунта: %v", err)
+		return
+	}
+
+	h.logger.Infof("✏️ Аккаунт Telegram %s: активен = %v", account.Phone, account.IsActive)
+	h.sendJSON(w, http.StatusOK, account)
+}
+
+// DeleteAccount удаляет аккаунт из пула
+func (h *Handlers) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный ID аккаунта: %v", err)
+		return
+	}
+
+	ok, err := h.accountRepo.Delete(r.Context(), id)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка удаления аккаунта: %v", err)
+		return
+	}
+	if !ok {
+		h.sendError(w, http.StatusNotFound, "Аккаунт %d не найден", id)
+		return
+	}
+
+	h.logger.Infof("🗑️ Аккаунт Telegram %d удален из пула", id)
+	h.sendJSON(w, http.StatusOK, map[string]string{"message": "Аккаунт удален"})
+}
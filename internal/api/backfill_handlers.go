@@ -0,0 +1,116 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+// backfillRequest тело запроса создания импорта истории
+type backfillRequest struct {
+	RuleID int64               `json:"rule_id"`
+	From   time.Time           `json:"from"`
+	To     *time.Time          `json:"to"` // null - до текущего момента
+	Mode   models.BackfillMode `json:"mode"`
+}
+
+// GetBackfills возвращает последние импорты истории
+func (h *Handlers) GetBackfills(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	if limit <= 0 {
+		limit = 50
+	}
+
+	jobs, err := h.backfillRepo.List(ctx, limit)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения импортов: %v", err)
+		return
+	}
+
+	if jobs == nil {
+		jobs = []*models.BackfillJob{}
+	}
+
+	h.sendJSON(w, http.StatusOK, jobs)
+}
+
+// CreateBackfill создает импорт истории канала правила. Импорт выполняет
+// процесс парсера, у которого есть сессия Telegram.
+func (h *Handlers) CreateBackfill(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req backfillRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный формат JSON: %v", err)
+		return
+	}
+
+	job := &models.BackfillJob{
+		RuleID: req.RuleID,
+		From:   req.From,
+		To:     time.Now(),
+		Mode:   req.Mode,
+	}
+	if req.To != nil {
+		job.To = *req.To
+	}
+	job.CreatedBy, _ = GetUsernameFromContext(ctx)
+
+	if err := job.Validate(); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Ошибка валидации: %v", err)
+		return
+	}
+
+	rule, err := h.ruleRepo.GetByID(ctx, job.RuleID)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения правила: %v", err)
+		return
+	}
+	if rule == nil {
+		h.sendError(w, http.StatusNotFound, "Правило %d не найдено", job.RuleID)
+		return
+	}
+
+	if err := h.backfillRepo.Create(ctx, job); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка создания импорта: %v", err)
+		return
+	}
+
+	h.logger.Infof("📚 Импорт %d канала %s создан пользователем %s", job.ID, rule.SourceChannel, job.CreatedBy)
+	h.sendJSON(w, http.StatusCreated, job)
+}
+
+// CancelBackfill отменяет незавершенный импорт. Уже сохраненные посты остаются.
+func (h *Handlers) CancelBackfill(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный ID импорта: %v", err)
+		return
+	}
+
+	ok, err := h.backfillRepo.Cancel(ctx, id)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка отмены импорта: %v", err)
+		return
+	}
+	if !ok {
+		h.sendError(w, http.StatusConflict, "Импорт %d не найден или уже завершен", id)
+		return
+	}
+
+	job, err := h.backfillRepo.GetByID(ctx, id)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения импорта: %v", err)
+		return
+	}
+
+	h.logger.Infof("🚫 Импорт %d отменен", id)
+	h.sendJSON(w, http.StatusOK, job)
+}
@@ -15,22 +15,36 @@ import (
 )
 
 type Handlers struct {
-	ruleRepo *storage.RuleRepository
-	postRepo *storage.PostRepository
-	userRepo *storage.UserRepository
-	logRepo  *storage.LogRepository
-	logger   *zap.SugaredLogger
-	cfg      *models.Config
+	ruleRepo     *storage.RuleRepository
+	postRepo     *storage.PostRepository
+	jobRepo      *storage.JobRepository
+	reviewRepo   *storage.ReviewRepository
+	backfillRepo *storage.BackfillRepository
+	cursorRepo   *storage.CursorRepository
+	statusRepo   *storage.ParserStatusRepository
+	accountRepo  *storage.AccountRepository
+	authRepo     *storage.AuthRepository
+	userRepo     *storage.UserRepository
+	logRepo      *storage.LogRepository
+	logger       *zap.SugaredLogger
+	cfg          *models.Config
 }
 
-func NewHandlers(ruleRepo *storage.RuleRepository, postRepo *storage.PostRepository, userRepo *storage.UserRepository, logRepo *storage.LogRepository, logger *zap.SugaredLogger, cfg *models.Config) *Handlers {
+func NewHandlers(ruleRepo *storage.RuleRepository, postRepo *storage.PostRepository, jobRepo *storage.JobRepository, reviewRepo *storage.ReviewRepository, backfillRepo *storage.BackfillRepository, cursorRepo *storage.CursorRepository, statusRepo *storage.ParserStatusRepository, accountRepo *storage.AccountRepository, authRepo *storage.AuthRepository, userRepo *storage.UserRepository, logRepo *storage.LogRepository, logger *zap.SugaredLogger, cfg *models.Config) *Handlers {
 	return &Handlers{
-		ruleRepo: ruleRepo,
-		postRepo: postRepo,
-		userRepo: userRepo,
-		logRepo:  logRepo,
-		logger:   logger,
-		cfg:      cfg,
+		ruleRepo:     ruleRepo,
+		postRepo:     postRepo,
+		jobRepo:      jobRepo,
+		reviewRepo:   reviewRepo,
+		backfillRepo: backfillRepo,
+		cursorRepo:   cursorRepo,
+		statusRepo:   statusRepo,
+		accountRepo:  accountRepo,
+		authRepo:     authRepo,
+		userRepo:     userRepo,
+		logRepo:      logRepo,
+		logger:       logger,
+		cfg:          cfg,
 	}
 }
 
@@ -159,13 +173,23 @@ func (h *Handlers) GetPosts(w http.ResponseWriter, r *http.Request) {
 	// Получаем параметры пагинации
 	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
 	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	reviewStatus := models.ReviewStatus(r.URL.Query().Get("review_status"))
 
 	if limit == 0 {
 		limit = 50
 	}
 
-	// Используем новый метод для всех постов
-	posts, err := h.postRepo.GetPosts(ctx, limit, offset)
+	var posts []*models.Post
+	var err error
+	switch reviewStatus {
+	case "":
+		posts, err = h.postRepo.GetPosts(ctx, limit, offset)
+	case models.ReviewNone, models.ReviewPending, models.ReviewApproved, models.ReviewRejected:
+		posts, err = h.postRepo.GetPostsByReviewStatus(ctx, reviewStatus, limit, offset)
+	default:
+		h.sendError(w, http.StatusBadRequest, "Неизвестный статус модерации: %s", reviewStatus)
+		return
+	}
 	if err != nil {
 		h.sendError(w, http.StatusInternalServerError, "Ошибка получения постов: %v", err)
 		return
@@ -176,6 +200,12 @@ func (h *Handlers) GetPosts(w http.ResponseWriter, r *http.Request) {
 		posts = []*models.Post{}
 	}
 
+	// Добавляем состояние очереди публикации
+	if err := h.jobRepo.AttachToPosts(ctx, posts); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения задач публикации: %v", err)
+		return
+	}
+
 	h.sendJSON(w, http.StatusOK, posts)
 }
 
@@ -229,6 +259,19 @@ func (h *Handlers) GetStats(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	// Состояние очереди публикации
+	jobCounts, err := h.jobRepo.CountByStatus(ctx)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения статистики очереди: %v", err)
+		return
+	}
+
+	pendingReview, err := h.reviewRepo.CountPending(ctx)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения статистики модерации: %v", err)
+		return
+	}
+
 	stats := map[string]interface{}{
 		// Основная статистика
 		"rules_count":    len(allRules),
@@ -242,6 +285,14 @@ func (h *Handlers) GetStats(w http.ResponseWriter, r *http.Request) {
 		"success_posts":  successPosts,
 		"failed_posts":   failedPosts,
 
+		// Очередь публикации
+		"jobs_pending":   jobCounts[models.JobPending] + jobCounts[models.JobRunning],
+		"jobs_dead":      jobCounts[models.JobDead],
+		"jobs_scheduled": jobCounts[models.JobScheduled],
+
+		// Модерация
+		"posts_pending_review": pendingReview,
+
 		"service": "tg-parser-bot",
 		"status":  "running",
 	}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+)
+
+// GetJobs возвращает задачи очереди публикации
+func (h *Handlers) GetJobs(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+	offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+	status := models.JobStatus(r.URL.Query().Get("status"))
+
+	if limit == 0 {
+		limit = 50
+	}
+
+	switch status {
+	case "", models.JobScheduled, models.JobPending, models.JobRunning, models.JobDone, models.JobDead:
+	default:
+		h.sendError(w, http.StatusBadRequest, "Неизвестный статус задачи: %s", status)
+		return
+	}
+
+	jobs, err := h.jobRepo.List(ctx, status, limit, offset)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения задач: %v", err)
+		return
+	}
+
+	if jobs == nil {
+		jobs = []*models.PublishJob{}
+	}
+
+	h.sendJSON(w, http.StatusOK, jobs)
+}
+
+// RetryJob сбрасывает попытки задачи и ставит ее на немедленное выполнение
+func (h *Handlers) RetryJob(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный ID задачи: %v", err)
+		return
+	}
+
+	job, err := h.jobRepo.Requeue(ctx, id)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка повтора задачи: %v", err)
+		return
+	}
+	if job == nil {
+		h.sendError(w, http.StatusConflict, "Задача %d не найдена или уже выполняется", id)
+		return
+	}
+
+	h.logger.Infof("🔁 Задача %d (пост %d, %s) поставлена на повтор", job.ID, job.PostID, job.Platform)
+	h.sendJSON(w, http.StatusOK, job)
+}
+
+// scheduleRequest тело запроса назначения времени публикации
+type scheduleRequest struct {
+	ScheduledAt *time.Time `json:"scheduled_at"` // null - вернуть пост под расписание правила
+}
+
+// SchedulePost назначает посту время публикации или сбрасывает его
+func (h *Handlers) SchedulePost(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.postIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req scheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный формат JSON: %v", err)
+		return
+	}
+
+	if err := h.jobRepo.SchedulePost(ctx, id, req.ScheduledAt); err != nil {
+		if errors.Is(err, storage.ErrNotSchedulable) {
+			h.sendError(w, http.StatusConflict, "Пост %d не найден, уже публикуется или опубликован", id)
+			return
+		}
+		h.sendError(w, http.StatusInternalServerError, "Ошибка назначения времени публикации: %v", err)
+		return
+	}
+
+	if req.ScheduledAt != nil {
+		h.logger.Infof("🕒 Пост %d запланирован на %s", id, req.ScheduledAt.Format(time.RFC3339))
+	} else {
+		h.logger.Infof("🕒 Время публикации поста %d сброшено", id)
+	}
+
+	post, err := h.postRepo.GetByID(ctx, id)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения поста: %v", err)
+		return
+	}
+	h.sendJSON(w, http.StatusOK, post)
+}
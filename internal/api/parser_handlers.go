@@ -0,0 +1,186 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+// parserAliveWindow сколько парсер считается запущенным после последнего
+// отчета о состоянии. Парсер отчитывается каждые 15 секунд.
+const parserAliveWindow = time.Minute
+
+// parserStatusResponse состояние парсинга для веб-интерфейса
+type parserStatusResponse struct {
+	Running   bool                     `json:"running"`
+	Instances []*models.ParserInstance `json:"instances"`
+	Rules     []*models.RuleHealth     `json:"rules"`
+}
+
+// GetParserStatus возвращает запущенные процессы парсера и состояние
+// чтения канала каждым правилом
+func (h *Handlers) GetParserStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	instances, err := h.statusRepo.ListAlive(ctx, time.Now().Add(-parserAliveWindow))
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения состояния парсера: %v", err)
+		return
+	}
+	if instances == nil {
+		instances = []*models.ParserInstance{}
+	}
+
+	rules, err := h.ruleRepo.ListAll(ctx)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения правил: %v", err)
+		return
+	}
+
+	cursors, err := h.cursorRepo.List(ctx)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения состояния каналов: %v", err)
+		return
+	}
+
+	// Курсор правила ищется по текущему каналу: смена канала начинает новый курсор
+	type cursorKey struct {
+		ruleID  int64
+		channel string
+	}
+	byRule := make(map[cursorKey]*models.ChannelCursor, len(cursors))
+	for _, cursor := range cursors {
+		byRule[cursorKey{cursor.RuleID, cursor.SourceChannel}] = cursor
+	}
+
+	monitors := make(map[int64]models.RuleMonitorStatus)
+	for _, instance := range instances {
+		for _, monitor := range instance.Monitors {
+			monitors[monitor.RuleID] = monitor
+		}
+	}
+
+	health := make([]*models.RuleHealth, 0, len(rules))
+	for _, rule := range rules {
+		item := &models.RuleHealth{
+			RuleID:        rule.ID,
+			RuleName:      rule.Name,
+			SourceChannel: rule.SourceChannel,
+			IsActive:      rule.IsActive,
+			IsPaused:      rule.IsPaused,
+		}
+		if monitor, ok := monitors[rule.ID]; ok {
+			item.Monitored = true
+			item.Mode = monitor.Mode
+		}
+		if cursor, ok := byRule[cursorKey{rule.ID, rule.SourceChannel}]; ok {
+			item.LastCheckedAt = cursor.LastCheckedAt
+			item.LastSuccessAt = cursor.LastSuccessAt
+			item.LastError = cursor.LastError
+			item.MessagesSeen = cursor.MessagesSeen
+			item.PostsCreated = cursor.PostsCreated
+		}
+		health = append(health, item)
+	}
+
+	h.sendJSON(w, http.StatusOK, parserStatusResponse{
+		Running:   len(instances) > 0,
+		Instances: instances,
+		Rules:     health,
+	})
+}
+
+// PauseParserRule приостанавливает чтение канала правилом. Парсер
+// останавливает мониторинг, получив уведомление об изменении правила.
+func (h *Handlers) PauseParserRule(w http.ResponseWriter, r *http.Request) {
+	h.setRulePaused(w, r, true)
+}
+
+// ResumeParserRule возобновляет чтение канала правилом с сохраненного курсора
+func (h *Handlers) ResumeParserRule(w http.ResponseWriter, r *http.Request) {
+	h.setRulePaused(w, r, false)
+}
+
+// setRulePaused приостанавливает или возобновляет правило
+func (h *Handlers) setRulePaused(w http.ResponseWriter, r *http.Request, paused bool) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный ID правила: %v", err)
+		return
+	}
+
+	ok, err := h.ruleRepo.SetPaused(ctx, id, paused)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка изменения правила: %v", err)
+		return
+	}
+	if !ok {
+		h.sendError(w, http.StatusNotFound, "Правило %d не найдено", id)
+		return
+	}
+
+	rule, err := h.ruleRepo.GetByID(ctx, id)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения правила: %v", err)
+		return
+	}
+	if rule == nil {
+		h.sendError(w, http.StatusNotFound, "Правило %d не найдено", id)
+		return
+	}
+
+	if paused {
+		h.logger.Infof("⏸️ Правило %q приостановлено", rule.Name)
+	} else {
+		h.logger.Infof("▶️ Правило %q возобновлено", rule.Name)
+	}
+	h.sendJSON(w, http.StatusOK, rule)
+}
+
+// CheckParserRuleNow просит парсер проверить канал правила, не дожидаясь
+// очередного опроса
+func (h *Handlers) CheckParserRuleNow(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный ID правила: %v", err)
+		return
+	}
+
+	rule, err := h.ruleRepo.GetByID(ctx, id)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения правила: %v", err)
+		return
+	}
+	if rule == nil {
+		h.sendError(w, http.StatusNotFound, "Правило %d не найдено", id)
+		return
+	}
+	if !rule.IsActive || rule.IsPaused {
+		h.sendError(w, http.StatusConflict, "Правило %q выключено или приостановлено", rule.Name)
+		return
+	}
+
+	instances, err := h.statusRepo.ListAlive(ctx, time.Now().Add(-parserAliveWindow))
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения состояния парсера: %v", err)
+		return
+	}
+	if len(instances) == 0 {
+		h.sendError(w, http.StatusConflict, "Парсер не запущен")
+		return
+	}
+
+	if err := h.ruleRepo.RequestCheck(ctx, id); err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка запроса проверки: %v", err)
+		return
+	}
+
+	h.logger.Infof("⚡ Запрошена внеочередная проверка канала %s правила %q", rule.SourceChannel, rule.Name)
+	h.sendJSON(w, http.StatusAccepted, map[string]string{"message": "Проверка канала запрошена"})
+}
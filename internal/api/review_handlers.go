@@ -0,0 +1,292 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+)
+
+// maxBulkReview максимальное количество постов в одном массовом действии
+const maxBulkReview = 200
+
+// errPostNotFound пост или его правило не найдены
+var errPostNotFound = errors.New("пост не найден")
+
+// reviewRequest тело запросов approve/reject
+type reviewRequest struct {
+	Comment string `json:"comment"`
+}
+
+// editRequest тело запроса edit
+type editRequest struct {
+	Content string `json:"content"`
+	Comment string `json:"comment"`
+	Approve bool   `json:"approve"` // одобрить пост сразу после правки
+}
+
+// bulkReviewRequest тело запроса массового действия
+type bulkReviewRequest struct {
+	IDs     []int64             `json:"ids"`
+	Action  models.ReviewAction `json:"action"` // approve или reject
+	Comment string              `json:"comment"`
+}
+
+// bulkReviewResult результат действия над одним постом
+type bulkReviewResult struct {
+	ID    int64  `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// newReview создает запись решения от имени текущего пользователя
+func newReview(ctx context.Context, postID int64, comment string) *models.PostReview {
+	userID, _ := GetUserIDFromContext(ctx)
+	username, _ := GetUsernameFromContext(ctx)
+	return &models.PostReview{
+		PostID:   postID,
+		UserID:   userID,
+		Username: username,
+		Comment:  strings.TrimSpace(comment),
+	}
+}
+
+// approvePost одобряет пост и ставит его в очередь на платформы правила
+func (h *Handlers) approvePost(ctx context.Context, review *models.PostReview) error {
+	post, err := h.postRepo.GetByID(ctx, review.PostID)
+	if err != nil {
+		return err
+	}
+	if post == nil {
+		return errPostNotFound
+	}
+
+	rule, err := h.ruleRepo.GetByID(ctx, post.RuleID)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		return fmt.Errorf("правило %d поста %d не найдено", post.RuleID, post.ID)
+	}
+
+	return h.reviewRepo.Approve(ctx, review, rule.TargetPlatforms, post.HoldUntil(rule, time.Now()))
+}
+
+// sendReviewError отправляет ошибку решения модерации с подходящим статусом
+func (h *Handlers) sendReviewError(w http.ResponseWriter, id int64, err error) {
+	switch {
+	case errors.Is(err, errPostNotFound):
+		h.sendError(w, http.StatusNotFound, "Пост %d не найден", id)
+	case errors.Is(err, storage.ErrNotPendingReview):
+		h.sendError(w, http.StatusConflict, "Пост %d не ожидает модерации", id)
+	default:
+		h.sendError(w, http.StatusInternalServerError, "Ошибка модерации поста %d: %v", id, err)
+	}
+}
+
+// postIDFromPath разбирает ID поста из пути
+func (h *Handlers) postIDFromPath(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный ID поста: %v", err)
+		return 0, false
+	}
+	return id, true
+}
+
+// decodeOptional разбирает JSON тело запроса, пустое тело допустимо
+func decodeOptional(r *http.Request, v interface{}) error {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+// ApprovePost одобряет пост и отправляет его в очередь публикации
+func (h *Handlers) ApprovePost(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.postIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req reviewRequest
+	if err := decodeOptional(r, &req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный формат JSON: %v", err)
+		return
+	}
+
+	review := newReview(ctx, id, req.Comment)
+	if err := h.approvePost(ctx, review); err != nil {
+		h.sendReviewError(w, id, err)
+		return
+	}
+
+	h.logger.Infof("✅ Пост %d одобрен пользователем %s", id, review.Username)
+	h.sendJSON(w, http.StatusOK, review)
+}
+
+// RejectPost отклоняет пост, он не будет опубликован
+func (h *Handlers) RejectPost(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.postIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req reviewRequest
+	if err := decodeOptional(r, &req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный формат JSON: %v", err)
+		return
+	}
+
+	review := newReview(ctx, id, req.Comment)
+	if err := h.reviewRepo.Reject(ctx, review); err != nil {
+		h.sendReviewError(w, id, err)
+		return
+	}
+
+	h.logger.Infof("🚫 Пост %d отклонен пользователем %s", id, review.Username)
+	h.sendJSON(w, http.StatusOK, review)
+}
+
+// EditPost меняет текст поста, ожидающего модерации, и при approve=true одобряет его
+func (h *Handlers) EditPost(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.postIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	var req editRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный формат JSON: %v", err)
+		return
+	}
+	if strings.TrimSpace(req.Content) == "" {
+		h.sendError(w, http.StatusBadRequest, "Текст поста не может быть пустым")
+		return
+	}
+
+	post, err := h.postRepo.GetByID(ctx, id)
+	if err != nil {
+		h.sendReviewError(w, id, err)
+		return
+	}
+	if post == nil {
+		h.sendReviewError(w, id, errPostNotFound)
+		return
+	}
+
+	// Разметка исходного сообщения не соответствует новому тексту
+	post.Content = req.Content
+	post.Entities = nil
+
+	if err := h.reviewRepo.Edit(ctx, post, newReview(ctx, id, req.Comment)); err != nil {
+		h.sendReviewError(w, id, err)
+		return
+	}
+	h.logger.Infof("✏️ Текст поста %d изменен при модерации", id)
+
+	if req.Approve {
+		review := newReview(ctx, id, req.Comment)
+		if err := h.approvePost(ctx, review); err != nil {
+			h.sendReviewError(w, id, err)
+			return
+		}
+		h.logger.Infof("✅ Пост %d одобрен пользователем %s", id, review.Username)
+	}
+
+	post, err = h.postRepo.GetByID(ctx, id)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения поста: %v", err)
+		return
+	}
+	h.sendJSON(w, http.StatusOK, post)
+}
+
+// BulkReviewPosts одобряет или отклоняет несколько постов.
+// Ошибка по одному посту не прерывает обработку остальных.
+func (h *Handlers) BulkReviewPosts(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req bulkReviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный формат JSON: %v", err)
+		return
+	}
+
+	switch req.Action {
+	case models.ReviewActionApprove, models.ReviewActionReject:
+	default:
+		h.sendError(w, http.StatusBadRequest, "Неизвестное действие: %s", req.Action)
+		return
+	}
+	if len(req.IDs) == 0 {
+		h.sendError(w, http.StatusBadRequest, "Не указаны ID постов")
+		return
+	}
+	if len(req.IDs) > maxBulkReview {
+		h.sendError(w, http.StatusBadRequest, "Слишком много постов: %d, максимум %d", len(req.IDs), maxBulkReview)
+		return
+	}
+
+	results := make([]bulkReviewResult, 0, len(req.IDs))
+	done := 0
+	for _, id := range req.IDs {
+		review := newReview(ctx, id, req.Comment)
+
+		var err error
+		if req.Action == models.ReviewActionApprove {
+			err = h.approvePost(ctx, review)
+		} else {
+			err = h.reviewRepo.Reject(ctx, review)
+		}
+
+		result := bulkReviewResult{ID: id, OK: err == nil}
+		if err != nil {
+			result.Error = err.Error()
+		} else {
+			done++
+		}
+		results = append(results, result)
+	}
+
+	username, _ := GetUsernameFromContext(ctx)
+	h.logger.Infof("📋 Массовое действие %s пользователя %s: %d из %d постов", req.Action, username, done, len(req.IDs))
+	h.sendJSON(w, http.StatusOK, results)
+}
+
+// GetPostReviews возвращает журнал модерации поста
+func (h *Handlers) GetPostReviews(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	id, ok := h.postIDFromPath(w, r)
+	if !ok {
+		return
+	}
+
+	reviews, err := h.reviewRepo.ListByPost(ctx, id)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения журнала модерации: %v", err)
+		return
+	}
+
+	if reviews == nil {
+		reviews = []*models.PostReview{}
+	}
+
+	h.sendJSON(w, http.StatusOK, reviews)
+}
@@ -8,8 +8,8 @@ import (
 	"go.uber.org/zap"
 )
 
-func SetupRoutes(ruleRepo *storage.RuleRepository, postRepo *storage.PostRepository, userRepo *storage.UserRepository, logRepo *storage.LogRepository, logger *zap.SugaredLogger, cfg *models.Config) http.Handler {
-	handlers := NewHandlers(ruleRepo, postRepo, userRepo, logRepo, logger, cfg)
+func SetupRoutes(ruleRepo *storage.RuleRepository, postRepo *storage.PostRepository, jobRepo *storage.JobRepository, reviewRepo *storage.ReviewRepository, backfillRepo *storage.BackfillRepository, cursorRepo *storage.CursorRepository, statusRepo *storage.ParserStatusRepository, accountRepo *storage.AccountRepository, authRepo *storage.AuthRepository, userRepo *storage.UserRepository, logRepo *storage.LogRepository, logger *zap.SugaredLogger, cfg *models.Config) http.Handler {
+	handlers := NewHandlers(ruleRepo, postRepo, jobRepo, reviewRepo, backfillRepo, cursorRepo, statusRepo, accountRepo, authRepo, userRepo, logRepo, logger, cfg)
 	mux := http.NewServeMux()
 
 	// ========== ПУБЛИЧНЫЕ ENDPOINTS (ДО AuthMiddleware) ==========
@@ -35,8 +35,44 @@ func SetupRoutes(ruleRepo *storage.RuleRepository, postRepo *storage.PostReposit
 	mux.HandleFunc("PUT /api/rules/{id}", handlers.UpdateRule)
 	mux.HandleFunc("DELETE /api/rules/{id}", handlers.DeleteRule)
 
+	// Templates API
+	mux.HandleFunc("POST /api/templates/preview", handlers.PreviewTemplate)
+
 	// Posts API
 	mux.HandleFunc("GET /api/posts", handlers.GetPosts)
+	mux.HandleFunc("POST /api/posts/bulk", handlers.BulkReviewPosts)
+	mux.HandleFunc("POST /api/posts/{id}/approve", handlers.ApprovePost)
+	mux.HandleFunc("POST /api/posts/{id}/reject", handlers.RejectPost)
+	mux.HandleFunc("POST /api/posts/{id}/edit", handlers.EditPost)
+	mux.HandleFunc("GET /api/posts/{id}/reviews", handlers.GetPostReviews)
+	mux.HandleFunc("PUT /api/posts/{id}/schedule", handlers.SchedulePost)
+
+	// Publish queue API
+	mux.HandleFunc("GET /api/jobs", handlers.GetJobs)
+	mux.HandleFunc("POST /api/jobs/{id}/retry", handlers.RetryJob)
+
+	// Backfill API
+	mux.HandleFunc("GET /api/backfills", handlers.GetBackfills)
+	mux.HandleFunc("POST /api/backfills", handlers.CreateBackfill)
+	mux.HandleFunc("POST /api/backfills/{id}/cancel", handlers.CancelBackfill)
+
+	// Parser control API
+	mux.HandleFunc("GET /api/parser/status", handlers.GetParserStatus)
+	mux.HandleFunc("POST /api/parser/rules/{id}/pause", handlers.PauseParserRule)
+	mux.HandleFunc("POST /api/parser/rules/{id}/resume", handlers.ResumeParserRule)
+	mux.HandleFunc("POST /api/parser/rules/{id}/check-now", handlers.CheckParserRuleNow)
+
+	// Telegram accounts API
+	mux.HandleFunc("GET /api/telegram/accounts", handlers.GetAccounts)
+	mux.HandleFunc("POST /api/telegram/accounts", handlers.CreateAccount)
+	mux.HandleFunc("PUT /api/telegram/accounts/{id}", handlers.UpdateAccount)
+	mux.HandleFunc("DELETE /api/telegram/accounts/{id}", handlers.DeleteAccount)
+
+	// Telegram login API
+	mux.HandleFunc("GET /api/telegram/auth/status", handlers.GetTelegramAuthStatus)
+	mux.HandleFunc("POST /api/telegram/auth/start", handlers.StartTelegramAuth)
+	mux.HandleFunc("POST /api/telegram/auth/code", handlers.SubmitTelegramAuthCode)
+	mux.HandleFunc("POST /api/telegram/auth/password", handlers.SubmitTelegramAuthPassword)
 
 	// Stats
 	mux.HandleFunc("GET /api/stats", handlers.GetStats)
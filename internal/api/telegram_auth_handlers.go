@@ -0,0 +1,209 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+)
+
+// telegramAuthRequest тело запросов входа в Telegram. Без account_id
+// используется аккаунт из конфигурации или единственный аккаунт пула.
+type telegramAuthRequest struct {
+	AccountID int64             `json:"account_id"`
+	Method    models.AuthMethod `json:"method"`
+	Code      string            `json:"code"`
+	Password  string            `json:"password"`
+}
+
+// GetTelegramAuthStatus возвращает этап входа аккаунта, по которому
+// интерфейс ведет администратора через вход
+func (h *Handlers) GetTelegramAuthStatus(w http.ResponseWriter, r *http.Request) {
+	var accountID int64
+	if value := r.URL.Query().Get("account_id"); value != "" {
+		id, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			h.sendError(w, http.StatusBadRequest, "Неверный ID аккаунта: %v", err)
+			return
+		}
+		accountID = id
+	}
+
+	account, status, err := h.authAccount(r.Context(), accountID)
+	if err != nil {
+		h.sendError(w, status, "%v", err)
+		return
+	}
+
+	h.sendAuthState(r.Context(), w, http.StatusOK, account.ID)
+}
+
+// StartTelegramAuth начинает вход аккаунта: клиент парсера отправляет код
+// или показывает QR-код. Уже начатый вход начинается заново.
+func (h *Handlers) StartTelegramAuth(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req telegramAuthRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный формат JSON: %v", err)
+		return
+	}
+	if req.Method == "" {
+		req.Method = models.AuthByCode
+	}
+	if !req.Method.Valid() {
+		h.sendError(w, http.StatusBadRequest, "Неизвестный способ входа %q, допустимы code и qr", req.Method)
+		return
+	}
+
+	account, status, err := h.authAccount(ctx, req.AccountID)
+	if err != nil {
+		h.sendError(w, status, "%v", err)
+		return
+	}
+	if !account.IsActive {
+		h.sendError(w, http.StatusConflict, "Аккаунт %s выключен", account.Phone)
+		return
+	}
+
+	state, err := h.authRepo.RequestLogin(ctx, account.ID, req.Method)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка начала входа: %v", err)
+		return
+	}
+
+	// Клиент аккаунта, потерявшего авторизацию, остановлен. Со статусом
+	// active пул перезапустит его, и клиент подхватит начатый вход.
+	if account.Status == models.AccountUnauthorized {
+		if err := h.accountRepo.SetStatus(ctx, account.ID, models.AccountActive, nil, ""); err != nil {
+			h.sendError(w, http.StatusInternalServerError, "Ошибка изменения аккаунта: %v", err)
+			return
+		}
+	}
+
+	h.logger.Infof("🔐 Начат вход аккаунта Telegram %s, способ: %s", account.Phone, req.Method)
+	h.sendJSON(w, http.StatusAccepted, state)
+}
+
+// SubmitTelegramAuthCode передает клиенту парсера код входа
+func (h *Handlers) SubmitTelegramAuthCode(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req telegramAuthRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный формат JSON: %v", err)
+		return
+	}
+	code := strings.TrimSpace(req.Code)
+	if code == "" {
+		h.sendError(w, http.StatusBadRequest, "Не указан код")
+		return
+	}
+
+	account, status, err := h.authAccount(ctx, req.AccountID)
+	if err != nil {
+		h.sendError(w, status, "%v", err)
+		return
+	}
+
+	ok, err := h.authRepo.SubmitCode(ctx, account.ID, code)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка передачи кода: %v", err)
+		return
+	}
+	if !ok {
+		h.sendError(w, http.StatusConflict, "Аккаунт %s сейчас не ждет код", account.Phone)
+		return
+	}
+
+	h.logger.Infof("📱 Код входа аккаунта Telegram %s передан клиенту", account.Phone)
+	h.sendAuthState(ctx, w, http.StatusAccepted, account.ID)
+}
+
+// SubmitTelegramAuthPassword передает клиенту парсера пароль двухэтапной проверки
+func (h *Handlers) SubmitTelegramAuthPassword(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req telegramAuthRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный формат JSON: %v", err)
+		return
+	}
+	if req.Password == "" {
+		h.sendError(w, http.StatusBadRequest, "Не указан пароль")
+		return
+	}
+
+	account, status, err := h.authAccount(ctx, req.AccountID)
+	if err != nil {
+		h.sendError(w, status, "%v", err)
+		return
+	}
+
+	ok, err := h.authRepo.SubmitPassword(ctx, account.ID, req.Password)
+	if errors.Is(err, storage.ErrPasswordLoginDisabled) {
+		h.sendError(w, http.StatusConflict, "%v", err)
+		return
+	}
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка передачи пароля: %v", err)
+		return
+	}
+	if !ok {
+		h.sendError(w, http.StatusConflict, "Аккаунт %s сейчас не ждет пароль", account.Phone)
+		return
+	}
+
+	h.logger.Infof("🔑 Пароль аккаунта Telegram %s передан клиенту", account.Phone)
+	h.sendAuthState(ctx, w, http.StatusAccepted, account.ID)
+}
+
+// authAccount находит аккаунт для входа. Без ID выбирается аккаунт из
+// конфигурации, а если его нет - единственный аккаунт пула. Вместе с
+// ошибкой возвращается HTTP статус ответа.
+func (h *Handlers) authAccount(ctx context.Context, accountID int64) (*models.TelegramAccount, int, error) {
+	if accountID != 0 {
+		account, err := h.accountRepo.GetByID(ctx, accountID)
+		if err != nil {
+			return nil, http.StatusInternalServerError, fmt.Errorf("Ошибка получения аккаунта: %v", err)
+		}
+		if account == nil {
+			return nil, http.StatusNotFound, fmt.Errorf("Аккаунт %d не найден", accountID)
+		}
+		return account, http.StatusOK, nil
+	}
+
+	accounts, err := h.accountRepo.List(ctx)
+	if err != nil {
+		return nil, http.StatusInternalServerError, fmt.Errorf("Ошибка получения аккаунтов: %v", err)
+	}
+	for _, account := range accounts {
+		if account.Phone == h.cfg.Telegram.Phone {
+			return account, http.StatusOK, nil
+		}
+	}
+	if len(accounts) == 1 {
+		return accounts[0], http.StatusOK, nil
+	}
+	return nil, http.StatusBadRequest, fmt.Errorf("Укажите account_id аккаунта")
+}
+
+// sendAuthState отправляет текущий этап входа аккаунта
+func (h *Handlers) sendAuthState(ctx context.Context, w http.ResponseWriter, status int, accountID int64) {
+	state, err := h.authRepo.Get(ctx, accountID)
+	if err != nil {
+		h.sendError(w, http.StatusInternalServerError, "Ошибка получения состояния входа: %v", err)
+		return
+	}
+	if state == nil {
+		state = &models.TelegramAuth{AccountID: accountID, State: models.AuthUnknown}
+	}
+
+	h.sendJSON(w, status, state)
+}
@@ -0,0 +1,65 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/publisher"
+)
+
+// templatePreviewRequest запрос предпросмотра шаблона поста
+type templatePreviewRequest struct {
+	Platform models.PlatformType `json:"platform"`
+	Template string              `json:"template"`  // пусто - шаблон по умолчанию
+	PostID   int64               `json:"post_id"`   // 0 - пример поста
+	RuleName string              `json:"rule_name"` // название правила для .RuleName
+}
+
+// PreviewTemplate оформляет пост шаблоном и возвращает результат
+func (h *Handlers) PreviewTemplate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var req templatePreviewRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.sendError(w, http.StatusBadRequest, "Неверный JSON: %v", err)
+		return
+	}
+
+	switch req.Platform {
+	case models.PlatformTelegram, models.PlatformVK:
+	default:
+		h.sendError(w, http.StatusBadRequest, "Неизвестная платформа: %s", req.Platform)
+		return
+	}
+
+	post := models.SamplePost()
+	if req.PostID != 0 {
+		var err error
+		post, err = h.postRepo.GetByID(ctx, req.PostID)
+		if err != nil {
+			h.sendError(w, http.StatusInternalServerError, "Ошибка получения поста: %v", err)
+			return
+		}
+		if post == nil {
+			h.sendError(w, http.StatusNotFound, "Пост %d не найден", req.PostID)
+			return
+		}
+	}
+
+	rule := &models.ParsingRule{
+		Name:      req.RuleName,
+		Templates: map[models.PlatformType]string{req.Platform: req.Template},
+	}
+
+	content, err := publisher.FormatPost(post, rule, req.Platform, rule.TemplateFor(req.Platform))
+	if err != nil {
+		h.sendError(w, http.StatusBadRequest, "Ошибка шаблона: %v", err)
+		return
+	}
+
+	h.sendJSON(w, http.StatusOK, map[string]interface{}{
+		"platform": req.Platform,
+		"content":  content,
+	})
+}
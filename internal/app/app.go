@@ -0,0 +1,179 @@
+// Package app собирает компоненты бота: web API, парсер и воркеры
+// публикации. Компоненты используют общие конфигурацию, пул соединений
+// с БД и логгер и могут работать в одном процессе или в разных.
+package app
+
+import (
+	"context"
+	"expvar"
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/drerr0r/tgparserbot/internal/config"
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/parser"
+	"github.com/drerr0r/tgparserbot/internal/publisher"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+	"github.com/drerr0r/tgparserbot/pkg/logger"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// Component компонент приложения, работает до отмены контекста
+type Component func(ctx context.Context) error
+
+// App общие зависимости компонентов приложения
+type App struct {
+	Config *models.Config
+	DB     *storage.DB
+	Logger *zap.SugaredLogger
+
+	publishersOnce sync.Once
+	multiPublisher *publisher.MultiPublisher
+	tgPublisher    *publisher.TelegramPublisher
+}
+
+// New загружает конфигурацию, инициализирует логгер и подключается к БД
+func New(configPath string) (*App, error) {
+	cfg, err := config.LoadConfig(configPath)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка загрузки конфигурации: %v", err)
+	}
+
+	if err := logger.Init(cfg.Logger.Level, cfg.Logger.Format, cfg.Logger.FilePath); err != nil {
+		return nil, fmt.Errorf("ошибка инициализации логгера: %v", err)
+	}
+
+	if err := config.Validate(cfg); err != nil {
+		return nil, fmt.Errorf("ошибка валидации конфигурации: %v", err)
+	}
+
+	db, err := storage.New(cfg.Database)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подключения к БД: %v", err)
+	}
+
+	sugar := logger.Sugar()
+	sugar.Info("✅ Успешное подключение к БД")
+
+	return &App{Config: cfg, DB: db, Logger: sugar}, nil
+}
+
+// Close закрывает пул соединений с БД и сбрасывает буфер логгера
+func (a *App) Close() {
+	a.DB.Close()
+	logger.Sync()
+}
+
+// Run запускает компоненты и ждет завершения всех. Ошибка одного
+// компонента останавливает остальные.
+func (a *App) Run(ctx context.Context, components ...Component) error {
+	g, ctx := errgroup.WithContext(ctx)
+
+	// Метрики лимитеров запросов
+	if a.Config.Metrics.Addr != "" {
+		g.Go(func() error {
+			return a.serveMetrics(ctx)
+		})
+	}
+
+	for _, component := range components {
+		g.Go(func() error {
+			return component(ctx)
+		})
+	}
+
+	return g.Wait()
+}
+
+// publishers возвращает общий MultiPublisher и Telegram публикатор.
+// Публикаторы создаются один раз для всех компонентов процесса.
+// Telegram публикатор nil, если бот не настроен.
+func (a *App) publishers() (*publisher.MultiPublisher, *publisher.TelegramPublisher) {
+	a.publishersOnce.Do(func() {
+		cfg := a.Config
+		registry := publisher.NewRegistry()
+
+		if cfg.Telegram.BotToken != "" {
+			tgPublisher, err := publisher.NewTelegramPublisher(&cfg.Telegram, a.Logger)
+			if err != nil {
+				a.Logger.Errorf("❌ Ошибка инициализации Telegram публикатора: %v", err)
+			} else {
+				a.tgPublisher = tgPublisher
+				registry.Register(tgPublisher)
+				a.Logger.Info("✅ Telegram публикатор инициализирован")
+			}
+		}
+		if cfg.VK.AccessToken != "" {
+			vkPublisher, err := publisher.NewVKPublisher(cfg.VK.AccessToken, cfg.VK.GroupID, a.Logger)
+			if err != nil {
+				a.Logger.Errorf("❌ Ошибка инициализации VK публикатора: %v", err)
+			} else {
+				registry.Register(vkPublisher)
+				a.Logger.Info("✅ VK публикатор инициализирован")
+			}
+		}
+
+		a.multiPublisher = publisher.NewMultiPublisher(
+			registry,
+			storage.NewPostRepository(a.DB),
+			storage.NewPublicationRepository(a.DB),
+			a.Logger,
+		)
+	})
+	return a.multiPublisher, a.tgPublisher
+}
+
+// MultiPublisher возвращает общий MultiPublisher процесса
+func (a *App) MultiPublisher() *publisher.MultiPublisher {
+	multi, _ := a.publishers()
+	return multi
+}
+
+// NewAccountPool создает пул аккаунтов Telegram. Сессии хранятся в БД,
+// если задан ключ шифрования, иначе в файлах. Если ключ обязателен
+// (require_session_key), без него пул не создается.
+func (a *App) NewAccountPool() (*parser.AccountPool, error) {
+	var sessionRepo *storage.SessionRepository
+	var err error
+	if a.Config.Telegram.SessionKey == "" && a.Config.Telegram.RequireSessionKey {
+		return nil, fmt.Errorf("не задан ключ шифрования сессий TG_SESSION_KEY: без него сессии Telegram не сохраняются между перезапусками")
+	}
+	if a.Config.Telegram.SessionKey != "" {
+		sessionRepo, err = storage.NewSessionRepository(a.DB, a.Config.Telegram.SessionKey)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка инициализации хранилища сессий: %v", err)
+		}
+	} else {
+		a.Logger.Warn("⚠️ Ключ шифрования сессий (TG_SESSION_KEY) не задан, сессии Telegram хранятся в файлах")
+	}
+	authRepo, err := storage.NewAuthRepository(a.DB, a.Config.Telegram.SessionKey)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка инициализации состояния входа: %v", err)
+	}
+
+	return parser.NewAccountPool(
+		&a.Config.Telegram,
+		storage.NewAccountRepository(a.DB),
+		storage.NewPeerRepository(a.DB),
+		sessionRepo,
+		authRepo,
+		a.Logger,
+	), nil
+}
+
+// serveMetrics публикует expvar метрики, в том числе состояние лимитеров
+// запросов. Метрики необязательны, поэтому ошибка сервера метрик не
+// останавливает приложение.
+func (a *App) serveMetrics(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle("GET /debug/vars", expvar.Handler())
+
+	a.Logger.Infof("📊 Метрики доступны на http://%s/debug/vars", a.Config.Metrics.Addr)
+	server := &http.Server{Addr: a.Config.Metrics.Addr, Handler: mux}
+	if err := a.serveHTTP(ctx, server); err != nil {
+		a.Logger.Errorf("❌ Ошибка сервера метрик: %v", err)
+	}
+	return nil
+}
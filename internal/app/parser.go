@@ -0,0 +1,92 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/media"
+	"github.com/drerr0r/tgparserbot/internal/parser"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+)
+
+// parserRestartDelay пауза перед повторным запуском парсера после ошибки
+const parserRestartDelay = 30 * time.Second
+
+// Parse запускает парсер каналов и импорт истории и останавливает их
+// при отмене контекста
+func (a *App) Parse(ctx context.Context) error {
+	cfg := a.Config
+	db := a.DB
+
+	// Пул аккаунтов Telegram, каналы распределяются между ними
+	accountPool, err := a.NewAccountPool()
+	if err != nil {
+		return err
+	}
+
+	mediaCache, err := media.NewCache(cfg.Media.CacheDir, int64(cfg.Media.MaxFileSizeMB)*1024*1024)
+	if err != nil {
+		return fmt.Errorf("ошибка инициализации кэша медиа: %v", err)
+	}
+
+	postRepo := storage.NewPostRepository(db)
+	telegramParser := parser.NewTelegramParser(
+		db,
+		storage.NewRuleRepository(db),
+		postRepo,
+		storage.NewCursorRepository(db),
+		storage.NewParserStatusRepository(db),
+		a.MultiPublisher(),
+		accountPool,
+		mediaCache,
+		cfg.Dedup,
+		a.Logger,
+	)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Периодически удаляем старые файлы из кэша медиа, кроме файлов
+	// постов, которые ждут модерации или публикации
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		mediaCache.RunCleanup(ctx, time.Hour, time.Duration(cfg.Media.CacheTTLHours)*time.Hour, postRepo.HeldMediaFiles, a.Logger)
+	}()
+
+	// Запуск парсера с перезапуском после ошибки
+	for {
+		a.Logger.Info("🔄 Запуск парсера...")
+
+		err := telegramParser.Start(ctx)
+		if err == nil {
+			break
+		}
+
+		a.Logger.Errorf("❌ Ошибка запуска парсера: %v", err)
+		a.Logger.Infof("🔄 Перезапуск через %v...", parserRestartDelay)
+
+		select {
+		case <-time.After(parserRestartDelay):
+		case <-ctx.Done():
+			a.Logger.Info("👋 Завершение работы парсера")
+			return nil
+		}
+	}
+	a.Logger.Info("✅ Парсер запущен. Ожидание сообщений...")
+
+	// Импорты истории, созданные через API, выполняются этой сессией Telegram
+	backfiller := parser.NewBackfiller(telegramParser, storage.NewBackfillRepository(db), a.Logger)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		backfiller.Run(ctx)
+	}()
+
+	<-ctx.Done()
+	telegramParser.Stop()
+	a.Logger.Info("👋 Завершение работы парсера")
+	return nil
+}
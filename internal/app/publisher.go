@@ -0,0 +1,56 @@
+package app
+
+import (
+	"context"
+	"sync"
+
+	"github.com/drerr0r/tgparserbot/internal/publisher"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+)
+
+// Publish запускает очередь публикации, планировщик отложенных постов и
+// модерацию в Telegram и ждет их остановки после отмены контекста
+func (a *App) Publish(ctx context.Context) error {
+	cfg := a.Config
+	db := a.DB
+
+	ruleRepo := storage.NewRuleRepository(db)
+	postRepo := storage.NewPostRepository(db)
+	jobRepo := storage.NewJobRepository(db)
+	multiPublisher, tgPublisher := a.publishers()
+
+	var wg sync.WaitGroup
+
+	// Воркеры очереди публикации
+	worker := publisher.NewWorker(jobRepo, postRepo, ruleRepo, multiPublisher, cfg.Publish, a.Logger)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		worker.Run(ctx)
+	}()
+
+	// Планировщик отпускает отложенные посты по расписанию правил
+	scheduler := publisher.NewScheduler(jobRepo, ruleRepo, cfg.Publish, a.Logger)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		scheduler.Run(ctx)
+	}()
+
+	// Модерация постов кнопками бота в чате редакторов
+	if tgPublisher != nil && cfg.Telegram.ModeratorsChat != "" {
+		moderationBot, err := publisher.NewModerationBot(tgPublisher, postRepo, ruleRepo, storage.NewReviewRepository(db), a.Logger)
+		if err != nil {
+			a.Logger.Errorf("❌ Ошибка инициализации модерации в Telegram: %v", err)
+		} else {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				moderationBot.Run(ctx)
+			}()
+		}
+	}
+
+	wg.Wait()
+	return nil
+}
@@ -0,0 +1,136 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/api"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+)
+
+// shutdownTimeout сколько ждать завершения текущих HTTP запросов при остановке
+const shutdownTimeout = 10 * time.Second
+
+// migrationPaths где искать миграции относительно рабочей директории
+var migrationPaths = []string{
+	"./migrations",  // относительный путь
+	"migrations",    // текущая директория
+	"../migrations", // на уровень выше
+}
+
+// Migrate применяет миграции и проверяет, что схема БД актуальна
+func (a *App) Migrate() error {
+	var migrationApplied bool
+	var migrationErr error
+
+	for _, path := range migrationPaths {
+		a.Logger.Infof("🔍 Проверяем миграции в: %s", path)
+
+		if _, err := os.Stat(path); err == nil {
+			// Папка существует, пробуем применить миграции
+			if err := a.DB.RunMigrations(path); err == nil {
+				a.Logger.Infof("✅ Миграции успешно применены из: %s", path)
+				migrationApplied = true
+				migrationErr = nil
+				break
+			} else {
+				migrationErr = err
+				a.Logger.Debugf("❌ Ошибка в пути %s: %v", path, err)
+			}
+		}
+	}
+
+	if !migrationApplied {
+		if migrationErr != nil {
+			return fmt.Errorf("не удалось применить миграции: %v", migrationErr)
+		}
+		a.Logger.Warn("⚠️ Папка миграций не найдена, проверяем состояние БД...")
+	}
+
+	// Финальная проверка состояния БД
+	if !a.DB.CheckMigrationsApplied() {
+		return fmt.Errorf("БД не соответствует требуемой схеме, проверьте миграции")
+	}
+	a.Logger.Info("✅ Проверка схемы БД пройдена успешно")
+	return nil
+}
+
+// Serve запускает web API и останавливает его при отмене контекста
+func (a *App) Serve(ctx context.Context) error {
+	cfg := a.Config
+	db := a.DB
+
+	authRepo, err := storage.NewAuthRepository(db, cfg.Telegram.SessionKey)
+	if err != nil {
+		return fmt.Errorf("ошибка инициализации состояния входа: %v", err)
+	}
+
+	handler := api.SetupRoutes(
+		storage.NewRuleRepository(db),
+		storage.NewPostRepository(db),
+		storage.NewJobRepository(db),
+		storage.NewReviewRepository(db),
+		storage.NewBackfillRepository(db),
+		storage.NewCursorRepository(db),
+		storage.NewParserStatusRepository(db),
+		storage.NewAccountRepository(db),
+		authRepo,
+		storage.NewUserRepository(db),
+		storage.NewLogRepository("logs/app.log"),
+		a.Logger,
+		cfg,
+	)
+
+	server := &http.Server{
+		Addr:         fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port),
+		Handler:      handler,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	a.Logger.Infof("🌐 Web сервер запущен на http://%s:%d", cfg.Server.Host, cfg.Server.Port)
+	a.Logger.Info("📋 Доступные endpoints:")
+	a.Logger.Info("   GET /health - Проверка здоровья")
+	a.Logger.Info("   GET /api/rules - Список правил")
+	a.Logger.Info("   GET /api/posts - Список постов")
+	a.Logger.Info("   GET /api/jobs - Очередь публикации")
+	a.Logger.Info("   GET /api/stats - Статистика")
+	a.Logger.Info("   GET /api/logs - Просмотр логов")
+
+	if err := a.serveHTTP(ctx, server); err != nil {
+		return fmt.Errorf("ошибка web сервера: %v", err)
+	}
+	a.Logger.Info("✅ Web сервер остановлен")
+	return nil
+}
+
+// serveHTTP обслуживает запросы до отмены контекста, затем дожидается
+// завершения текущих запросов
+func (a *App) serveHTTP(ctx context.Context, server *http.Server) error {
+	errCh := make(chan error, 1)
+	go func() {
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("ошибка graceful shutdown: %v", err)
+	}
+	if err := <-errCh; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
@@ -146,12 +146,23 @@ func loadFromEnv(config *models.Config) {
 	if phone := os.Getenv("TG_PHONE"); phone != "" {
 		config.Telegram.Phone = phone
 	}
+	if sessionKey := os.Getenv("TG_SESSION_KEY"); sessionKey != "" {
+		config.Telegram.SessionKey = sessionKey
+	}
+	if require := os.Getenv("TG_REQUIRE_SESSION_KEY"); require != "" {
+		if v, err := strconv.ParseBool(require); err == nil {
+			config.Telegram.RequireSessionKey = v
+		}
+	}
 	if botToken := os.Getenv("TG_BOT_TOKEN"); botToken != "" {
 		config.Telegram.BotToken = botToken
 	}
 	if targetChannel := os.Getenv("TG_TARGET_CHANNEL"); targetChannel != "" {
 		config.Telegram.TargetChannel = targetChannel
 	}
+	if moderatorsChat := os.Getenv("TG_MODERATORS_CHAT"); moderatorsChat != "" {
+		config.Telegram.ModeratorsChat = moderatorsChat
+	}
 
 	// VK
 	if accessToken := os.Getenv("VK_ACCESS_TOKEN"); accessToken != "" {
@@ -163,6 +174,35 @@ func loadFromEnv(config *models.Config) {
 		}
 	}
 
+	// Media
+	if cacheDir := os.Getenv("MEDIA_CACHE_DIR"); cacheDir != "" {
+		config.Media.CacheDir = cacheDir
+	}
+	if maxSize := os.Getenv("MEDIA_MAX_FILE_SIZE_MB"); maxSize != "" {
+		if size, err := strconv.Atoi(maxSize); err == nil {
+			config.Media.MaxFileSizeMB = size
+		}
+	}
+
+	// Publish
+	if workers := os.Getenv("PUBLISH_WORKERS"); workers != "" {
+		if n, err := strconv.Atoi(workers); err == nil {
+			config.Publish.Workers = n
+		}
+	}
+
+	// Dedup
+	if window := os.Getenv("DEDUP_WINDOW_HOURS"); window != "" {
+		if n, err := strconv.Atoi(window); err == nil {
+			config.Dedup.WindowHours = n
+		}
+	}
+
+	// Metrics
+	if addr := os.Getenv("METRICS_ADDR"); addr != "" {
+		config.Metrics.Addr = addr
+	}
+
 	// Server
 	if host := os.Getenv("SERVER_HOST"); host != "" {
 		config.Server.Host = host
@@ -232,6 +272,39 @@ func setDefaults(config *models.Config) {
 	if config.Auth.JWTDuration == 0 {
 		config.Auth.JWTDuration = 24
 	}
+	if config.Media.CacheDir == "" {
+		config.Media.CacheDir = "media_cache"
+	}
+	if config.Media.MaxFileSizeMB == 0 {
+		config.Media.MaxFileSizeMB = 50 // лимит Bot API на загрузку файлов
+	}
+	if config.Media.CacheTTLHours == 0 {
+		config.Media.CacheTTLHours = 24
+	}
+	if config.Publish.Workers == 0 {
+		config.Publish.Workers = 2
+	}
+	if config.Publish.PollSeconds == 0 {
+		config.Publish.PollSeconds = 5
+	}
+	if config.Publish.MaxAttempts == 0 {
+		config.Publish.MaxAttempts = 8
+	}
+	if config.Publish.RetryBaseSeconds == 0 {
+		config.Publish.RetryBaseSeconds = 30
+	}
+	if config.Publish.RetryMaxMinutes == 0 {
+		config.Publish.RetryMaxMinutes = 60
+	}
+	if config.Dedup.WindowHours == 0 {
+		config.Dedup.WindowHours = 24
+	}
+	if config.Dedup.SimHashDistance == 0 {
+		config.Dedup.SimHashDistance = 6
+	}
+	if config.Dedup.ImageHashDistance == 0 {
+		config.Dedup.ImageHashDistance = 5
+	}
 }
 
 // Validate проверяет обязательные поля конфигурации
@@ -248,9 +321,6 @@ func Validate(config *models.Config) error {
 	if config.Telegram.APIHash == "" {
 		return fmt.Errorf("telegram api_hash is required")
 	}
-	if config.Telegram.Phone == "" {
-		return fmt.Errorf("telegram phone is required")
-	}
 
 	return nil
 }
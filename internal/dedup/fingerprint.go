@@ -0,0 +1,156 @@
+// Package dedup вычисляет отпечатки содержимого постов для поиска
+// дубликатов и почти дубликатов из разных каналов.
+package dedup
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"hash/fnv"
+	"math/bits"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// minSimHashWords минимальное количество слов, при котором SimHash
+// достаточно устойчив для сравнения. У более коротких текстов сравнивается
+// только точный хэш.
+const minSimHashWords = 8
+
+// shingleSize количество слов в шингле SimHash
+const shingleSize = 2
+
+var (
+	linkRe    = regexp.MustCompile(`(?i)(?:https?://|www\.|t\.me/)\S+`)
+	mentionRe = regexp.MustCompile(`@[A-Za-z0-9_]+`)
+)
+
+// Fingerprint отпечаток содержимого поста. Нулевые поля не сравниваются.
+type Fingerprint struct {
+	TextHash  string // SHA-256 нормализованного текста
+	SimHash   uint64 // SimHash шинглов нормализованного текста
+	ImageHash uint64 // перцептивный хэш первого изображения
+}
+
+// Candidate отпечаток ранее сохраненного поста
+type Candidate struct {
+	PostID int64
+	Fingerprint
+}
+
+// Options пороги сравнения отпечатков
+type Options struct {
+	SimHashDistance   int // максимальное расстояние Хэмминга между SimHash
+	ImageHashDistance int // максимальное расстояние Хэмминга между хэшами изображений
+}
+
+// Words возвращает слова нормализованного текста: без ссылок, упоминаний,
+// знаков препинания и эмодзи, в нижнем регистре
+func Words(text string) []string {
+	text = linkRe.ReplaceAllString(text, " ")
+	text = mentionRe.ReplaceAllString(text, " ")
+	text = strings.ToLower(text)
+	text = strings.ReplaceAll(text, "ё", "е")
+
+	return strings.FieldsFunc(text, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// TextFingerprint вычисляет текстовую часть отпечатка
+func TextFingerprint(text string) Fingerprint {
+	words := Words(text)
+	if len(words) == 0 {
+		return Fingerprint{}
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+	fp := Fingerprint{TextHash: hex.EncodeToString(sum[:])}
+	if len(words) >= minSimHashWords {
+		fp.SimHash = SimHash(words)
+	}
+	return fp
+}
+
+// SimHash вычисляет 64-битный SimHash по шинглам из shingleSize слов
+func SimHash(words []string) uint64 {
+	var weights [64]int
+
+	n := len(words) - shingleSize + 1
+	if n < 1 {
+		n = 1
+	}
+	for i := 0; i < n; i++ {
+		end := i + shingleSize
+		if end > len(words) {
+			end = len(words)
+		}
+
+		h := fnv.New64a()
+		h.Write([]byte(strings.Join(words[i:end], " ")))
+		sum := h.Sum64()
+
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	var result uint64
+	for bit, w := range weights {
+		if w > 0 {
+			result |= 1 << uint(bit)
+		}
+	}
+	return result
+}
+
+// Distance возвращает расстояние Хэмминга между хэшами
+func Distance(a, b uint64) int {
+	return bits.OnesCount64(a ^ b)
+}
+
+// Similar проверяет, является ли содержимое отпечатков одинаковым.
+// Совпадающий текст - дубликат независимо от медиа. Совпадающее изображение
+// считается дубликатом, если у одного из постов нет сравнимого текста или
+// тексты тоже похожи с вдвое большим допуском: одна и та же стоковая
+// картинка с разными новостями дубликатом не считается.
+func Similar(a, b Fingerprint, opts Options) bool {
+	if a.TextHash != "" && a.TextHash == b.TextHash {
+		return true
+	}
+
+	textComparable := a.SimHash != 0 && b.SimHash != 0
+	textDistance := 64
+	if textComparable {
+		textDistance = Distance(a.SimHash, b.SimHash)
+		if textDistance <= opts.SimHashDistance {
+			return true
+		}
+	}
+
+	if a.ImageHash != 0 && b.ImageHash != 0 && Distance(a.ImageHash, b.ImageHash) <= opts.ImageHashDistance {
+		if a.TextHash == "" || b.TextHash == "" {
+			return true
+		}
+		return textComparable && textDistance <= 2*opts.SimHashDistance
+	}
+
+	return false
+}
+
+// FindDuplicate ищет среди кандидатов пост с тем же содержимым и возвращает его ID
+func FindDuplicate(fp Fingerprint, candidates []Candidate, opts Options) (int64, bool) {
+	if fp == (Fingerprint{}) {
+		return 0, false
+	}
+	for _, c := range candidates {
+		if Similar(fp, c.Fingerprint, opts) {
+			return c.PostID, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,230 @@
+package dedup
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestWords(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "пустой текст", text: "", want: nil},
+		{name: "регистр и пунктуация", text: "Привет, МИР! Как дела?", want: []string{"привет", "мир", "как", "дела"}},
+		{name: "ё заменяется на е", text: "Ёлка ещё зелёная", want: []string{"елка", "еще", "зеленая"}},
+		{name: "ссылки удаляются", text: "Читать https://example.com/a?b=1 и www.site.ru/x и t.me/channel далее", want: []string{"читать", "и", "и", "далее"}},
+		{name: "упоминания удаляются", text: "Спасибо @news_bot и @Channel1", want: []string{"спасибо", "и"}},
+		{name: "эмодзи удаляются", text: "🔥Скидки🔥 до 50% 🎉", want: []string{"скидки", "до", "50"}},
+		{name: "переносы строк", text: "строка1\nстрока2\tстрока3", want: []string{"строка1", "строка2", "строка3"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Words(tt.text)
+			if len(got) != len(tt.want) || (len(got) > 0 && !reflect.DeepEqual(got, tt.want)) {
+				t.Errorf("Words(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+const longText = "Центральный банк сегодня сохранил ключевую ставку на прежнем уровне и пообещал следить за инфляцией в ближайшие месяцы"
+
+func TestTextFingerprint(t *testing.T) {
+	short := TextFingerprint("Короткий текст")
+	if short.TextHash == "" {
+		t.Error("короткий текст без TextHash")
+	}
+	if short.SimHash != 0 {
+		t.Error("SimHash короткого текста должен быть пустым")
+	}
+
+	if fp := TextFingerprint("🔥 https://t.me/x @user"); fp != (Fingerprint{}) {
+		t.Errorf("текст без слов дал отпечаток %+v", fp)
+	}
+
+	long := TextFingerprint(longText)
+	if long.SimHash == 0 {
+		t.Error("длинный текст без SimHash")
+	}
+
+	// Регистр, эмодзи, ссылки и упоминания не меняют отпечаток
+	decorated := TextFingerprint("‼️ " + strings.ToUpper(longText) + "!!! https://example.com @news")
+	if decorated != long {
+		t.Errorf("отпечаток зависит от оформления: %+v != %+v", decorated, long)
+	}
+}
+
+func TestSimHashStable(t *testing.T) {
+	words := Words(longText)
+	first := SimHash(words)
+	for i := 0; i < 10; i++ {
+		if got := SimHash(words); got != first {
+			t.Fatalf("SimHash нестабилен: %x != %x", got, first)
+		}
+	}
+
+	// Одно измененное слово дает близкий хэш
+	edited := Words(strings.Replace(longText, "сегодня", "вчера", 1))
+	if d := Distance(first, SimHash(edited)); d > 10 {
+		t.Errorf("расстояние после правки одного слова = %d", d)
+	}
+
+	// Другой текст дает далекий хэш
+	other := Words("Сборная по футболу выиграла товарищеский матч со счетом три один на домашнем стадионе при полных трибунах")
+	if d := Distance(first, SimHash(other)); d < 15 {
+		t.Errorf("расстояние между разными текстами = %d", d)
+	}
+
+	// Текст короче шингла все равно хэшируется
+	if SimHash([]string{"слово"}) == 0 {
+		t.Error("SimHash одного слова пустой")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	tests := []struct {
+		a, b uint64
+		want int
+	}{
+		{a: 0, b: 0, want: 0},
+		{a: 0b1011, b: 0b0001, want: 2},
+		{a: 0, b: ^uint64(0), want: 64},
+	}
+
+	for _, tt := range tests {
+		if got := Distance(tt.a, tt.b); got != tt.want {
+			t.Errorf("Distance(%b, %b) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	opts := Options{SimHashDistance: 3, ImageHashDistance: 5}
+
+	const (
+		img      uint64 = 0xF0F0F0F0F0F0F0F0
+		imgClose uint64 = 0xF0F0F0F0F0F0F0F3 // 2 бита от img
+		imgFar   uint64 = 0x0F0F0F0F0F0F0F0F
+		sim      uint64 = 0xAAAAAAAAAAAAAAAA
+		simClose uint64 = 0xAAAAAAAAAAAAAAAB // 1 бит от sim
+		simMid   uint64 = 0xAAAAAAAAAAAAAA55 // 8 бит от sim
+		simHalf  uint64 = 0xAAAAAAAAAAAAAAA5 // 4 бита от sim
+	)
+
+	tests := []struct {
+		name string
+		a, b Fingerprint
+		want bool
+	}{
+		{
+			name: "пустые отпечатки",
+			want: false,
+		},
+		{
+			name: "одинаковый текст",
+			a:    Fingerprint{TextHash: "h1"},
+			b:    Fingerprint{TextHash: "h1"},
+			want: true,
+		},
+		{
+			name: "одинаковый текст с разными картинками",
+			a:    Fingerprint{TextHash: "h1", ImageHash: img},
+			b:    Fingerprint{TextHash: "h1", ImageHash: imgFar},
+			want: true,
+		},
+		{
+			name: "разный короткий текст",
+			a:    Fingerprint{TextHash: "h1"},
+			b:    Fingerprint{TextHash: "h2"},
+			want: false,
+		},
+		{
+			name: "похожий текст",
+			a:    Fingerprint{TextHash: "h1", SimHash: sim},
+			b:    Fingerprint{TextHash: "h2", SimHash: simClose},
+			want: true,
+		},
+		{
+			name: "текст на границе порога",
+			a:    Fingerprint{TextHash: "h1", SimHash: sim},
+			b:    Fingerprint{TextHash: "h2", SimHash: sim ^ 0b111},
+			want: true,
+		},
+		{
+			name: "текст за порогом без картинки",
+			a:    Fingerprint{TextHash: "h1", SimHash: sim},
+			b:    Fingerprint{TextHash: "h2", SimHash: simHalf},
+			want: false,
+		},
+		{
+			name: "одна картинка без текста",
+			a:    Fingerprint{ImageHash: img},
+			b:    Fingerprint{ImageHash: imgClose},
+			want: true,
+		},
+		{
+			name: "одна картинка, текст только у одного",
+			a:    Fingerprint{TextHash: "h1", SimHash: sim, ImageHash: img},
+			b:    Fingerprint{ImageHash: imgClose},
+			want: true,
+		},
+		{
+			name: "разные картинки без текста",
+			a:    Fingerprint{ImageHash: img},
+			b:    Fingerprint{ImageHash: imgFar},
+			want: false,
+		},
+		{
+			name: "одна картинка с разным текстом",
+			a:    Fingerprint{TextHash: "h1", SimHash: sim, ImageHash: img},
+			b:    Fingerprint{TextHash: "h2", SimHash: simMid, ImageHash: img},
+			want: false,
+		},
+		{
+			name: "одна картинка с текстом в двойном допуске",
+			a:    Fingerprint{TextHash: "h1", SimHash: sim, ImageHash: img},
+			b:    Fingerprint{TextHash: "h2", SimHash: simHalf, ImageHash: imgClose},
+			want: true,
+		},
+		{
+			name: "одна картинка с коротким разным текстом",
+			a:    Fingerprint{TextHash: "h1", ImageHash: img},
+			b:    Fingerprint{TextHash: "h2", ImageHash: img},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Similar(tt.a, tt.b, opts); got != tt.want {
+				t.Errorf("Similar() = %v, want %v", got, tt.want)
+			}
+			if got := Similar(tt.b, tt.a, opts); got != tt.want {
+				t.Errorf("Similar() в обратном порядке = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindDuplicate(t *testing.T) {
+	opts := Options{SimHashDistance: 3, ImageHashDistance: 5}
+	candidates := []Candidate{
+		{PostID: 1, Fingerprint: Fingerprint{TextHash: "a"}},
+		{PostID: 2, Fingerprint: Fingerprint{TextHash: "b"}},
+		{PostID: 3, Fingerprint: Fingerprint{TextHash: "b"}},
+	}
+
+	if id, ok := FindDuplicate(Fingerprint{TextHash: "b"}, candidates, opts); !ok || id != 2 {
+		t.Errorf("FindDuplicate() = (%d, %v), want (2, true)", id, ok)
+	}
+	if _, ok := FindDuplicate(Fingerprint{TextHash: "c"}, candidates, opts); ok {
+		t.Error("найден дубликат для нового текста")
+	}
+	if _, ok := FindDuplicate(Fingerprint{}, []Candidate{{PostID: 4}}, opts); ok {
+		t.Error("пустой отпечаток не должен совпадать с пустым кандидатом")
+	}
+}
@@ -0,0 +1,77 @@
+package dedup
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"os"
+)
+
+// ImageHash вычисляет разностный перцептивный хэш (dHash) изображения:
+// картинка уменьшается до 9x8 в оттенках серого, каждый бит - сравнение
+// яркости соседних пикселей строки. Хэш устойчив к пересжатию и изменению размера.
+func ImageHash(path string) (uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка открытия изображения: %v", err)
+	}
+	defer f.Close()
+
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return 0, fmt.Errorf("ошибка декодирования изображения: %v", err)
+	}
+
+	gray := downscaleGray(img, 9, 8)
+
+	var hash uint64
+	bit := 0
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			if gray[y][x] < gray[y][x+1] {
+				hash |= 1 << uint(bit)
+			}
+			bit++
+		}
+	}
+	return hash, nil
+}
+
+// downscaleGray уменьшает изображение до w x h усреднением яркости по областям
+func downscaleGray(img image.Image, w, h int) [][]float64 {
+	bounds := img.Bounds()
+	result := make([][]float64, h)
+
+	for y := 0; y < h; y++ {
+		result[y] = make([]float64, w)
+		y0 := bounds.Min.Y + y*bounds.Dy()/h
+		y1 := bounds.Min.Y + (y+1)*bounds.Dy()/h
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+
+		for x := 0; x < w; x++ {
+			x0 := bounds.Min.X + x*bounds.Dx()/w
+			x1 := bounds.Min.X + (x+1)*bounds.Dx()/w
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+
+			var sum float64
+			var count int
+			// Для больших изображений берем не больше 16x16 точек области
+			stepX, stepY := max((x1-x0)/16, 1), max((y1-y0)/16, 1)
+			for py := y0; py < y1; py += stepY {
+				for px := x0; px < x1; px += stepX {
+					r, g, b, _ := img.At(px, py).RGBA()
+					sum += 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)
+					count++
+				}
+			}
+			result[y][x] = sum / float64(count)
+		}
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+package dedup
+
+import (
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// testImage рисует картинку w x h с плавными пятнами яркости, масштаб
+// рисунка не зависит от размера
+func testImage(w, h int, phase float64) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			fx, fy := float64(x)/float64(w), float64(y)/float64(h)
+			v := 0.5 + 0.25*math.Sin(7*fx+phase) + 0.25*math.Cos(5*fy+2*fx+phase)
+			c := uint8(v * 255)
+			img.Set(x, y, color.RGBA{R: c, G: uint8(float64(c) * 0.8), B: 255 - c, A: 255})
+		}
+	}
+	return img
+}
+
+func savePNG(t *testing.T, img image.Image, name string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := png.Encode(f, img); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func saveJPEG(t *testing.T, img image.Image, name string, quality int) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := jpeg.Encode(f, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func imageHash(t *testing.T, path string) uint64 {
+	t.Helper()
+	hash, err := ImageHash(path)
+	if err != nil {
+		t.Fatalf("ImageHash(%s): %v", path, err)
+	}
+	return hash
+}
+
+func TestImageHash(t *testing.T) {
+	original := imageHash(t, savePNG(t, testImage(640, 480, 0), "original.png"))
+	if original == 0 {
+		t.Fatal("пустой хэш изображения")
+	}
+
+	tests := []struct {
+		name    string
+		path    string
+		maxDist int // для копий: не больше, для других картинок: больше
+		same    bool
+	}{
+		{name: "тот же файл", path: savePNG(t, testImage(640, 480, 0), "copy.png"), maxDist: 0, same: true},
+		{name: "уменьшенная копия", path: savePNG(t, testImage(160, 120, 0), "small.png"), maxDist: 5, same: true},
+		{name: "увеличенная копия", path: savePNG(t, testImage(1280, 960, 0), "large.png"), maxDist: 5, same: true},
+		{name: "пересжатый JPEG", path: saveJPEG(t, testImage(640, 480, 0), "recoded.jpg", 40), maxDist: 5, same: true},
+		{name: "уменьшенный JPEG", path: saveJPEG(t, testImage(320, 240, 0), "small.jpg", 60), maxDist: 5, same: true},
+		{name: "другая картинка", path: savePNG(t, testImage(640, 480, 2.5), "other.png"), maxDist: 10, same: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := Distance(original, imageHash(t, tt.path))
+			if tt.same && d > tt.maxDist {
+				t.Errorf("расстояние до копии = %d, want <= %d", d, tt.maxDist)
+			}
+			if !tt.same && d <= tt.maxDist {
+				t.Errorf("расстояние до другой картинки = %d, want > %d", d, tt.maxDist)
+			}
+		})
+	}
+}
+
+func TestImageHashErrors(t *testing.T) {
+	if _, err := ImageHash(filepath.Join(t.TempDir(), "missing.png")); err == nil {
+		t.Error("нет ошибки для отсутствующего файла")
+	}
+
+	path := filepath.Join(t.TempDir(), "broken.png")
+	if err := os.WriteFile(path, []byte("not an image"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ImageHash(path); err == nil {
+		t.Error("нет ошибки для поврежденного файла")
+	}
+}
+
+func TestDownscaleGrayTinyImage(t *testing.T) {
+	// Картинка меньше 9x8 не должна давать деление на ноль
+	img := image.NewGray(image.Rect(0, 0, 3, 2))
+	img.SetGray(2, 1, color.Gray{Y: 255})
+
+	gray := downscaleGray(img, 9, 8)
+	for y, row := range gray {
+		for x, v := range row {
+			if math.IsNaN(v) {
+				t.Fatalf("NaN в точке %d,%d", x, y)
+			}
+		}
+	}
+}
@@ -0,0 +1,480 @@
+// Package filter реализует язык выражений для фильтрации сообщений в правилах парсинга.
+//
+// Примеры выражений:
+//
+//	акции AND (скидка OR распродажа) AND NOT реклама
+//	"черная пятница" OR /промо-?код/i
+//	акци* AND media:photo AND length>=100
+//	has_link AND NOT forwarded_from:@spam_channel
+//
+// Слова и фразы в кавычках ищутся целиком без учета регистра, слово со звездочкой
+// в конце ищется как начало слова, text:подстрока ищет подстроку.
+// Соседние условия без оператора объединяются через AND.
+package filter
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Message данные сообщения, доступные выражению
+type Message struct {
+	Text          string
+	MediaTypes    []string // типы медиа всех частей сообщения
+	HasLink       bool
+	ForwardedFrom []string // источники пересылки: @username, название, ID; пусто - не переслано
+}
+
+// Expr скомпилированное выражение фильтра
+type Expr struct {
+	src  string
+	root node
+}
+
+// Compile разбирает и компилирует выражение
+func Compile(src string) (*Expr, error) {
+	root, err := compile(src)
+	if err != nil {
+		if se, ok := err.(*SyntaxError); ok {
+			se.Near = near(src, se.Pos)
+		}
+		return nil, err
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+func compile(src string) (node, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, errorAt(1, "empty expression")
+	}
+
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorAt(t.pos, "unexpected %s", t.describe())
+	}
+	return root, nil
+}
+
+// near возвращает фрагмент выражения начиная с позиции ошибки
+func near(src string, pos int) string {
+	runes := []rune(src)
+	start := pos - 1
+	if start < 0 || start >= len(runes) {
+		return ""
+	}
+	end := start + 20
+	if end > len(runes) {
+		end = len(runes)
+	}
+	return string(runes[start:end])
+}
+
+// Match проверяет, подходит ли сообщение под выражение
+func (e *Expr) Match(msg *Message) bool {
+	return e.root.eval(msg)
+}
+
+// String возвращает исходный текст выражения
+func (e *Expr) String() string {
+	return e.src
+}
+
+// parser рекурсивный разбор выражения:
+//
+//	or      = and { OR and }
+//	and     = not { [AND] not }
+//	not     = NOT not | primary
+//	primary = "(" or ")" | term | field op value | flag
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (node, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek().kind == tokOr {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = orNode{left, right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAnd() (node, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch p.peek().kind {
+		case tokAnd:
+			p.next()
+		case tokWord, tokString, tokRegex, tokLParen, tokNot:
+			// Неявный AND между соседними условиями
+		default:
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = andNode{left, right}
+	}
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		operand, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return notNode{operand}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+
+	switch t.kind {
+	case tokLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokRParen {
+			return nil, errorAt(closing.pos, "expected \")\" to close \"(\" at position %d, got %s", t.pos, closing.describe())
+		}
+		return inner, nil
+
+	case tokString:
+		return phraseTerm(t)
+
+	case tokRegex:
+		return regexTerm(t)
+
+	case tokWord:
+		if p.peek().kind == tokOp {
+			return p.parsePredicate(t)
+		}
+		if n, ok := flagNode(t.text); ok {
+			return n, nil
+		}
+		return wordTerm(t)
+
+	case tokEOF:
+		return nil, errorAt(t.pos, "unexpected end of expression, expected a term")
+
+	default:
+		return nil, errorAt(t.pos, "unexpected %s, expected a term", t.describe())
+	}
+}
+
+// parsePredicate разбирает условие вида поле:значение или поле>=значение
+func (p *parser) parsePredicate(field token) (node, error) {
+	op := p.next()
+	value := p.next()
+	if value.kind != tokWord && value.kind != tokString && value.kind != tokRegex {
+		return nil, errorAt(value.pos, "expected a value after %q, got %s", field.text+op.text, value.describe())
+	}
+
+	name := strings.ToLower(field.text)
+	switch name {
+	case "text":
+		if op.text != ":" {
+			return nil, errorAt(op.pos, "field %q supports only \":\"", name)
+		}
+		if value.kind == tokRegex {
+			return regexTerm(value)
+		}
+		return containsNode{strings.ToLower(value.text)}, nil
+
+	case "media", "type":
+		negate, err := equalityOp(op, name)
+		if err != nil {
+			return nil, err
+		}
+		mediaType := strings.ToLower(value.text)
+		if !knownMediaTypes[mediaType] {
+			return nil, errorAt(value.pos, "unknown media type %q", value.text)
+		}
+		return withNegate(mediaNode{mediaType}, negate), nil
+
+	case "length", "len":
+		n, err := strconv.Atoi(value.text)
+		if err != nil || value.kind != tokWord || n < 0 {
+			return nil, errorAt(value.pos, "length must be a non-negative integer, got %s", value.describe())
+		}
+		return lengthNode{op: op.text, n: n}, nil
+
+	case "forwarded_from", "from":
+		negate, err := equalityOp(op, name)
+		if err != nil {
+			return nil, err
+		}
+		return withNegate(forwardedFromNode{normalizeSource(value.text)}, negate), nil
+
+	case "has_link", "has_media", "forwarded":
+		negate, err := equalityOp(op, name)
+		if err != nil {
+			return nil, err
+		}
+		b, err := strconv.ParseBool(strings.ToLower(value.text))
+		if err != nil {
+			return nil, errorAt(value.pos, "field %q expects true or false, got %s", name, value.describe())
+		}
+		n, _ := flagNode(name)
+		return withNegate(n, negate == b), nil
+
+	default:
+		return nil, errorAt(field.pos, "unknown field %q", field.text)
+	}
+}
+
+// equalityOp проверяет, что оператор - сравнение на равенство, и возвращает признак отрицания
+func equalityOp(op token, field string) (bool, error) {
+	switch op.text {
+	case ":", "=":
+		return false, nil
+	case "!=":
+		return true, nil
+	default:
+		return false, errorAt(op.pos, "field %q supports only \":\", \"=\" and \"!=\"", field)
+	}
+}
+
+func withNegate(n node, negate bool) node {
+	if negate {
+		return notNode{n}
+	}
+	return n
+}
+
+// knownMediaTypes типы медиа, которые можно указать в условии media
+var knownMediaTypes = map[string]bool{
+	"text":     true,
+	"photo":    true,
+	"video":    true,
+	"document": true,
+	"voice":    true,
+	"sticker":  true,
+}
+
+// flagNode возвращает условие для логического поля, указанного без значения
+func flagNode(name string) (node, bool) {
+	switch strings.ToLower(name) {
+	case "has_link":
+		return hasLinkNode{}, true
+	case "has_media":
+		return hasMediaNode{}, true
+	case "forwarded":
+		return forwardedNode{}, true
+	}
+	return nil, false
+}
+
+// boundary граница слова для регулярных выражений
+const boundary = `[^\p{L}\p{N}_]`
+
+// isWordChar проверяет, является ли символ частью слова
+func isWordChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_'
+}
+
+// wordTerm ищет слово целиком, слово со звездочкой в конце - как начало слова
+func wordTerm(t token) (node, error) {
+	word := t.text
+	prefix := strings.HasSuffix(word, "*")
+	if prefix {
+		word = strings.TrimSuffix(word, "*")
+		if word == "" {
+			return nil, errorAt(t.pos, "prefix must not be empty")
+		}
+	}
+	return compileWords(t, word, !prefix)
+}
+
+// phraseTerm ищет фразу целиком, пробелы во фразе совпадают с любыми пробельными символами
+func phraseTerm(t token) (node, error) {
+	if strings.TrimSpace(t.text) == "" {
+		return nil, errorAt(t.pos, "empty phrase")
+	}
+	return compileWords(t, t.text, true)
+}
+
+// compileWords строит регулярное выражение для поиска слов с учетом границ слова
+func compileWords(t token, text string, wholeEnd bool) (node, error) {
+	fields := strings.Fields(text)
+	quoted := make([]string, len(fields))
+	for i, f := range fields {
+		quoted[i] = regexp.QuoteMeta(f)
+	}
+	pattern := strings.Join(quoted, `\s+`)
+
+	joined := strings.Join(fields, " ")
+	first, _ := utf8.DecodeRuneInString(joined)
+	last, _ := utf8.DecodeLastRuneInString(joined)
+	if isWordChar(first) {
+		pattern = `(?:^|` + boundary + `)` + pattern
+	}
+	if wholeEnd && isWordChar(last) {
+		pattern = pattern + `(?:$|` + boundary + `)`
+	}
+
+	re, err := regexp.Compile(`(?i)` + pattern)
+	if err != nil {
+		return nil, errorAt(t.pos, "invalid term %s: %v", t.describe(), err)
+	}
+	return regexNode{re}, nil
+}
+
+// regexTerm компилирует регулярное выражение с флагами i, m, s
+func regexTerm(t token) (node, error) {
+	for _, f := range t.flags {
+		if !strings.ContainsRune("ims", f) {
+			return nil, errorAt(t.pos, "unknown regular expression flag %q", string(f))
+		}
+	}
+
+	pattern := t.text
+	if t.flags != "" {
+		pattern = "(?" + t.flags + ")" + pattern
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, errorAt(t.pos, "invalid regular expression: %v", err)
+	}
+	return regexNode{re}, nil
+}
+
+// normalizeSource приводит источник пересылки к виду для сравнения
+func normalizeSource(source string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(source), "@"))
+}
+
+// node узел скомпилированного выражения
+type node interface {
+	eval(msg *Message) bool
+}
+
+type andNode struct{ left, right node }
+
+func (n andNode) eval(msg *Message) bool { return n.left.eval(msg) && n.right.eval(msg) }
+
+type orNode struct{ left, right node }
+
+func (n orNode) eval(msg *Message) bool { return n.left.eval(msg) || n.right.eval(msg) }
+
+type notNode struct{ operand node }
+
+func (n notNode) eval(msg *Message) bool { return !n.operand.eval(msg) }
+
+type regexNode struct{ re *regexp.Regexp }
+
+func (n regexNode) eval(msg *Message) bool { return n.re.MatchString(msg.Text) }
+
+type containsNode struct{ substr string }
+
+func (n containsNode) eval(msg *Message) bool {
+	return strings.Contains(strings.ToLower(msg.Text), n.substr)
+}
+
+type mediaNode struct{ mediaType string }
+
+func (n mediaNode) eval(msg *Message) bool {
+	if len(msg.MediaTypes) == 0 {
+		return n.mediaType == "text"
+	}
+	for _, mt := range msg.MediaTypes {
+		if mt == n.mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// lengthNode сравнивает длину текста в символах
+type lengthNode struct {
+	op string
+	n  int
+}
+
+func (n lengthNode) eval(msg *Message) bool {
+	length := utf8.RuneCountInString(msg.Text)
+	switch n.op {
+	case "<":
+		return length < n.n
+	case "<=":
+		return length <= n.n
+	case ">":
+		return length > n.n
+	case ">=":
+		return length >= n.n
+	case "!=":
+		return length != n.n
+	default:
+		return length == n.n
+	}
+}
+
+type hasLinkNode struct{}
+
+func (hasLinkNode) eval(msg *Message) bool { return msg.HasLink }
+
+type hasMediaNode struct{}
+
+func (hasMediaNode) eval(msg *Message) bool {
+	for _, mt := range msg.MediaTypes {
+		if mt != "text" {
+			return true
+		}
+	}
+	return false
+}
+
+type forwardedNode struct{}
+
+func (forwardedNode) eval(msg *Message) bool { return len(msg.ForwardedFrom) > 0 }
+
+type forwardedFromNode struct{ source string }
+
+func (n forwardedFromNode) eval(msg *Message) bool {
+	for _, source := range msg.ForwardedFrom {
+		if normalizeSource(source) == n.source {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,126 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		expr string
+		msg  Message
+		want bool
+	}{
+		// Слова ищутся целиком и без учета регистра
+		{expr: "акция", msg: Message{Text: "Большая АКЦИЯ сегодня"}, want: true},
+		{expr: "акция", msg: Message{Text: "акциями"}, want: false},
+		{expr: "акци*", msg: Message{Text: "акциями"}, want: true},
+		{expr: "акци*", msg: Message{Text: "реакция"}, want: false},
+		{expr: `"черная пятница"`, msg: Message{Text: "Черная\nпятница!"}, want: true},
+		{expr: `"черная пятница"`, msg: Message{Text: "черная суббота, пятница"}, want: false},
+		{expr: "text:кци", msg: Message{Text: "АКЦИЯ"}, want: true},
+		{expr: "/промо-?код/i", msg: Message{Text: "ПРОМОКОД"}, want: true},
+		{expr: "/промо-?код/", msg: Message{Text: "ПРОМОКОД"}, want: false},
+		{expr: "text:/^a/", msg: Message{Text: "abc"}, want: true},
+
+		// AND связывает сильнее OR, NOT сильнее AND
+		{expr: "a OR b AND c", msg: Message{Text: "a"}, want: true},
+		{expr: "a OR b AND c", msg: Message{Text: "b"}, want: false},
+		{expr: "(a OR b) AND c", msg: Message{Text: "a"}, want: false},
+		{expr: "(a OR b) AND c", msg: Message{Text: "b c"}, want: true},
+		{expr: "NOT a OR b", msg: Message{Text: "b"}, want: true},
+		{expr: "NOT a OR b", msg: Message{Text: "a"}, want: false},
+		{expr: "NOT (a OR b)", msg: Message{Text: "b"}, want: false},
+		{expr: "!!a", msg: Message{Text: "a"}, want: true},
+
+		// Соседние условия объединяются через AND
+		{expr: "a b", msg: Message{Text: "a"}, want: false},
+		{expr: "a b", msg: Message{Text: "b a"}, want: true},
+		{expr: "a NOT b", msg: Message{Text: "a"}, want: true},
+
+		// Поля
+		{expr: "media:photo", msg: Message{MediaTypes: []string{"video", "photo"}}, want: true},
+		{expr: "media:text", msg: Message{Text: "x"}, want: true},
+		{expr: "media!=photo", msg: Message{MediaTypes: []string{"photo"}}, want: false},
+		{expr: "length>=3", msg: Message{Text: "абв"}, want: true},
+		{expr: "length>3", msg: Message{Text: "абв"}, want: false},
+		{expr: "len<=2", msg: Message{Text: "абв"}, want: false},
+		{expr: "length!=3", msg: Message{Text: "ab"}, want: true},
+		{expr: "length=2", msg: Message{Text: "ab"}, want: true},
+		{expr: "has_link", msg: Message{HasLink: true}, want: true},
+		{expr: "has_link:false", msg: Message{HasLink: true}, want: false},
+		{expr: "has_link!=false", msg: Message{HasLink: true}, want: true},
+		{expr: "has_media", msg: Message{MediaTypes: []string{"text"}}, want: false},
+		{expr: "forwarded", msg: Message{ForwardedFrom: []string{"@news"}}, want: true},
+		{expr: "forwarded_from:@News", msg: Message{ForwardedFrom: []string{"news"}}, want: true},
+		{expr: "NOT from:@spam", msg: Message{ForwardedFrom: []string{"@other"}}, want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := Compile(tt.expr)
+			if err != nil {
+				t.Fatalf("Compile(%q) error: %v", tt.expr, err)
+			}
+			msg := tt.msg
+			if got := expr.Match(&msg); got != tt.want {
+				t.Errorf("Compile(%q).Match(%+v) = %v, want %v", tt.expr, tt.msg, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+		near string
+	}{
+		{expr: "", pos: 1},
+		{expr: "(a OR b", pos: 8},
+		{expr: "a OR", pos: 5},
+		{expr: "a OR )", pos: 6, near: ")"},
+		{expr: "a )", pos: 3, near: ")"},
+		{expr: "AND a", pos: 1, near: "AND a"},
+		{expr: "foo:bar", pos: 1, near: "foo:bar"},
+		{expr: "media:gif", pos: 7, near: "gif"},
+		{expr: "media>photo", pos: 6, near: ">photo"},
+		{expr: "length>=abc", pos: 9, near: "abc"},
+		{expr: "length>=-1", pos: 9, near: "-1"},
+		{expr: "text>x", pos: 5, near: ">x"},
+		{expr: "has_link:maybe", pos: 10, near: "maybe"},
+		{expr: "length:", pos: 8},
+		{expr: `""`, pos: 1, near: `""`},
+		{expr: "*", pos: 1, near: "*"},
+		{expr: "/a/x", pos: 1, near: "/a/x"},
+		{expr: "/(/", pos: 1, near: "/(/"},
+		{expr: "акция OR (скидка", pos: 17},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			_, err := Compile(tt.expr)
+			var se *SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("Compile(%q) error = %v, want SyntaxError", tt.expr, err)
+			}
+			if se.Pos != tt.pos {
+				t.Errorf("Compile(%q) error position = %d, want %d (%v)", tt.expr, se.Pos, tt.pos, se)
+			}
+			if se.Near != tt.near {
+				t.Errorf("Compile(%q) error near = %q, want %q", tt.expr, se.Near, tt.near)
+			}
+		})
+	}
+}
+
+func TestExprString(t *testing.T) {
+	const src = "a AND (b OR c)"
+	expr, err := Compile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := expr.String(); got != src {
+		t.Errorf("String() = %q, want %q", got, src)
+	}
+}
@@ -0,0 +1,204 @@
+package filter
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// tokenKind тип лексемы выражения
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+	tokWord
+	tokString
+	tokRegex
+	tokOp
+)
+
+// token лексема выражения. pos - позиция первого символа (с 1)
+type token struct {
+	kind  tokenKind
+	text  string
+	flags string // флаги регулярного выражения
+	pos   int
+}
+
+// describe возвращает описание лексемы для сообщений об ошибках
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of expression"
+	case tokString:
+		return fmt.Sprintf("%q", t.text)
+	case tokRegex:
+		return "/" + t.text + "/"
+	default:
+		return fmt.Sprintf("%q", t.text)
+	}
+}
+
+// SyntaxError ошибка разбора выражения с позицией символа (с 1)
+type SyntaxError struct {
+	Pos  int
+	Msg  string
+	Near string // фрагмент выражения с места ошибки
+}
+
+func (e *SyntaxError) Error() string {
+	if e.Near != "" {
+		return fmt.Sprintf("position %d: %s (near %q)", e.Pos, e.Msg, e.Near)
+	}
+	return fmt.Sprintf("position %d: %s", e.Pos, e.Msg)
+}
+
+func errorAt(pos int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// isWordRune проверяет, может ли символ входить в слово без кавычек
+func isWordRune(r rune) bool {
+	if unicode.IsSpace(r) {
+		return false
+	}
+	return !strings.ContainsRune(`()"':<>=!&|`, r)
+}
+
+// lex разбивает выражение на лексемы
+func lex(src string) ([]token, error) {
+	runes := []rune(src)
+	var tokens []token
+
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		pos := i + 1
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+
+		case r == '(':
+			tokens = append(tokens, token{kind: tokLParen, text: "(", pos: pos})
+			i++
+
+		case r == ')':
+			tokens = append(tokens, token{kind: tokRParen, text: ")", pos: pos})
+			i++
+
+		case r == '&' || r == '|':
+			if i+1 >= len(runes) || runes[i+1] != r {
+				return nil, errorAt(pos, "unexpected %q, use %q", string(r), string([]rune{r, r}))
+			}
+			kind := tokAnd
+			if r == '|' {
+				kind = tokOr
+			}
+			tokens = append(tokens, token{kind: kind, text: string([]rune{r, r}), pos: pos})
+			i += 2
+
+		case r == '!' && (i+1 >= len(runes) || runes[i+1] != '='):
+			tokens = append(tokens, token{kind: tokNot, text: "!", pos: pos})
+			i++
+
+		case r == ':' || r == '=' || r == '!' || r == '<' || r == '>':
+			op := string(r)
+			if i+1 < len(runes) && runes[i+1] == '=' && r != ':' && r != '=' {
+				op += "="
+			}
+			tokens = append(tokens, token{kind: tokOp, text: op, pos: pos})
+			i += len([]rune(op))
+
+		case r == '"' || r == '\'':
+			text, next, err := lexQuoted(runes, i, r)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokString, text: text, pos: pos})
+			i = next
+
+		case r == '/':
+			pattern, flags, next, err := lexRegex(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokRegex, text: pattern, flags: flags, pos: pos})
+			i = next
+
+		default:
+			start := i
+			for i < len(runes) && isWordRune(runes[i]) {
+				i++
+			}
+			word := string(runes[start:i])
+
+			kind := tokWord
+			switch strings.ToUpper(word) {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			tokens = append(tokens, token{kind: kind, text: word, pos: pos})
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokEOF, pos: len(runes) + 1})
+	return tokens, nil
+}
+
+// lexQuoted читает строку в кавычках, начиная с открывающей кавычки
+func lexQuoted(runes []rune, start int, quote rune) (string, int, error) {
+	var sb strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			if i+1 < len(runes) {
+				i++
+				sb.WriteRune(runes[i])
+			}
+		case quote:
+			return sb.String(), i + 1, nil
+		default:
+			sb.WriteRune(runes[i])
+		}
+	}
+	return "", 0, errorAt(start+1, "unterminated string")
+}
+
+// lexRegex читает регулярное выражение /.../флаги, начиная с первого слэша
+func lexRegex(runes []rune, start int) (string, string, int, error) {
+	var sb strings.Builder
+	for i := start + 1; i < len(runes); i++ {
+		switch runes[i] {
+		case '\\':
+			// \/ - экранированный слэш, остальные экранирования передаются как есть
+			if i+1 < len(runes) && runes[i+1] == '/' {
+				sb.WriteRune('/')
+				i++
+				continue
+			}
+			sb.WriteRune('\\')
+			if i+1 < len(runes) {
+				i++
+				sb.WriteRune(runes[i])
+			}
+		case '/':
+			j := i + 1
+			for j < len(runes) && unicode.IsLetter(runes[j]) {
+				j++
+			}
+			return sb.String(), string(runes[i+1 : j]), j, nil
+		default:
+			sb.WriteRune(runes[i])
+		}
+	}
+	return "", "", 0, errorAt(start+1, "unterminated regular expression")
+}
@@ -0,0 +1,137 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestLex(t *testing.T) {
+	tests := []struct {
+		name   string
+		src    string
+		tokens []token
+	}{
+		{
+			name: "keywords in any case",
+			src:  "a AND b or NOT c",
+			tokens: []token{
+				{kind: tokWord, text: "a", pos: 1},
+				{kind: tokAnd, text: "AND", pos: 3},
+				{kind: tokWord, text: "b", pos: 7},
+				{kind: tokOr, text: "or", pos: 9},
+				{kind: tokNot, text: "NOT", pos: 12},
+				{kind: tokWord, text: "c", pos: 16},
+				{kind: tokEOF, pos: 17},
+			},
+		},
+		{
+			name: "symbolic operators",
+			src:  "a&&b||!c",
+			tokens: []token{
+				{kind: tokWord, text: "a", pos: 1},
+				{kind: tokAnd, text: "&&", pos: 2},
+				{kind: tokWord, text: "b", pos: 4},
+				{kind: tokOr, text: "||", pos: 5},
+				{kind: tokNot, text: "!", pos: 7},
+				{kind: tokWord, text: "c", pos: 8},
+				{kind: tokEOF, pos: 9},
+			},
+		},
+		{
+			name: "comparison operators",
+			src:  "length>=10 media!=photo len<5 from:x",
+			tokens: []token{
+				{kind: tokWord, text: "length", pos: 1},
+				{kind: tokOp, text: ">=", pos: 7},
+				{kind: tokWord, text: "10", pos: 9},
+				{kind: tokWord, text: "media", pos: 12},
+				{kind: tokOp, text: "!=", pos: 17},
+				{kind: tokWord, text: "photo", pos: 19},
+				{kind: tokWord, text: "len", pos: 25},
+				{kind: tokOp, text: "<", pos: 28},
+				{kind: tokWord, text: "5", pos: 29},
+				{kind: tokWord, text: "from", pos: 31},
+				{kind: tokOp, text: ":", pos: 35},
+				{kind: tokWord, text: "x", pos: 36},
+				{kind: tokEOF, pos: 37},
+			},
+		},
+		{
+			name: "quoted strings with escapes",
+			src:  `"a \"b\"" 'c d'`,
+			tokens: []token{
+				{kind: tokString, text: `a "b"`, pos: 1},
+				{kind: tokString, text: "c d", pos: 11},
+				{kind: tokEOF, pos: 16},
+			},
+		},
+		{
+			name: "regex with escaped slash and flags",
+			src:  `/a\/b\d/is`,
+			tokens: []token{
+				{kind: tokRegex, text: `a/b\d`, flags: "is", pos: 1},
+				{kind: tokEOF, pos: 11},
+			},
+		},
+		{
+			name: "positions count runes, not bytes",
+			src:  "(акция OR скидка)",
+			tokens: []token{
+				{kind: tokLParen, text: "(", pos: 1},
+				{kind: tokWord, text: "акция", pos: 2},
+				{kind: tokOr, text: "OR", pos: 8},
+				{kind: tokWord, text: "скидка", pos: 11},
+				{kind: tokRParen, text: ")", pos: 17},
+				{kind: tokEOF, pos: 18},
+			},
+		},
+		{
+			name:   "empty",
+			src:    "   ",
+			tokens: []token{{kind: tokEOF, pos: 4}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := lex(tt.src)
+			if err != nil {
+				t.Fatalf("lex(%q) error: %v", tt.src, err)
+			}
+			if len(got) != len(tt.tokens) {
+				t.Fatalf("lex(%q) = %+v, want %+v", tt.src, got, tt.tokens)
+			}
+			for i := range got {
+				if got[i] != tt.tokens[i] {
+					t.Errorf("lex(%q) token %d = %+v, want %+v", tt.src, i, got[i], tt.tokens[i])
+				}
+			}
+		})
+	}
+}
+
+func TestLexErrors(t *testing.T) {
+	tests := []struct {
+		src string
+		pos int
+	}{
+		{src: "a & b", pos: 3},
+		{src: "a | b", pos: 3},
+		{src: `x "abc`, pos: 3},
+		{src: `'abc`, pos: 1},
+		{src: "акция /abc", pos: 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.src, func(t *testing.T) {
+			_, err := lex(tt.src)
+			var se *SyntaxError
+			if !errors.As(err, &se) {
+				t.Fatalf("lex(%q) error = %v, want SyntaxError", tt.src, err)
+			}
+			if se.Pos != tt.pos {
+				t.Errorf("lex(%q) error position = %d, want %d (%v)", tt.src, se.Pos, tt.pos, se)
+			}
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package media
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ErrTooLarge возвращается, если файл превышает допустимый размер кэша
+var ErrTooLarge = errors.New("файл превышает допустимый размер")
+
+// Cache локальный кэш медиафайлов с адресацией по содержимому.
+// Имя файла - SHA-256 его содержимого, поэтому один и тот же файл
+// из разных сообщений хранится один раз.
+type Cache struct {
+	dir     string
+	maxSize int64
+}
+
+// NewCache создает кэш в указанной директории. maxSize - максимальный
+// размер одного файла в байтах, 0 - без ограничения.
+func NewCache(dir string, maxSize int64) (*Cache, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("директория кэша медиа не указана")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("ошибка создания директории кэша медиа: %v", err)
+	}
+
+	return &Cache{
+		dir:     dir,
+		maxSize: maxSize,
+	}, nil
+}
+
+// MaxSize возвращает максимальный размер одного файла
+func (c *Cache) MaxSize() int64 {
+	return c.maxSize
+}
+
+// Fits проверяет, помещается ли файл заданного размера в ограничение кэша
+func (c *Cache) Fits(size int64) bool {
+	return c.maxSize <= 0 || size <= c.maxSize
+}
+
+// Write сохраняет в кэш содержимое, записанное функцией fill, и возвращает
+// путь к файлу. ext - расширение файла вместе с точкой.
+func (c *Cache) Write(ext string, fill func(w io.Writer) error) (string, error) {
+	tmp, err := os.CreateTemp(c.dir, "download-*")
+	if err != nil {
+		return "", fmt.Errorf("ошибка создания временного файла: %v", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath)
+
+	hash := sha256.New()
+	w := &limitedWriter{w: io.MultiWriter(tmp, hash), limit: c.maxSize}
+
+	if err := fill(w); err != nil {
+		tmp.Close()
+		if w.exceeded {
+			return "", ErrTooLarge
+		}
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", fmt.Errorf("ошибка записи временного файла: %v", err)
+	}
+	if w.written == 0 {
+		return "", fmt.Errorf("файл пустой")
+	}
+
+	key := hex.EncodeToString(hash.Sum(nil))
+	path := filepath.Join(c.dir, key[:2], key+strings.ToLower(ext))
+
+	// Файл с таким содержимым уже есть - обновляем время, чтобы его не удалила очистка
+	if _, err := os.Stat(path); err == nil {
+		now := time.Now()
+		_ = os.Chtimes(path, now, now)
+		return path, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("ошибка создания директории кэша: %v", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return "", fmt.Errorf("ошибка сохранения файла в кэш: %v", err)
+	}
+
+	return path, nil
+}
+
+// HeldFiles возвращает файлы кэша, которые еще понадобятся: медиа постов,
+// ожидающих модерации или публикации
+type HeldFiles func(ctx context.Context) ([]string, error)
+
+// Cleanup удаляет файлы, которые не использовались дольше maxAge, кроме
+// файлов из held. Возвращает количество удаленных файлов.
+func (c *Cache) Cleanup(maxAge time.Duration, held []string) (int, error) {
+	deadline := time.Now().Add(-maxAge)
+	removed := 0
+
+	keep := make(map[string]bool, len(held))
+	for _, path := range held {
+		keep[filepath.Clean(path)] = true
+	}
+
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || keep[filepath.Clean(path)] {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return nil
+		}
+		if info.ModTime().Before(deadline) {
+			if err := os.Remove(path); err == nil {
+				removed++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return removed, fmt.Errorf("ошибка очистки кэша медиа: %v", err)
+	}
+
+	return removed, nil
+}
+
+// RunCleanup периодически очищает кэш до отмены контекста. Если список
+// нужных файлов получить не удалось, очистка откладывается до следующего раза.
+func (c *Cache) RunCleanup(ctx context.Context, interval, maxAge time.Duration, held HeldFiles, logger *zap.SugaredLogger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			files, err := held(ctx)
+			if err != nil {
+				logger.Errorf("❌ Очистка кэша медиа отложена: %v", err)
+				continue
+			}
+			removed, err := c.Cleanup(maxAge, files)
+			if err != nil {
+				logger.Errorf("❌ %v", err)
+				continue
+			}
+			if removed > 0 {
+				logger.Infof("🧹 Удалено %d файлов из кэша медиа", removed)
+			}
+		}
+	}
+}
+
+// limitedWriter прерывает запись при превышении лимита
+type limitedWriter struct {
+	w        io.Writer
+	limit    int64
+	written  int64
+	exceeded bool
+}
+
+func (l *limitedWriter) Write(p []byte) (int, error) {
+	if l.limit > 0 && l.written+int64(len(p)) > l.limit {
+		l.exceeded = true
+		return 0, ErrTooLarge
+	}
+	n, err := l.w.Write(p)
+	l.written += int64(n)
+	return n, err
+}
@@ -0,0 +1,55 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// AccountStatus состояние аккаунта Telegram в пуле парсера
+type AccountStatus string
+
+const (
+	AccountActive       AccountStatus = "active"       // читает каналы
+	AccountFloodWait    AccountStatus = "flood_wait"   // ограничен сервером до FloodWaitUntil
+	AccountUnauthorized AccountStatus = "unauthorized" // сессия отозвана или аккаунт заблокирован
+)
+
+// TelegramAccount аккаунт Telegram, от имени которого парсер читает каналы.
+// У каждого аккаунта своя сессия MTProto.
+type TelegramAccount struct {
+	ID             int64         `json:"id"`
+	Phone          string        `json:"phone"`
+	SessionFile    string        `json:"session_file"`
+	Status         AccountStatus `json:"status"`
+	FloodWaitUntil *time.Time    `json:"flood_wait_until,omitempty"`
+	LastError      string        `json:"last_error"`
+	IsActive       bool          `json:"is_active"` // false - аккаунт выключен вручную
+	CreatedAt      time.Time     `json:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at"`
+}
+
+// Validate проверяет параметры аккаунта
+func (a *TelegramAccount) Validate() error {
+	if a.Phone == "" {
+		return errors.New("phone is required")
+	}
+	if a.SessionFile == "" {
+		return errors.New("session_file is required")
+	}
+	return nil
+}
+
+// Available проверяет, можно ли назначать аккаунту каналы
+func (a *TelegramAccount) Available(now time.Time) bool {
+	if !a.IsActive {
+		return false
+	}
+	switch a.Status {
+	case AccountUnauthorized:
+		return false
+	case AccountFloodWait:
+		return a.FloodWaitUntil == nil || !now.Before(*a.FloodWaitUntil)
+	default:
+		return true
+	}
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// AuthState этап входа аккаунта в Telegram
+type AuthState string
+
+const (
+	AuthUnknown          AuthState = "unknown"           // клиент аккаунта еще не запускался
+	AuthWaiting          AuthState = "waiting"           // сессии нет, клиент ждет начала входа
+	AuthRequested        AuthState = "requested"         // вход начат через API, клиент отправляет код
+	AuthAwaitingCode     AuthState = "awaiting_code"     // код отправлен, клиент ждет код
+	AuthAwaitingPassword AuthState = "awaiting_password" // включена двухэтапная проверка, клиент ждет пароль
+	AuthAwaitingQR       AuthState = "awaiting_qr"       // клиент ждет сканирования QR-кода
+	AuthAuthorized       AuthState = "authorized"        // вход выполнен
+	AuthFailed           AuthState = "failed"            // вход не удался, его можно начать заново
+)
+
+// AuthMethod способ входа в Telegram
+type AuthMethod string
+
+const (
+	AuthByCode AuthMethod = "code" // код из Telegram или SMS
+	AuthByQR   AuthMethod = "qr"   // QR-код, который сканируют в приложении Telegram
+)
+
+// Valid проверяет, известен ли способ входа
+func (m AuthMethod) Valid() bool {
+	return m == AuthByCode || m == AuthByQR
+}
+
+// TelegramAuth состояние входа аккаунта. Вход ведет клиент парсера,
+// администратор передает ему код и пароль через API. Код и пароль
+// в ответы API не попадают.
+type TelegramAuth struct {
+	AccountID    int64      `json:"account_id"`
+	State        AuthState  `json:"state"`
+	Method       AuthMethod `json:"method,omitempty"`
+	CodeType     string     `json:"code_type,omitempty"`     // куда отправлен код: app, sms, call...
+	PasswordHint string     `json:"password_hint,omitempty"` // подсказка к паролю двухэтапной проверки
+	QRURL        string     `json:"qr_url,omitempty"`        // ссылка tg://login для QR-кода
+	QRExpiresAt  *time.Time `json:"qr_expires_at,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+}
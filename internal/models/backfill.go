@@ -0,0 +1,75 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// BackfillMode что делать с сообщениями, импортированными из истории канала
+type BackfillMode string
+
+const (
+	BackfillPublish BackfillMode = "publish" // как новые сообщения: модерация и расписание правила
+	BackfillQueue   BackfillMode = "queue"   // в очередь модерации, публикуются после одобрения
+	BackfillArchive BackfillMode = "archive" // только сохраняются, без публикации
+)
+
+// BackfillStatus статус импорта истории
+type BackfillStatus string
+
+const (
+	BackfillPending   BackfillStatus = "pending"   // ждет запуска или продолжения
+	BackfillRunning   BackfillStatus = "running"   // выполняется
+	BackfillDone      BackfillStatus = "done"      // весь период импортирован
+	BackfillFailed    BackfillStatus = "failed"    // остановлен с ошибкой
+	BackfillCancelled BackfillStatus = "cancelled" // отменен пользователем
+)
+
+// BackfillJob импорт истории канала правила за период [From, To).
+// CursorID - последнее обработанное сообщение: прерванный импорт
+// продолжается со следующего.
+type BackfillJob struct {
+	ID         int64          `json:"id"`
+	RuleID     int64          `json:"rule_id"`
+	From       time.Time      `json:"from"`
+	To         time.Time      `json:"to"`
+	Mode       BackfillMode   `json:"mode"`
+	Status     BackfillStatus `json:"status"`
+	CursorID   int64          `json:"cursor_id"`
+	Fetched    int            `json:"fetched"`  // получено сообщений из периода
+	Imported   int            `json:"imported"` // сохранено постов
+	LastError  string         `json:"last_error"`
+	CreatedBy  string         `json:"created_by"`
+	CreatedAt  time.Time      `json:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at"`
+	FinishedAt *time.Time     `json:"finished_at,omitempty"`
+}
+
+// Validate проверяет параметры импорта
+func (j *BackfillJob) Validate() error {
+	if j.RuleID <= 0 {
+		return errors.New("rule_id is required")
+	}
+	if j.From.IsZero() || j.To.IsZero() {
+		return errors.New("from and to are required")
+	}
+	if !j.From.Before(j.To) {
+		return errors.New("from must be before to")
+	}
+	switch j.Mode {
+	case BackfillPublish, BackfillQueue, BackfillArchive:
+	default:
+		return fmt.Errorf("unknown mode %q", j.Mode)
+	}
+	return nil
+}
+
+// IsFinished проверяет, завершен ли импорт
+func (j *BackfillJob) IsFinished() bool {
+	switch j.Status {
+	case BackfillDone, BackfillFailed, BackfillCancelled:
+		return true
+	}
+	return false
+}
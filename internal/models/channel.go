@@ -0,0 +1,152 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ChannelRefKind способ, которым в правиле указан канал-источник
+type ChannelRefKind string
+
+const (
+	ChannelByUsername ChannelRefKind = "username" // публичный канал: @name, t.me/name
+	ChannelByInvite   ChannelRefKind = "invite"   // приватный канал по приглашению: t.me/+hash, t.me/joinchat/hash
+	ChannelByID       ChannelRefKind = "id"       // канал по ID: -100123, t.me/c/123
+)
+
+// channelIDPrefix префикс, с которым Bot API и клиенты показывают ID каналов
+const channelIDPrefix = "-100"
+
+var (
+	// channelUsernameRe допустимый username канала без @
+	channelUsernameRe = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]{3,31}$`)
+	// inviteHashRe допустимый хэш ссылки-приглашения
+	inviteHashRe = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// ChannelRef разобранный канал-источник правила
+type ChannelRef struct {
+	Kind       ChannelRefKind
+	Username   string // без @, для ChannelByUsername
+	InviteHash string // для ChannelByInvite
+	ChannelID  int64  // ID канала в MTProto (без -100), для ChannelByID
+}
+
+// ParseChannelRef разбирает канал-источник: @username, ссылку t.me/username,
+// ссылку-приглашение t.me/+hash, ссылку t.me/c/<id> или ID вида -100<id>
+func ParseChannelRef(source string) (ChannelRef, error) {
+	source = strings.TrimSpace(source)
+	if source == "" {
+		return ChannelRef{}, errors.New("channel is empty")
+	}
+
+	if strings.HasPrefix(source, channelIDPrefix) {
+		return parseChannelID(strings.TrimPrefix(source, channelIDPrefix), source)
+	}
+
+	link := strings.TrimPrefix(strings.TrimPrefix(source, "https://"), "http://")
+	for _, host := range []string{"www.t.me/", "t.me/", "telegram.me/"} {
+		if !strings.HasPrefix(link, host) {
+			continue
+		}
+
+		path := strings.TrimPrefix(link, host)
+		if i := strings.IndexAny(path, "?#"); i >= 0 {
+			path = path[:i]
+		}
+		parts := strings.Split(strings.Trim(path, "/"), "/")
+		switch {
+		case strings.HasPrefix(parts[0], "+"):
+			return parseInviteHash(strings.TrimPrefix(parts[0], "+"), source)
+		case parts[0] == "joinchat":
+			if len(parts) < 2 {
+				return ChannelRef{}, fmt.Errorf("invalid invite link %q", source)
+			}
+			return parseInviteHash(parts[1], source)
+		case parts[0] == "c" && len(parts) > 1:
+			return parseChannelID(parts[1], source)
+		case parts[0] == "s" && len(parts) > 1:
+			// Веб-превью публичного канала t.me/s/name
+			return parseUsername(parts[1], source)
+		default:
+			return parseUsername(parts[0], source)
+		}
+	}
+
+	if strings.HasPrefix(source, "+") {
+		return parseInviteHash(strings.TrimPrefix(source, "+"), source)
+	}
+	return parseUsername(strings.TrimPrefix(source, "@"), source)
+}
+
+// parseUsername проверяет username канала
+func parseUsername(username, source string) (ChannelRef, error) {
+	if !channelUsernameRe.MatchString(username) {
+		return ChannelRef{}, fmt.Errorf("channel %q must be @username, t.me link, invite link or -100 ID", source)
+	}
+	return ChannelRef{Kind: ChannelByUsername, Username: username}, nil
+}
+
+// parseInviteHash проверяет хэш ссылки-приглашения
+func parseInviteHash(hash, source string) (ChannelRef, error) {
+	if !inviteHashRe.MatchString(hash) {
+		return ChannelRef{}, fmt.Errorf("invalid invite link %q", source)
+	}
+	return ChannelRef{Kind: ChannelByInvite, InviteHash: hash}, nil
+}
+
+// parseChannelID проверяет ID канала без префикса -100
+func parseChannelID(value, source string) (ChannelRef, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return ChannelRef{}, fmt.Errorf("invalid channel ID %q", source)
+	}
+	return ChannelRef{Kind: ChannelByID, ChannelID: id}, nil
+}
+
+// String возвращает канонический вид канала: @username, +hash или -100<id>.
+// Под этим ключом канал хранится в кэше найденных каналов.
+func (r ChannelRef) String() string {
+	switch r.Kind {
+	case ChannelByInvite:
+		return "+" + r.InviteHash
+	case ChannelByID:
+		return channelIDPrefix + strconv.FormatInt(r.ChannelID, 10)
+	default:
+		return "@" + r.Username
+	}
+}
+
+// MessageLink возвращает ссылку на сообщение канала. По ссылке-приглашению
+// ID канала неизвестен, поэтому ссылка не строится.
+func (r ChannelRef) MessageLink(messageID int64) string {
+	if messageID == 0 {
+		return ""
+	}
+	switch r.Kind {
+	case ChannelByUsername:
+		return fmt.Sprintf("https://t.me/%s/%d", r.Username, messageID)
+	case ChannelByID:
+		return fmt.Sprintf("https://t.me/c/%d/%d", r.ChannelID, messageID)
+	default:
+		return ""
+	}
+}
+
+// ResolvedPeer - канал-источник, найденный аккаунтом. Access hash позволяет
+// читать канал без повторного поиска, даже если у канала сменился или пропал
+// username. Access hash у каждого аккаунта свой.
+type ResolvedPeer struct {
+	AccountID  int64     `json:"account_id"`
+	Source     string    `json:"source"` // канонический вид канала из ChannelRef.String
+	ChannelID  int64     `json:"channel_id"`
+	AccessHash int64     `json:"-"`
+	Username   string    `json:"username"` // текущий username без @, пусто - приватный канал
+	Title      string    `json:"title"`
+	ResolvedAt time.Time `json:"resolved_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
@@ -0,0 +1,99 @@
+package models
+
+import "testing"
+
+func TestParseChannelRef(t *testing.T) {
+	username := func(name string) ChannelRef { return ChannelRef{Kind: ChannelByUsername, Username: name} }
+	invite := func(hash string) ChannelRef { return ChannelRef{Kind: ChannelByInvite, InviteHash: hash} }
+	byID := func(id int64) ChannelRef { return ChannelRef{Kind: ChannelByID, ChannelID: id} }
+
+	tests := []struct {
+		source string
+		want   ChannelRef
+		str    string
+	}{
+		{source: "@durov_news", want: username("durov_news"), str: "@durov_news"},
+		{source: "durov_news", want: username("durov_news"), str: "@durov_news"},
+		{source: "  @durov_news  ", want: username("durov_news"), str: "@durov_news"},
+		{source: "t.me/durov_news", want: username("durov_news"), str: "@durov_news"},
+		{source: "https://t.me/durov_news", want: username("durov_news"), str: "@durov_news"},
+		{source: "http://www.t.me/durov_news/", want: username("durov_news"), str: "@durov_news"},
+		{source: "https://telegram.me/durov_news", want: username("durov_news"), str: "@durov_news"},
+		{source: "https://t.me/durov_news/123", want: username("durov_news"), str: "@durov_news"},
+		{source: "https://t.me/durov_news?start=x", want: username("durov_news"), str: "@durov_news"},
+		{source: "https://t.me/s/durov_news", want: username("durov_news"), str: "@durov_news"},
+		{source: "https://t.me/+AbC-d_12", want: invite("AbC-d_12"), str: "+AbC-d_12"},
+		{source: "t.me/joinchat/AbCd12", want: invite("AbCd12"), str: "+AbCd12"},
+		{source: "+AbCd12", want: invite("AbCd12"), str: "+AbCd12"},
+		{source: "-1001234567890", want: byID(1234567890), str: "-1001234567890"},
+		{source: "https://t.me/c/1234567890", want: byID(1234567890), str: "-1001234567890"},
+		{source: "https://t.me/c/1234567890/55", want: byID(1234567890), str: "-1001234567890"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			got, err := ParseChannelRef(tt.source)
+			if err != nil {
+				t.Fatalf("ParseChannelRef(%q) error: %v", tt.source, err)
+			}
+			if got != tt.want {
+				t.Errorf("ParseChannelRef(%q) = %+v, want %+v", tt.source, got, tt.want)
+			}
+			if got.String() != tt.str {
+				t.Errorf("ParseChannelRef(%q).String() = %q, want %q", tt.source, got.String(), tt.str)
+			}
+
+			// Канонический вид разбирается в тот же канал
+			again, err := ParseChannelRef(got.String())
+			if err != nil || again != got {
+				t.Errorf("ParseChannelRef(%q) = %+v, %v, want %+v", got.String(), again, err, got)
+			}
+		})
+	}
+}
+
+func TestParseChannelRefErrors(t *testing.T) {
+	sources := []string{
+		"",
+		"   ",
+		"@abc",           // короче 5 символов
+		"@1channel",      // начинается с цифры
+		"@bad-name",      // недопустимый символ
+		"https://t.me/",  // нет пути
+		"t.me/+",         // пустой хэш
+		"t.me/+bad hash", // пробел в хэше
+		"t.me/joinchat",  // нет хэша
+		"-100",           // нет ID
+		"-100abc",        // ID не число
+		"-100-5",         // отрицательный ID
+		"t.me/c/0",       // нулевой ID
+		"https://example.com/channel",
+	}
+
+	for _, source := range sources {
+		t.Run(source, func(t *testing.T) {
+			if got, err := ParseChannelRef(source); err == nil {
+				t.Errorf("ParseChannelRef(%q) = %+v, want error", source, got)
+			}
+		})
+	}
+}
+
+func TestChannelRefMessageLink(t *testing.T) {
+	tests := []struct {
+		ref  ChannelRef
+		id   int64
+		want string
+	}{
+		{ref: ChannelRef{Kind: ChannelByUsername, Username: "news_chan"}, id: 7, want: "https://t.me/news_chan/7"},
+		{ref: ChannelRef{Kind: ChannelByID, ChannelID: 123}, id: 7, want: "https://t.me/c/123/7"},
+		{ref: ChannelRef{Kind: ChannelByInvite, InviteHash: "abc"}, id: 7, want: ""},
+		{ref: ChannelRef{Kind: ChannelByUsername, Username: "news_chan"}, id: 0, want: ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.ref.MessageLink(tt.id); got != tt.want {
+			t.Errorf("%+v.MessageLink(%d) = %q, want %q", tt.ref, tt.id, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package models
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf16"
+)
+
+// EntityType тип форматирования фрагмента текста
+type EntityType string
+
+const (
+	EntityBold        EntityType = "bold"
+	EntityItalic      EntityType = "italic"
+	EntityUnderline   EntityType = "underline"
+	EntityStrike      EntityType = "strike"
+	EntitySpoiler     EntityType = "spoiler"
+	EntityCode        EntityType = "code"
+	EntityPre         EntityType = "pre"
+	EntityBlockquote  EntityType = "blockquote"
+	EntityTextLink    EntityType = "text_link"
+	EntityMentionName EntityType = "mention_name"
+)
+
+// TextEntity форматирование фрагмента текста сообщения. Offset и Length
+// считаются в UTF-16 единицах, как в Telegram.
+type TextEntity struct {
+	Type     EntityType `json:"type"`
+	Offset   int        `json:"offset"`
+	Length   int        `json:"length"`
+	URL      string     `json:"url,omitempty"`      // для text_link
+	UserID   int64      `json:"user_id,omitempty"`  // для mention_name
+	Language string     `json:"language,omitempty"` // для pre
+}
+
+// End возвращает позицию конца фрагмента
+func (e TextEntity) End() int {
+	return e.Offset + e.Length
+}
+
+// UTF16Len возвращает длину строки в UTF-16 единицах
+func UTF16Len(s string) int {
+	n := 0
+	for _, r := range s {
+		n += utf16.RuneLen(r)
+	}
+	return n
+}
+
+// textEdit замена фрагмента текста в UTF-16 позициях исходного текста
+type textEdit struct {
+	start  int
+	oldLen int
+	newLen int
+}
+
+// shiftPos переносит позицию исходного текста в текст после замен.
+// Позиция внутри замененного фрагмента переносится к его началу, если это
+// начало сущности, и к концу замены, если это конец сущности. Вставленный
+// в позицию начала сущности текст остается вне сущности.
+func shiftPos(pos int, edits []textEdit, isEnd bool) int {
+	shift := 0
+	for _, e := range edits {
+		switch {
+		case pos < e.start:
+			return pos + shift
+		case pos == e.start && (e.oldLen > 0 || isEnd):
+			return pos + shift
+		case pos >= e.start+e.oldLen:
+			shift += e.newLen - e.oldLen
+		default:
+			if isEnd {
+				return e.start + shift + e.newLen
+			}
+			return e.start + shift
+		}
+	}
+	return pos + shift
+}
+
+// applyEdits пересчитывает сущности после замен и удаляет ставшие пустыми
+func applyEdits(entities []TextEntity, edits []textEdit) []TextEntity {
+	if len(edits) == 0 {
+		return entities
+	}
+
+	result := make([]TextEntity, 0, len(entities))
+	for _, e := range entities {
+		start := shiftPos(e.Offset, edits, false)
+		end := shiftPos(e.End(), edits, true)
+		if end <= start {
+			continue
+		}
+		e.Offset = start
+		e.Length = end - start
+		result = append(result, e)
+	}
+	return result
+}
+
+// ReplaceWithEntities заменяет все вхождения old на new и сдвигает сущности
+func ReplaceWithEntities(text, old, new string, entities []TextEntity) (string, []TextEntity) {
+	if old == "" || !strings.Contains(text, old) {
+		return text, entities
+	}
+
+	var edits []textEdit
+	oldLen, newLen := UTF16Len(old), UTF16Len(new)
+	pos, rest := 0, text
+	for {
+		i := strings.Index(rest, old)
+		if i < 0 {
+			break
+		}
+		pos += UTF16Len(rest[:i])
+		edits = append(edits, textEdit{start: pos, oldLen: oldLen, newLen: newLen})
+		pos += oldLen
+		rest = rest[i+len(old):]
+	}
+
+	return strings.ReplaceAll(text, old, new), applyEdits(entities, edits)
+}
+
+// PrependWithEntities добавляет текст в начало и сдвигает сущности
+func PrependWithEntities(text, prefix string, entities []TextEntity) (string, []TextEntity) {
+	if prefix == "" {
+		return text, entities
+	}
+	edits := []textEdit{{start: 0, oldLen: 0, newLen: UTF16Len(prefix)}}
+	return prefix + text, applyEdits(entities, edits)
+}
+
+// SortEntities сортирует сущности по началу, более длинные - раньше,
+// чтобы вложенные сущности шли после внешних
+func SortEntities(entities []TextEntity) []TextEntity {
+	sorted := append([]TextEntity(nil), entities...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Offset != sorted[j].Offset {
+			return sorted[i].Offset < sorted[j].Offset
+		}
+		return sorted[i].Length > sorted[j].Length
+	})
+	return sorted
+}
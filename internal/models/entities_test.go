@@ -0,0 +1,142 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestUTF16Len(t *testing.T) {
+	tests := []struct {
+		s    string
+		want int
+	}{
+		{s: "", want: 0},
+		{s: "abc", want: 3},
+		{s: "привет", want: 6},
+		{s: "😀", want: 2},
+		{s: "a😀b", want: 4},
+	}
+
+	for _, tt := range tests {
+		if got := UTF16Len(tt.s); got != tt.want {
+			t.Errorf("UTF16Len(%q) = %d, want %d", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestReplaceWithEntities(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		old, new     string
+		entities     []TextEntity
+		wantText     string
+		wantEntities []TextEntity
+	}{
+		{
+			name:         "entity covering the replacement grows",
+			text:         "hello world",
+			old:          "world",
+			new:          "there!",
+			entities:     []TextEntity{{Type: EntityBold, Offset: 6, Length: 5}},
+			wantText:     "hello there!",
+			wantEntities: []TextEntity{{Type: EntityBold, Offset: 6, Length: 6}},
+		},
+		{
+			name:         "entity after the replacement shifts in UTF-16 units",
+			text:         "😀 a b",
+			old:          "a",
+			new:          "xyz",
+			entities:     []TextEntity{{Type: EntityItalic, Offset: 5, Length: 1}},
+			wantText:     "😀 xyz b",
+			wantEntities: []TextEntity{{Type: EntityItalic, Offset: 7, Length: 1}},
+		},
+		{
+			name:         "entity of a removed fragment is dropped",
+			text:         "a bad b",
+			old:          "bad",
+			new:          "",
+			entities:     []TextEntity{{Type: EntityBold, Offset: 2, Length: 3}},
+			wantText:     "a  b",
+			wantEntities: []TextEntity{},
+		},
+		{
+			name:         "entity ending inside the replacement ends after it",
+			text:         "abcdef",
+			old:          "cd",
+			new:          "X",
+			entities:     []TextEntity{{Type: EntityBold, Offset: 0, Length: 3}},
+			wantText:     "abXef",
+			wantEntities: []TextEntity{{Type: EntityBold, Offset: 0, Length: 3}},
+		},
+		{
+			name:         "every occurrence is shifted",
+			text:         "a-a-a",
+			old:          "a",
+			new:          "bb",
+			entities:     []TextEntity{{Type: EntityCode, Offset: 2, Length: 1}},
+			wantText:     "bb-bb-bb",
+			wantEntities: []TextEntity{{Type: EntityCode, Offset: 3, Length: 2}},
+		},
+		{
+			name:         "no occurrence leaves entities untouched",
+			text:         "abc",
+			old:          "x",
+			new:          "y",
+			entities:     []TextEntity{{Type: EntityBold, Offset: 1, Length: 1}},
+			wantText:     "abc",
+			wantEntities: []TextEntity{{Type: EntityBold, Offset: 1, Length: 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, entities := ReplaceWithEntities(tt.text, tt.old, tt.new, tt.entities)
+			if text != tt.wantText {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+			if !reflect.DeepEqual(entities, tt.wantEntities) {
+				t.Errorf("entities = %+v, want %+v", entities, tt.wantEntities)
+			}
+		})
+	}
+}
+
+func TestPrependWithEntities(t *testing.T) {
+	entities := []TextEntity{{Type: EntityBold, Offset: 0, Length: 4}}
+
+	text, got := PrependWithEntities("text", "😀 ", entities)
+	if text != "😀 text" {
+		t.Errorf("text = %q, want %q", text, "😀 text")
+	}
+	want := []TextEntity{{Type: EntityBold, Offset: 3, Length: 4}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("entities = %+v, want %+v", got, want)
+	}
+
+	text, got = PrependWithEntities("text", "", entities)
+	if text != "text" || !reflect.DeepEqual(got, entities) {
+		t.Errorf("empty prefix changed text or entities: %q %+v", text, got)
+	}
+}
+
+func TestSortEntities(t *testing.T) {
+	entities := []TextEntity{
+		{Type: EntityItalic, Offset: 5, Length: 1},
+		{Type: EntityBold, Offset: 0, Length: 2},
+		{Type: EntityTextLink, Offset: 0, Length: 10},
+	}
+
+	got := SortEntities(entities)
+	want := []TextEntity{
+		{Type: EntityTextLink, Offset: 0, Length: 10},
+		{Type: EntityBold, Offset: 0, Length: 2},
+		{Type: EntityItalic, Offset: 5, Length: 1},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SortEntities = %+v, want %+v", got, want)
+	}
+	if entities[0].Offset != 5 {
+		t.Error("SortEntities modified its argument")
+	}
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/filter"
 )
 
 // PlatformType - тип платформы
@@ -12,6 +14,23 @@ const (
 	PlatformVK       PlatformType = "vk"
 )
 
+// Destination - место публикации на платформе
+type Destination struct {
+	Platform PlatformType `json:"platform"`
+	// Target - chat ID или @username для Telegram, ID группы для VK
+	Target string `json:"target"`
+}
+
+// FetchMode - режим получения сообщений из канала-источника
+type FetchMode string
+
+const (
+	// FetchModeStream - сообщения приходят через поток обновлений MTProto
+	FetchModeStream FetchMode = "stream"
+	// FetchModePolling - периодический опрос истории канала
+	FetchModePolling FetchMode = "polling"
+)
+
 // MediaType - тип медиа контента
 type MediaType string
 
@@ -26,22 +45,33 @@ const (
 
 // ParsingRule - правило парсинга
 type ParsingRule struct {
-	ID               int64             `json:"id"`
-	Name             string            `json:"name"`
-	SourceChannel    string            `json:"source_channel"`
-	Keywords         []string          `json:"keywords"`
-	ExcludeWords     []string          `json:"exclude_words"`
-	MediaTypes       []MediaType       `json:"media_types"`
-	MinTextLength    int               `json:"min_text_length"`
-	MaxTextLength    int               `json:"max_text_length"`
-	TextReplacements map[string]string `json:"text_replacements"`
-	AddPrefix        string            `json:"add_prefix"`
-	AddSuffix        string            `json:"add_suffix"`
-	TargetPlatforms  []PlatformType    `json:"target_platforms"`
-	CheckInterval    int               `json:"check_interval"`
-	IsActive         bool              `json:"is_active"`
-	CreatedAt        time.Time         `json:"created_at"`
-	UpdatedAt        time.Time         `json:"updated_at"`
+	ID               int64                   `json:"id"`
+	Name             string                  `json:"name"`
+	SourceChannel    string                  `json:"source_channel"`
+	Keywords         []string                `json:"keywords"`
+	ExcludeWords     []string                `json:"exclude_words"`
+	MediaTypes       []MediaType             `json:"media_types"`
+	MinTextLength    int                     `json:"min_text_length"`
+	MaxTextLength    int                     `json:"max_text_length"`
+	Transformations  []Transformation        `json:"transformations"` // шаги преобразования текста по порядку
+	AddPrefix        string                  `json:"add_prefix"`
+	AddSuffix        string                  `json:"add_suffix"`
+	TargetPlatforms  []PlatformType          `json:"target_platforms"`
+	Destinations     []Destination           `json:"destinations"`
+	CheckInterval    int                     `json:"check_interval"`
+	FetchMode        FetchMode               `json:"fetch_mode"`
+	SyncEdits        bool                    `json:"sync_edits"`        // повторять правки исходных сообщений
+	SyncDeletes      bool                    `json:"sync_deletes"`      // удалять копии удаленных сообщений
+	RequiresApproval bool                    `json:"requires_approval"` // посты публикуются после одобрения редактором
+	Filter           string                  `json:"filter"`            // выражение фильтра, пусто - без фильтра
+	Templates        map[PlatformType]string `json:"templates"`         // шаблоны поста по платформам, пусто - шаблон по умолчанию
+	Schedule         *PostingSchedule        `json:"schedule"`          // окна и лимиты публикации, nil - публиковать сразу
+	IsActive         bool                    `json:"is_active"`
+	IsPaused         bool                    `json:"is_paused"` // чтение канала приостановлено через API парсера
+	CreatedAt        time.Time               `json:"created_at"`
+	UpdatedAt        time.Time               `json:"updated_at"`
+
+	compiledFilter *filter.Expr // скомпилированный Filter
 }
 
 // Post - модель поста
@@ -53,14 +83,124 @@ type Post struct {
 	Content       string    `json:"content"`
 	MediaType     MediaType `json:"media_type"`
 	MediaURL      string    `json:"media_url"`
+	MediaFile     string    `json:"media_file"` // путь к файлу медиа в локальном кэше
+	GroupedID     int64     `json:"grouped_id"` // ID альбома в Telegram, 0 - не альбом
 	PostedAt      time.Time `json:"posted_at"`
 	ParsedAt      time.Time `json:"parsed_at"`
 
+	Entities []TextEntity  `json:"entities"`       // форматирование текста поста
+	Media    []PostMedia   `json:"media"`          // элементы медиа поста по порядку
+	Jobs     []*PublishJob `json:"jobs,omitempty"` // задачи публикации по платформам
+
+	TextHash    string `json:"text_hash,omitempty"`    // SHA-256 нормализованного текста
+	SimHash     int64  `json:"simhash,omitempty"`      // SimHash текста
+	ImageHash   int64  `json:"image_hash,omitempty"`   // перцептивный хэш первого изображения
+	DuplicateOf *int64 `json:"duplicate_of,omitempty"` // ID оригинала, если пост подавлен как дубликат
+	Archived    bool   `json:"archived"`               // импортирован из истории канала без публикации
+
+	ReviewStatus ReviewStatus `json:"review_status"`         // состояние модерации
+	ReviewedBy   string       `json:"reviewed_by,omitempty"` // кто одобрил или отклонил пост
+	ReviewedAt   *time.Time   `json:"reviewed_at,omitempty"` // когда принято решение
+
+	ScheduledAt    *time.Time `json:"scheduled_at,omitempty"` // не публиковать раньше этого времени
+	ScheduleManual bool       `json:"schedule_manual"`        // время назначено вручную, окна и лимиты правила не действуют
+	ReleasedAt     *time.Time `json:"released_at,omitempty"`  // когда пост отправлен воркерам публикации
+
 	PublishError      string `json:"publish_error"`
 	PublishedTelegram bool   `json:"published_telegram" db:"published_telegram"`
 	PublishedVK       bool   `json:"published_vk" db:"published_vk"`
 }
 
+// PostMedia - элемент медиа поста (фото или видео альбома)
+type PostMedia struct {
+	ID        int64     `json:"id"`
+	PostID    int64     `json:"post_id"`
+	Position  int       `json:"position"`
+	MediaType MediaType `json:"media_type"`
+	MediaURL  string    `json:"media_url"`
+	MediaFile string    `json:"media_file"`
+}
+
+// Publication - опубликованная копия поста на платформе
+type Publication struct {
+	ID         int64        `json:"id"`
+	PostID     int64        `json:"post_id"`
+	Platform   PlatformType `json:"platform"`
+	Target     string       `json:"target"`      // чат Telegram или ID группы VK
+	MessageIDs []int64      `json:"message_ids"` // ID сообщений Telegram или записи VK
+	Partial    bool         `json:"partial"`     // альбом отправлен не целиком
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// JobStatus статус задачи публикации
+type JobStatus string
+
+const (
+	JobScheduled JobStatus = "scheduled" // ждет своего времени в расписании
+	JobPending   JobStatus = "pending"   // ждет публикации или повторной попытки
+	JobRunning   JobStatus = "running"   // выполняется воркером
+	JobDone      JobStatus = "done"      // опубликовано
+	JobDead      JobStatus = "dead"      // попытки исчерпаны
+)
+
+// ReviewStatus состояние модерации поста
+type ReviewStatus string
+
+const (
+	ReviewNone     ReviewStatus = "none"           // правило не требует модерации
+	ReviewPending  ReviewStatus = "pending_review" // ждет решения редактора
+	ReviewApproved ReviewStatus = "approved"       // одобрен и поставлен в очередь публикации
+	ReviewRejected ReviewStatus = "rejected"       // отклонен, не публикуется
+)
+
+// ReviewAction действие редактора с постом
+type ReviewAction string
+
+const (
+	ReviewActionApprove ReviewAction = "approve"
+	ReviewActionReject  ReviewAction = "reject"
+	ReviewActionEdit    ReviewAction = "edit"
+)
+
+// PostReview - запись журнала модерации поста
+type PostReview struct {
+	ID        int64        `json:"id"`
+	PostID    int64        `json:"post_id"`
+	Action    ReviewAction `json:"action"`
+	UserID    int64        `json:"user_id"`
+	Username  string       `json:"username"`
+	Comment   string       `json:"comment"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// PublishJob - задача публикации поста на одной платформе
+type PublishJob struct {
+	ID            int64        `json:"id"`
+	PostID        int64        `json:"post_id"`
+	Platform      PlatformType `json:"platform"`
+	Status        JobStatus    `json:"status"`
+	Attempts      int          `json:"attempts"`
+	NextAttemptAt time.Time    `json:"next_attempt_at"`
+	LastError     string       `json:"last_error"`
+	CreatedAt     time.Time    `json:"created_at"`
+	UpdatedAt     time.Time    `json:"updated_at"`
+}
+
+// ChannelCursor - позиция чтения канала правилом. LastMessageID сдвигается
+// на каждое просмотренное сообщение, даже не прошедшее фильтры.
+type ChannelCursor struct {
+	RuleID        int64      `json:"rule_id"`
+	SourceChannel string     `json:"source_channel"`
+	LastMessageID int64      `json:"last_message_id"`
+	Pts           int        `json:"pts"`                       // pts последнего обновления из потока канала
+	LastCheckedAt *time.Time `json:"last_checked_at,omitempty"` // последний опрос истории
+	LastError     string     `json:"last_error"`                // ошибка последнего опроса, пусто - успешно
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"` // последний успешный опрос истории
+	MessagesSeen  int64      `json:"messages_seen"`             // сообщений прочитано из канала
+	PostsCreated  int64      `json:"posts_created"`             // постов создано из сообщений канала
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
 // Config - основная структура конфигурации
 type Config struct {
 	Database DatabaseConfig `yaml:"database"`
@@ -69,6 +209,39 @@ type Config struct {
 	Server   ServerConfig   `yaml:"server"`
 	Logger   LoggerConfig   `yaml:"logger"`
 	Auth     AuthConfig     `yaml:"auth"`
+	Media    MediaConfig    `yaml:"media"`
+	Publish  PublishConfig  `yaml:"publish"`
+	Dedup    DedupConfig    `yaml:"dedup"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+}
+
+// MediaConfig конфигурация загрузки медиа из Telegram
+type MediaConfig struct {
+	CacheDir      string `yaml:"cache_dir"`
+	MaxFileSizeMB int    `yaml:"max_file_size_mb"`
+	CacheTTLHours int    `yaml:"cache_ttl_hours"`
+}
+
+// PublishConfig конфигурация очереди публикации
+type PublishConfig struct {
+	Workers          int `yaml:"workers"`            // количество параллельных воркеров
+	PollSeconds      int `yaml:"poll_seconds"`       // интервал проверки очереди
+	MaxAttempts      int `yaml:"max_attempts"`       // попыток до перевода задачи в dead
+	RetryBaseSeconds int `yaml:"retry_base_seconds"` // задержка перед первой повторной попыткой
+	RetryMaxMinutes  int `yaml:"retry_max_minutes"`  // максимальная задержка между попытками
+}
+
+// DedupConfig конфигурация поиска дубликатов постов
+type DedupConfig struct {
+	Disabled          bool `yaml:"disabled"`            // отключить поиск дубликатов
+	WindowHours       int  `yaml:"window_hours"`        // за какой период сравнивать посты
+	SimHashDistance   int  `yaml:"simhash_distance"`    // допустимое отличие текста, бит из 64
+	ImageHashDistance int  `yaml:"image_hash_distance"` // допустимое отличие изображения, бит из 64
+}
+
+// MetricsConfig конфигурация метрик
+type MetricsConfig struct {
+	Addr string `yaml:"addr"` // адрес для /debug/vars, пусто - метрики не публикуются
 }
 
 // AuthConfig конфигурация аутентификации
@@ -87,12 +260,21 @@ type DatabaseConfig struct {
 }
 
 type TelegramConfig struct {
-	APIID         int    `yaml:"api_id"`
-	APIHash       string `yaml:"api_hash"`
-	Phone         string `yaml:"phone"`
-	SessionFile   string `yaml:"session_file"`
-	BotToken      string `yaml:"bot_token"`
-	TargetChannel string `yaml:"target_channel"`
+	APIID       int    `yaml:"api_id"`
+	APIHash     string `yaml:"api_hash"`
+	Phone       string `yaml:"phone"`
+	SessionFile string `yaml:"session_file"`
+	// SessionKey ключ шифрования сессий MTProto в БД. Пусто - сессии
+	// хранятся в файлах session_file.
+	SessionKey string `yaml:"session_key"`
+	// RequireSessionKey запрещает запуск без SessionKey, чтобы в контейнере
+	// без постоянного диска сессии не сохранялись в файлы и не терялись
+	RequireSessionKey bool   `yaml:"require_session_key"`
+	BotToken          string `yaml:"bot_token"`
+	TargetChannel     string `yaml:"target_channel"`
+	// ModeratorsChat чат редакторов (ID или @username), куда бот присылает
+	// посты на модерацию. Пусто - модерация только через веб-интерфейс.
+	ModeratorsChat string `yaml:"moderators_chat"`
 }
 
 type VKConfig struct {
@@ -0,0 +1,46 @@
+package models
+
+import "time"
+
+// MonitorMode способ, которым парсер читает канал правила
+type MonitorMode string
+
+const (
+	MonitorStreaming MonitorMode = "streaming" // поток обновлений MTProto
+	MonitorPolling   MonitorMode = "polling"   // периодический опрос истории
+)
+
+// RuleMonitorStatus запущенный мониторинг канала правила
+type RuleMonitorStatus struct {
+	RuleID        int64       `json:"rule_id"`
+	RuleName      string      `json:"rule_name"`
+	SourceChannel string      `json:"source_channel"`
+	Mode          MonitorMode `json:"mode"` // пусто - мониторинг еще запускается
+	StartedAt     time.Time   `json:"started_at"`
+}
+
+// ParserInstance состояние запущенного процесса парсера
+type ParserInstance struct {
+	ID            string              `json:"id"`
+	Hostname      string              `json:"hostname"`
+	AccountsReady bool                `json:"accounts_ready"` // есть аккаунт Telegram, готовый читать каналы
+	Monitors      []RuleMonitorStatus `json:"monitors"`
+	StartedAt     time.Time           `json:"started_at"`
+	HeartbeatAt   time.Time           `json:"heartbeat_at"`
+}
+
+// RuleHealth состояние чтения канала правилом для веб-интерфейса
+type RuleHealth struct {
+	RuleID        int64       `json:"rule_id"`
+	RuleName      string      `json:"rule_name"`
+	SourceChannel string      `json:"source_channel"`
+	IsActive      bool        `json:"is_active"`
+	IsPaused      bool        `json:"is_paused"`
+	Monitored     bool        `json:"monitored"`      // канал читает запущенный парсер
+	Mode          MonitorMode `json:"mode,omitempty"` // способ чтения, если канал читается
+	LastCheckedAt *time.Time  `json:"last_checked_at,omitempty"`
+	LastSuccessAt *time.Time  `json:"last_success_at,omitempty"`
+	LastError     string      `json:"last_error"`
+	MessagesSeen  int64       `json:"messages_seen"`
+	PostsCreated  int64       `json:"posts_created"`
+}
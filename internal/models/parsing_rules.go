@@ -2,28 +2,38 @@ package models
 
 import (
 	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/filter"
 )
 
+// telegramUsernameRe допустимый формат @username канала Telegram
+var telegramUsernameRe = regexp.MustCompile(`^@[A-Za-z][A-Za-z0-9_]{3,31}$`)
+
 // NewParsingRule создает новое правило с настройками по умолчанию
 func NewParsingRule(name, sourceChannel string) *ParsingRule {
 	return &ParsingRule{
-		Name:             name,
-		SourceChannel:    sourceChannel,
-		Keywords:         []string{},
-		ExcludeWords:     []string{},
-		MediaTypes:       []MediaType{MediaText, MediaPhoto},
-		MinTextLength:    0,
-		MaxTextLength:    0,
-		TextReplacements: make(map[string]string),
-		AddPrefix:        "",
-		AddSuffix:        "",
-		TargetPlatforms:  []PlatformType{PlatformTelegram},
-		CheckInterval:    2,
-		IsActive:         true,
-		CreatedAt:        time.Now(),
-		UpdatedAt:        time.Now(),
+		Name:            name,
+		SourceChannel:   sourceChannel,
+		Keywords:        []string{},
+		ExcludeWords:    []string{},
+		MediaTypes:      []MediaType{MediaText, MediaPhoto},
+		MinTextLength:   0,
+		MaxTextLength:   0,
+		Transformations: []Transformation{},
+		AddPrefix:       "",
+		AddSuffix:       "",
+		TargetPlatforms: []PlatformType{PlatformTelegram},
+		Destinations:    []Destination{},
+		CheckInterval:   2,
+		FetchMode:       FetchModeStream,
+		IsActive:        true,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
 	}
 }
 
@@ -57,6 +67,38 @@ func (r *ParsingRule) ContainsExcludedWords(text string) bool {
 	return false
 }
 
+// CompileFilter компилирует выражение фильтра правила и сохраняет результат
+func (r *ParsingRule) CompileFilter() error {
+	r.compiledFilter = nil
+	if strings.TrimSpace(r.Filter) == "" {
+		return nil
+	}
+
+	expr, err := filter.Compile(r.Filter)
+	if err != nil {
+		return err
+	}
+	r.compiledFilter = expr
+	return nil
+}
+
+// MatchesFilter проверяет сообщение выражением фильтра.
+// Правило с некорректным выражением не пропускает ни одного сообщения.
+func (r *ParsingRule) MatchesFilter(msg *filter.Message) bool {
+	if strings.TrimSpace(r.Filter) == "" {
+		return true
+	}
+
+	expr := r.compiledFilter
+	if expr == nil {
+		var err error
+		if expr, err = filter.Compile(r.Filter); err != nil {
+			return false
+		}
+	}
+	return expr.Match(msg)
+}
+
 // SupportsMediaType проверяет, поддерживается ли тип медиа
 func (r *ParsingRule) SupportsMediaType(mediaType MediaType) bool {
 	if len(r.MediaTypes) == 0 {
@@ -86,20 +128,33 @@ func (r *ParsingRule) TextLengthValid(text string) bool {
 
 // ApplyTransformations применяет трансформации к тексту
 func (r *ParsingRule) ApplyTransformations(text string) string {
+	result, _ := r.ApplyTransformationsWithEntities(text, nil)
+	return result
+}
+
+// ApplyTransformationsWithEntities применяет шаги преобразования по порядку
+// и сдвигает сущности форматирования вслед за изменениями текста.
+// Шаг, завершившийся ошибкой, пропускается.
+func (r *ParsingRule) ApplyTransformationsWithEntities(text string, entities []TextEntity) (string, []TextEntity) {
 	result := text
 
-	for old, new := range r.TextReplacements {
-		result = strings.ReplaceAll(result, old, new)
+	for _, step := range r.Transformations {
+		stepText, stepEntities, err := step.Apply(result, entities)
+		if err != nil {
+			continue
+		}
+		result, entities = stepText, stepEntities
 	}
 
 	if r.AddPrefix != "" {
-		result = r.AddPrefix + result
+		result, entities = PrependWithEntities(result, r.AddPrefix, entities)
 	}
 	if r.AddSuffix != "" {
+		// Суффикс не сдвигает сущности
 		result = result + r.AddSuffix
 	}
 
-	return result
+	return result, entities
 }
 
 // SupportsPlatform проверяет, поддерживается ли платформа для публикации
@@ -112,6 +167,49 @@ func (r *ParsingRule) SupportsPlatform(platform PlatformType) bool {
 	return false
 }
 
+// DestinationsFor возвращает назначения правила для платформы
+func (r *ParsingRule) DestinationsFor(platform PlatformType) []Destination {
+	var destinations []Destination
+	for _, d := range r.Destinations {
+		if d.Platform == platform {
+			destinations = append(destinations, d)
+		}
+	}
+	return destinations
+}
+
+// Validate проверяет формат назначения для известных платформ
+func (d Destination) Validate() error {
+	target := strings.TrimSpace(d.Target)
+	if target == "" {
+		return fmt.Errorf("destination target is required for platform %s", d.Platform)
+	}
+
+	switch d.Platform {
+	case PlatformTelegram:
+		if _, err := strconv.ParseInt(target, 10, 64); err == nil {
+			return nil
+		}
+		if !telegramUsernameRe.MatchString(target) {
+			return fmt.Errorf("telegram destination %q must be a chat ID or @username", target)
+		}
+	case PlatformVK:
+		groupID, err := strconv.Atoi(target)
+		if err != nil || groupID == 0 {
+			return fmt.Errorf("vk destination %q must be a group ID", target)
+		}
+	case "":
+		return errors.New("destination platform is required")
+	}
+	return nil
+}
+
+// IsStreaming проверяет, получает ли правило сообщения через поток обновлений.
+// Пустой режим считается потоковым.
+func (r *ParsingRule) IsStreaming() bool {
+	return r.FetchMode != FetchModePolling
+}
+
 // Validate проверяет валидность правила
 func (r *ParsingRule) Validate() error {
 	if r.Name == "" {
@@ -120,8 +218,37 @@ func (r *ParsingRule) Validate() error {
 	if r.SourceChannel == "" {
 		return errors.New("source channel is required")
 	}
+	if _, err := ParseChannelRef(r.SourceChannel); err != nil {
+		return fmt.Errorf("source channel: %v", err)
+	}
 	if len(r.TargetPlatforms) == 0 {
 		return errors.New("at least one target platform is required")
 	}
+	for _, d := range r.Destinations {
+		if err := d.Validate(); err != nil {
+			return err
+		}
+	}
+	switch r.FetchMode {
+	case "", FetchModeStream, FetchModePolling:
+	default:
+		return errors.New("fetch mode must be either stream or polling")
+	}
+	for i, step := range r.Transformations {
+		if err := step.Validate(); err != nil {
+			return fmt.Errorf("transformation %d (%s): %v", i+1, step.Type, err)
+		}
+	}
+	if err := r.validateTemplates(); err != nil {
+		return err
+	}
+	if err := r.CompileFilter(); err != nil {
+		return fmt.Errorf("invalid filter: %v", err)
+	}
+	if r.Schedule != nil {
+		if err := r.Schedule.Validate(); err != nil {
+			return fmt.Errorf("invalid schedule: %v", err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,194 @@
+package models
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+)
+
+// PostContent текст поста с форматированием. В шаблоне выводится в виде
+// для платформы: для Telegram в HTML, для VK простым текстом. truncate
+// обрезает текст вместе с форматированием до вывода, поэтому теги и ссылки
+// не разрезаются.
+type PostContent struct {
+	Text     string
+	Entities []TextEntity
+	render   func(text string, entities []TextEntity) string
+}
+
+// NewPostContent создает текст поста, который выводится функцией render.
+// render nil - текст выводится как есть.
+func NewPostContent(text string, entities []TextEntity, render func(text string, entities []TextEntity) string) PostContent {
+	return PostContent{Text: text, Entities: entities, render: render}
+}
+
+// String возвращает текст поста в виде для платформы
+func (c PostContent) String() string {
+	if c.render == nil {
+		return c.Text
+	}
+	return c.render(c.Text, c.Entities)
+}
+
+// Truncate обрезает текст до n символов по границе слова, сдвигая форматирование
+func (c PostContent) Truncate(n int) PostContent {
+	c.Text, c.Entities = truncateText(c.Text, n, "", c.Entities)
+	return c
+}
+
+// PostTemplateData данные, доступные шаблону поста
+type PostTemplateData struct {
+	Content       PostContent // текст поста; для Telegram выводится в HTML, для VK - простым текстом
+	SourceChannel string      // канал источник
+	MessageLink   string      // ссылка на исходное сообщение, пусто - канал указан ссылкой-приглашением
+	PostedAt      time.Time   // время публикации исходного сообщения
+	MediaType     MediaType   // тип медиа поста
+	RuleName      string      // название правила
+}
+
+// defaultPostTemplates шаблоны по умолчанию: текст и подпись с источником
+var defaultPostTemplates = map[PlatformType]string{
+	PlatformTelegram: "{{.Content}}{{if .SourceChannel}}\n\n📎 <i>Источник: {{escapeHTML .SourceChannel}}</i>{{end}}",
+	PlatformVK:       "{{.Content}}{{if .SourceChannel}}\n\n📎 Источник: {{.SourceChannel}}{{end}}",
+}
+
+// postTemplateFuncs вспомогательные функции шаблонов поста
+var postTemplateFuncs = template.FuncMap{
+	// truncate обрезает строку или текст поста до n символов по границе
+	// слова: {{.Content | truncate 200}}
+	"truncate": func(n int, v interface{}) interface{} {
+		switch v := v.(type) {
+		case PostContent:
+			return v.Truncate(n)
+		case string:
+			result, _ := truncateText(v, n, "", nil)
+			return result
+		default:
+			result, _ := truncateText(fmt.Sprint(v), n, "", nil)
+			return result
+		}
+	},
+	"escapeHTML": html.EscapeString,
+	"hashtagify": hashtagify,
+}
+
+// DefaultPostTemplate возвращает шаблон поста по умолчанию для платформы
+func DefaultPostTemplate(platform PlatformType) string {
+	if tmpl, ok := defaultPostTemplates[platform]; ok {
+		return tmpl
+	}
+	return "{{.Content}}"
+}
+
+// TemplateFor возвращает шаблон поста правила для платформы или шаблон по умолчанию.
+// Правило может быть nil.
+func (r *ParsingRule) TemplateFor(platform PlatformType) string {
+	if r != nil {
+		if tmpl := r.Templates[platform]; strings.TrimSpace(tmpl) != "" {
+			return tmpl
+		}
+	}
+	return DefaultPostTemplate(platform)
+}
+
+// ParsePostTemplate разбирает шаблон поста
+func ParsePostTemplate(text string) (*template.Template, error) {
+	return template.New("post").Funcs(postTemplateFuncs).Parse(text)
+}
+
+// RenderPostTemplate разбирает шаблон и выполняет его для данных поста
+func RenderPostTemplate(text string, data PostTemplateData) (string, error) {
+	tmpl, err := ParsePostTemplate(text)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// NewPostTemplateData собирает данные шаблона из поста и правила.
+// content - текст поста с форматированием для платформы.
+func NewPostTemplateData(post *Post, rule *ParsingRule, content PostContent) PostTemplateData {
+	data := PostTemplateData{
+		Content:       content,
+		SourceChannel: post.SourceChannel,
+		MessageLink:   post.MessageLink(),
+		PostedAt:      post.PostedAt,
+		MediaType:     post.MediaType,
+	}
+	if rule != nil {
+		data.RuleName = rule.Name
+	}
+	return data
+}
+
+// SamplePost возвращает пример поста для проверки и предпросмотра шаблонов
+func SamplePost() *Post {
+	return &Post{
+		MessageID:     42,
+		SourceChannel: "@example_channel",
+		Content:       "Пример текста поста с <разметкой> и ссылкой https://example.com",
+		MediaType:     MediaPhoto,
+		PostedAt:      time.Date(2024, 1, 15, 12, 30, 0, 0, time.UTC),
+		Entities:      []TextEntity{{Type: EntityBold, Offset: 0, Length: 6}},
+	}
+}
+
+// validateTemplates проверяет шаблоны правила на примере поста
+func (r *ParsingRule) validateTemplates() error {
+	for platform, text := range r.Templates {
+		switch platform {
+		case PlatformTelegram, PlatformVK:
+		default:
+			return fmt.Errorf("template for unknown platform %q", platform)
+		}
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+
+		post := SamplePost()
+		content := NewPostContent(post.Content, post.Entities, nil)
+		if _, err := RenderPostTemplate(text, NewPostTemplateData(post, r, content)); err != nil {
+			return fmt.Errorf("invalid %s template: %v", platform, err)
+		}
+	}
+	return nil
+}
+
+// MessageLink возвращает ссылку на исходное сообщение. Для канала по
+// ссылке-приглашению ссылка не строится.
+func (p *Post) MessageLink() string {
+	ref, err := ParseChannelRef(p.SourceChannel)
+	if err != nil {
+		return ""
+	}
+	return ref.MessageLink(p.MessageID)
+}
+
+// hashtagify превращает строку в хэштег: "Новости мира" -> "#Новости_мира"
+func hashtagify(s string) string {
+	var sb strings.Builder
+	pendingSep := false
+	for _, r := range strings.TrimPrefix(strings.TrimSpace(s), "@") {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			if pendingSep && sb.Len() > 0 {
+				sb.WriteRune('_')
+			}
+			pendingSep = false
+			sb.WriteRune(r)
+			continue
+		}
+		pendingSep = true
+	}
+	if sb.Len() == 0 {
+		return ""
+	}
+	return "#" + sb.String()
+}
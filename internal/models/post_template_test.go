@@ -0,0 +1,139 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHashtagify(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{in: "Новости мира", want: "#Новости_мира"},
+		{in: "@tech_news", want: "#tech_news"},
+		{in: "  spaces   around  ", want: "#spaces_around"},
+		{in: "Рынок - итоги, 2024!", want: "#Рынок_итоги_2024"},
+		{in: "🔥 Горячее 🔥", want: "#Горячее"},
+		{in: "!!!", want: ""},
+		{in: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := hashtagify(tt.in); got != tt.want {
+				t.Errorf("hashtagify(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateTruncate(t *testing.T) {
+	truncate := postTemplateFuncs["truncate"].(func(int, interface{}) interface{})
+
+	tests := []struct {
+		name  string
+		n     int
+		value interface{}
+		want  interface{}
+	}{
+		{name: "short string", n: 10, value: "коротко", want: "коротко"},
+		{name: "string at word boundary", n: 10, value: "Привет мир как дела", want: "Привет…"},
+		{name: "string keeps emoji whole", n: 4, value: "😀😀😀😀😀", want: "😀😀😀…"},
+		{name: "non-string value", n: 3, value: 123456, want: "12…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := truncate(tt.n, tt.value); got != tt.want {
+				t.Errorf("truncate(%d, %v) = %q, want %q", tt.n, tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPostContentTruncate(t *testing.T) {
+	bold := func(offset, length int) TextEntity {
+		return TextEntity{Type: EntityBold, Offset: offset, Length: length}
+	}
+
+	tests := []struct {
+		name         string
+		n            int
+		text         string
+		entities     []TextEntity
+		wantText     string
+		wantEntities []TextEntity
+	}{
+		{
+			name:         "short text unchanged",
+			n:            20,
+			text:         "Привет мир",
+			entities:     []TextEntity{bold(0, 6)},
+			wantText:     "Привет мир",
+			wantEntities: []TextEntity{bold(0, 6)},
+		},
+		{
+			name:         "entity after cut dropped",
+			n:            10,
+			text:         "Привет мир как дела",
+			entities:     []TextEntity{bold(0, 6), bold(7, 3)},
+			wantText:     "Привет…",
+			wantEntities: []TextEntity{bold(0, 6)},
+		},
+		{
+			name:         "entity across cut shortened",
+			n:            10,
+			text:         "Привет мир как дела",
+			entities:     []TextEntity{bold(0, 19)},
+			wantText:     "Привет…",
+			wantEntities: []TextEntity{bold(0, 6)},
+		},
+		{
+			name:         "UTF-16 offsets after emoji",
+			n:            8,
+			text:         "😀 один два три",
+			entities:     []TextEntity{bold(3, 4), bold(8, 3)},
+			wantText:     "😀 один…",
+			wantEntities: []TextEntity{bold(3, 4)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := NewPostContent(tt.text, tt.entities, nil).Truncate(tt.n)
+			if got.Text != tt.wantText {
+				t.Errorf("text = %q, want %q", got.Text, tt.wantText)
+			}
+			if !reflect.DeepEqual(got.Entities, tt.wantEntities) {
+				t.Errorf("entities = %+v, want %+v", got.Entities, tt.wantEntities)
+			}
+		})
+	}
+}
+
+func TestPostContentString(t *testing.T) {
+	entities := []TextEntity{{Type: EntityBold, Offset: 0, Length: 1}}
+
+	if got := NewPostContent("<b>", entities, nil).String(); got != "<b>" {
+		t.Errorf("String() without render = %q", got)
+	}
+
+	render := func(text string, entities []TextEntity) string {
+		return "[" + text + "]"
+	}
+	content := NewPostContent("Привет мир как дела", entities, render)
+	if got := content.String(); got != "[Привет мир как дела]" {
+		t.Errorf("String() = %q", got)
+	}
+
+	// Шаблон обрезает текст до вывода, поэтому render получает обрезанный текст
+	data := PostTemplateData{Content: content}
+	got, err := RenderPostTemplate("{{.Content | truncate 10}}", data)
+	if err != nil {
+		t.Fatalf("RenderPostTemplate: %v", err)
+	}
+	if got != "[Привет…]" {
+		t.Errorf("RenderPostTemplate() = %q, want %q", got, "[Привет…]")
+	}
+}
@@ -19,6 +19,7 @@ func NewPost(ruleID, messageID int64, sourceChannel, content string, mediaType M
 		PublishedTelegram: false,
 		PublishedVK:       false,
 		PublishError:      "",
+		ReviewStatus:      ReviewNone,
 	}
 }
 
@@ -41,6 +42,21 @@ func (p *Post) SetMediaURL(url string) {
 	p.MediaURL = url
 }
 
+// IsAlbum проверяет, содержит ли пост несколько элементов медиа
+func (p *Post) IsAlbum() bool {
+	return len(p.Media) > 1
+}
+
+// HasMedia проверяет, есть ли у поста медиа: загруженный файл или URL
+func (p *Post) HasMedia() bool {
+	return p.MediaFile != "" || p.MediaURL != ""
+}
+
+// IsPendingReview проверяет, ждет ли пост решения редактора
+func (p *Post) IsPendingReview() bool {
+	return p.ReviewStatus == ReviewPending
+}
+
 // IsProcessed проверяет, был ли пост обработан
 func (p *Post) IsProcessed() bool {
 	return p.PublishedTelegram || p.PublishedVK || p.PublishError != ""
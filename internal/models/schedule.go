@@ -0,0 +1,235 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxSlotIterations ограничивает поиск слота публикации: каждая итерация
+// сдвигает время вперед, поэтому на практике хватает нескольких шагов
+const maxSlotIterations = 64
+
+// weekdays сокращения дней недели в расписании
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// PostingWindow окно публикации: время суток в выбранные дни недели.
+// Окно с To раньше From переходит через полночь, день относится к началу окна.
+type PostingWindow struct {
+	Days []string `json:"days,omitempty"` // mon, tue, ..., sun; пусто - каждый день
+	From string   `json:"from"`           // начало окна, "09:00"
+	To   string   `json:"to"`             // конец окна, "21:00"
+}
+
+// PostingSchedule расписание публикации постов правила
+type PostingSchedule struct {
+	Timezone      string          `json:"timezone,omitempty"`        // IANA, например Europe/Moscow; пусто - UTC
+	Windows       []PostingWindow `json:"windows,omitempty"`         // окна публикации, пусто - круглосуточно
+	MinGapMinutes int             `json:"min_gap_minutes,omitempty"` // минимальный интервал между постами
+	MaxPerHour    int             `json:"max_per_hour,omitempty"`    // не больше постов за скользящий час, 0 - без ограничения
+	MaxPerDay     int             `json:"max_per_day,omitempty"`     // не больше постов за календарный день, 0 - без ограничения
+}
+
+// parseClock разбирает время суток "ЧЧ:ММ" в минуты от полуночи
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Validate проверяет окно публикации
+func (w PostingWindow) Validate() error {
+	from, err := parseClock(w.From)
+	if err != nil {
+		return err
+	}
+	to, err := parseClock(w.To)
+	if err != nil {
+		return err
+	}
+	if from == to {
+		return errors.New("window start and end must differ")
+	}
+	for _, day := range w.Days {
+		if _, ok := weekdays[strings.ToLower(strings.TrimSpace(day))]; !ok {
+			return fmt.Errorf("unknown weekday %q", day)
+		}
+	}
+	return nil
+}
+
+// hasDay проверяет, начинается ли окно в этот день недели
+func (w PostingWindow) hasDay(day time.Weekday) bool {
+	if len(w.Days) == 0 {
+		return true
+	}
+	for _, d := range w.Days {
+		if weekdays[strings.ToLower(strings.TrimSpace(d))] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// bounds возвращает начало и конец окна, начинающегося в день date
+func (w PostingWindow) bounds(date time.Time) (time.Time, time.Time) {
+	from, _ := parseClock(w.From)
+	to, _ := parseClock(w.To)
+
+	y, m, d := date.Date()
+	start := time.Date(y, m, d, from/60, from%60, 0, 0, date.Location())
+	end := time.Date(y, m, d, to/60, to%60, 0, 0, date.Location())
+	if to < from {
+		end = end.AddDate(0, 0, 1)
+	}
+	return start, end
+}
+
+// Validate проверяет расписание
+func (s *PostingSchedule) Validate() error {
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", s.Timezone)
+	}
+	for i, w := range s.Windows {
+		if err := w.Validate(); err != nil {
+			return fmt.Errorf("window %d: %v", i+1, err)
+		}
+	}
+	if s.MinGapMinutes < 0 || s.MaxPerHour < 0 || s.MaxPerDay < 0 {
+		return errors.New("gap and limits must not be negative")
+	}
+	return nil
+}
+
+// Location возвращает часовой пояс расписания, при ошибке - UTC
+func (s *PostingSchedule) Location() *time.Location {
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// NextWindow возвращает ближайший момент не раньше t внутри окна публикации
+func (s *PostingSchedule) NextWindow(t time.Time) time.Time {
+	if len(s.Windows) == 0 {
+		return t
+	}
+
+	local := t.In(s.Location())
+	var best time.Time
+	// Вчерашнее окно может продолжаться после полуночи, поэтому начинаем с -1 дня
+	for offset := -1; offset <= 7; offset++ {
+		date := local.AddDate(0, 0, offset)
+		for _, w := range s.Windows {
+			if !w.hasDay(date.Weekday()) {
+				continue
+			}
+			start, end := w.bounds(date)
+			if !end.After(local) {
+				continue
+			}
+			candidate := start
+			if candidate.Before(local) {
+				candidate = local
+			}
+			if best.IsZero() || candidate.Before(best) {
+				best = candidate
+			}
+		}
+	}
+
+	if best.IsZero() {
+		return t
+	}
+	return best.In(t.Location())
+}
+
+// NextSlot возвращает ближайшее время не раньше t, когда можно опубликовать
+// следующий пост с учетом окон, интервала и лимитов. released - время
+// публикации предыдущих постов правила, достаточно последних суток.
+func (s *PostingSchedule) NextSlot(t time.Time, released []time.Time) time.Time {
+	sorted := append([]time.Time(nil), released...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Before(sorted[j]) })
+
+	gap := time.Duration(s.MinGapMinutes) * time.Minute
+	loc := s.Location()
+
+	for i := 0; i < maxSlotIterations; i++ {
+		next := t
+
+		if gap > 0 && len(sorted) > 0 {
+			if earliest := sorted[len(sorted)-1].Add(gap); next.Before(earliest) {
+				next = earliest
+			}
+		}
+
+		if s.MaxPerHour > 0 {
+			var inHour []time.Time
+			for _, r := range sorted {
+				if r.After(next.Add(-time.Hour)) && !r.After(next) {
+					inHour = append(inHour, r)
+				}
+			}
+			if len(inHour) >= s.MaxPerHour {
+				// Ждем, пока самый ранний лишний пост выйдет из скользящего часа
+				next = inHour[len(inHour)-s.MaxPerHour].Add(time.Hour)
+			}
+		}
+
+		if s.MaxPerDay > 0 {
+			local := next.In(loc)
+			y, m, d := local.Date()
+			dayStart := time.Date(y, m, d, 0, 0, 0, 0, loc)
+			count := 0
+			for _, r := range sorted {
+				if !r.Before(dayStart) && r.Before(dayStart.AddDate(0, 0, 1)) {
+					count++
+				}
+			}
+			if count >= s.MaxPerDay {
+				next = dayStart.AddDate(0, 0, 1)
+			}
+		}
+
+		next = s.NextWindow(next)
+		if next.Equal(t) {
+			return t
+		}
+		t = next
+	}
+	return t
+}
+
+// InitialSchedule возвращает время, на которое откладывается новый пост
+// правила с расписанием: ближайшее окно публикации. Без расписания - nil,
+// пост публикуется сразу.
+func (r *ParsingRule) InitialSchedule(now time.Time) *time.Time {
+	if r == nil || r.Schedule == nil {
+		return nil
+	}
+	at := r.Schedule.NextWindow(now)
+	return &at
+}
+
+// HoldUntil возвращает время, до которого пост ждет в очереди публикации:
+// явно назначенное время или ближайшее окно расписания правила.
+// nil - пост публикуется сразу.
+func (p *Post) HoldUntil(rule *ParsingRule, now time.Time) *time.Time {
+	if p.ScheduleManual && p.ScheduledAt != nil {
+		return p.ScheduledAt
+	}
+	return rule.InitialSchedule(now)
+}
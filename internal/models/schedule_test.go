@@ -0,0 +1,257 @@
+package models
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+)
+
+// at возвращает время в часовом поясе loc
+func at(loc *time.Location, year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, loc)
+}
+
+func mustLocation(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return loc
+}
+
+func TestPostingScheduleNextWindow(t *testing.T) {
+	utc := time.UTC
+	moscow := mustLocation(t, "Europe/Moscow")
+	berlin := mustLocation(t, "Europe/Berlin")
+
+	daytime := []PostingWindow{{From: "09:00", To: "21:00"}}
+	overnight := []PostingWindow{{From: "22:00", To: "02:00"}}
+
+	// 2026-01-05 - понедельник
+	tests := []struct {
+		name     string
+		schedule PostingSchedule
+		t        time.Time
+		want     time.Time
+	}{
+		{
+			name: "no windows",
+			t:    at(utc, 2026, 1, 5, 3, 0),
+			want: at(utc, 2026, 1, 5, 3, 0),
+		},
+		{
+			name:     "before window",
+			schedule: PostingSchedule{Windows: daytime},
+			t:        at(utc, 2026, 1, 5, 8, 0),
+			want:     at(utc, 2026, 1, 5, 9, 0),
+		},
+		{
+			name:     "inside window",
+			schedule: PostingSchedule{Windows: daytime},
+			t:        at(utc, 2026, 1, 5, 10, 15),
+			want:     at(utc, 2026, 1, 5, 10, 15),
+		},
+		{
+			name:     "window end is exclusive",
+			schedule: PostingSchedule{Windows: daytime},
+			t:        at(utc, 2026, 1, 5, 21, 0),
+			want:     at(utc, 2026, 1, 6, 9, 0),
+		},
+		{
+			name:     "overnight window continues after midnight",
+			schedule: PostingSchedule{Windows: overnight},
+			t:        at(utc, 2026, 1, 5, 1, 0),
+			want:     at(utc, 2026, 1, 5, 1, 0),
+		},
+		{
+			name:     "after overnight window",
+			schedule: PostingSchedule{Windows: overnight},
+			t:        at(utc, 2026, 1, 5, 3, 0),
+			want:     at(utc, 2026, 1, 5, 22, 0),
+		},
+		{
+			name:     "overnight window belongs to its start day",
+			schedule: PostingSchedule{Windows: []PostingWindow{{Days: []string{"fri"}, From: "22:00", To: "02:00"}}},
+			t:        at(utc, 2026, 1, 10, 1, 0),
+			want:     at(utc, 2026, 1, 10, 1, 0),
+		},
+		{
+			name:     "overnight window of another day",
+			schedule: PostingSchedule{Windows: []PostingWindow{{Days: []string{"fri"}, From: "22:00", To: "02:00"}}},
+			t:        at(utc, 2026, 1, 11, 1, 0),
+			want:     at(utc, 2026, 1, 16, 22, 0),
+		},
+		{
+			name:     "next week",
+			schedule: PostingSchedule{Windows: []PostingWindow{{Days: []string{"Mon"}, From: "09:00", To: "10:00"}}},
+			t:        at(utc, 2026, 1, 6, 12, 0),
+			want:     at(utc, 2026, 1, 12, 9, 0),
+		},
+		{
+			name: "earliest of several windows",
+			schedule: PostingSchedule{Windows: []PostingWindow{
+				{From: "18:00", To: "20:00"},
+				{From: "12:00", To: "13:00"},
+			}},
+			t:    at(utc, 2026, 1, 5, 11, 0),
+			want: at(utc, 2026, 1, 5, 12, 0),
+		},
+		{
+			name:     "windows in schedule timezone",
+			schedule: PostingSchedule{Timezone: "Europe/Moscow", Windows: daytime},
+			t:        at(utc, 2026, 1, 5, 5, 0),
+			want:     at(moscow, 2026, 1, 5, 9, 0).In(utc),
+		},
+		{
+			name:     "wall clock kept across DST change",
+			schedule: PostingSchedule{Timezone: "Europe/Berlin", Windows: []PostingWindow{{From: "09:00", To: "10:00"}}},
+			t:        at(berlin, 2026, 3, 28, 10, 30),
+			want:     at(berlin, 2026, 3, 29, 9, 0),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.schedule.NextWindow(tt.t)
+			if !got.Equal(tt.want) {
+				t.Errorf("NextWindow(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+			if got.Location() != tt.t.Location() {
+				t.Errorf("NextWindow(%v) location = %v, want %v", tt.t, got.Location(), tt.t.Location())
+			}
+		})
+	}
+
+	// Проверка самого перехода: 9:00 летнего времени - 7:00 UTC, зимнего - 8:00 UTC
+	s := PostingSchedule{Timezone: "Europe/Berlin", Windows: []PostingWindow{{From: "09:00", To: "10:00"}}}
+	if got, want := s.NextWindow(at(utc, 2026, 3, 28, 9, 30)), at(utc, 2026, 3, 29, 7, 0); !got.Equal(want) {
+		t.Errorf("NextWindow across DST = %v, want %v", got, want)
+	}
+}
+
+func TestPostingScheduleNextSlot(t *testing.T) {
+	utc := time.UTC
+	day := func(hour, min int) time.Time { return at(utc, 2026, 1, 5, hour, min) }
+
+	tests := []struct {
+		name     string
+		schedule PostingSchedule
+		t        time.Time
+		released []time.Time
+		want     time.Time
+	}{
+		{
+			name: "no limits",
+			t:    day(10, 0),
+			want: day(10, 0),
+		},
+		{
+			name:     "minimal gap",
+			schedule: PostingSchedule{MinGapMinutes: 30},
+			t:        day(10, 10),
+			released: []time.Time{day(10, 0)},
+			want:     day(10, 30),
+		},
+		{
+			name:     "gap already passed",
+			schedule: PostingSchedule{MinGapMinutes: 30},
+			t:        day(11, 0),
+			released: []time.Time{day(10, 0)},
+			want:     day(11, 0),
+		},
+		{
+			name:     "gap uses the latest release regardless of order",
+			schedule: PostingSchedule{MinGapMinutes: 30},
+			t:        day(10, 10),
+			released: []time.Time{day(10, 0), day(8, 0)},
+			want:     day(10, 30),
+		},
+		{
+			name:     "per hour limit waits for the oldest post to leave the hour",
+			schedule: PostingSchedule{MaxPerHour: 2},
+			t:        day(10, 30),
+			released: []time.Time{day(10, 20), day(10, 0)},
+			want:     day(11, 0),
+		},
+		{
+			name:     "per hour limit not reached",
+			schedule: PostingSchedule{MaxPerHour: 3},
+			t:        day(10, 30),
+			released: []time.Time{day(10, 0), day(10, 20)},
+			want:     day(10, 30),
+		},
+		{
+			name:     "per day limit moves to the next day window",
+			schedule: PostingSchedule{MaxPerDay: 2, Windows: []PostingWindow{{From: "09:00", To: "21:00"}}},
+			t:        day(12, 0),
+			released: []time.Time{day(9, 0), day(10, 0)},
+			want:     at(utc, 2026, 1, 6, 9, 0),
+		},
+		{
+			name:     "per day limit counts days in schedule timezone",
+			schedule: PostingSchedule{Timezone: "Europe/Moscow", MaxPerDay: 2},
+			t:        day(21, 30),
+			released: []time.Time{day(20, 0), day(20, 30)},
+			want:     day(21, 30),
+		},
+		{
+			name:     "gap pushes past the window end",
+			schedule: PostingSchedule{MinGapMinutes: 60, Windows: []PostingWindow{{From: "09:00", To: "21:00"}}},
+			t:        day(20, 40),
+			released: []time.Time{day(20, 30)},
+			want:     at(utc, 2026, 1, 6, 9, 0),
+		},
+		{
+			name:     "window start then gap",
+			schedule: PostingSchedule{MinGapMinutes: 30, Windows: []PostingWindow{{From: "22:00", To: "02:00"}}},
+			t:        day(21, 0),
+			released: []time.Time{day(21, 50)},
+			want:     day(22, 20),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			released := append([]time.Time(nil), tt.released...)
+			got := tt.schedule.NextSlot(tt.t, released)
+			if !got.Equal(tt.want) {
+				t.Errorf("NextSlot(%v) = %v, want %v", tt.t, got, tt.want)
+			}
+			for i := range released {
+				if !released[i].Equal(tt.released[i]) {
+					t.Fatal("NextSlot modified released")
+				}
+			}
+		})
+	}
+}
+
+func TestPostingScheduleValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule PostingSchedule
+		wantErr  bool
+	}{
+		{name: "empty", schedule: PostingSchedule{}},
+		{name: "full", schedule: PostingSchedule{
+			Timezone:      "Europe/Moscow",
+			Windows:       []PostingWindow{{Days: []string{"mon", "FRI"}, From: "22:00", To: "02:00"}},
+			MinGapMinutes: 10, MaxPerHour: 2, MaxPerDay: 10,
+		}},
+		{name: "unknown timezone", schedule: PostingSchedule{Timezone: "Mars/Base"}, wantErr: true},
+		{name: "bad time", schedule: PostingSchedule{Windows: []PostingWindow{{From: "9", To: "10:00"}}}, wantErr: true},
+		{name: "empty window", schedule: PostingSchedule{Windows: []PostingWindow{{From: "10:00", To: "10:00"}}}, wantErr: true},
+		{name: "unknown day", schedule: PostingSchedule{Windows: []PostingWindow{{Days: []string{"monday"}, From: "09:00", To: "10:00"}}}, wantErr: true},
+		{name: "negative limit", schedule: PostingSchedule{MaxPerDay: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.schedule.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
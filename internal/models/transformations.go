@@ -0,0 +1,350 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+	"unicode/utf8"
+)
+
+// TransformType тип шага преобразования текста
+type TransformType string
+
+const (
+	TransformReplace        TransformType = "replace"         // замена строки Find на Replace
+	TransformRegexReplace   TransformType = "regex_replace"   // замена по Pattern, в Replace доступны $1, ${name}
+	TransformStripLinks     TransformType = "strip_links"     // удаление ссылок
+	TransformStripMentions  TransformType = "strip_mentions"  // удаление @упоминаний
+	TransformStripHashtags  TransformType = "strip_hashtags"  // удаление #хэштегов
+	TransformRemoveEmoji    TransformType = "remove_emoji"    // удаление эмодзи
+	TransformTruncate       TransformType = "truncate"        // обрезка до Limit символов по границе слова
+	TransformAppendHashtags TransformType = "append_hashtags" // добавление Hashtags в конец текста
+	TransformTemplate       TransformType = "template"        // оборачивание текста шаблоном Template
+)
+
+// defaultEllipsis добавляется к обрезанному тексту, если Ellipsis не указан
+const defaultEllipsis = "…"
+
+// Transformation шаг преобразования текста. Шаги правила применяются по порядку.
+type Transformation struct {
+	Type     TransformType `json:"type"`
+	Find     string        `json:"find,omitempty"`
+	Pattern  string        `json:"pattern,omitempty"`
+	Replace  string        `json:"replace,omitempty"`
+	Limit    int           `json:"limit,omitempty"`
+	Ellipsis string        `json:"ellipsis,omitempty"`
+	Hashtags []string      `json:"hashtags,omitempty"`
+	Template string        `json:"template,omitempty"` // text/template, текст доступен как {{.Text}}
+}
+
+var (
+	linkRe    = regexp.MustCompile(`(?i)(?:https?://|www\.|t\.me/)[^\s<>"]+`)
+	mentionRe = regexp.MustCompile(`@[A-Za-z][A-Za-z0-9_]{3,31}`)
+	hashtagRe = regexp.MustCompile(`#[\p{L}\p{N}_]+`)
+)
+
+// Validate проверяет параметры шага
+func (t Transformation) Validate() error {
+	switch t.Type {
+	case TransformReplace:
+		if t.Find == "" {
+			return errors.New("find is required")
+		}
+	case TransformRegexReplace:
+		if t.Pattern == "" {
+			return errors.New("pattern is required")
+		}
+		if _, err := regexp.Compile(t.Pattern); err != nil {
+			return fmt.Errorf("invalid pattern: %v", err)
+		}
+	case TransformStripLinks, TransformStripMentions, TransformStripHashtags, TransformRemoveEmoji:
+	case TransformTruncate:
+		if t.Limit <= 0 {
+			return errors.New("limit must be positive")
+		}
+	case TransformAppendHashtags:
+		if len(normalizeHashtags(t.Hashtags)) == 0 {
+			return errors.New("at least one hashtag is required")
+		}
+	case TransformTemplate:
+		if _, err := template.New("step").Parse(t.Template); err != nil {
+			return fmt.Errorf("invalid template: %v", err)
+		}
+	default:
+		return fmt.Errorf("unknown transformation type %q", t.Type)
+	}
+	return nil
+}
+
+// Apply применяет шаг к тексту и сдвигает сущности форматирования
+func (t Transformation) Apply(text string, entities []TextEntity) (string, []TextEntity, error) {
+	switch t.Type {
+	case TransformReplace:
+		text, entities = ReplaceWithEntities(text, t.Find, t.Replace, entities)
+		return text, entities, nil
+
+	case TransformRegexReplace:
+		re, err := regexp.Compile(t.Pattern)
+		if err != nil {
+			return text, entities, fmt.Errorf("ошибка компиляции шаблона %q: %v", t.Pattern, err)
+		}
+		var ranges []textRange
+		for _, m := range re.FindAllStringSubmatchIndex(text, -1) {
+			repl := string(re.ExpandString(nil, t.Replace, text, m))
+			ranges = append(ranges, textRange{start: m[0], end: m[1], repl: repl})
+		}
+		text, entities = replaceRanges(text, ranges, entities)
+		return text, entities, nil
+
+	case TransformStripLinks:
+		text, entities = removeMatches(text, linkRe.FindAllStringIndex(text, -1), entities)
+		// Ссылки, спрятанные в тексте, превращаются в обычный текст
+		result := entities[:0:0]
+		for _, e := range entities {
+			if e.Type != EntityTextLink {
+				result = append(result, e)
+			}
+		}
+		return text, result, nil
+
+	case TransformStripMentions:
+		text, entities = removeMatches(text, wordStartMatches(text, mentionRe), entities)
+		return text, entities, nil
+
+	case TransformStripHashtags:
+		text, entities = removeMatches(text, wordStartMatches(text, hashtagRe), entities)
+		return text, entities, nil
+
+	case TransformRemoveEmoji:
+		text, entities = removeMatches(text, emojiRanges(text), entities)
+		return text, entities, nil
+
+	case TransformTruncate:
+		text, entities = truncateText(text, t.Limit, t.Ellipsis, entities)
+		return text, entities, nil
+
+	case TransformAppendHashtags:
+		text = appendHashtags(text, t.Hashtags)
+		return text, entities, nil
+
+	case TransformTemplate:
+		return applyTemplate(text, t.Template, entities)
+
+	default:
+		return text, entities, fmt.Errorf("неизвестный тип преобразования %q", t.Type)
+	}
+}
+
+// textRange замена фрагмента text[start:end] на repl, позиции в байтах
+type textRange struct {
+	start int
+	end   int
+	repl  string
+}
+
+// replaceRanges заменяет непересекающиеся фрагменты, упорядоченные по началу,
+// и сдвигает сущности
+func replaceRanges(text string, ranges []textRange, entities []TextEntity) (string, []TextEntity) {
+	if len(ranges) == 0 {
+		return text, entities
+	}
+
+	var sb strings.Builder
+	edits := make([]textEdit, 0, len(ranges))
+	prev, pos := 0, 0
+	for _, r := range ranges {
+		pos += UTF16Len(text[prev:r.start])
+		sb.WriteString(text[prev:r.start])
+		sb.WriteString(r.repl)
+
+		oldLen := UTF16Len(text[r.start:r.end])
+		edits = append(edits, textEdit{start: pos, oldLen: oldLen, newLen: UTF16Len(r.repl)})
+		pos += oldLen
+		prev = r.end
+	}
+	sb.WriteString(text[prev:])
+
+	return sb.String(), applyEdits(entities, edits)
+}
+
+// removeMatches удаляет найденные фрагменты вместе с одним соседним пробелом,
+// чтобы на месте удаленного фрагмента не оставалось двойных пробелов
+func removeMatches(text string, matches [][]int, entities []TextEntity) (string, []TextEntity) {
+	ranges := make([]textRange, 0, len(matches))
+	lastEnd := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if start > lastEnd && text[start-1] == ' ' {
+			start--
+		} else if end < len(text) && text[end] == ' ' {
+			end++
+		}
+		if start < lastEnd {
+			start = lastEnd
+		}
+		ranges = append(ranges, textRange{start: start, end: end})
+		lastEnd = end
+	}
+	return replaceRanges(text, ranges, entities)
+}
+
+// wordStartMatches возвращает совпадения, которые начинаются не внутри слова,
+// чтобы не задеть, например, адреса почты
+func wordStartMatches(text string, re *regexp.Regexp) [][]int {
+	var result [][]int
+	for _, m := range re.FindAllStringIndex(text, -1) {
+		if m[0] > 0 {
+			r, _ := utf8.DecodeLastRuneInString(text[:m[0]])
+			if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+				continue
+			}
+		}
+		result = append(result, m)
+	}
+	return result
+}
+
+// isEmoji проверяет, относится ли символ к эмодзи или служебным символам эмодзи
+func isEmoji(r rune) bool {
+	switch {
+	case r >= 0x1F000 && r <= 0x1FAFF: // пиктограммы, смайлы, флаги
+		return true
+	case r >= 0x2600 && r <= 0x27BF: // разные символы и dingbats
+		return true
+	case r >= 0x2B00 && r <= 0x2BFF: // стрелки и фигуры
+		return true
+	case r >= 0x2300 && r <= 0x23FF: // технические символы: ⌚ ⏰
+		return true
+	case r >= 0xE0020 && r <= 0xE007F: // теги флагов
+		return true
+	case r == 0x200D || r == 0xFE0F || r == 0x20E3: // ZWJ, селектор варианта, keycap
+		return true
+	}
+	return false
+}
+
+// emojiRanges возвращает байтовые диапазоны последовательностей эмодзи
+func emojiRanges(text string) [][]int {
+	var ranges [][]int
+	start := -1
+	for i, r := range text {
+		if isEmoji(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start >= 0 {
+			ranges = append(ranges, []int{start, i})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		ranges = append(ranges, []int{start, len(text)})
+	}
+	return ranges
+}
+
+// truncateText обрезает текст до limit символов по границе слова и добавляет ellipsis.
+// Многоточие входит в limit и не попадает в сущности.
+func truncateText(text string, limit int, ellipsis string, entities []TextEntity) (string, []TextEntity) {
+	if utf8.RuneCountInString(text) <= limit {
+		return text, entities
+	}
+	if ellipsis == "" {
+		ellipsis = defaultEllipsis
+	}
+
+	keep := limit - utf8.RuneCountInString(ellipsis)
+	if keep < 0 {
+		keep = 0
+	}
+
+	// Байтовая позиция после keep символов
+	cut := len(text)
+	n := 0
+	for i := range text {
+		if n == keep {
+			cut = i
+			break
+		}
+		n++
+	}
+
+	// Отступаем к последнему пробелу, если обрезка попала внутрь слова
+	if r, _ := utf8.DecodeRuneInString(text[cut:]); !unicode.IsSpace(r) {
+		if i := strings.LastIndexFunc(text[:cut], unicode.IsSpace); i > 0 {
+			cut = i
+		}
+	}
+	head := strings.TrimRightFunc(text[:cut], func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.IsPunct(r)
+	})
+
+	edits := []textEdit{{start: UTF16Len(head), oldLen: UTF16Len(text[len(head):]), newLen: 0}}
+	return head + ellipsis, applyEdits(entities, edits)
+}
+
+// normalizeHashtags приводит хэштеги к виду #тег и убирает пустые
+func normalizeHashtags(tags []string) []string {
+	var result []string
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.TrimPrefix(strings.TrimSpace(tag), "#")), "_")
+		if tag != "" {
+			result = append(result, "#"+tag)
+		}
+	}
+	return result
+}
+
+// appendHashtags добавляет в конец текста хэштеги, которых в нем еще нет
+func appendHashtags(text string, tags []string) string {
+	existing := make(map[string]bool)
+	for _, m := range hashtagRe.FindAllString(text, -1) {
+		existing[strings.ToLower(m)] = true
+	}
+
+	var add []string
+	for _, tag := range normalizeHashtags(tags) {
+		if !existing[strings.ToLower(tag)] {
+			existing[strings.ToLower(tag)] = true
+			add = append(add, tag)
+		}
+	}
+	if len(add) == 0 {
+		return text
+	}
+	if strings.TrimSpace(text) == "" {
+		return strings.Join(add, " ")
+	}
+	return text + "\n\n" + strings.Join(add, " ")
+}
+
+// templateMarker подставляется вместо текста, чтобы найти его место в результате шаблона
+const templateMarker = "\x00text\x00"
+
+// applyTemplate оборачивает текст шаблоном. Сущности сдвигаются, если текст
+// входит в результат ровно один раз, иначе сбрасываются.
+func applyTemplate(text, tmpl string, entities []TextEntity) (string, []TextEntity, error) {
+	t, err := template.New("step").Parse(tmpl)
+	if err != nil {
+		return text, entities, fmt.Errorf("ошибка разбора шаблона: %v", err)
+	}
+
+	var sb strings.Builder
+	if err := t.Execute(&sb, struct{ Text string }{Text: templateMarker}); err != nil {
+		return text, entities, fmt.Errorf("ошибка выполнения шаблона: %v", err)
+	}
+	rendered := sb.String()
+
+	if strings.Count(rendered, templateMarker) != 1 {
+		return strings.ReplaceAll(rendered, templateMarker, text), nil, nil
+	}
+
+	before, after, _ := strings.Cut(rendered, templateMarker)
+	result, entities := PrependWithEntities(text, before, entities)
+	return result + after, entities, nil
+}
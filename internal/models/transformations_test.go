@@ -0,0 +1,147 @@
+package models
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTransformationApply(t *testing.T) {
+	bold := func(offset, length int) TextEntity {
+		return TextEntity{Type: EntityBold, Offset: offset, Length: length}
+	}
+
+	tests := []struct {
+		name         string
+		step         Transformation
+		text         string
+		entities     []TextEntity
+		wantText     string
+		wantEntities []TextEntity
+	}{
+		{
+			name:         "truncate at word boundary",
+			step:         Transformation{Type: TransformTruncate, Limit: 10},
+			text:         "Привет мир как дела",
+			entities:     []TextEntity{bold(0, 6), bold(7, 3)},
+			wantText:     "Привет…",
+			wantEntities: []TextEntity{bold(0, 6)},
+		},
+		{
+			name:         "truncate keeps short text",
+			step:         Transformation{Type: TransformTruncate, Limit: 10, Ellipsis: "..."},
+			text:         "коротко",
+			entities:     []TextEntity{bold(0, 7)},
+			wantText:     "коротко",
+			wantEntities: []TextEntity{bold(0, 7)},
+		},
+		{
+			name: "strip links drops hidden links",
+			step: Transformation{Type: TransformStripLinks},
+			text: "see https://x.com now",
+			entities: []TextEntity{
+				{Type: EntityTextLink, Offset: 0, Length: 3, URL: "https://y.com"},
+				bold(18, 3),
+			},
+			wantText:     "see now",
+			wantEntities: []TextEntity{bold(4, 3)},
+		},
+		{
+			name:         "strip mentions skips emails",
+			step:         Transformation{Type: TransformStripMentions},
+			text:         "hi @user_name and a@user.com",
+			entities:     []TextEntity{bold(14, 3)},
+			wantText:     "hi and a@user.com",
+			wantEntities: []TextEntity{bold(3, 3)},
+		},
+		{
+			name:         "strip hashtags",
+			step:         Transformation{Type: TransformStripHashtags},
+			text:         "#акция скидки #sale",
+			entities:     []TextEntity{bold(7, 6)},
+			wantText:     "скидки",
+			wantEntities: []TextEntity{bold(0, 6)},
+		},
+		{
+			name:         "remove emoji shifts by surrogate pairs",
+			step:         Transformation{Type: TransformRemoveEmoji},
+			text:         "🔥Горячо🔥",
+			entities:     []TextEntity{bold(2, 6)},
+			wantText:     "Горячо",
+			wantEntities: []TextEntity{bold(0, 6)},
+		},
+		{
+			name:         "regex replace with groups",
+			step:         Transformation{Type: TransformRegexReplace, Pattern: `(\d+) руб`, Replace: "$1 ₽"},
+			text:         "Цена 100 руб!",
+			entities:     []TextEntity{bold(5, 7)},
+			wantText:     "Цена 100 ₽!",
+			wantEntities: []TextEntity{bold(5, 5)},
+		},
+		{
+			name:         "append only missing hashtags",
+			step:         Transformation{Type: TransformAppendHashtags, Hashtags: []string{"SALE", "#new", "two words"}},
+			text:         "text #sale",
+			entities:     []TextEntity{bold(0, 4)},
+			wantText:     "text #sale\n\n#new #two_words",
+			wantEntities: []TextEntity{bold(0, 4)},
+		},
+		{
+			name:         "template shifts entities by the prefix",
+			step:         Transformation{Type: TransformTemplate, Template: "🔥 {{.Text}}\n— источник"},
+			text:         "abc",
+			entities:     []TextEntity{bold(0, 3)},
+			wantText:     "🔥 abc\n— источник",
+			wantEntities: []TextEntity{bold(3, 3)},
+		},
+		{
+			name:         "template repeating the text drops entities",
+			step:         Transformation{Type: TransformTemplate, Template: "{{.Text}} {{.Text}}"},
+			text:         "abc",
+			entities:     []TextEntity{bold(0, 3)},
+			wantText:     "abc abc",
+			wantEntities: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, entities, err := tt.step.Apply(tt.text, tt.entities)
+			if err != nil {
+				t.Fatalf("Apply error: %v", err)
+			}
+			if text != tt.wantText {
+				t.Errorf("text = %q, want %q", text, tt.wantText)
+			}
+			if len(entities) == 0 && len(tt.wantEntities) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(entities, tt.wantEntities) {
+				t.Errorf("entities = %+v, want %+v", entities, tt.wantEntities)
+			}
+		})
+	}
+}
+
+func TestTransformationValidate(t *testing.T) {
+	tests := []struct {
+		step    Transformation
+		wantErr bool
+	}{
+		{step: Transformation{Type: TransformReplace, Find: "a"}},
+		{step: Transformation{Type: TransformReplace}, wantErr: true},
+		{step: Transformation{Type: TransformRegexReplace, Pattern: "a+"}},
+		{step: Transformation{Type: TransformRegexReplace, Pattern: "("}, wantErr: true},
+		{step: Transformation{Type: TransformStripLinks}},
+		{step: Transformation{Type: TransformTruncate, Limit: 0}, wantErr: true},
+		{step: Transformation{Type: TransformAppendHashtags, Hashtags: []string{" # "}}, wantErr: true},
+		{step: Transformation{Type: TransformTemplate, Template: "{{.Text"}, wantErr: true},
+		{step: Transformation{Type: "unknown"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		err := tt.step.Validate()
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Validate(%+v) error = %v, wantErr %v", tt.step, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,622 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/tg"
+	"go.uber.org/zap"
+
+	"github.com/drerr0r/tgparserbot/internal/media"
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/ratelimit"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+)
+
+// accountCheckInterval как часто пул сверяет аккаунты с БД и проверяет их клиенты
+const accountCheckInterval = 30 * time.Second
+
+// poolMember аккаунт пула и его клиент
+type poolMember struct {
+	account *models.TelegramAccount
+	client  *MTProtoClient
+}
+
+// AccountPool пул аккаунтов Telegram. Каждый канал читает один аккаунт:
+// каналы распределяются между аккаунтами по хэшу и закрепляются за ними.
+// Если аккаунт попал под долгий FLOOD_WAIT, потерял авторизацию или не
+// может прочитать канал, канал переходит к другому аккаунту.
+type AccountPool struct {
+	cfg      *models.TelegramConfig
+	accounts *storage.AccountRepository
+	peers    *storage.PeerRepository
+	sessions *storage.SessionRepository
+	auths    *storage.AuthRepository
+	logger   *zap.SugaredLogger
+	wg       sync.WaitGroup
+
+	mu            sync.RWMutex
+	runCtx        context.Context           // контекст запуска клиентов
+	members       map[int64]*poolMember     // ID аккаунта -> аккаунт и клиент
+	assignments   map[string]int64          // канонический вид канала -> ID аккаунта
+	denied        map[string]map[int64]bool // канал -> аккаунты без доступа к нему
+	watched       map[string]int64          // отслеживаемый канал -> ID канала
+	updateHandler UpdateHandler
+	deleteHandler DeleteHandler
+	gapHandler    func(channel string)
+}
+
+// NewAccountPool создает пул аккаунтов. Аккаунт из конфигурации
+// добавляется в пул при запуске.
+func NewAccountPool(cfg *models.TelegramConfig, accounts *storage.AccountRepository, peers *storage.PeerRepository, sessions *storage.SessionRepository, auths *storage.AuthRepository, logger *zap.SugaredLogger) *AccountPool {
+	return &AccountPool{
+		cfg:         cfg,
+		accounts:    accounts,
+		peers:       peers,
+		sessions:    sessions,
+		auths:       auths,
+		logger:      logger,
+		members:     make(map[int64]*poolMember),
+		assignments: make(map[string]int64),
+		denied:      make(map[string]map[int64]bool),
+		watched:     make(map[string]int64),
+	}
+}
+
+// SetUpdateHandler задает обработчик новых и отредактированных сообщений
+// для всех аккаунтов пула
+func (p *AccountPool) SetUpdateHandler(handler UpdateHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.updateHandler = handler
+}
+
+// SetDeleteHandler задает обработчик удалений сообщений для всех аккаунтов пула
+func (p *AccountPool) SetDeleteHandler(handler DeleteHandler) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.deleteHandler = handler
+}
+
+// SetGapHandler задает обработчик разрывов потока обновлений. Он же
+// вызывается после перехода канала к другому аккаунту, чтобы догрузить
+// пропущенные сообщения.
+func (p *AccountPool) SetGapHandler(handler func(channel string)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.gapHandler = handler
+}
+
+// Start запускает клиенты активных аккаунтов и следит за их состоянием
+func (p *AccountPool) Start(ctx context.Context) error {
+	if p.cfg.Phone != "" {
+		if _, err := p.accounts.Ensure(ctx, p.cfg.Phone, p.cfg.SessionFile); err != nil {
+			return err
+		}
+	}
+
+	assignments, err := p.accounts.GetAssignments(ctx)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	p.assignments = assignments
+	p.runCtx = ctx
+	p.mu.Unlock()
+
+	if err := p.syncAccounts(ctx); err != nil {
+		return err
+	}
+
+	p.mu.RLock()
+	count := len(p.members)
+	p.mu.RUnlock()
+	if count == 0 {
+		return fmt.Errorf("нет активных аккаунтов Telegram")
+	}
+	p.logger.Infof("👥 Запущено аккаунтов Telegram: %d", count)
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.watchAccounts(ctx)
+	}()
+	return nil
+}
+
+// Wait ждет остановки пула после отмены контекста запуска: наблюдения за
+// аккаунтами и клиентов всех аккаунтов
+func (p *AccountPool) Wait() {
+	p.wg.Wait()
+
+	p.mu.RLock()
+	clients := make([]*MTProtoClient, 0, len(p.members))
+	for _, member := range p.members {
+		clients = append(clients, member.client)
+	}
+	p.mu.RUnlock()
+
+	for _, client := range clients {
+		client.Wait()
+	}
+}
+
+// WaitReady ждет, пока клиент хотя бы одного аккаунта будет готов к работе.
+// Возвращает false, если за timeout ни один клиент не готов.
+func (p *AccountPool) WaitReady(ctx context.Context, timeout time.Duration) bool {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	p.mu.RLock()
+	clients := make([]*MTProtoClient, 0, len(p.members))
+	for _, member := range p.members {
+		clients = append(clients, member.client)
+	}
+	p.mu.RUnlock()
+
+	ready := make(chan struct{}, len(clients))
+	for _, client := range clients {
+		go func(client *MTProtoClient) {
+			if client.WaitReady(ctx) == nil {
+				ready <- struct{}{}
+			}
+		}(client)
+	}
+
+	select {
+	case <-ready:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Ready проверяет, есть ли в пуле аккаунт, готовый читать каналы
+func (p *AccountPool) Ready() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	now := time.Now()
+	for _, member := range p.members {
+		if member.usable(now) {
+			return true
+		}
+	}
+	return false
+}
+
+// usable проверяет, можно ли читать каналы через аккаунт
+func (m *poolMember) usable(now time.Time) bool {
+	return m.account.Available(now) && m.client.Ready()
+}
+
+// WatchChannel подписывается на обновления канала через закрепленный за ним аккаунт
+func (p *AccountPool) WatchChannel(ctx context.Context, channel string) (int64, error) {
+	var channelID int64
+	err := p.do(ctx, channel, func(client *MTProtoClient) error {
+		var err error
+		channelID, err = client.WatchChannel(ctx, channel)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	p.mu.Lock()
+	p.watched[channelKey(channel)] = channelID
+	p.mu.Unlock()
+	return channelID, nil
+}
+
+// UnwatchChannel прекращает передачу обновлений канала в обработчик
+func (p *AccountPool) UnwatchChannel(channelID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	for key, id := range p.watched {
+		if id == channelID {
+			delete(p.watched, key)
+		}
+	}
+	for _, member := range p.members {
+		member.client.UnwatchChannel(channelID)
+	}
+}
+
+// GetChannelMessages получает последние сообщения канала
+func (p *AccountPool) GetChannelMessages(ctx context.Context, channel string, limit int) ([]*ParsedMessage, error) {
+	var messages []*ParsedMessage
+	err := p.do(ctx, channel, func(client *MTProtoClient) error {
+		var err error
+		messages, err = client.GetChannelMessages(ctx, channel, limit)
+		return err
+	})
+	return messages, err
+}
+
+// GetHistoryPage получает страницу истории канала, см. MTProtoClient.GetHistoryPage
+func (p *AccountPool) GetHistoryPage(ctx context.Context, channel string, afterID int64, from time.Time, limit int) (*HistoryPage, error) {
+	var page *HistoryPage
+	err := p.do(ctx, channel, func(client *MTProtoClient) error {
+		var err error
+		page, err = client.GetHistoryPage(ctx, channel, afterID, from, limit)
+		return err
+	})
+	return page, err
+}
+
+// DownloadMedia скачивает медиа через аккаунт, получивший сообщение
+func (p *AccountPool) DownloadMedia(ctx context.Context, ref *MediaRef, cache *media.Cache) (string, error) {
+	if ref == nil {
+		return "", fmt.Errorf("у сообщения нет медиа")
+	}
+
+	p.mu.RLock()
+	member, ok := p.members[ref.AccountID]
+	p.mu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("аккаунт %d, получивший сообщение, не найден в пуле", ref.AccountID)
+	}
+	return member.client.DownloadMedia(ctx, ref, cache)
+}
+
+// do выполняет запрос к каналу через закрепленный за ним аккаунт. Если
+// аккаунт не может выполнить запрос, канал переходит к следующему
+// аккаунту и запрос повторяется.
+func (p *AccountPool) do(ctx context.Context, channel string, call func(client *MTProtoClient) error) error {
+	p.mu.RLock()
+	attempts := len(p.members)
+	p.mu.RUnlock()
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		member, err := p.memberFor(ctx, channel)
+		if err != nil {
+			if lastErr != nil {
+				return lastErr
+			}
+			return err
+		}
+
+		err = call(member.client)
+		if err == nil || ctx.Err() != nil {
+			return err
+		}
+		if !p.failover(ctx, member, channel, err) {
+			return err
+		}
+		lastErr = err
+	}
+
+	if lastErr == nil {
+		return fmt.Errorf("нет активных аккаунтов Telegram")
+	}
+	return lastErr
+}
+
+// memberFor возвращает аккаунт, закрепленный за каналом. Если аккаунт
+// недоступен, канал закрепляется за доступным аккаунтом с наибольшим
+// весом для этого канала, так что распределение почти не меняется при
+// добавлении и удалении аккаунтов.
+func (p *AccountPool) memberFor(ctx context.Context, channel string) (*poolMember, error) {
+	key := channelKey(channel)
+	now := time.Now()
+
+	p.mu.Lock()
+	if id, ok := p.assignments[key]; ok {
+		if member, ok := p.members[id]; ok && member.usable(now) && !p.denied[key][id] {
+			p.mu.Unlock()
+			return member, nil
+		}
+	}
+
+	var best *poolMember
+	var bestScore uint64
+	for id, member := range p.members {
+		if !member.usable(now) || p.denied[key][id] {
+			continue
+		}
+		if score := shardScore(key, id); best == nil || score > bestScore {
+			best, bestScore = member, score
+		}
+	}
+	if best == nil {
+		// Ни один аккаунт не прочитал канал - в следующий раз пробуем все снова
+		delete(p.denied, key)
+		p.mu.Unlock()
+		return nil, fmt.Errorf("нет доступных аккаунтов Telegram для канала %s", channel)
+	}
+	account := best.account
+	p.assignments[key] = account.ID
+	p.mu.Unlock()
+
+	if err := p.accounts.Assign(ctx, key, account.ID); err != nil {
+		p.logger.Warnf("⚠️ %v", err)
+	}
+	p.logger.Infof("📌 Канал %s закреплен за аккаунтом %s", key, account.Phone)
+	return best, nil
+}
+
+// failover разбирает ошибку запроса. Возвращает true, если канал нужно
+// передать другому аккаунту.
+func (p *AccountPool) failover(ctx context.Context, member *poolMember, channel string, err error) bool {
+	account := p.accountOf(member)
+
+	if wait, ok := ratelimit.AsWait(err); ok {
+		until := time.Now().Add(wait.Wait)
+		p.logger.Warnf("⏳ Аккаунт %s ограничен FLOOD_WAIT до %s, каналы переходят к другим аккаунтам",
+			account.Phone, until.Format(time.RFC3339))
+		p.setStatus(ctx, member, models.AccountFloodWait, &until, err)
+		p.releaseChannels(account.ID)
+		return true
+	}
+
+	if auth.IsUnauthorized(err) {
+		p.logger.Errorf("🔒 Аккаунт %s потерял авторизацию, каналы переходят к другим аккаунтам: %v", account.Phone, err)
+		p.setStatus(ctx, member, models.AccountUnauthorized, nil, err)
+		member.client.Stop()
+		p.releaseChannels(account.ID)
+		return true
+	}
+
+	if noChannelAccess(err) {
+		key := channelKey(channel)
+		p.logger.Warnf("🚫 Аккаунт %s не может читать канал %s, канал переходит к другому аккаунту: %v", account.Phone, key, err)
+
+		p.mu.Lock()
+		if p.denied[key] == nil {
+			p.denied[key] = make(map[int64]bool)
+		}
+		p.denied[key][account.ID] = true
+		delete(p.assignments, key)
+		_, watched := p.watched[key]
+		p.mu.Unlock()
+
+		if watched {
+			p.rewatch(member, []string{key})
+		}
+		return true
+	}
+
+	return false
+}
+
+// noChannelAccess проверяет, означает ли ошибка, что у аккаунта нет доступа к каналу
+func noChannelAccess(err error) bool {
+	return errors.Is(err, errNoAccess) ||
+		tg.IsChannelPrivate(err) ||
+		tg.IsChannelInvalid(err) ||
+		tg.IsUserBannedInChannel(err) ||
+		tg.IsInviteHashExpired(err)
+}
+
+// setStatus сохраняет состояние аккаунта в памяти и в БД
+func (p *AccountPool) setStatus(ctx context.Context, member *poolMember, status models.AccountStatus, until *time.Time, cause error) {
+	lastError := ""
+	if cause != nil {
+		lastError = cause.Error()
+	}
+
+	p.mu.Lock()
+	account := *member.account
+	account.Status = status
+	account.FloodWaitUntil = until
+	account.LastError = lastError
+	member.account = &account
+	p.mu.Unlock()
+
+	if err := p.accounts.SetStatus(ctx, account.ID, status, until, lastError); err != nil {
+		p.logger.Errorf("❌ %v", err)
+	}
+
+	// Интерфейс предложит войти заново через /api/telegram/auth/start
+	if status == models.AccountUnauthorized {
+		state := &models.TelegramAuth{AccountID: account.ID, State: models.AuthFailed, LastError: lastError}
+		if err := p.auths.Report(ctx, state); err != nil {
+			p.logger.Errorf("❌ %v", err)
+		}
+	}
+}
+
+// releaseChannels снимает каналы с аккаунта, следующий запрос к каналу
+// закрепит его за другим аккаунтом
+func (p *AccountPool) releaseChannels(accountID int64) {
+	p.mu.Lock()
+	var watched []string
+	for key, id := range p.assignments {
+		if id != accountID {
+			continue
+		}
+		delete(p.assignments, key)
+		if _, ok := p.watched[key]; ok {
+			watched = append(watched, key)
+		}
+	}
+	member := p.members[accountID]
+	p.mu.Unlock()
+
+	p.rewatch(member, watched)
+}
+
+// rewatch переносит подписку на обновления каналов с аккаунта на другие
+// аккаунты пула и догружает сообщения, пропущенные за время переноса
+func (p *AccountPool) rewatch(from *poolMember, channels []string) {
+	p.mu.RLock()
+	gapHandler := p.gapHandler
+	ctx := p.runCtx
+	p.mu.RUnlock()
+
+	for _, channel := range channels {
+		go func(channel string) {
+			if from != nil {
+				p.mu.RLock()
+				channelID := p.watched[channel]
+				p.mu.RUnlock()
+				from.client.UnwatchChannel(channelID)
+			}
+
+			if _, err := p.WatchChannel(ctx, channel); err != nil {
+				p.logger.Errorf("❌ Не удалось перенести канал %s на другой аккаунт: %v", channel, err)
+				return
+			}
+			if gapHandler != nil {
+				gapHandler(channel)
+			}
+		}(channel)
+	}
+}
+
+// watchAccounts периодически сверяет пул с аккаунтами в БД
+func (p *AccountPool) watchAccounts(ctx context.Context) {
+	ticker := time.NewTicker(accountCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.mu.RLock()
+			for _, member := range p.members {
+				member.client.Stop()
+			}
+			p.mu.RUnlock()
+			return
+		case <-ticker.C:
+			if err := p.syncAccounts(ctx); err != nil && ctx.Err() == nil {
+				p.logger.Errorf("❌ %v", err)
+			}
+		}
+	}
+}
+
+// syncAccounts запускает клиенты новых и включенных аккаунтов, останавливает
+// выключенные и удаленные, перезапускает остановившиеся клиенты
+func (p *AccountPool) syncAccounts(ctx context.Context) error {
+	accounts, err := p.accounts.List(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	present := make(map[int64]bool, len(accounts))
+	for _, account := range accounts {
+		present[account.ID] = true
+
+		p.mu.Lock()
+		member, ok := p.members[account.ID]
+		if ok {
+			member.account = account
+		}
+		p.mu.Unlock()
+
+		if !account.IsActive {
+			if ok {
+				p.removeMember(member, "выключен")
+			}
+			continue
+		}
+
+		if account.Status == models.AccountFloodWait && account.Available(now) {
+			p.logger.Infof("✅ Ограничение FLOOD_WAIT аккаунта %s закончилось", account.Phone)
+			if ok {
+				p.setStatus(ctx, member, models.AccountActive, nil, nil)
+			} else if err := p.accounts.SetStatus(ctx, account.ID, models.AccountActive, nil, ""); err != nil {
+				p.logger.Errorf("❌ %v", err)
+			}
+		}
+
+		if !ok {
+			p.addMember(ctx, account)
+			continue
+		}
+
+		// Клиент остановился сам: сессия отозвана или соединение потеряно
+		if !member.client.Running() && account.Status != models.AccountUnauthorized {
+			if runErr := member.client.Err(); runErr != nil && auth.IsUnauthorized(runErr) {
+				p.logger.Errorf("🔒 Аккаунт %s потерял авторизацию: %v", account.Phone, runErr)
+				p.setStatus(ctx, member, models.AccountUnauthorized, nil, runErr)
+				p.releaseChannels(account.ID)
+				continue
+			}
+
+			p.logger.Warnf("⚠️ Клиент аккаунта %s остановлен, перезапуск", account.Phone)
+			p.releaseChannels(account.ID)
+			if err := member.client.Start(ctx); err != nil {
+				p.logger.Errorf("❌ Ошибка запуска клиента аккаунта %s: %v", account.Phone, err)
+			}
+		}
+	}
+
+	p.mu.RLock()
+	var removed []*poolMember
+	for id, member := range p.members {
+		if !present[id] {
+			removed = append(removed, member)
+		}
+	}
+	p.mu.RUnlock()
+	for _, member := range removed {
+		p.removeMember(member, "удален")
+	}
+
+	return nil
+}
+
+// addMember создает и запускает клиент аккаунта
+func (p *AccountPool) addMember(ctx context.Context, account *models.TelegramAccount) {
+	client := NewMTProtoClient(p.cfg.APIID, p.cfg.APIHash, account, p.peers, p.sessions, p.auths, p.logger)
+
+	p.mu.Lock()
+	client.SetUpdateHandler(p.updateHandler)
+	client.SetDeleteHandler(p.deleteHandler)
+	client.SetGapHandler(p.gapHandler)
+	p.members[account.ID] = &poolMember{account: account, client: client}
+	p.mu.Unlock()
+
+	p.logger.Infof("➕ Аккаунт %s добавлен в пул", account.Phone)
+	if err := client.Start(ctx); err != nil {
+		p.logger.Errorf("❌ Ошибка запуска клиента аккаунта %s: %v", account.Phone, err)
+	}
+}
+
+// removeMember останавливает клиент аккаунта и передает его каналы другим аккаунтам
+func (p *AccountPool) removeMember(member *poolMember, reason string) {
+	account := p.accountOf(member)
+	p.logger.Infof("➖ Аккаунт %s %s, его каналы переходят к другим аккаунтам", account.Phone, reason)
+	member.client.Stop()
+	p.releaseChannels(account.ID)
+
+	p.mu.Lock()
+	delete(p.members, account.ID)
+	p.mu.Unlock()
+}
+
+// accountOf возвращает текущее состояние аккаунта участника пула.
+// Состояние заменяется под блокировкой пула, поэтому читается так же.
+func (p *AccountPool) accountOf(member *poolMember) *models.TelegramAccount {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return member.account
+}
+
+// channelKey возвращает канонический вид канала для назначений
+func channelKey(channel string) string {
+	if ref, err := models.ParseChannelRef(channel); err == nil {
+		return ref.String()
+	}
+	return channel
+}
+
+// shardScore вес аккаунта для канала при распределении каналов
+func shardScore(channel string, accountID int64) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(channel))
+	h.Write([]byte{0})
+	h.Write([]byte(strconv.FormatInt(accountID, 10)))
+	return h.Sum64()
+}
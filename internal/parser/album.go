@@ -0,0 +1,124 @@
+package parser
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// albumFlushDelay время ожидания остальных частей альбома из потока обновлений
+const albumFlushDelay = 2 * time.Second
+
+// mergeAlbum объединяет части альбома в одно сообщение. ID сообщения - ID
+// первой части, текст берется из первой части с подписью.
+func mergeAlbum(parts []*ParsedMessage) *ParsedMessage {
+	sort.Slice(parts, func(i, j int) bool {
+		return parts[i].ID < parts[j].ID
+	})
+
+	merged := *parts[0]
+	merged.Parts = parts
+	for _, part := range parts {
+		if part.Content != "" {
+			merged.Content = part.Content
+			merged.Entities = part.Entities
+			break
+		}
+	}
+	for _, part := range parts {
+		if part.Pts > merged.Pts {
+			merged.Pts = part.Pts
+		}
+	}
+
+	return &merged
+}
+
+// groupAlbums объединяет сообщения с одинаковым GroupedID, сохраняя порядок
+// первого появления группы в списке
+func groupAlbums(messages []*ParsedMessage) []*ParsedMessage {
+	groups := make(map[int64][]*ParsedMessage)
+	for _, msg := range messages {
+		if msg.GroupedID != 0 {
+			groups[msg.GroupedID] = append(groups[msg.GroupedID], msg)
+		}
+	}
+	if len(groups) == 0 {
+		return messages
+	}
+
+	result := make([]*ParsedMessage, 0, len(messages))
+	for _, msg := range messages {
+		if msg.GroupedID == 0 {
+			result = append(result, msg)
+			continue
+		}
+		if parts, ok := groups[msg.GroupedID]; ok {
+			result = append(result, mergeAlbum(parts))
+			delete(groups, msg.GroupedID)
+		}
+	}
+
+	return result
+}
+
+// albumCollector собирает части альбомов, которые приходят отдельными
+// обновлениями, и отдает альбом целиком, когда новые части перестают приходить
+type albumCollector struct {
+	mu      sync.Mutex
+	delay   time.Duration
+	pending map[int64]*pendingAlbum
+	flush   func(msg *ParsedMessage)
+}
+
+// pendingAlbum альбом, ожидающий остальные части
+type pendingAlbum struct {
+	parts []*ParsedMessage
+	timer *time.Timer
+}
+
+// newAlbumCollector создает сборщик альбомов
+func newAlbumCollector(delay time.Duration, flush func(msg *ParsedMessage)) *albumCollector {
+	return &albumCollector{
+		delay:   delay,
+		pending: make(map[int64]*pendingAlbum),
+		flush:   flush,
+	}
+}
+
+// Add добавляет сообщение. Сообщения вне альбомов отдаются сразу.
+func (c *albumCollector) Add(msg *ParsedMessage) {
+	if msg.GroupedID == 0 {
+		c.flush(msg)
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	album, ok := c.pending[msg.GroupedID]
+	if !ok {
+		groupedID := msg.GroupedID
+		album = &pendingAlbum{}
+		album.timer = time.AfterFunc(c.delay, func() {
+			c.flushAlbum(groupedID)
+		})
+		c.pending[groupedID] = album
+	} else {
+		album.timer.Reset(c.delay)
+	}
+
+	album.parts = append(album.parts, msg)
+}
+
+// flushAlbum отдает собранный альбом
+func (c *albumCollector) flushAlbum(groupedID int64) {
+	c.mu.Lock()
+	album, ok := c.pending[groupedID]
+	delete(c.pending, groupedID)
+	c.mu.Unlock()
+
+	if ok && len(album.parts) > 0 {
+		c.flush(mergeAlbum(album.parts))
+	}
+}
@@ -0,0 +1,126 @@
+package parser
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// msg создает сообщение с ID и альбомом, 0 - сообщение не из альбома
+func msg(id, groupedID int64) *ParsedMessage {
+	return &ParsedMessage{ID: id, GroupedID: groupedID}
+}
+
+// ids возвращает ID сообщений и их частей: альбом записывается как
+// ID первой части со списком частей
+func ids(messages []*ParsedMessage) [][]int64 {
+	var result [][]int64
+	for _, m := range messages {
+		item := []int64{m.ID}
+		for _, part := range m.Parts {
+			item = append(item, part.ID)
+		}
+		result = append(result, item)
+	}
+	return result
+}
+
+func TestGroupAlbums(t *testing.T) {
+	tests := []struct {
+		name     string
+		messages []*ParsedMessage
+		want     [][]int64
+	}{
+		{
+			name: "пусто",
+			want: nil,
+		},
+		{
+			name:     "без альбомов",
+			messages: []*ParsedMessage{msg(1, 0), msg(2, 0)},
+			want:     [][]int64{{1}, {2}},
+		},
+		{
+			name:     "один альбом",
+			messages: []*ParsedMessage{msg(1, 10), msg(2, 10), msg(3, 10)},
+			want:     [][]int64{{1, 1, 2, 3}},
+		},
+		{
+			name:     "альбом между сообщениями",
+			messages: []*ParsedMessage{msg(1, 0), msg(2, 10), msg(3, 10), msg(4, 0)},
+			want:     [][]int64{{1}, {2, 2, 3}, {4}},
+		},
+		{
+			name:     "два альбома подряд",
+			messages: []*ParsedMessage{msg(1, 10), msg(2, 10), msg(3, 20), msg(4, 20)},
+			want:     [][]int64{{1, 1, 2}, {3, 3, 4}},
+		},
+		{
+			name:     "части альбома не по порядку",
+			messages: []*ParsedMessage{msg(3, 10), msg(1, 0), msg(2, 10)},
+			want:     [][]int64{{2, 2, 3}, {1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ids(groupAlbums(tt.messages)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("groupAlbums() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeAlbum(t *testing.T) {
+	parts := []*ParsedMessage{
+		{ID: 12, GroupedID: 1, Pts: 7},
+		{ID: 11, GroupedID: 1, Pts: 5},
+		{ID: 13, GroupedID: 1, Pts: 6, Content: "подпись"},
+	}
+
+	merged := mergeAlbum(parts)
+	if merged.ID != 11 {
+		t.Errorf("ID = %d, want 11", merged.ID)
+	}
+	if merged.Content != "подпись" {
+		t.Errorf("Content = %q, want подпись из части с текстом", merged.Content)
+	}
+	if merged.Pts != 7 {
+		t.Errorf("Pts = %d, want наибольший 7", merged.Pts)
+	}
+	if merged.MaxID() != 13 {
+		t.Errorf("MaxID() = %d, want 13", merged.MaxID())
+	}
+}
+
+func TestAlbumCollector(t *testing.T) {
+	var mu sync.Mutex
+	var flushed []*ParsedMessage
+	done := make(chan struct{}, 4)
+
+	c := newAlbumCollector(20*time.Millisecond, func(m *ParsedMessage) {
+		mu.Lock()
+		flushed = append(flushed, m)
+		mu.Unlock()
+		done <- struct{}{}
+	})
+
+	c.Add(msg(1, 0))
+	c.Add(msg(3, 10))
+	c.Add(msg(2, 10))
+
+	for i := 0; i < 2; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("альбом не отдан")
+		}
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if got, want := ids(flushed), [][]int64{{1}, {2, 2, 3}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("отдано %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,188 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+	"go.uber.org/zap"
+)
+
+const (
+	// backfillStaleAfter через сколько без отметок прогресса импорт считается
+	// брошенным и его может продолжить другой процесс
+	backfillStaleAfter = 5 * time.Minute
+	// backfillPollInterval как часто проверяются новые импорты
+	backfillPollInterval = 30 * time.Second
+)
+
+// Backfiller импортирует историю каналов за период. Сообщения проходят
+// фильтры правила и сохраняются так же, как новые, а прогресс сохраняется
+// после каждой страницы истории.
+type Backfiller struct {
+	parser *TelegramParser
+	repo   *storage.BackfillRepository
+	logger *zap.SugaredLogger
+}
+
+// NewBackfiller создает импорт истории поверх парсера: используются его
+// MTProto клиент, кэш медиа и обработка сообщений
+func NewBackfiller(parser *TelegramParser, repo *storage.BackfillRepository, logger *zap.SugaredLogger) *Backfiller {
+	return &Backfiller{
+		parser: parser,
+		repo:   repo,
+		logger: logger,
+	}
+}
+
+// Run выполняет импорты, созданные через API, по одному до отмены контекста
+func (b *Backfiller) Run(ctx context.Context) {
+	b.logger.Info("📚 Импорт истории каналов запущен")
+
+	for {
+		if b.parser.pool.Ready() {
+			job, err := b.repo.ClaimNext(ctx, backfillStaleAfter)
+			if err != nil {
+				b.logger.Errorf("❌ %v", err)
+			} else if job != nil {
+				if err := b.Execute(ctx, job); err != nil {
+					b.logger.Errorf("❌ %v", err)
+				}
+				continue
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			b.logger.Info("🛑 Импорт истории каналов остановлен")
+			return
+		case <-time.After(backfillPollInterval):
+		}
+	}
+}
+
+// Claim занимает импорт для выполнения в этом процессе. nil - импорт
+// уже выполняется другим процессом или завершен.
+func (b *Backfiller) Claim(ctx context.Context, id int64) (*models.BackfillJob, error) {
+	return b.repo.Claim(ctx, id, backfillStaleAfter)
+}
+
+// Execute выполняет занятый импорт до конца периода, отмены или ошибки.
+// При отмене контекста импорт возвращается в pending и продолжится
+// с последнего обработанного сообщения. Сообщение, которое не удалось
+// обработать, останавливает импорт с ошибкой, продолжение начнет с него.
+func (b *Backfiller) Execute(ctx context.Context, job *models.BackfillJob) error {
+	rule, err := b.parser.ruleRepo.GetByID(ctx, job.RuleID)
+	if err != nil {
+		b.suspend(job)
+		return fmt.Errorf("ошибка получения правила импорта %d: %v", job.ID, err)
+	}
+	if rule == nil {
+		return b.fail(job, fmt.Errorf("правило %d не найдено", job.RuleID))
+	}
+
+	channel := b.parser.normalizeChannel(rule.SourceChannel)
+	b.logger.Infof("📚 Импорт %d: канал %s с %s по %s, режим %s, продолжение после сообщения %d",
+		job.ID, channel, job.From.Format(time.RFC3339), job.To.Format(time.RFC3339), job.Mode, job.CursorID)
+
+	for {
+		done, err := b.importPage(ctx, job, rule, channel)
+		if ctx.Err() != nil {
+			b.suspend(job)
+			b.logger.Infof("⏸️ Импорт %d приостановлен после сообщения %d", job.ID, job.CursorID)
+			return nil
+		}
+		if err != nil {
+			return b.fail(job, err)
+		}
+
+		running, err := b.repo.Checkpoint(ctx, job)
+		if err != nil {
+			b.suspend(job)
+			return err
+		}
+		if !running {
+			b.logger.Infof("🚫 Импорт %d отменен", job.ID)
+			return nil
+		}
+
+		b.logger.Infof("📚 Импорт %d: обработано %d сообщений, сохранено %d постов", job.ID, job.Fetched, job.Imported)
+
+		if done {
+			if err := b.repo.Finish(ctx, job.ID, models.BackfillDone, ""); err != nil {
+				return err
+			}
+			b.logger.Infof("✅ Импорт %d завершен: сохранено %d постов из %d сообщений", job.ID, job.Imported, job.Fetched)
+			return nil
+		}
+	}
+}
+
+// importPage обрабатывает следующую страницу истории после курсора.
+// Возвращает true, когда период импортирован полностью.
+func (b *Backfiller) importPage(ctx context.Context, job *models.BackfillJob, rule *models.ParsingRule, channel string) (bool, error) {
+	page, err := b.parser.pool.GetHistoryPage(ctx, channel, job.CursorID, job.From, historyPageSize)
+	if err != nil {
+		return false, err
+	}
+	if page.Count == 0 {
+		return true, nil
+	}
+
+	messages, next := page.completeMessages(historyPageSize)
+	for _, msg := range messages {
+		if msg.Date.Before(job.From) {
+			job.CursorID = msg.MaxID()
+			continue
+		}
+		if !msg.Date.Before(job.To) {
+			return true, nil
+		}
+
+		stored, err := b.parser.storeMessage(ctx, rule, msg, job.Mode)
+		if err != nil {
+			if ctx.Err() != nil {
+				return false, ctx.Err()
+			}
+			// Курсор остается перед сообщением: импорт останавливается, и
+			// продолжение (-resume) начнет с этого сообщения
+			return false, fmt.Errorf("ошибка обработки сообщения %d: %v", msg.ID, err)
+		}
+		job.Fetched++
+		if stored {
+			job.Imported++
+		}
+		job.CursorID = msg.MaxID()
+	}
+
+	if next > job.CursorID {
+		job.CursorID = next
+	}
+	return page.Count < historyPageSize, nil
+}
+
+// fail завершает импорт с ошибкой
+func (b *Backfiller) fail(job *models.BackfillJob, cause error) error {
+	ctx := context.Background()
+	if _, err := b.repo.Checkpoint(ctx, job); err != nil {
+		b.logger.Errorf("❌ %v", err)
+	}
+	if err := b.repo.Finish(ctx, job.ID, models.BackfillFailed, cause.Error()); err != nil {
+		b.logger.Errorf("❌ %v", err)
+	}
+	return fmt.Errorf("импорт %d остановлен: %v", job.ID, cause)
+}
+
+// suspend сохраняет прогресс и возвращает импорт в pending. Контекст
+// выполнения к этому моменту может быть отменен.
+func (b *Backfiller) suspend(job *models.BackfillJob) {
+	ctx := context.Background()
+	if _, err := b.repo.Checkpoint(ctx, job); err != nil {
+		b.logger.Errorf("❌ %v", err)
+	}
+	if err := b.repo.Suspend(ctx, job.ID); err != nil {
+		b.logger.Errorf("❌ %v", err)
+	}
+}
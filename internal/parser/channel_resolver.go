@@ -0,0 +1,272 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/gotd/td/telegram/query"
+	"github.com/gotd/td/tg"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+// errNoAccess аккаунт не может читать канал: канал закрыт для него
+// или аккаунт не знает канал. Канал может прочитать другой аккаунт пула.
+var errNoAccess = errors.New("у аккаунта нет доступа к каналу")
+
+// resolveChannel находит канал по источнику правила. Найденный канал
+// хранится в памяти и в БД вместе с access hash, поэтому username ищется
+// один раз и смена username канала не мешает его читать.
+func (m *MTProtoClient) resolveChannel(ctx context.Context, channel string) (*models.ResolvedPeer, error) {
+	ref, err := models.ParseChannelRef(channel)
+	if err != nil {
+		return nil, err
+	}
+	source := ref.String()
+
+	m.mu.RLock()
+	peer, ok := m.resolved[source]
+	m.mu.RUnlock()
+	if ok {
+		return peer, nil
+	}
+
+	if m.peers != nil {
+		peer, err := m.peers.Get(ctx, m.accountID, source)
+		if err != nil {
+			m.logger.Warnf("⚠️ %v", err)
+		} else if peer != nil {
+			m.rememberPeer(peer)
+			return peer, nil
+		}
+	}
+
+	var found *tg.Channel
+	switch ref.Kind {
+	case models.ChannelByInvite:
+		found, err = m.joinByInvite(ctx, ref.InviteHash)
+	case models.ChannelByID:
+		found, err = m.findChannelByID(ctx, ref.ChannelID)
+	default:
+		found, err = m.resolveUsername(ctx, ref.Username)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	peer = &models.ResolvedPeer{
+		AccountID:  m.accountID,
+		Source:     source,
+		ChannelID:  found.ID,
+		AccessHash: found.AccessHash,
+		Username:   found.Username,
+		Title:      found.Title,
+	}
+	if m.peers != nil {
+		if err := m.peers.Save(ctx, peer); err != nil {
+			m.logger.Warnf("⚠️ %v", err)
+		}
+	}
+	m.rememberPeer(peer)
+
+	m.logger.Infof("🔎 Канал %s найден: %s (ID: %d)", source, found.Title, found.ID)
+	return peer, nil
+}
+
+// withChannel выполняет запрос к каналу. Если Telegram отклонил сохраненный
+// access hash, канал удаляется из кэша, ищется заново и запрос повторяется.
+func (m *MTProtoClient) withChannel(ctx context.Context, channel string, call func(peer *models.ResolvedPeer) error) error {
+	peer, err := m.resolveChannel(ctx, channel)
+	if err != nil {
+		return err
+	}
+
+	err = call(peer)
+	if !tg.IsChannelInvalid(err) {
+		return err
+	}
+
+	m.logger.Warnf("⚠️ Сохраненные данные канала %s устарели, повторный поиск", peer.Source)
+	m.forgetChannel(ctx, peer.ChannelID)
+
+	peer, err = m.resolveChannel(ctx, channel)
+	if err != nil {
+		return err
+	}
+	return call(peer)
+}
+
+// rememberPeer сохраняет найденный канал в памяти
+func (m *MTProtoClient) rememberPeer(peer *models.ResolvedPeer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resolved[peer.Source] = peer
+}
+
+// forgetChannel удаляет канал из кэша под всеми источниками
+func (m *MTProtoClient) forgetChannel(ctx context.Context, channelID int64) {
+	m.mu.Lock()
+	for source, peer := range m.resolved {
+		if peer.ChannelID == channelID {
+			delete(m.resolved, source)
+		}
+	}
+	m.mu.Unlock()
+
+	if m.peers != nil {
+		if err := m.peers.DeleteChannel(ctx, m.accountID, channelID); err != nil {
+			m.logger.Warnf("⚠️ %v", err)
+		}
+	}
+}
+
+// refreshPeer обновляет username и название канала в кэше, если они изменились
+func (m *MTProtoClient) refreshPeer(ctx context.Context, peer *models.ResolvedPeer, channel *tg.Channel) {
+	if peer.Username == channel.Username && peer.Title == channel.Title {
+		return
+	}
+	if peer.Username != channel.Username {
+		m.logger.Infof("✏️ Канал %s сменил username: %q -> %q", peer.Source, peer.Username, channel.Username)
+	}
+
+	m.mu.Lock()
+	for _, cached := range m.resolved {
+		if cached.ChannelID == channel.ID {
+			cached.Username = channel.Username
+			cached.Title = channel.Title
+		}
+	}
+	m.mu.Unlock()
+
+	if m.peers != nil {
+		if err := m.peers.UpdateInfo(ctx, channel.ID, channel.Username, channel.Title); err != nil {
+			m.logger.Warnf("⚠️ %v", err)
+		}
+	}
+}
+
+// getChannel запрашивает актуальные данные канала: username, название и
+// состоит ли в нем аккаунт
+func (m *MTProtoClient) getChannel(ctx context.Context, peer *models.ResolvedPeer) (*tg.Channel, error) {
+	chats, err := m.client.API().ChannelsGetChannels(ctx, []tg.InputChannelClass{inputChannel(peer)})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, chat := range chats.GetChats() {
+		switch c := chat.(type) {
+		case *tg.Channel:
+			if c.ID == peer.ChannelID {
+				return c, nil
+			}
+		case *tg.ChannelForbidden:
+			if c.ID == peer.ChannelID {
+				return nil, fmt.Errorf("%w %s: доступ закрыт", errNoAccess, peer.Source)
+			}
+		}
+	}
+	return nil, fmt.Errorf("канал %s не найден", peer.Source)
+}
+
+// resolveUsername находит публичный канал по username
+func (m *MTProtoClient) resolveUsername(ctx context.Context, username string) (*tg.Channel, error) {
+	resolved, err := m.client.API().ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{
+		Username: username,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка поиска канала @%s: %w", username, err)
+	}
+
+	if c := firstChannel(resolved.Chats); c != nil {
+		return c, nil
+	}
+	return nil, fmt.Errorf("канал @%s не найден", username)
+}
+
+// joinByInvite находит приватный канал по ссылке-приглашению. Если аккаунт
+// еще не состоит в канале, он вступает по приглашению.
+func (m *MTProtoClient) joinByInvite(ctx context.Context, hash string) (*tg.Channel, error) {
+	api := m.client.API()
+
+	invite, err := api.MessagesCheckChatInvite(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка проверки приглашения +%s: %w", hash, err)
+	}
+	if already, ok := invite.(*tg.ChatInviteAlready); ok {
+		if c, ok := already.Chat.(*tg.Channel); ok {
+			return c, nil
+		}
+		return nil, fmt.Errorf("приглашение +%s ведет не в канал", hash)
+	}
+
+	m.logger.Infof("➕ Вступаем в канал по приглашению +%s", hash)
+	result, err := api.MessagesImportChatInvite(ctx, hash)
+	if err != nil {
+		if tg.IsInviteRequestSent(err) {
+			return nil, fmt.Errorf("заявка на вступление по приглашению +%s отправлена, канал будет доступен после одобрения", hash)
+		}
+		return nil, fmt.Errorf("ошибка вступления по приглашению +%s: %w", hash, err)
+	}
+
+	var chats []tg.ChatClass
+	switch u := result.(type) {
+	case *tg.Updates:
+		chats = u.Chats
+	case *tg.UpdatesCombined:
+		chats = u.Chats
+	}
+	if c := firstChannel(chats); c != nil {
+		return c, nil
+	}
+	return nil, fmt.Errorf("канал по приглашению +%s не найден", hash)
+}
+
+// findChannelByID находит канал по ID. Access hash канала без username
+// можно узнать только из уже известных каналов или из диалогов аккаунта.
+func (m *MTProtoClient) findChannelByID(ctx context.Context, channelID int64) (*tg.Channel, error) {
+	if m.peers != nil {
+		known, err := m.peers.GetByChannelID(ctx, m.accountID, channelID)
+		if err != nil {
+			m.logger.Warnf("⚠️ %v", err)
+		} else if known != nil {
+			return &tg.Channel{
+				ID:         known.ChannelID,
+				AccessHash: known.AccessHash,
+				Username:   known.Username,
+				Title:      known.Title,
+			}, nil
+		}
+	}
+
+	iter := query.GetDialogs(m.client.API()).BatchSize(100).Iter()
+	for iter.Next(ctx) {
+		if c, ok := iter.Value().Entities.Channel(channelID); ok {
+			return c, nil
+		}
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("ошибка поиска канала %d в диалогах: %w", channelID, err)
+	}
+	return nil, fmt.Errorf("%w %d: канал не найден среди диалогов аккаунта, укажите ссылку-приглашение", errNoAccess, channelID)
+}
+
+// firstChannel возвращает первый канал из списка чатов ответа
+func firstChannel(chats []tg.ChatClass) *tg.Channel {
+	for _, chat := range chats {
+		if c, ok := chat.(*tg.Channel); ok {
+			return c
+		}
+	}
+	return nil
+}
+
+// inputChannel возвращает канал для запросов channels.*
+func inputChannel(peer *models.ResolvedPeer) *tg.InputChannel {
+	return &tg.InputChannel{ChannelID: peer.ChannelID, AccessHash: peer.AccessHash}
+}
+
+// inputPeer возвращает канал для запросов messages.*
+func inputPeer(peer *models.ResolvedPeer) *tg.InputPeerChannel {
+	return &tg.InputPeerChannel{ChannelID: peer.ChannelID, AccessHash: peer.AccessHash}
+}
@@ -0,0 +1,75 @@
+package parser
+
+import (
+	"context"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/dedup"
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+// fingerprint вычисляет отпечаток содержимого поста. Текст берется из исходного
+// сообщения до преобразований правила, чтобы одинаковые новости из разных
+// каналов с разными правилами давали одинаковый отпечаток.
+func fingerprint(post *models.Post, sourceText string) {
+	fp := dedup.TextFingerprint(sourceText)
+	post.TextHash = fp.TextHash
+	post.SimHash = int64(fp.SimHash)
+
+	for _, item := range post.Media {
+		if item.MediaType != models.MediaPhoto || item.MediaFile == "" {
+			continue
+		}
+		if hash, err := dedup.ImageHash(item.MediaFile); err == nil {
+			post.ImageHash = int64(hash)
+		}
+		break
+	}
+}
+
+// findDuplicate ищет среди постов за окно дедупликации пост с тем же
+// содержимым. Сравниваются только посты правил, публикующих хотя бы в одно
+// общее назначение: пост для другого канала дубликатом не считается.
+func (p *TelegramParser) findDuplicate(ctx context.Context, post *models.Post) (int64, bool) {
+	if p.dedup.Disabled || p.dedup.WindowHours <= 0 {
+		return 0, false
+	}
+
+	fp := dedup.Fingerprint{
+		TextHash:  post.TextHash,
+		SimHash:   uint64(post.SimHash),
+		ImageHash: uint64(post.ImageHash),
+	}
+	if fp == (dedup.Fingerprint{}) {
+		return 0, false
+	}
+
+	since := time.Now().Add(-time.Duration(p.dedup.WindowHours) * time.Hour)
+
+	// Одинаковый текст ищется по индексу
+	if fp.TextHash != "" {
+		originalID, err := p.postRepo.FindByTextHash(ctx, post.RuleID, fp.TextHash, since)
+		if err != nil {
+			p.logger.Warnf("⚠️ %v", err)
+			return 0, false
+		}
+		if originalID != 0 {
+			return originalID, true
+		}
+	}
+	if fp.SimHash == 0 && fp.ImageHash == 0 {
+		return 0, false
+	}
+
+	candidates, err := p.postRepo.GetSimilarCandidates(ctx, post.RuleID, since)
+	if err != nil {
+		// Ошибка поиска не должна останавливать публикацию
+		p.logger.Warnf("⚠️ %v", err)
+		return 0, false
+	}
+
+	return dedup.FindDuplicate(fp, candidates, dedup.Options{
+		SimHashDistance:   p.dedup.SimHashDistance,
+		ImageHashDistance: p.dedup.ImageHashDistance,
+	})
+}
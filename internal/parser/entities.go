@@ -0,0 +1,52 @@
+package parser
+
+import (
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/gotd/td/tg"
+)
+
+// convertEntities преобразует сущности Telegram в сущности поста.
+// Автоматически распознаваемые Telegram ссылки, упоминания и хэштеги
+// не сохраняются - они снова распознаются при публикации.
+func convertEntities(entities []tg.MessageEntityClass) []models.TextEntity {
+	var result []models.TextEntity
+	for _, entity := range entities {
+		e := models.TextEntity{
+			Offset: entity.GetOffset(),
+			Length: entity.GetLength(),
+		}
+
+		switch entity := entity.(type) {
+		case *tg.MessageEntityBold:
+			e.Type = models.EntityBold
+		case *tg.MessageEntityItalic:
+			e.Type = models.EntityItalic
+		case *tg.MessageEntityUnderline:
+			e.Type = models.EntityUnderline
+		case *tg.MessageEntityStrike:
+			e.Type = models.EntityStrike
+		case *tg.MessageEntitySpoiler:
+			e.Type = models.EntitySpoiler
+		case *tg.MessageEntityCode:
+			e.Type = models.EntityCode
+		case *tg.MessageEntityPre:
+			e.Type = models.EntityPre
+			e.Language = entity.Language
+		case *tg.MessageEntityBlockquote:
+			e.Type = models.EntityBlockquote
+		case *tg.MessageEntityTextURL:
+			e.Type = models.EntityTextLink
+			e.URL = entity.URL
+		case *tg.MessageEntityMentionName:
+			e.Type = models.EntityMentionName
+			e.UserID = entity.UserID
+		default:
+			continue
+		}
+
+		if e.Length > 0 {
+			result = append(result, e)
+		}
+	}
+	return result
+}
@@ -0,0 +1,254 @@
+package parser
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/telegram/auth/qrlogin"
+	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+// loginPollInterval как часто клиент проверяет, не передал ли
+// администратор код или пароль через API
+const loginPollInterval = 2 * time.Second
+
+// errLoginRestarted администратор начал вход заново
+var errLoginRestarted = errors.New("вход начат заново")
+
+// login ждет, пока администратор проведет вход через API: выберет способ
+// входа, передаст код и пароль. Неудачный вход можно начать заново.
+func (m *MTProtoClient) login(ctx context.Context) error {
+	if err := m.auths.SetWaiting(ctx, m.accountID); err != nil {
+		return err
+	}
+	m.logger.Warn("🔐 Аккаунт не авторизован, начните вход через POST /api/telegram/auth/start")
+
+	for {
+		method, err := m.waitLoginRequest(ctx)
+		if err != nil {
+			return err
+		}
+
+		m.logger.Infof("🔐 Вход в Telegram, способ: %s", method)
+		if method == models.AuthByQR {
+			err = m.loginQR(ctx)
+		} else {
+			err = m.loginCode(ctx)
+		}
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if errors.Is(err, errLoginRestarted) {
+			m.logger.Info("🔁 Вход начат заново")
+			continue
+		}
+
+		m.logger.Errorf("❌ Ошибка входа: %v", err)
+		m.reportAuth(ctx, &models.TelegramAuth{State: models.AuthFailed, LastError: err.Error()})
+	}
+}
+
+// waitLoginRequest ждет, пока администратор начнет вход, и возвращает способ входа
+func (m *MTProtoClient) waitLoginRequest(ctx context.Context) (models.AuthMethod, error) {
+	ticker := time.NewTicker(loginPollInterval)
+	defer ticker.Stop()
+
+	for {
+		state, err := m.auths.Get(ctx, m.accountID)
+		if err != nil {
+			m.logger.Warnf("⚠️ %v", err)
+		} else if state != nil && state.State == models.AuthRequested {
+			return state.Method, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// loginCode входит по коду, который Telegram присылает в приложение или SMS
+func (m *MTProtoClient) loginCode(ctx context.Context) error {
+	client := m.client.Auth()
+
+	sent, err := client.SendCode(ctx, m.phone, auth.SendCodeOptions{})
+	if err != nil {
+		return fmt.Errorf("ошибка отправки кода: %w", err)
+	}
+	sentCode, ok := sent.(*tg.AuthSentCode)
+	if !ok {
+		// Telegram авторизовал сессию без кода
+		return nil
+	}
+
+	codeType := sentCodeType(sentCode.Type)
+	m.logger.Infof("📱 Код отправлен (%s), передайте его через POST /api/telegram/auth/code", codeType)
+	lastError := ""
+	for {
+		m.reportAuth(ctx, &models.TelegramAuth{State: models.AuthAwaitingCode, CodeType: codeType, LastError: lastError})
+
+		code, err := m.waitSecret(ctx, m.auths.TakeCode)
+		if err != nil {
+			return err
+		}
+
+		_, err = client.SignIn(ctx, m.phone, code, sentCode.PhoneCodeHash)
+		switch {
+		case err == nil:
+			return nil
+		case errors.Is(err, auth.ErrPasswordAuthNeeded):
+			return m.loginPassword(ctx)
+		case tg.IsPhoneCodeInvalid(err):
+			m.logger.Warn("⚠️ Неверный код входа")
+			lastError = "неверный код"
+		default:
+			var signUp *auth.SignUpRequired
+			if errors.As(err, &signUp) {
+				return fmt.Errorf("номер %s не зарегистрирован в Telegram", m.phone)
+			}
+			return fmt.Errorf("ошибка входа по коду: %w", err)
+		}
+	}
+}
+
+// loginQR входит по QR-коду: администратор сканирует его в приложении
+// Telegram на устройстве, где аккаунт уже авторизован
+func (m *MTProtoClient) loginQR(ctx context.Context) error {
+	// QR-код ждет сканирования без опроса БД, поэтому повторное начало
+	// входа отслеживается отдельно
+	qrCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go m.watchLoginRestart(qrCtx, cancel)
+
+	_, err := m.client.QR().Auth(qrCtx, m.loginToken, func(ctx context.Context, token qrlogin.Token) error {
+		expires := token.Expires()
+		m.logger.Info("📷 QR-код для входа обновлен")
+		return m.auths.Report(ctx, &models.TelegramAuth{
+			AccountID:   m.accountID,
+			State:       models.AuthAwaitingQR,
+			QRURL:       token.URL(),
+			QRExpiresAt: &expires,
+		})
+	})
+	if cause := context.Cause(qrCtx); errors.Is(cause, errLoginRestarted) {
+		return cause
+	}
+	if tgerr.Is(err, "SESSION_PASSWORD_NEEDED") {
+		return m.loginPassword(ctx)
+	}
+	if err != nil {
+		return fmt.Errorf("ошибка входа по QR-коду: %w", err)
+	}
+	return nil
+}
+
+// loginPassword завершает вход паролем двухэтапной проверки. При неверном
+// пароле клиент ждет пароль снова.
+func (m *MTProtoClient) loginPassword(ctx context.Context) error {
+	hint := ""
+	if password, err := m.client.API().AccountGetPassword(ctx); err == nil {
+		hint = password.Hint
+	}
+
+	m.logger.Info("🔑 Включена двухэтапная проверка, передайте пароль через POST /api/telegram/auth/password")
+	lastError := ""
+	for {
+		m.reportAuth(ctx, &models.TelegramAuth{State: models.AuthAwaitingPassword, PasswordHint: hint, LastError: lastError})
+
+		password, err := m.waitSecret(ctx, m.auths.TakePassword)
+		if err != nil {
+			return err
+		}
+
+		_, err = m.client.Auth().Password(ctx, password)
+		if errors.Is(err, auth.ErrPasswordInvalid) {
+			m.logger.Warn("⚠️ Неверный пароль двухэтапной проверки")
+			lastError = "неверный пароль"
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("ошибка входа по паролю: %w", err)
+		}
+		return nil
+	}
+}
+
+// waitSecret ждет код или пароль от администратора. Если вход начали
+// заново, возвращает errLoginRestarted.
+func (m *MTProtoClient) waitSecret(ctx context.Context, take func(ctx context.Context, accountID int64) (string, error)) (string, error) {
+	ticker := time.NewTicker(loginPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-ticker.C:
+		}
+
+		value, err := take(ctx, m.accountID)
+		if err != nil {
+			m.logger.Warnf("⚠️ %v", err)
+			continue
+		}
+		if value != "" {
+			return value, nil
+		}
+		if m.loginRestarted(ctx) {
+			return "", errLoginRestarted
+		}
+	}
+}
+
+// watchLoginRestart отменяет ожидание QR-кода, если вход начали заново
+func (m *MTProtoClient) watchLoginRestart(ctx context.Context, cancel context.CancelCauseFunc) {
+	ticker := time.NewTicker(loginPollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if m.loginRestarted(ctx) {
+			cancel(errLoginRestarted)
+			return
+		}
+	}
+}
+
+// loginRestarted проверяет, не начал ли администратор вход заново
+func (m *MTProtoClient) loginRestarted(ctx context.Context) bool {
+	state, err := m.auths.Get(ctx, m.accountID)
+	return err == nil && state != nil && state.State == models.AuthRequested
+}
+
+// reportAuth сохраняет этап входа аккаунта для веб-интерфейса
+func (m *MTProtoClient) reportAuth(ctx context.Context, state *models.TelegramAuth) {
+	state.AccountID = m.accountID
+	if err := m.auths.Report(ctx, state); err != nil {
+		m.logger.Warnf("⚠️ %v", err)
+	}
+}
+
+// sentCodeType возвращает, куда Telegram отправил код: app, sms, call...
+func sentCodeType(t tg.AuthSentCodeTypeClass) string {
+	if t == nil {
+		return ""
+	}
+	name := strings.TrimPrefix(t.TypeName(), "auth.sentCodeType")
+	return strings.ToLower(name)
+}
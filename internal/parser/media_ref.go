@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"mime"
+	"path/filepath"
+
+	"github.com/gotd/td/tg"
+)
+
+// MediaRef ссылка на файл медиа в Telegram для загрузки через upload.getFile
+type MediaRef struct {
+	Location  tg.InputFileLocationClass
+	Size      int64
+	MimeType  string
+	FileName  string
+	AccountID int64 // аккаунт, получивший сообщение: file reference действует только для него
+}
+
+// Ext возвращает расширение файла с точкой
+func (r *MediaRef) Ext() string {
+	if ext := filepath.Ext(r.FileName); ext != "" {
+		return ext
+	}
+	if r.MimeType != "" {
+		if exts, err := mime.ExtensionsByType(r.MimeType); err == nil && len(exts) > 0 {
+			return exts[0]
+		}
+	}
+	return ".bin"
+}
+
+// photoMediaRef выбирает самый большой размер фото
+func photoMediaRef(photo *tg.Photo) *MediaRef {
+	var (
+		bestType string
+		bestArea int
+		bestSize int
+	)
+
+	for _, size := range photo.Sizes {
+		switch s := size.(type) {
+		case *tg.PhotoSize:
+			if area := s.W * s.H; area > bestArea {
+				bestType, bestArea, bestSize = s.Type, area, s.Size
+			}
+		case *tg.PhotoSizeProgressive:
+			if area := s.W * s.H; area > bestArea && len(s.Sizes) > 0 {
+				bestType, bestArea, bestSize = s.Type, area, s.Sizes[len(s.Sizes)-1]
+			}
+		}
+	}
+
+	if bestType == "" {
+		return nil
+	}
+
+	return &MediaRef{
+		Location: &tg.InputPhotoFileLocation{
+			ID:            photo.ID,
+			AccessHash:    photo.AccessHash,
+			FileReference: photo.FileReference,
+			ThumbSize:     bestType,
+		},
+		Size:     int64(bestSize),
+		MimeType: "image/jpeg",
+		FileName: "photo.jpg",
+	}
+}
+
+// documentMediaRef создает ссылку на документ (видео, файл, анимацию)
+func documentMediaRef(doc *tg.Document) *MediaRef {
+	ref := &MediaRef{
+		Location: &tg.InputDocumentFileLocation{
+			ID:            doc.ID,
+			AccessHash:    doc.AccessHash,
+			FileReference: doc.FileReference,
+		},
+		Size:     doc.Size,
+		MimeType: doc.MimeType,
+	}
+
+	for _, attr := range doc.Attributes {
+		if name, ok := attr.(*tg.DocumentAttributeFilename); ok {
+			ref.FileName = name.FileName
+		}
+	}
+
+	return ref
+}
+
+// isVideoDocument проверяет, является ли документ видео
+func isVideoDocument(doc *tg.Document) bool {
+	for _, attr := range doc.Attributes {
+		if _, ok := attr.(*tg.DocumentAttributeVideo); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -67,6 +67,9 @@ func (p *MessageProcessor) processForRule(ctx context.Context, message *ParsedMe
 	post := models.NewPost(rule.ID, message.ID, message.SourceChannel, transformedContent, message.MediaType)
 	post.MediaURL = message.MediaURL
 	post.PostedAt = message.Date
+	if rule.RequiresApproval {
+		post.ReviewStatus = models.ReviewPending
+	}
 
 	if err := post.Validate(); err != nil {
 		return fmt.Errorf("ошибка валидации поста: %v", err)
@@ -3,67 +3,187 @@ package parser
 import (
 	"context"
 	"fmt"
-	"strings"
+	"io"
+	"sort"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/gotd/td/bin"
+	"github.com/gotd/td/session"
 	"github.com/gotd/td/telegram"
-	"github.com/gotd/td/telegram/auth"
+	"github.com/gotd/td/telegram/auth/qrlogin"
+	"github.com/gotd/td/telegram/downloader"
+	"github.com/gotd/td/telegram/updates"
+	updhook "github.com/gotd/td/telegram/updates/hook"
 	"github.com/gotd/td/tg"
+	"github.com/gotd/td/tgerr"
 	"go.uber.org/zap"
 
+	"github.com/drerr0r/tgparserbot/internal/media"
 	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/ratelimit"
+	"github.com/drerr0r/tgparserbot/internal/storage"
 )
 
-// MTProtoClient клиент для работы с MTProto API
+// mtprotoLimits лимиты запросов к MTProto. Лимиты MTProto не документированы,
+// держимся консервативной частоты, остальное регулирует FLOOD_WAIT от сервера.
+// Лимиты у каждого аккаунта свои, ключ - ID аккаунта.
+var mtprotoLimits = ratelimit.NewGroup("telegram_mtproto", 10, 20)
+
+// UpdateHandler получатель сообщений, пришедших через поток обновлений
+type UpdateHandler func(ctx context.Context, msg *ParsedMessage)
+
+// DeleteHandler получатель удалений сообщений в отслеживаемых каналах
+type DeleteHandler func(ctx context.Context, channelID int64, messageIDs []int64)
+
+// MTProtoClient клиент для работы с MTProto API от имени одного аккаунта
 type MTProtoClient struct {
-	client  *telegram.Client
-	apiID   int
-	apiHash string
-	phone   string
-	session string
-	logger  *zap.SugaredLogger
-	isAuth  bool
-	running bool
-}
-
-// NewMTProtoClient создает новый MTProto клиент
-func NewMTProtoClient(apiID int, apiHash, phone, session string, logger *zap.SugaredLogger) *MTProtoClient {
+	client    *telegram.Client
+	limiter   *ratelimit.Limiter
+	apiID     int
+	apiHash   string
+	accountID int64
+	phone     string
+	session   string
+	peers     *storage.PeerRepository
+	sessions  *storage.SessionRepository
+	auths     *storage.AuthRepository
+	logger    *zap.SugaredLogger
+
+	loginToken qrlogin.LoggedIn // сигнал о сканировании QR-кода входа
+
+	mu            sync.RWMutex
+	gaps          *updates.Manager
+	cancel        context.CancelFunc
+	isAuth        bool
+	running       bool
+	done          chan struct{}                   // закрывается, когда клиент остановился
+	ready         chan struct{}                   // закрывается, когда клиент готов получать обновления
+	runErr        error                           // ошибка, с которой остановился клиент
+	watched       map[int64]string                // ID канала -> канал в том виде, как он указан в правиле
+	resolved      map[string]*models.ResolvedPeer // канонический вид источника -> найденный канал
+	updateHandler UpdateHandler
+	deleteHandler DeleteHandler
+	gapHandler    func(channel string)
+}
+
+// NewMTProtoClient создает новый MTProto клиент аккаунта. peers - кэш
+// найденных каналов в БД, nil - каналы кэшируются только в памяти.
+// sessions - сессии в БД, nil - сессия хранится в файле аккаунта.
+// auths - состояние входа, через которое администратор проводит вход.
+func NewMTProtoClient(apiID int, apiHash string, account *models.TelegramAccount, peers *storage.PeerRepository, sessions *storage.SessionRepository, auths *storage.AuthRepository, logger *zap.SugaredLogger) *MTProtoClient {
 	return &MTProtoClient{
-		apiID:   apiID,
-		apiHash: apiHash,
-		phone:   phone,
-		session: session,
-		logger:  logger,
-		isAuth:  false,
-		running: false,
+		apiID:     apiID,
+		apiHash:   apiHash,
+		accountID: account.ID,
+		phone:     account.Phone,
+		session:   account.SessionFile,
+		peers:     peers,
+		sessions:  sessions,
+		auths:     auths,
+		logger:    logger.With("account", account.Phone),
+		watched:   make(map[int64]string),
+		resolved:  make(map[string]*models.ResolvedPeer),
+		limiter:   mtprotoLimits.Get(strconv.FormatInt(account.ID, 10)),
 	}
 }
 
+// sessionStorage возвращает хранилище сессии аккаунта: БД, если она
+// настроена, иначе файл. Файл остается источником для переноса в БД.
+func (m *MTProtoClient) sessionStorage() session.Storage {
+	file := m.session + ".session"
+	if m.sessions != nil {
+		return m.sessions.Storage(m.accountID, file)
+	}
+	return &session.FileStorage{Path: file}
+}
+
+// SetUpdateHandler задает обработчик новых и отредактированных сообщений
+// из отслеживаемых каналов
+func (m *MTProtoClient) SetUpdateHandler(handler UpdateHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.updateHandler = handler
+}
+
+// SetDeleteHandler задает обработчик удалений сообщений в отслеживаемых каналах
+func (m *MTProtoClient) SetDeleteHandler(handler DeleteHandler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.deleteHandler = handler
+}
+
+// SetGapHandler задает обработчик ситуации, когда разрыв в потоке обновлений
+// канала слишком велик и его нельзя восстановить через getChannelDifference
+func (m *MTProtoClient) SetGapHandler(handler func(channel string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.gapHandler = handler
+}
+
 // Start запускает клиент и выполняет аутентификацию
 func (m *MTProtoClient) Start(ctx context.Context) error {
-	if m.running {
+	if m.Running() {
 		return fmt.Errorf("клиент уже запущен")
 	}
 
 	m.logger.Info("🔗 Запуск MTProto клиента...")
 
+	// Менеджер обновлений следит за pts каналов и сам восстанавливает
+	// пропуски через updates.getChannelDifference после переподключений
+	dispatcher := tg.NewUpdateDispatcher()
+	dispatcher.OnNewChannelMessage(func(ctx context.Context, e tg.Entities, u *tg.UpdateNewChannelMessage) error {
+		m.handleChannelMessage(ctx, u.Message, e.Channels, u.Pts, false)
+		return nil
+	})
+	dispatcher.OnEditChannelMessage(func(ctx context.Context, e tg.Entities, u *tg.UpdateEditChannelMessage) error {
+		m.handleChannelMessage(ctx, u.Message, e.Channels, u.Pts, true)
+		return nil
+	})
+	dispatcher.OnDeleteChannelMessages(func(ctx context.Context, e tg.Entities, u *tg.UpdateDeleteChannelMessages) error {
+		m.handleChannelDelete(ctx, u.ChannelID, u.Messages)
+		return nil
+	})
+	m.loginToken = qrlogin.OnLoginToken(dispatcher)
+
+	gaps := updates.New(updates.Config{
+		Handler:          dispatcher,
+		OnChannelTooLong: m.handleChannelTooLong,
+		Logger:           m.logger.Desugar().Named("updates"),
+	})
+
 	client := telegram.NewClient(m.apiID, m.apiHash, telegram.Options{
-		SessionStorage: &telegram.FileSessionStorage{
-			Path: m.session + ".session",
+		SessionStorage: m.sessionStorage(),
+		Logger:         m.logger.Desugar(),
+		UpdateHandler:  gaps,
+		Middlewares: []telegram.Middleware{
+			m.rateLimit(),
+			updhook.UpdateHook(gaps.Handle),
 		},
-		Logger: m.logger.Desugar(),
 	})
 
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	ready := make(chan struct{})
+	m.mu.Lock()
+	m.gaps = gaps
+	m.cancel = cancel
 	m.client = client
+	m.runErr = nil
 	m.running = true
+	m.done = done
+	m.ready = ready
+	m.mu.Unlock()
 
 	// Запускаем клиент в отдельной горутине
 	go func() {
+		defer close(done)
 		if err := client.Run(ctx, func(ctx context.Context) error {
 			m.logger.Info("✅ Соединение с Telegram установлено")
 
 			// Проверяем статус аутентификации
-			authStatus, err := m.client.Auth().Status(ctx)
+			authStatus, err := client.Auth().Status(ctx)
 			if err != nil {
 				m.logger.Errorf("❌ Ошибка проверки статуса аутентификации: %v", err)
 				return err
@@ -71,96 +191,280 @@ func (m *MTProtoClient) Start(ctx context.Context) error {
 
 			if authStatus.Authorized {
 				m.logger.Info("✅ Уже авторизованы в Telegram")
-				m.isAuth = true
 			} else {
-				m.logger.Info("🔐 Аутентификация в Telegram...")
-				m.logger.Info("📱 Запрос кода аутентификации...")
-
-				// Если не авторизованы, запрашиваем код
-				flow := auth.NewFlow(
-					auth.Constant(m.phone, "", auth.CodeAuthenticatorFunc(func(ctx context.Context, sentCode *tg.AuthSentCode) (string, error) {
-						m.logger.Info("📱 Введите код из Telegram:")
-						var code string
-						fmt.Scanln(&code)
-						return code, nil
-					})),
-					auth.SendCodeOptions{},
-				)
-
-				if err := m.client.Auth().IfNecessary(ctx, flow); err != nil {
+				if err := m.login(ctx); err != nil {
 					m.logger.Errorf("❌ Ошибка аутентификации: %v", err)
 					return err
 				}
-
-				m.isAuth = true
 				m.logger.Info("✅ Успешная аутентификация в Telegram")
 			}
+			m.mu.Lock()
+			m.isAuth = true
+			m.mu.Unlock()
+			m.reportAuth(ctx, &models.TelegramAuth{State: models.AuthAuthorized})
+
+			self, err := client.Self(ctx)
+			if err != nil {
+				m.logger.Errorf("❌ Ошибка получения текущего пользователя: %v", err)
+				return err
+			}
 
-			// Держим соединение открытым
-			m.logger.Info("🔄 Клиент готов к работе, ожидание запросов...")
-			<-ctx.Done()
-			return nil
+			// Держим соединение открытым и получаем обновления
+			return gaps.Run(ctx, client.API(), self.ID, updates.AuthOptions{
+				OnStart: func(ctx context.Context) {
+					m.logger.Info("🔄 Клиент готов к работе, ожидание обновлений...")
+					close(ready)
+				},
+			})
 		}); err != nil {
 			m.logger.Errorf("❌ Ошибка работы клиента: %v", err)
+			m.mu.Lock()
+			m.runErr = err
+			m.mu.Unlock()
 		}
 
+		m.mu.Lock()
 		m.running = false
+		m.mu.Unlock()
 		m.logger.Info("🛑 MTProto клиент остановлен")
 	}()
 
-	// Ждем инициализации
-	time.Sleep(2 * time.Second)
 	return nil
 }
 
+// WaitReady ждет, пока клиент авторизуется и будет готов получать
+// обновления. Возвращает ошибку, если клиент остановился раньше.
+func (m *MTProtoClient) WaitReady(ctx context.Context) error {
+	m.mu.RLock()
+	ready, done := m.ready, m.done
+	m.mu.RUnlock()
+	if ready == nil {
+		return fmt.Errorf("клиент не запущен")
+	}
+
+	select {
+	case <-ready:
+		return nil
+	case <-done:
+		return fmt.Errorf("клиент остановлен")
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Stop останавливает клиент
 func (m *MTProtoClient) Stop() {
+	m.mu.Lock()
+	cancel := m.cancel
 	m.running = false
+	m.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+	}
 }
 
-// GetChannelMessages получает сообщения из канала
-func (m *MTProtoClient) GetChannelMessages(ctx context.Context, channel string, limit int) ([]*ParsedMessage, error) {
-	if !m.isAuth || m.client == nil {
-		return nil, fmt.Errorf("клиент не аутентифицирован или не инициализирован")
+// Wait ждет остановки клиента. Обработчики обновлений выполняются внутри
+// клиента, поэтому Stop не ждет сам: его вызывают и из обработчиков.
+func (m *MTProtoClient) Wait() {
+	m.mu.RLock()
+	done := m.done
+	m.mu.RUnlock()
+	if done != nil {
+		<-done
 	}
+}
+
+// Err возвращает ошибку, с которой остановился клиент, nil - клиент
+// работает или остановлен штатно
+func (m *MTProtoClient) Err() error {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.runErr
+}
+
+// Running проверяет, запущен ли клиент
+func (m *MTProtoClient) Running() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.running
+}
 
-	normalizedChannel := strings.TrimPrefix(channel, "@")
-	m.logger.Infof("📥 Получение сообщений из канала: %s (лимит: %d)", normalizedChannel, limit)
+// authenticated проверяет, выполнена ли аутентификация клиента
+func (m *MTProtoClient) authenticated() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.isAuth && m.client != nil
+}
 
-	api := m.client.API()
+// Ready проверяет, подключен ли клиент и выполнена ли аутентификация
+func (m *MTProtoClient) Ready() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.running && m.isAuth && m.client != nil
+}
+
+// WatchChannel подписывает клиент на обновления канала. При необходимости
+// аккаунт вступает в канал, иначе Telegram не присылает по нему обновления.
+func (m *MTProtoClient) WatchChannel(ctx context.Context, channel string) (int64, error) {
+	if !m.authenticated() {
+		return 0, fmt.Errorf("клиент не аутентифицирован или не инициализирован")
+	}
 
-	// Ищем канал
-	resolved, err := api.ContactsResolveUsername(ctx, &tg.ContactsResolveUsernameRequest{
-		Username: normalizedChannel,
+	var peer *models.ResolvedPeer
+	var channelInfo *tg.Channel
+	err := m.withChannel(ctx, channel, func(p *models.ResolvedPeer) error {
+		var err error
+		peer = p
+		channelInfo, err = m.getChannel(ctx, p)
+		return err
 	})
 	if err != nil {
-		return nil, fmt.Errorf("ошибка поиска канала %s: %v", normalizedChannel, err)
+		return 0, fmt.Errorf("ошибка получения канала %s: %w", channel, err)
+	}
+	m.refreshPeer(ctx, peer, channelInfo)
+
+	if channelInfo.Left {
+		m.logger.Infof("➕ Вступаем в канал %s для получения обновлений", channel)
+		if ref, _ := models.ParseChannelRef(channel); ref.Kind == models.ChannelByInvite {
+			_, err = m.client.API().MessagesImportChatInvite(ctx, ref.InviteHash)
+		} else {
+			_, err = m.client.API().ChannelsJoinChannel(ctx, inputChannel(peer))
+		}
+		if err != nil {
+			return 0, fmt.Errorf("ошибка вступления в канал %s: %w", channel, err)
+		}
 	}
 
-	var channelPeer *tg.Channel
-	for _, chat := range resolved.Chats {
-		if c, ok := chat.(*tg.Channel); ok {
-			channelPeer = c
-			break
+	m.mu.Lock()
+	m.watched[peer.ChannelID] = channel
+	m.mu.Unlock()
+
+	m.logger.Infof("📡 Подписка на обновления канала %s (ID: %d)", channel, peer.ChannelID)
+	return peer.ChannelID, nil
+}
+
+// UnwatchChannel прекращает передачу обновлений канала в обработчик
+func (m *MTProtoClient) UnwatchChannel(channelID int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.watched, channelID)
+}
+
+// rateLimit ограничивает частоту запросов к MTProto и выдерживает FLOOD_WAIT
+func (m *MTProtoClient) rateLimit() telegram.Middleware {
+	return telegram.MiddlewareFunc(func(next tg.Invoker) telegram.InvokeFunc {
+		return func(ctx context.Context, input bin.Encoder, output bin.Decoder) error {
+			return m.limiter.Do(ctx, floodWait, func() error {
+				err := next.Invoke(ctx, input, output)
+				if wait, ok := tgerr.AsFloodWait(err); ok {
+					m.logger.Warnf("⏳ FLOOD_WAIT от Telegram: ожидание %v", wait)
+				}
+				return err
+			})
 		}
+	})
+}
+
+// floodWait распознает FLOOD_WAIT_X в ошибке MTProto
+func floodWait(err error) (time.Duration, bool) {
+	return tgerr.AsFloodWait(err)
+}
+
+// handleChannelMessage преобразует сообщение из обновления и передает его обработчику
+func (m *MTProtoClient) handleChannelMessage(ctx context.Context, msg tg.MessageClass, channels map[int64]*tg.Channel, pts int, edited bool) {
+	message, ok := msg.(*tg.Message)
+	if !ok {
+		return
 	}
 
-	if channelPeer == nil {
-		return nil, fmt.Errorf("канал %s не найден", normalizedChannel)
+	peer, ok := message.PeerID.(*tg.PeerChannel)
+	if !ok {
+		return
 	}
 
-	m.logger.Infof("✅ Найден канал: %s (ID: %d)", normalizedChannel, channelPeer.ID)
+	m.mu.RLock()
+	channel, watched := m.watched[peer.ChannelID]
+	handler := m.updateHandler
+	m.mu.RUnlock()
 
-	// Получаем историю сообщений
-	history, err := api.MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
-		Peer: &tg.InputPeerChannel{
-			ChannelID:  channelPeer.ID,
-			AccessHash: channelPeer.AccessHash,
-		},
-		Limit: limit,
+	if !watched || handler == nil {
+		return
+	}
+
+	parsedMsg, err := m.parseMessage(message, channel, channels)
+	if err != nil {
+		m.logger.Warnf("⚠️ Ошибка парсинга сообщения из обновления: %v", err)
+		return
+	}
+	if parsedMsg == nil {
+		return
+	}
+
+	parsedMsg.IsEdited = edited
+	parsedMsg.Pts = pts
+
+	m.logger.Debugf("📨 Обновление из канала %s: сообщение %d (редактирование: %v)", channel, parsedMsg.ID, edited)
+	handler(ctx, parsedMsg)
+}
+
+// handleChannelDelete передает обработчику удаленные сообщения отслеживаемого канала
+func (m *MTProtoClient) handleChannelDelete(ctx context.Context, channelID int64, messages []int) {
+	m.mu.RLock()
+	channel, watched := m.watched[channelID]
+	handler := m.deleteHandler
+	m.mu.RUnlock()
+
+	if !watched || handler == nil {
+		return
+	}
+
+	messageIDs := make([]int64, 0, len(messages))
+	for _, id := range messages {
+		messageIDs = append(messageIDs, int64(id))
+	}
+
+	m.logger.Debugf("🗑️ Удалены сообщения %v в канале %s", messageIDs, channel)
+	handler(ctx, channelID, messageIDs)
+}
+
+// handleChannelTooLong вызывается, когда пропуск в обновлениях канала
+// не удается восстановить через getChannelDifference
+func (m *MTProtoClient) handleChannelTooLong(channelID int64) {
+	m.mu.RLock()
+	channel, watched := m.watched[channelID]
+	handler := m.gapHandler
+	m.mu.RUnlock()
+
+	if !watched {
+		return
+	}
+
+	m.logger.Warnf("⚠️ Слишком большой разрыв обновлений канала %s, требуется опрос истории", channel)
+	if handler != nil {
+		handler(channel)
+	}
+}
+
+// GetChannelMessages получает сообщения из канала
+func (m *MTProtoClient) GetChannelMessages(ctx context.Context, channel string, limit int) ([]*ParsedMessage, error) {
+	if !m.authenticated() {
+		return nil, fmt.Errorf("клиент не аутентифицирован или не инициализирован")
+	}
+
+	m.logger.Infof("📥 Получение сообщений из канала: %s (лимит: %d)", channel, limit)
+
+	var history tg.MessagesMessagesClass
+	err := m.withChannel(ctx, channel, func(peer *models.ResolvedPeer) error {
+		var err error
+		history, err = m.client.API().MessagesGetHistory(ctx, &tg.MessagesGetHistoryRequest{
+			Peer:  inputPeer(peer),
+			Limit: limit,
+		})
+		return err
 	})
 	if err != nil {
-		return nil, fmt.Errorf("ошибка получения истории сообщений: %v", err)
+		return nil, fmt.Errorf("ошибка получения истории сообщений: %w", err)
 	}
 
 	var parsedMessages []*ParsedMessage
@@ -169,8 +473,9 @@ func (m *MTProtoClient) GetChannelMessages(ctx context.Context, channel string,
 	switch result := history.(type) {
 	case *tg.MessagesChannelMessages:
 		m.logger.Infof("📊 Получено %d сообщений из канала", len(result.Messages))
+		channels := channelsByID(result.Chats)
 		for _, msg := range result.Messages {
-			parsedMsg, err := m.parseMessage(msg, channel)
+			parsedMsg, err := m.parseMessage(msg, channel, channels)
 			if err != nil {
 				m.logger.Warnf("⚠️ Ошибка парсинга сообщения: %v", err)
 				continue
@@ -181,8 +486,9 @@ func (m *MTProtoClient) GetChannelMessages(ctx context.Context, channel string,
 		}
 	case *tg.MessagesMessages:
 		m.logger.Infof("📊 Получено %d сообщений", len(result.Messages))
+		channels := channelsByID(result.Chats)
 		for _, msg := range result.Messages {
-			parsedMsg, err := m.parseMessage(msg, channel)
+			parsedMsg, err := m.parseMessage(msg, channel, channels)
 			if err != nil {
 				m.logger.Warnf("⚠️ Ошибка парсинга сообщения: %v", err)
 				continue
@@ -195,12 +501,74 @@ func (m *MTProtoClient) GetChannelMessages(ctx context.Context, channel string,
 		return nil, fmt.Errorf("неожиданный тип результата: %T", history)
 	}
 
-	m.logger.Infof("✅ Успешно обработано %d сообщений из канала %s", len(parsedMessages), normalizedChannel)
+	// Части альбомов объединяем в одно сообщение
+	parsedMessages = groupAlbums(parsedMessages)
+
+	m.logger.Infof("✅ Успешно обработано %d сообщений из канала %s", len(parsedMessages), channel)
 	return parsedMessages, nil
 }
 
-// parseMessage парсит сообщение Telegram в нашу структуру
-func (m *MTProtoClient) parseMessage(msg tg.MessageClass, channel string) (*ParsedMessage, error) {
+// channelsByID индексирует каналы из ответа API по ID
+func channelsByID(chats []tg.ChatClass) map[int64]*tg.Channel {
+	channels := make(map[int64]*tg.Channel)
+	for _, chat := range chats {
+		if c, ok := chat.(*tg.Channel); ok {
+			channels[c.ID] = c
+		}
+	}
+	return channels
+}
+
+// forwardSources возвращает источники пересылки сообщения: @username и название
+// канала, если канал известен, ID отправителя и подпись скрытого отправителя
+func forwardSources(message *tg.Message, channels map[int64]*tg.Channel) []string {
+	fwd, ok := message.GetFwdFrom()
+	if !ok {
+		return nil
+	}
+
+	var sources []string
+	if name, ok := fwd.GetFromName(); ok && name != "" {
+		sources = append(sources, name)
+	}
+	if from, ok := fwd.GetFromID(); ok {
+		switch peer := from.(type) {
+		case *tg.PeerChannel:
+			sources = append(sources, strconv.FormatInt(peer.ChannelID, 10))
+			if c, ok := channels[peer.ChannelID]; ok {
+				if c.Username != "" {
+					sources = append(sources, "@"+c.Username)
+				}
+				sources = append(sources, c.Title)
+			}
+		case *tg.PeerUser:
+			sources = append(sources, strconv.FormatInt(peer.UserID, 10))
+		case *tg.PeerChat:
+			sources = append(sources, strconv.FormatInt(peer.ChatID, 10))
+		}
+	}
+	if len(sources) == 0 {
+		// Переслано, но источник неизвестен
+		sources = append(sources, "")
+	}
+	return sources
+}
+
+// hasLink проверяет, есть ли в сообщении ссылки
+func hasLink(message *tg.Message) bool {
+	for _, entity := range message.Entities {
+		switch entity.(type) {
+		case *tg.MessageEntityURL, *tg.MessageEntityTextURL:
+			return true
+		}
+	}
+	_, ok := message.Media.(*tg.MessageMediaWebPage)
+	return ok
+}
+
+// parseMessage парсит сообщение Telegram в нашу структуру.
+// channels - известные каналы для определения источника пересылки, может быть nil
+func (m *MTProtoClient) parseMessage(msg tg.MessageClass, channel string, channels map[int64]*tg.Channel) (*ParsedMessage, error) {
 	var message *tg.Message
 
 	switch msg := msg.(type) {
@@ -215,22 +583,29 @@ func (m *MTProtoClient) parseMessage(msg tg.MessageClass, channel string) (*Pars
 	}
 
 	content := message.Message
-	if content == "" {
-		return nil, nil
-	}
 
 	mediaType := models.MediaText
 	var mediaURL string
+	var mediaRef *MediaRef
 
 	if message.Media != nil {
 		switch media := message.Media.(type) {
 		case *tg.MessageMediaPhoto:
 			mediaType = models.MediaPhoto
+			if photo, ok := media.Photo.(*tg.Photo); ok {
+				mediaRef = photoMediaRef(photo)
+			}
 			m.logger.Debugf("📷 Найдено фото в сообщении %d", message.ID)
 
 		case *tg.MessageMediaDocument:
 			mediaType = models.MediaDocument
-			m.logger.Debugf("📄 Найден документ в сообщении %d", message.ID)
+			if doc, ok := media.Document.(*tg.Document); ok {
+				mediaRef = documentMediaRef(doc)
+				if isVideoDocument(doc) {
+					mediaType = models.MediaVideo
+				}
+			}
+			m.logger.Debugf("📄 Найден документ в сообщении %d (тип: %s)", message.ID, mediaType)
 
 		case *tg.MessageMediaWebPage:
 			if media.Webpage != nil {
@@ -242,46 +617,167 @@ func (m *MTProtoClient) parseMessage(msg tg.MessageClass, channel string) (*Pars
 		}
 	}
 
+	// Пропускаем сообщения без текста и без медиа, которое можно переслать
+	if mediaRef != nil {
+		mediaRef.AccountID = m.accountID
+	}
+
+	if content == "" && mediaRef == nil {
+		return nil, nil
+	}
+
 	parsedMsg := &ParsedMessage{
 		ID:            int64(message.ID),
 		SourceChannel: channel,
 		Content:       content,
+		Entities:      convertEntities(message.Entities),
 		MediaType:     mediaType,
 		MediaURL:      mediaURL,
+		Media:         mediaRef,
 		Date:          time.Unix(int64(message.Date), 0),
+		HasLink:       hasLink(message),
+		ForwardedFrom: forwardSources(message, channels),
+	}
+	if groupedID, ok := message.GetGroupedID(); ok {
+		parsedMsg.GroupedID = groupedID
+	}
+	if peer, ok := message.PeerID.(*tg.PeerChannel); ok {
+		parsedMsg.ChannelID = peer.ChannelID
 	}
 
 	m.logger.Debugf("📝 Обработано сообщение %d: %s", message.ID, truncateText(content, 100))
 	return parsedMsg, nil
 }
 
-// GetNewMessages получает только новые сообщения (после указанного ID)
-func (m *MTProtoClient) GetNewMessages(ctx context.Context, channel string, lastMessageID int64) ([]*ParsedMessage, error) {
-	if !m.isAuth || m.client == nil {
+// DownloadMedia скачивает файл медиа сообщения через upload.getFile и
+// сохраняет его в кэш. Возвращает путь к файлу в кэше.
+func (m *MTProtoClient) DownloadMedia(ctx context.Context, ref *MediaRef, cache *media.Cache) (string, error) {
+	if !m.authenticated() {
+		return "", fmt.Errorf("клиент не аутентифицирован или не инициализирован")
+	}
+	if ref == nil {
+		return "", fmt.Errorf("у сообщения нет медиа")
+	}
+	if ref.Size > 0 && !cache.Fits(ref.Size) {
+		return "", fmt.Errorf("%w: %d байт (лимит %d)", media.ErrTooLarge, ref.Size, cache.MaxSize())
+	}
+
+	path, err := cache.Write(ref.Ext(), func(w io.Writer) error {
+		_, err := downloader.NewDownloader().Download(m.client.API(), ref.Location).Stream(ctx, w)
+		return err
+	})
+	if err != nil {
+		return "", fmt.Errorf("ошибка скачивания медиа: %w", err)
+	}
+
+	m.logger.Debugf("💾 Медиа сохранено в кэш: %s", path)
+	return path, nil
+}
+
+// historyPageSize сообщений за один запрос messages.getHistory, максимум API
+const historyPageSize = 100
+
+// HistoryPage страница истории канала в порядке возрастания ID
+type HistoryPage struct {
+	Messages []*ParsedMessage // сообщения с текстом или медиа, части альбомов не объединены
+	Count    int              // сколько сообщений вернул Telegram, включая служебные
+	LastID   int64            // наибольший ID на странице, включая служебные сообщения
+}
+
+// completeMessages возвращает сообщения страницы, объединяя альбомы, и ID,
+// после которого читать следующую страницу. Альбом на границе полной
+// страницы мог попасть в нее не целиком - он откладывается до следующей.
+func (p *HistoryPage) completeMessages(limit int) ([]*ParsedMessage, int64) {
+	messages, next := p.Messages, p.LastID
+
+	if p.Count >= limit && len(messages) > 0 {
+		if groupedID := messages[len(messages)-1].GroupedID; groupedID != 0 {
+			i := len(messages)
+			for i > 0 && messages[i-1].GroupedID == groupedID {
+				i--
+			}
+			if i > 0 {
+				next = messages[i].ID - 1
+				messages = messages[:i]
+			}
+		}
+	}
+
+	return groupAlbums(messages), next
+}
+
+// GetHistoryPage получает до limit сообщений канала, следующих за afterID.
+// При afterID = 0 страница начинается с первого сообщения не раньше from.
+func (m *MTProtoClient) GetHistoryPage(ctx context.Context, channel string, afterID int64, from time.Time, limit int) (*HistoryPage, error) {
+	if !m.authenticated() {
 		return nil, fmt.Errorf("клиент не аутентифицирован или не инициализирован")
 	}
 
-	// Получаем последние сообщения и фильтруем те, что новее lastMessageID
-	allMessages, err := m.GetChannelMessages(ctx, channel, 50)
+	// История отдается от новых к старым. Отрицательный add_offset
+	// сдвигает страницу к более новым сообщениям от точки отсчета.
+	req := &tg.MessagesGetHistoryRequest{
+		AddOffset: -limit,
+		Limit:     limit,
+	}
+	if afterID > 0 {
+		req.OffsetID = int(afterID) + 1
+		req.MinID = int(afterID)
+	} else {
+		req.OffsetDate = int(from.Unix())
+	}
+
+	var history tg.MessagesMessagesClass
+	err := m.withChannel(ctx, channel, func(peer *models.ResolvedPeer) error {
+		var err error
+		req.Peer = inputPeer(peer)
+		history, err = m.client.API().MessagesGetHistory(ctx, req)
+		return err
+	})
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("ошибка получения истории сообщений: %w", err)
 	}
 
-	var newMessages []*ParsedMessage
-	for _, msg := range allMessages {
-		if msg.ID > lastMessageID {
-			newMessages = append(newMessages, msg)
-			m.logger.Debugf("🆕 Найдено новое сообщение ID %d", msg.ID)
+	var messages []tg.MessageClass
+	var chats []tg.ChatClass
+	switch result := history.(type) {
+	case *tg.MessagesChannelMessages:
+		messages, chats = result.Messages, result.Chats
+	case *tg.MessagesMessages:
+		messages, chats = result.Messages, result.Chats
+	case *tg.MessagesMessagesSlice:
+		messages, chats = result.Messages, result.Chats
+	default:
+		return nil, fmt.Errorf("неожиданный тип результата: %T", history)
+	}
+
+	page := &HistoryPage{}
+	channels := channelsByID(chats)
+	for _, msg := range messages {
+		if int64(msg.GetID()) <= afterID {
+			continue
+		}
+		page.Count++
+		if id := int64(msg.GetID()); id > page.LastID {
+			page.LastID = id
+		}
+
+		parsedMsg, err := m.parseMessage(msg, channel, channels)
+		if err != nil {
+			m.logger.Warnf("⚠️ Ошибка парсинга сообщения: %v", err)
+			continue
+		}
+		if parsedMsg != nil {
+			page.Messages = append(page.Messages, parsedMsg)
 		}
 	}
 
-	m.logger.Infof("✅ Найдено %d новых сообщений в канале %s", len(newMessages), channel)
-	return newMessages, nil
+	sort.Slice(page.Messages, func(i, j int) bool { return page.Messages[i].ID < page.Messages[j].ID })
+	return page, nil
 }
 
 // TestConnection проверяет подключение к Telegram
 func (m *MTProtoClient) TestConnection(ctx context.Context) error {
-	if !m.isAuth || m.client == nil {
+	if !m.authenticated() {
 		return fmt.Errorf("клиент не аутентифицирован или не инициализирован")
 	}
 
@@ -0,0 +1,108 @@
+package parser
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gotd/td/tgerr"
+)
+
+func TestFloodWait(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantWait time.Duration
+		wantOK   bool
+	}{
+		{name: "nil", err: nil},
+		{name: "обычная ошибка", err: errors.New("boom")},
+		{name: "другая ошибка RPC", err: tgerr.New(400, "CHANNEL_INVALID")},
+		{name: "FLOOD_WAIT", err: tgerr.New(420, "FLOOD_WAIT_30"), wantWait: 30 * time.Second, wantOK: true},
+		{name: "FLOOD_PREMIUM_WAIT", err: tgerr.New(420, "FLOOD_PREMIUM_WAIT_5"), wantWait: 5 * time.Second, wantOK: true},
+		{
+			name:     "обернутый FLOOD_WAIT",
+			err:      fmt.Errorf("ошибка получения истории: %w", tgerr.New(420, "FLOOD_WAIT_120")),
+			wantWait: 2 * time.Minute,
+			wantOK:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, ok := floodWait(tt.err)
+			if ok != tt.wantOK || wait != tt.wantWait {
+				t.Errorf("floodWait() = (%v, %v), want (%v, %v)", wait, ok, tt.wantWait, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestHistoryPageCompleteMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		page     HistoryPage
+		limit    int
+		want     [][]int64
+		wantNext int64
+	}{
+		{
+			name:     "неполная страница",
+			page:     HistoryPage{Messages: []*ParsedMessage{msg(1, 0), msg(2, 10), msg(3, 10)}, Count: 3, LastID: 3},
+			limit:    5,
+			want:     [][]int64{{1}, {2, 2, 3}},
+			wantNext: 3,
+		},
+		{
+			name:     "полная страница без альбома в конце",
+			page:     HistoryPage{Messages: []*ParsedMessage{msg(1, 10), msg(2, 10), msg(3, 0)}, Count: 3, LastID: 3},
+			limit:    3,
+			want:     [][]int64{{1, 1, 2}, {3}},
+			wantNext: 3,
+		},
+		{
+			// Остальные части альбома могут быть на следующей странице
+			name:     "альбом на границе полной страницы откладывается",
+			page:     HistoryPage{Messages: []*ParsedMessage{msg(1, 0), msg(2, 0), msg(3, 10), msg(4, 10)}, Count: 4, LastID: 4},
+			limit:    4,
+			want:     [][]int64{{1}, {2}},
+			wantNext: 2,
+		},
+		{
+			name:     "служебные сообщения после альбома",
+			page:     HistoryPage{Messages: []*ParsedMessage{msg(1, 0), msg(5, 10)}, Count: 6, LastID: 6},
+			limit:    6,
+			want:     [][]int64{{1}},
+			wantNext: 4,
+		},
+		{
+			// Альбом на всю страницу нельзя отложить, иначе чтение не продвинется
+			name:     "страница из одного альбома",
+			page:     HistoryPage{Messages: []*ParsedMessage{msg(1, 10), msg(2, 10)}, Count: 2, LastID: 2},
+			limit:    2,
+			want:     [][]int64{{1, 1, 2}},
+			wantNext: 2,
+		},
+		{
+			name:     "пустая полная страница",
+			page:     HistoryPage{Count: 2, LastID: 9},
+			limit:    2,
+			want:     nil,
+			wantNext: 9,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, next := tt.page.completeMessages(tt.limit)
+			if got := ids(messages); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("сообщения = %v, want %v", got, tt.want)
+			}
+			if next != tt.wantNext {
+				t.Errorf("next = %d, want %d", next, tt.wantNext)
+			}
+		})
+	}
+}
@@ -47,6 +47,11 @@ func (e *RuleEngine) matchesRule(message *ParsedMessage, rule *models.ParsingRul
 		return false
 	}
 
+	// Проверка выражения фильтра
+	if !rule.MatchesFilter(message.filterMessage()) {
+		return false
+	}
+
 	return true
 }
 
@@ -0,0 +1,192 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+const (
+	// ruleSyncInterval как часто правила сверяются с БД на случай
+	// пропущенных уведомлений
+	ruleSyncInterval = time.Minute
+	// ruleListenRetry пауза перед повторной подпиской на изменения правил
+	ruleListenRetry = 10 * time.Second
+)
+
+// ruleMonitor запущенный мониторинг канала правила
+type ruleMonitor struct {
+	rule      *models.ParsingRule
+	cancel    context.CancelFunc
+	done      chan struct{}
+	check     chan struct{} // запрос проверки канала вне расписания
+	startedAt time.Time
+
+	mu   sync.Mutex
+	mode models.MonitorMode
+}
+
+// setMode запоминает, как мониторинг читает канал
+func (m *ruleMonitor) setMode(mode models.MonitorMode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.mode = mode
+}
+
+// status возвращает состояние мониторинга для отчета парсера
+func (m *ruleMonitor) status() models.RuleMonitorStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return models.RuleMonitorStatus{
+		RuleID:        m.rule.ID,
+		RuleName:      m.rule.Name,
+		SourceChannel: m.rule.SourceChannel,
+		Mode:          m.mode,
+		StartedAt:     m.startedAt,
+	}
+}
+
+// stop останавливает мониторинг и ждет его завершения
+func (m *ruleMonitor) stop() {
+	m.cancel()
+	<-m.done
+}
+
+// watchRules применяет изменения правил, о которых сообщает БД, и
+// периодически сверяет запущенные мониторинги с активными правилами
+func (p *TelegramParser) watchRules(ctx context.Context) {
+	changes := make(chan int64, 1)
+	go p.listenRules(ctx, changes)
+
+	ticker := time.NewTicker(ruleSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.stopMonitors()
+			return
+		case <-changes:
+		case <-ticker.C:
+		}
+
+		if err := p.syncRules(ctx); err != nil && ctx.Err() == nil {
+			p.logger.Errorf("❌ %v", err)
+		}
+	}
+}
+
+// listenRules держит подписку на изменения правил и переподписывается
+// после потери соединения с БД
+func (p *TelegramParser) listenRules(ctx context.Context, changes chan<- int64) {
+	for {
+		err := p.ruleRepo.ListenChanges(ctx, func(ruleID int64) {
+			p.logger.Debugf("🔔 Правило %d изменено", ruleID)
+			// Сверка применяет все накопившиеся изменения сразу
+			select {
+			case changes <- ruleID:
+			default:
+			}
+		}, p.checkNow)
+		if ctx.Err() != nil {
+			return
+		}
+
+		p.logger.Warnf("⚠️ Подписка на изменения правил прервана, повтор через %v: %v", ruleListenRetry, err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(ruleListenRetry):
+		}
+	}
+}
+
+// syncRules запускает мониторинг новых активных правил, перезапускает
+// измененные и останавливает выключенные и удаленные
+func (p *TelegramParser) syncRules(ctx context.Context) error {
+	rules, err := p.ruleRepo.GetActiveRules(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки правил: %v", err)
+	}
+
+	p.syncMu.Lock()
+	defer p.syncMu.Unlock()
+
+	active := make(map[int64]bool, len(rules))
+	for _, rule := range rules {
+		active[rule.ID] = true
+
+		monitor, ok := p.monitors[rule.ID]
+		if ok && monitor.rule.UpdatedAt.Equal(rule.UpdatedAt) {
+			continue
+		}
+		if ok {
+			p.logger.Infof("🔁 Правило %q изменено, перезапуск мониторинга канала %s", rule.Name, rule.SourceChannel)
+			monitor.stop()
+		} else {
+			p.logger.Infof("🎯 Запуск мониторинга для канала: %s", rule.SourceChannel)
+		}
+		p.startMonitor(ctx, rule)
+	}
+
+	for id, monitor := range p.monitors {
+		if active[id] {
+			continue
+		}
+		p.logger.Infof("⏹️ Правило %q выключено или удалено, остановка мониторинга канала %s", monitor.rule.Name, monitor.rule.SourceChannel)
+		monitor.stop()
+		delete(p.monitors, id)
+	}
+
+	return nil
+}
+
+// startMonitor запускает мониторинг канала правила. Вызывается под syncMu.
+func (p *TelegramParser) startMonitor(ctx context.Context, rule *models.ParsingRule) {
+	monitorCtx, cancel := context.WithCancel(ctx)
+	monitor := &ruleMonitor{
+		rule:      rule,
+		cancel:    cancel,
+		done:      make(chan struct{}),
+		check:     make(chan struct{}, 1),
+		startedAt: time.Now(),
+	}
+	p.monitors[rule.ID] = monitor
+
+	go func() {
+		defer close(monitor.done)
+		p.monitorChannel(monitorCtx, monitor)
+	}()
+}
+
+// checkNow просит мониторинг правила проверить канал вне расписания
+func (p *TelegramParser) checkNow(ruleID int64) {
+	p.syncMu.Lock()
+	monitor, ok := p.monitors[ruleID]
+	p.syncMu.Unlock()
+
+	if !ok {
+		p.logger.Warnf("⚠️ Запрошена проверка правила %d, но его канал не отслеживается", ruleID)
+		return
+	}
+
+	p.logger.Infof("⚡ Внеочередная проверка канала %s", monitor.rule.SourceChannel)
+	select {
+	case monitor.check <- struct{}{}:
+	default:
+	}
+}
+
+// stopMonitors останавливает мониторинг всех правил
+func (p *TelegramParser) stopMonitors() {
+	p.syncMu.Lock()
+	defer p.syncMu.Unlock()
+
+	for id, monitor := range p.monitors {
+		monitor.stop()
+		delete(p.monitors, id)
+	}
+}
@@ -0,0 +1,62 @@
+package parser
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+// statusReportInterval как часто парсер сохраняет свое состояние в БД.
+// Веб-интерфейс считает парсер остановленным, если отчетов долго нет.
+const statusReportInterval = 15 * time.Second
+
+// reportStatus периодически сохраняет состояние парсера: готовность
+// аккаунтов и запущенные мониторинги. При остановке запись удаляется.
+func (p *TelegramParser) reportStatus(ctx context.Context) {
+	hostname, _ := os.Hostname()
+	instance := &models.ParserInstance{
+		ID:        fmt.Sprintf("%s-%d", hostname, os.Getpid()),
+		Hostname:  hostname,
+		StartedAt: time.Now(),
+	}
+
+	ticker := time.NewTicker(statusReportInterval)
+	defer ticker.Stop()
+
+	for {
+		instance.AccountsReady = p.pool.Ready()
+		instance.Monitors = p.monitorStatuses()
+		if err := p.statusRepo.Save(ctx, instance); err != nil && ctx.Err() == nil {
+			p.logger.Warnf("⚠️ %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			// Контекст парсера уже отменен, запись удаляется с отдельным таймаутом
+			cleanupCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			if err := p.statusRepo.Delete(cleanupCtx, instance.ID); err != nil {
+				p.logger.Warnf("⚠️ %v", err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// monitorStatuses возвращает состояние запущенных мониторингов по ID правила
+func (p *TelegramParser) monitorStatuses() []models.RuleMonitorStatus {
+	p.syncMu.Lock()
+	statuses := make([]models.RuleMonitorStatus, 0, len(p.monitors))
+	for _, monitor := range p.monitors {
+		statuses = append(statuses, monitor.status())
+	}
+	p.syncMu.Unlock()
+
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].RuleID < statuses[j].RuleID })
+	return statuses
+}
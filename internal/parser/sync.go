@@ -0,0 +1,122 @@
+package parser
+
+import (
+	"context"
+	"slices"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+// Правки и удаления приходят только через поток обновлений, поэтому
+// синхронизация работает для правил в потоковом режиме.
+
+// rulesForChannel возвращает потоковые правила канала
+func (p *TelegramParser) rulesForChannel(channelID int64) []*models.ParsingRule {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]*models.ParsingRule(nil), p.streamRules[channelID]...)
+}
+
+// handleEdit обновляет пост и его опубликованные копии после правки исходного сообщения
+func (p *TelegramParser) handleEdit(ctx context.Context, msg *ParsedMessage) {
+	for _, rule := range p.rulesForChannel(msg.ChannelID) {
+		p.logger.Debugf("✏️ Сообщение %d в канале %s отредактировано", msg.ID, rule.SourceChannel)
+
+		post, err := p.findPost(ctx, rule, msg)
+		if err != nil {
+			p.logger.Errorf("❌ Ошибка поиска поста для правки: %v", err)
+			continue
+		}
+		if post != nil && post.RuleID != rule.ID {
+			// Пост создан другим правилом канала, его правит это правило
+			continue
+		}
+
+		if post == nil {
+			// После правки сообщение может пройти фильтры. Отдельную часть
+			// альбома не публикуем - альбом обрабатывается целиком.
+			if msg.GroupedID != 0 {
+				continue
+			}
+			if err := p.processMessage(ctx, rule, msg); err != nil {
+				p.logger.Errorf("❌ Ошибка обработки отредактированного сообщения: %v", err)
+			}
+			continue
+		}
+
+		if !rule.SyncEdits {
+			continue
+		}
+
+		// Подпись альбома хранится в одной из частей, правки остальных частей ее не меняют
+		if post.GroupedID != 0 && msg.Content == "" {
+			continue
+		}
+
+		content, entities := rule.ApplyTransformationsWithEntities(msg.Content, msg.Entities)
+		if content == post.Content && slices.Equal(entities, post.Entities) {
+			continue
+		}
+
+		post.Content = content
+		post.Entities = entities
+		updated, err := p.postRepo.UpdateContent(ctx, post)
+		if err != nil {
+			p.logger.Errorf("❌ %v", err)
+			continue
+		}
+		if !updated {
+			// Редактор уже правил текст или решает судьбу поста - правка
+			// источника не должна незаметно заменить то, что он видит
+			p.logger.Infof("✋ Правка исходного сообщения %d не применена к посту %d: пост на модерации или изменен редактором",
+				msg.ID, post.ID)
+			continue
+		}
+
+		if err := p.multiPublisher.SyncEdit(ctx, post, rule); err != nil {
+			p.logger.Errorf("❌ Ошибка синхронизации правки поста %d: %v", post.ID, err)
+		}
+	}
+}
+
+// handleDelete удаляет опубликованные копии постов удаленных исходных сообщений
+func (p *TelegramParser) handleDelete(ctx context.Context, channelID int64, messageIDs []int64) {
+	deleted := make(map[int64]bool)
+
+	for _, rule := range p.rulesForChannel(channelID) {
+		if !rule.SyncDeletes {
+			continue
+		}
+
+		for _, messageID := range messageIDs {
+			post, err := p.postRepo.GetByMessageID(ctx, rule.SourceChannel, messageID)
+			if err != nil {
+				p.logger.Errorf("❌ Ошибка поиска поста для удаления: %v", err)
+				continue
+			}
+			// Пост другого правила синхронизируется по настройкам того правила
+			if post == nil || post.RuleID != rule.ID || deleted[post.ID] {
+				continue
+			}
+			deleted[post.ID] = true
+
+			p.logger.Infof("🗑️ Исходное сообщение %d поста %d удалено в канале %s", messageID, post.ID, rule.SourceChannel)
+			if err := p.multiPublisher.SyncDelete(ctx, post); err != nil {
+				p.logger.Errorf("❌ Ошибка синхронизации удаления поста %d: %v", post.ID, err)
+			}
+		}
+	}
+}
+
+// findPost находит пост исходного сообщения, в том числе по альбому
+func (p *TelegramParser) findPost(ctx context.Context, rule *models.ParsingRule, msg *ParsedMessage) (*models.Post, error) {
+	post, err := p.postRepo.GetByMessageID(ctx, rule.SourceChannel, msg.ID)
+	if err != nil || post != nil {
+		return post, err
+	}
+
+	if msg.GroupedID != 0 {
+		return p.postRepo.GetByGroupedID(ctx, rule.SourceChannel, msg.GroupedID)
+	}
+	return nil, nil
+}
@@ -2,27 +2,72 @@ package parser
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/drerr0r/tgparserbot/internal/filter"
+	"github.com/drerr0r/tgparserbot/internal/media"
 	"github.com/drerr0r/tgparserbot/internal/models"
 	"github.com/drerr0r/tgparserbot/internal/publisher"
 	"github.com/drerr0r/tgparserbot/internal/storage"
 	"go.uber.org/zap"
 )
 
+// streamRetryDelay через сколько опрос истории повторит сообщение из потока
+// обновлений, которое не удалось обработать
+const streamRetryDelay = 30 * time.Second
+
+// streamCheckInterval как часто поток обновлений отмечает успешное чтение
+// канала правила, чтобы не писать в БД на каждое сообщение
+const streamCheckInterval = time.Minute
+
+// accountReadyTimeout сколько при запуске ждать готовности аккаунтов
+const accountReadyTimeout = 30 * time.Second
+
+// streamWatchRetry как часто мониторинг в режиме опроса пробует снова
+// подписаться на обновления канала потокового правила
+const streamWatchRetry = time.Minute
+
+// mediaDownloadAttempts сколько раз повторяется сообщение без текста, медиа
+// которого не удалось загрузить, прежде чем оно будет пропущено
+const mediaDownloadAttempts = 5
+
+// mediaKey сообщение канала правила, медиа которого не удалось загрузить
+type mediaKey struct {
+	ruleID    int64
+	messageID int64
+}
+
 // TelegramParser парсер Telegram каналов с реальным MTProto
 type TelegramParser struct {
 	storage        *storage.DB
 	ruleRepo       *storage.RuleRepository
 	postRepo       *storage.PostRepository
+	cursorRepo     *storage.CursorRepository
+	statusRepo     *storage.ParserStatusRepository
 	multiPublisher *publisher.MultiPublisher
-	mtprotoClient  *MTProtoClient
+	pool           *AccountPool
+	mediaCache     *media.Cache
+	dedup          models.DedupConfig
 	logger         *zap.SugaredLogger
 	isRunning      bool
 	cancelFunc     context.CancelFunc
-	lastMessageIDs map[string]int64 // Храним последние ID сообщений по каналам
+	wg             sync.WaitGroup // фоновые горутины парсера
+
+	mu          sync.Mutex
+	runCtx      context.Context                 // Контекст запуска, в нем обрабатываются обновления
+	streamRules map[int64][]*models.ParsingRule // Правила в потоковом режиме по ID канала
+	stalled     map[int64]bool                  // Правила, сообщение которых из потока не обработано
+	checkedAt   map[int64]time.Time             // Когда поток последний раз отметил успешное чтение правила
+	mediaFails  map[mediaKey]int                // Неудачные попытки загрузить медиа сообщения без текста
+	albums      *albumCollector
+
+	syncMu   sync.Mutex
+	monitors map[int64]*ruleMonitor // Запущенные мониторинги по ID правила
 }
 
 // NewTelegramParser создает новый парсер
@@ -30,20 +75,34 @@ func NewTelegramParser(
 	storage *storage.DB,
 	ruleRepo *storage.RuleRepository,
 	postRepo *storage.PostRepository,
+	cursorRepo *storage.CursorRepository,
+	statusRepo *storage.ParserStatusRepository,
 	multiPublisher *publisher.MultiPublisher,
-	mtprotoClient *MTProtoClient,
+	pool *AccountPool,
+	mediaCache *media.Cache,
+	dedupCfg models.DedupConfig,
 	logger *zap.SugaredLogger,
 ) *TelegramParser {
-	return &TelegramParser{
+	p := &TelegramParser{
 		storage:        storage,
 		ruleRepo:       ruleRepo,
 		postRepo:       postRepo,
+		cursorRepo:     cursorRepo,
+		statusRepo:     statusRepo,
 		multiPublisher: multiPublisher,
-		mtprotoClient:  mtprotoClient,
+		pool:           pool,
+		mediaCache:     mediaCache,
+		dedup:          dedupCfg,
 		logger:         logger,
 		isRunning:      false,
-		lastMessageIDs: make(map[string]int64),
+		streamRules:    make(map[int64][]*models.ParsingRule),
+		stalled:        make(map[int64]bool),
+		checkedAt:      make(map[int64]time.Time),
+		mediaFails:     make(map[mediaKey]int),
+		monitors:       make(map[int64]*ruleMonitor),
 	}
+	p.albums = newAlbumCollector(albumFlushDelay, p.dispatchUpdate)
+	return p
 }
 
 // Start запускает парсинг каналов
@@ -54,64 +113,84 @@ func (p *TelegramParser) Start(ctx context.Context) error {
 
 	p.logger.Info("🚀 Запуск Telegram парсера каналов с MTProto...")
 
-	// Запускаем MTProto клиент
-	if err := p.mtprotoClient.Start(ctx); err != nil {
-		return fmt.Errorf("ошибка запуска MTProto клиента: %v", err)
-	}
-
-	// Ждем немного для инициализации
-	time.Sleep(3 * time.Second)
+	// Обновления из потока MTProto передаются сразу в обработку
+	p.pool.SetUpdateHandler(p.handleUpdate)
+	p.pool.SetDeleteHandler(p.handleDelete)
+	p.pool.SetGapHandler(p.handleGap)
 
-	// Загружаем активные правила
-	rules, err := p.ruleRepo.GetActiveRules(ctx)
-	if err != nil {
-		return fmt.Errorf("ошибка загрузки правил: %v", err)
+	// Создаем контекст с отменой, Stop останавливает и клиенты аккаунтов.
+	// Обновления могут прийти сразу после запуска клиентов, поэтому
+	// контекст их обработки задается заранее.
+	ctx, cancel := context.WithCancel(ctx)
+	p.mu.Lock()
+	p.runCtx = ctx
+	p.mu.Unlock()
+
+	// Запускаем MTProto клиенты аккаунтов
+	if err := p.pool.Start(ctx); err != nil {
+		cancel()
+		return fmt.Errorf("ошибка запуска аккаунтов Telegram: %v", err)
 	}
 
-	if len(rules) == 0 {
-		p.logger.Warn("⚠️ Нет активных правил для парсинга")
-		return nil
+	// Ждем, пока хотя бы один аккаунт будет готов читать каналы. Аккаунты
+	// без сессии ждут входа через API: мониторинги начнут с опроса и
+	// подпишутся на обновления, когда вход завершится.
+	if !p.pool.WaitReady(ctx, accountReadyTimeout) && ctx.Err() == nil {
+		p.logger.Warnf("⚠️ Ни один аккаунт Telegram не готов за %v, каналы будут ждать входа", accountReadyTimeout)
 	}
 
-	p.logger.Infof("📋 Загружено %d активных правил", len(rules))
+	// Запускаем мониторинг активных правил, дальше набор правил
+	// обновляется по уведомлениям об их изменении
+	if err := p.syncRules(ctx); err != nil {
+		cancel()
+		p.pool.Wait()
+		return err
+	}
 
-	// Создаем контекст с отменой
-	ctx, cancel := context.WithCancel(ctx)
 	p.cancelFunc = cancel
 	p.isRunning = true
 
-	// Инициализируем lastMessageIDs
-	for _, rule := range rules {
-		lastPost, err := p.postRepo.GetLastMessageID(ctx, rule.SourceChannel)
-		if err == nil && lastPost != nil {
-			p.lastMessageIDs[rule.SourceChannel] = lastPost.MessageID
-		} else {
-			p.lastMessageIDs[rule.SourceChannel] = 0
-		}
+	p.syncMu.Lock()
+	count := len(p.monitors)
+	p.syncMu.Unlock()
+	if count == 0 {
+		p.logger.Warn("⚠️ Нет активных правил для парсинга, ожидаем их появления")
+	} else {
+		p.logger.Infof("📋 Загружено %d активных правил", count)
 	}
 
-	// Запускаем мониторинг для каждого правила
-	for _, rule := range rules {
-		p.logger.Infof("🎯 Запуск мониторинга для канала: %s", rule.SourceChannel)
-		go p.monitorChannel(ctx, rule)
-	}
+	p.wg.Add(2)
+	go func() {
+		defer p.wg.Done()
+		p.watchRules(ctx)
+	}()
+	go func() {
+		defer p.wg.Done()
+		p.reportStatus(ctx)
+	}()
 
 	p.logger.Info("✅ Telegram парсер успешно запущен")
 	return nil
 }
 
-// Stop останавливает парсинг
+// Stop останавливает парсинг и ждет, пока остановятся клиенты аккаунтов,
+// мониторинги каналов и отчет о состоянии, чтобы после возврата парсер
+// больше не обращался к БД
 func (p *TelegramParser) Stop() {
 	if p.cancelFunc != nil {
 		p.cancelFunc()
 	}
-	// MTProto клиент автоматически закрывается при отмене контекста
+	// Клиенты закрываются при отмене контекста, обновления из них
+	// обрабатываются до их остановки
+	p.pool.Wait()
+	p.wg.Wait()
 	p.isRunning = false
 	p.logger.Info("🛑 Парсер остановлен")
 }
 
 // monitorChannel мониторит конкретный канал
-func (p *TelegramParser) monitorChannel(ctx context.Context, rule *models.ParsingRule) {
+func (p *TelegramParser) monitorChannel(ctx context.Context, monitor *ruleMonitor) {
+	rule := monitor.rule
 	channelDisplay := p.getChannelDisplayName(rule.SourceChannel)
 	p.logger.Infof("🔍 Начало мониторинга канала: %s", channelDisplay)
 
@@ -120,11 +199,64 @@ func (p *TelegramParser) monitorChannel(ctx context.Context, rule *models.Parsin
 		p.logger.Errorf("❌ Ошибка проверки исторических сообщений: %v", err)
 	}
 
+	// В потоковом режиме новые сообщения приходят через обновления MTProto,
+	// опрос истории остается запасным вариантом
+	if rule.IsStreaming() {
+		channelID, err := p.pool.WatchChannel(ctx, p.normalizeChannel(rule.SourceChannel))
+		if err == nil {
+			p.streamChannel(ctx, monitor, channelID)
+			return
+		}
+		p.logger.Warnf("⚠️ Не удалось подписаться на обновления канала %s, переходим на опрос: %v", channelDisplay, err)
+	}
+
+	p.pollChannel(ctx, monitor)
+}
+
+// streamChannel получает сообщения канала через поток обновлений до
+// остановки мониторинга
+func (p *TelegramParser) streamChannel(ctx context.Context, monitor *ruleMonitor, channelID int64) {
+	rule := monitor.rule
+	channelDisplay := p.getChannelDisplayName(rule.SourceChannel)
+
+	p.addStreamRule(channelID, rule)
+	monitor.setMode(models.MonitorStreaming)
+	p.logger.Infof("📡 Канал %s отслеживается через поток обновлений", channelDisplay)
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.removeStreamRule(channelID, rule)
+			p.logger.Infof("🛑 Остановка мониторинга канала: %s", channelDisplay)
+			return
+		case <-monitor.check:
+			if err := p.checkNewMessages(ctx, rule); err != nil {
+				p.logger.Errorf("❌ Ошибка проверки сообщений в канале %s: %v", channelDisplay, err)
+			}
+		}
+	}
+}
+
+// pollChannel опрашивает историю канала с интервалом правила. Потоковое
+// правило периодически пробует подписаться на обновления и после
+// подписки переходит на поток.
+func (p *TelegramParser) pollChannel(ctx context.Context, monitor *ruleMonitor) {
+	rule := monitor.rule
+	channelDisplay := p.getChannelDisplayName(rule.SourceChannel)
+
 	// Настраиваем интервал проверки
 	interval := p.getCheckInterval(rule)
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
+	var watchRetry <-chan time.Time
+	if rule.IsStreaming() {
+		retry := time.NewTicker(streamWatchRetry)
+		defer retry.Stop()
+		watchRetry = retry.C
+	}
+
+	monitor.setMode(models.MonitorPolling)
 	p.logger.Infof("⏰ Мониторинг канала %s с интервалом %v", channelDisplay, interval)
 
 	for {
@@ -132,130 +264,482 @@ func (p *TelegramParser) monitorChannel(ctx context.Context, rule *models.Parsin
 		case <-ctx.Done():
 			p.logger.Infof("🛑 Остановка мониторинга канала: %s", channelDisplay)
 			return
-		case <-ticker.C:
+		case <-watchRetry:
+			channelID, err := p.pool.WatchChannel(ctx, p.normalizeChannel(rule.SourceChannel))
+			if err != nil {
+				p.logger.Debugf("🔄 Канал %s пока читается опросом: %v", channelDisplay, err)
+				continue
+			}
+			// Догружаем сообщения, пришедшие с последнего опроса
 			if err := p.checkNewMessages(ctx, rule); err != nil {
 				p.logger.Errorf("❌ Ошибка проверки сообщений в канале %s: %v", channelDisplay, err)
 			}
+			p.streamChannel(ctx, monitor, channelID)
+			return
+		case <-monitor.check:
+			// Внеочередная проверка откладывает плановую
+			ticker.Reset(interval)
+		case <-ticker.C:
+		}
+
+		if err := p.checkNewMessages(ctx, rule); err != nil {
+			p.logger.Errorf("❌ Ошибка проверки сообщений в канале %s: %v", channelDisplay, err)
 		}
 	}
 }
 
-// checkHistoricalMessages проверяет исторические сообщения
+// checkHistoricalMessages начинает чтение канала. Если канал уже читался,
+// догружает сообщения после сохраненного курсора, иначе обрабатывает
+// последние сообщения канала.
 func (p *TelegramParser) checkHistoricalMessages(ctx context.Context, rule *models.ParsingRule) error {
 	channelDisplay := p.getChannelDisplayName(rule.SourceChannel)
+
+	cursor, err := p.cursorRepo.Get(ctx, rule.ID, rule.SourceChannel)
+	if err != nil {
+		return err
+	}
+	if cursor != nil && cursor.LastMessageID > 0 {
+		p.logger.Infof("📚 Продолжаем чтение канала %s после сообщения %d", channelDisplay, cursor.LastMessageID)
+		return p.checkNewMessages(ctx, rule)
+	}
+
 	p.logger.Infof("📚 Проверка исторических сообщений для канала: %s", channelDisplay)
 
 	// Получаем реальные сообщения с канала через MTProto
-	messages, err := p.mtprotoClient.GetChannelMessages(ctx, p.normalizeChannel(rule.SourceChannel), 20)
+	messages, err := p.pool.GetChannelMessages(ctx, p.normalizeChannel(rule.SourceChannel), 20)
 	if err != nil {
+		p.markChecked(ctx, rule, err)
 		return fmt.Errorf("❌ Ошибка получения сообщений: %v", err)
 	}
 
 	p.logger.Infof("📥 Получено %d сообщений с канала %s", len(messages), channelDisplay)
 
-	// Обрабатываем сообщения
+	// Обрабатываем сообщения от старых к новым, чтобы курсор шел вперед
+	sort.Slice(messages, func(i, j int) bool { return messages[i].ID < messages[j].ID })
+
 	processedCount := 0
+	var lastErr error
 	for _, msg := range messages {
 		if err := p.processMessage(ctx, rule, msg); err != nil {
-			p.logger.Errorf("❌ Ошибка обработки сообщения: %v", err)
-			continue
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			// Курсор остается перед сообщением, следующая проверка повторит его
+			p.logger.Errorf("❌ Ошибка обработки сообщения %d, повтор при следующей проверке: %v", msg.ID, err)
+			lastErr = err
+			break
 		}
 		processedCount++
 
-		// Обновляем последний ID сообщения
-		if msg.ID > p.lastMessageIDs[rule.SourceChannel] {
-			p.lastMessageIDs[rule.SourceChannel] = msg.ID
-		}
+		// Курсор сдвигается и на сообщения, не прошедшие фильтры
+		p.advanceCursor(ctx, rule, msg.MaxID(), 0)
 
 		// Небольшая задержка между обработкой
 		time.Sleep(100 * time.Millisecond)
 	}
 
+	if lastErr == nil {
+		p.setStalled(rule.ID, false)
+	}
+	p.markChecked(ctx, rule, lastErr)
 	p.logger.Infof("✅ Обработано %d/%d сообщений с канала %s", processedCount, len(messages), channelDisplay)
 	return nil
 }
 
-// checkNewMessages проверяет новые сообщения
+// checkNewMessages обрабатывает сообщения после курсора канала
+// страницами, пока не дочитает историю до конца
 func (p *TelegramParser) checkNewMessages(ctx context.Context, rule *models.ParsingRule) error {
 	channelDisplay := p.getChannelDisplayName(rule.SourceChannel)
+
+	cursor, err := p.cursorRepo.Get(ctx, rule.ID, rule.SourceChannel)
+	if err != nil {
+		return err
+	}
+	if cursor == nil || cursor.LastMessageID == 0 {
+		// Канал еще не читался - начинаем с последних сообщений
+		return p.checkHistoricalMessages(ctx, rule)
+	}
+
 	p.logger.Debugf("🔄 Проверка новых сообщений в канале: %s", channelDisplay)
 
-	lastMessageID := p.lastMessageIDs[rule.SourceChannel]
+	lastMessageID := cursor.LastMessageID
+	found := 0
+	var lastErr error
+	for {
+		page, err := p.pool.GetHistoryPage(ctx, p.normalizeChannel(rule.SourceChannel), lastMessageID, time.Time{}, historyPageSize)
+		if err != nil {
+			p.markChecked(ctx, rule, err)
+			return fmt.Errorf("ошибка получения новых сообщений: %v", err)
+		}
 
-	// Получаем только новые сообщения через MTProto
-	messages, err := p.mtprotoClient.GetNewMessages(ctx, p.normalizeChannel(rule.SourceChannel), lastMessageID)
-	if err != nil {
-		return fmt.Errorf("ошибка получения новых сообщений: %v", err)
+		messages, next := page.completeMessages(historyPageSize)
+		for _, msg := range messages {
+			found++
+			if err := p.processMessage(ctx, rule, msg); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				// Страница обрывается на сообщении с ошибкой, курсор остается
+				// перед ним, и следующая проверка повторит его
+				p.logger.Errorf("❌ Ошибка обработки нового сообщения %d, повтор при следующей проверке: %v", msg.ID, err)
+				lastErr = err
+				break
+			}
+			p.advanceCursor(ctx, rule, msg.MaxID(), 0)
+		}
+		if lastErr != nil {
+			break
+		}
+
+		// Служебные сообщения в конце страницы тоже считаются прочитанными
+		if next <= lastMessageID {
+			break
+		}
+		p.advanceCursor(ctx, rule, next, 0)
+		lastMessageID = next
+
+		if page.Count < historyPageSize {
+			break
+		}
 	}
 
-	if len(messages) > 0 {
-		p.logger.Infof("🆕 Найдено %d новых сообщений в канале %s", len(messages), channelDisplay)
+	if found > 0 {
+		p.logger.Infof("🆕 Найдено %d новых сообщений в канале %s", found, channelDisplay)
+	}
+	if lastErr == nil {
+		p.setStalled(rule.ID, false)
 	}
+	p.markChecked(ctx, rule, lastErr)
+	return nil
+}
 
-	for _, msg := range messages {
+// advanceCursor сдвигает курсор канала правила. Ошибка только логируется:
+// в худшем случае сообщения прочитаются повторно и будут пропущены как
+// уже обработанные.
+func (p *TelegramParser) advanceCursor(ctx context.Context, rule *models.ParsingRule, messageID int64, pts int) {
+	if err := p.cursorRepo.Advance(ctx, rule.ID, rule.SourceChannel, messageID, pts); err != nil {
+		p.logger.Errorf("❌ %v", err)
+	}
+}
+
+// markChecked записывает время и результат проверки канала правила
+func (p *TelegramParser) markChecked(ctx context.Context, rule *models.ParsingRule, checkErr error) {
+	lastError := ""
+	if checkErr != nil {
+		lastError = checkErr.Error()
+	}
+	if err := p.cursorRepo.MarkChecked(ctx, rule.ID, rule.SourceChannel, lastError); err != nil {
+		p.logger.Errorf("❌ %v", err)
+	}
+}
+
+// handleUpdate обрабатывает сообщение, пришедшее через поток обновлений.
+// Части альбомов сначала собираются вместе, правки обрабатываются сразу.
+func (p *TelegramParser) handleUpdate(ctx context.Context, msg *ParsedMessage) {
+	if msg.IsEdited {
+		p.handleEdit(ctx, msg)
+		return
+	}
+	p.albums.Add(msg)
+}
+
+// dispatchUpdate передает сообщение из потока обновлений правилам канала
+func (p *TelegramParser) dispatchUpdate(msg *ParsedMessage) {
+	p.mu.Lock()
+	rules := append([]*models.ParsingRule(nil), p.streamRules[msg.ChannelID]...)
+	ctx := p.runCtx
+	p.mu.Unlock()
+
+	for _, rule := range rules {
 		if err := p.processMessage(ctx, rule, msg); err != nil {
-			p.logger.Errorf("❌ Ошибка обработки нового сообщения: %v", err)
-		} else {
-			// Обновляем последний ID сообщения
-			if msg.ID > p.lastMessageIDs[rule.SourceChannel] {
-				p.lastMessageIDs[rule.SourceChannel] = msg.ID
+			if ctx.Err() != nil {
+				return
+			}
+			// Курсор остается перед сообщением, его повторит опрос истории
+			p.logger.Errorf("❌ Ошибка обработки сообщения %d из обновления, повтор через %v: %v", msg.ID, streamRetryDelay, err)
+			p.setStalled(rule.ID, true)
+			p.markChecked(ctx, rule, err)
+			ruleID := rule.ID
+			time.AfterFunc(streamRetryDelay, func() { p.checkNow(ruleID) })
+			continue
+		}
+
+		// Пока сообщение с ошибкой не обработано, курсор не сдвигается
+		// дальше него: иначе опрос истории его пропустит
+		if !p.isStalled(rule.ID) {
+			p.advanceCursor(ctx, rule, msg.MaxID(), msg.Pts)
+			if p.streamCheckDue(rule.ID) {
+				p.markChecked(ctx, rule, nil)
 			}
 		}
 	}
+}
 
-	return nil
+// streamCheckDue проверяет, пора ли отметить успешное чтение правила из
+// потока обновлений, и запоминает время отметки
+func (p *TelegramParser) streamCheckDue(ruleID int64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := time.Now()
+	if now.Sub(p.checkedAt[ruleID]) < streamCheckInterval {
+		return false
+	}
+	p.checkedAt[ruleID] = now
+	return true
+}
+
+// retryMedia учитывает неудачную загрузку медиа сообщения без текста и
+// проверяет, остались ли попытки
+func (p *TelegramParser) retryMedia(ruleID, messageID int64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	key := mediaKey{ruleID: ruleID, messageID: messageID}
+	p.mediaFails[key]++
+	return p.mediaFails[key] < mediaDownloadAttempts
+}
+
+// forgetMedia сбрасывает счетчик неудачных загрузок медиа сообщения
+func (p *TelegramParser) forgetMedia(ruleID, messageID int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	delete(p.mediaFails, mediaKey{ruleID: ruleID, messageID: messageID})
+}
+
+// setStalled отмечает, что у правила есть сообщение из потока, которое
+// не удалось обработать
+func (p *TelegramParser) setStalled(ruleID int64, stalled bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if stalled {
+		p.stalled[ruleID] = true
+	} else {
+		delete(p.stalled, ruleID)
+	}
+}
+
+// isStalled проверяет, ждет ли правило повтора сообщения из потока
+func (p *TelegramParser) isStalled(ruleID int64) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.stalled[ruleID]
+}
+
+// handleGap догружает сообщения опросом истории, если поток обновлений
+// канала не удалось восстановить
+func (p *TelegramParser) handleGap(channel string) {
+	p.mu.Lock()
+	var rules []*models.ParsingRule
+	for _, channelRules := range p.streamRules {
+		for _, rule := range channelRules {
+			if p.normalizeChannel(rule.SourceChannel) == channel {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	ctx := p.runCtx
+	p.mu.Unlock()
+
+	for _, rule := range rules {
+		p.wg.Add(1)
+		go func(rule *models.ParsingRule) {
+			defer p.wg.Done()
+			if err := p.checkNewMessages(ctx, rule); err != nil {
+				p.logger.Errorf("❌ Ошибка догрузки сообщений канала %s: %v", channel, err)
+			}
+		}(rule)
+	}
+}
+
+// addStreamRule регистрирует правило для обработки обновлений канала
+func (p *TelegramParser) addStreamRule(channelID int64, rule *models.ParsingRule) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.streamRules[channelID] = append(p.streamRules[channelID], rule)
+}
+
+// removeStreamRule снимает правило с обработки обновлений канала
+func (p *TelegramParser) removeStreamRule(channelID int64, rule *models.ParsingRule) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.checkedAt, rule.ID)
+
+	rules := p.streamRules[channelID]
+	for i, r := range rules {
+		if r == rule {
+			rules = append(rules[:i], rules[i+1:]...)
+			break
+		}
+	}
+
+	if len(rules) == 0 {
+		delete(p.streamRules, channelID)
+		p.pool.UnwatchChannel(channelID)
+		return
+	}
+	p.streamRules[channelID] = rules
 }
 
-// processMessage обрабатывает сообщение (остается без изменений)
+// processMessage обрабатывает новое сообщение канала и учитывает его
+// в счетчиках канала правила
 func (p *TelegramParser) processMessage(ctx context.Context, rule *models.ParsingRule, msg *ParsedMessage) error {
+	stored, err := p.storeMessage(ctx, rule, msg, models.BackfillPublish)
+	if err != nil {
+		// Сообщение будет повторено и учтется, когда его обработают
+		return err
+	}
+
+	created := 0
+	if stored {
+		created = 1
+	}
+	if countErr := p.cursorRepo.Count(ctx, rule.ID, rule.SourceChannel, 1, created); countErr != nil {
+		p.logger.Errorf("❌ %v", countErr)
+	}
+	return nil
+}
+
+// storeMessage применяет к сообщению фильтры правила и сохраняет пост.
+// mode определяет, публикуется ли пост: новые сообщения обрабатываются
+// в режиме publish, импорт истории может отправить их на модерацию или
+// только сохранить. Возвращает true, если пост сохранен.
+func (p *TelegramParser) storeMessage(ctx context.Context, rule *models.ParsingRule, msg *ParsedMessage, mode models.BackfillMode) (bool, error) {
 	// Проверяем, не обрабатывали ли мы уже это сообщение
 	existingPost, err := p.postRepo.GetByMessageID(ctx, rule.SourceChannel, msg.ID)
 	if err != nil {
-		return fmt.Errorf("ошибка проверки существующего поста: %v", err)
+		return false, fmt.Errorf("ошибка проверки существующего поста: %v", err)
 	}
 
 	if existingPost != nil {
 		p.logger.Debugf("⚠️ Сообщение %d уже обработано", msg.ID)
-		return nil
+		return false, nil
+	}
+
+	// Часть альбома могла прийти позже остальных - альбом уже сохранен
+	if msg.GroupedID != 0 {
+		albumPost, err := p.postRepo.GetByGroupedID(ctx, rule.SourceChannel, msg.GroupedID)
+		if err != nil {
+			return false, fmt.Errorf("ошибка проверки существующего альбома: %v", err)
+		}
+		if albumPost != nil {
+			p.logger.Debugf("⚠️ Альбом %d уже обработан", msg.GroupedID)
+			return false, nil
+		}
 	}
 
 	// Применяем фильтры правила
 	if !p.applyFilters(rule, msg) {
 		p.logger.Debugf("🚫 Сообщение %d не прошло фильтры", msg.ID)
-		return nil
+		return false, nil
 	}
 
-	// Применяем трансформации
-	transformedContent := rule.ApplyTransformations(msg.Content)
+	// Применяем трансформации, сдвигая форматирование вслед за текстом
+	transformedContent, entities := rule.ApplyTransformationsWithEntities(msg.Content, msg.Entities)
 
 	// Создаем пост
 	post := models.NewPost(rule.ID, msg.ID, rule.SourceChannel, transformedContent, msg.MediaType)
+	post.Entities = entities
 	post.MediaURL = msg.MediaURL
 	post.PostedAt = msg.Date
-	// По умолчанию оба статуса false (уже установлено в NewPost)
-	post.PublishedTelegram = false
-	post.PublishedVK = false
+	post.GroupedID = msg.GroupedID
+
+	// Скачиваем медиа только для сообщений, прошедших фильтры.
+	// Архивным постам файлы не нужны - они не публикуются.
+	if p.mediaCache != nil && mode != models.BackfillArchive {
+		// Слишком большой файл не загрузится и при повторе, ошибки
+		// загрузки запоминаются, чтобы повторять только временные
+		var downloadErr error
+		for _, part := range msg.MediaParts() {
+			path, err := p.pool.DownloadMedia(ctx, part.Media, p.mediaCache)
+			if err != nil {
+				p.logger.Warnf("⚠️ Не удалось загрузить медиа сообщения %d: %v", part.ID, err)
+				if !errors.Is(err, media.ErrTooLarge) {
+					downloadErr = err
+				}
+				continue
+			}
+			post.Media = append(post.Media, models.PostMedia{
+				MediaType: part.MediaType,
+				MediaFile: path,
+			})
+		}
+
+		if len(post.Media) > 0 {
+			post.MediaFile = post.Media[0].MediaFile
+			post.MediaType = post.Media[0].MediaType
+		} else if len(msg.MediaParts()) > 0 {
+			// Без текста публиковать нечего - при временной ошибке вернем
+			// ошибку, чтобы повторить позже, иначе пропустим сообщение
+			if transformedContent == "" {
+				if downloadErr != nil && p.retryMedia(rule.ID, msg.ID) {
+					return false, fmt.Errorf("ошибка загрузки медиа сообщения %d: %v", msg.ID, downloadErr)
+				}
+				p.logger.Warnf("⏭️ Медиа сообщения %d без текста не загружено, сообщение пропущено", msg.ID)
+				p.forgetMedia(rule.ID, msg.ID)
+				return false, nil
+			}
+			p.logger.Warnf("⚠️ Медиа сообщения %d не загружено, публикуем только текст", msg.ID)
+		}
+		p.forgetMedia(rule.ID, msg.ID)
+	}
 
 	if err := post.Validate(); err != nil {
-		return fmt.Errorf("ошибка валидации поста: %v", err)
+		return false, fmt.Errorf("ошибка валидации поста: %v", err)
 	}
 
-	// Сохраняем в БД
-	if err := p.postRepo.Create(ctx, post); err != nil {
-		return fmt.Errorf("ошибка сохранения поста: %v", err)
+	// Дубликат поста из другого канала сохраняем со ссылкой на оригинал, но не публикуем
+	platforms := rule.TargetPlatforms
+	fingerprint(post, msg.Content)
+	if originalID, ok := p.findDuplicate(ctx, post); ok {
+		post.DuplicateOf = &originalID
+		platforms = nil
+	} else if mode == models.BackfillArchive {
+		// Архивный пост участвует в поиске дубликатов, но не публикуется
+		post.Archived = true
+		platforms = nil
+	} else if rule.RequiresApproval || mode == models.BackfillQueue {
+		// Пост ждет решения редактора, задачи публикации создаются при одобрении
+		post.ReviewStatus = models.ReviewPending
+		platforms = nil
+	} else {
+		// У правила с расписанием пост ждет планировщика: окна, интервал и лимиты
+		post.ScheduledAt = rule.InitialSchedule(time.Now())
 	}
 
-	p.logger.Infof("💾 Сообщение %d сохранено как пост ID %d", msg.ID, post.ID)
+	// Сохраняем в БД и ставим в очередь публикации одной транзакцией,
+	// публикуют воркеры очереди
+	if err := p.postRepo.CreateWithJobs(ctx, post, platforms); err != nil {
+		return false, fmt.Errorf("ошибка сохранения поста: %v", err)
+	}
 
-	// Публикуем пост
-	p.logger.Infof("📤 Начинаем публикацию поста ID %d", post.ID)
-	if err := p.multiPublisher.Publish(ctx, post, rule); err != nil {
-		p.logger.Errorf("❌ Ошибка публикации поста %d: %v", post.ID, err)
-		return fmt.Errorf("ошибка публикации: %v", err)
+	if post.DuplicateOf != nil {
+		p.logger.Infof("🔁 Сообщение %d сохранено как пост ID %d - дубликат поста %d, публикация пропущена",
+			msg.ID, post.ID, *post.DuplicateOf)
+		return true, nil
 	}
 
-	p.logger.Infof("✅ Пост ID %d успешно опубликован", post.ID)
-	return nil
+	if post.Archived {
+		p.logger.Infof("🗄️ Сообщение %d сохранено в архив как пост ID %d", msg.ID, post.ID)
+		return true, nil
+	}
+
+	if post.IsPendingReview() {
+		p.logger.Infof("📝 Сообщение %d сохранено как пост ID %d и ожидает модерации", msg.ID, post.ID)
+		// Пост уже сохранен: без уведомления его можно одобрить в веб-интерфейсе
+		if err := p.multiPublisher.RequestReview(ctx, post, rule); err != nil {
+			p.logger.Warnf("⚠️ %v", err)
+		}
+		return true, nil
+	}
+
+	if post.ScheduledAt != nil {
+		p.logger.Infof("🕒 Сообщение %d сохранено как пост ID %d, публикация не раньше %s",
+			msg.ID, post.ID, post.ScheduledAt.Format(time.RFC3339))
+		return true, nil
+	}
+
+	p.logger.Infof("💾 Сообщение %d сохранено как пост ID %d и поставлено в очередь публикации", msg.ID, post.ID)
+	return true, nil
 }
 
 // applyFilters применяет фильтры правила к сообщению (остается без изменений)
@@ -280,17 +764,23 @@ func (p *TelegramParser) applyFilters(rule *models.ParsingRule, msg *ParsedMessa
 		return false
 	}
 
+	// Проверка выражения фильтра
+	if !rule.MatchesFilter(msg.filterMessage()) {
+		return false
+	}
+
 	return true
 }
 
-// normalizeChannel нормализует формат канала (остается без изменений)
+// normalizeChannel приводит канал к каноническому виду: @username,
+// +hash приглашения или -100<id>. Неразобранный канал возвращается как есть,
+// ошибка появится при поиске канала.
 func (p *TelegramParser) normalizeChannel(channel string) string {
-	channel = strings.TrimSpace(channel)
-	channel = strings.TrimPrefix(channel, "https://")
-	channel = strings.TrimPrefix(channel, "http://")
-	channel = strings.TrimPrefix(channel, "t.me/")
-	channel = strings.TrimPrefix(channel, "@")
-	return "@" + channel
+	ref, err := models.ParseChannelRef(channel)
+	if err != nil {
+		return strings.TrimSpace(channel)
+	}
+	return ref.String()
 }
 
 // getChannelDisplayName возвращает отображаемое имя канала (остается без изменений)
@@ -314,7 +804,62 @@ type ParsedMessage struct {
 	ID            int64
 	SourceChannel string
 	Content       string
+	Entities      []models.TextEntity // Форматирование текста
 	MediaType     models.MediaType
 	MediaURL      string
+	Media         *MediaRef // Файл медиа для загрузки через MTProto
 	Date          time.Time
+	ChannelID     int64            // ID канала в Telegram
+	IsEdited      bool             // Сообщение пришло как редактирование
+	Pts           int              // pts обновления канала, 0 - сообщение получено не из потока
+	GroupedID     int64            // ID альбома, 0 - сообщение не из альбома
+	Parts         []*ParsedMessage // Части альбома по порядку, если сообщение собрано из альбома
+	HasLink       bool             // В тексте есть ссылки
+	ForwardedFrom []string         // Источники пересылки, пусто - сообщение не переслано
+}
+
+// filterMessage возвращает данные сообщения для выражения фильтра правила
+func (m *ParsedMessage) filterMessage() *filter.Message {
+	msg := &filter.Message{
+		Text:          m.Content,
+		HasLink:       m.HasLink,
+		ForwardedFrom: m.ForwardedFrom,
+	}
+
+	parts := m.Parts
+	if len(parts) == 0 {
+		parts = []*ParsedMessage{m}
+	}
+	for _, part := range parts {
+		msg.MediaTypes = append(msg.MediaTypes, string(part.MediaType))
+		msg.HasLink = msg.HasLink || part.HasLink
+	}
+	return msg
+}
+
+// MediaParts возвращает части сообщения с медиа: части альбома или само сообщение
+func (m *ParsedMessage) MediaParts() []*ParsedMessage {
+	parts := m.Parts
+	if len(parts) == 0 {
+		parts = []*ParsedMessage{m}
+	}
+
+	var result []*ParsedMessage
+	for _, part := range parts {
+		if part.Media != nil {
+			result = append(result, part)
+		}
+	}
+	return result
+}
+
+// MaxID возвращает наибольший ID среди частей сообщения
+func (m *ParsedMessage) MaxID() int64 {
+	maxID := m.ID
+	for _, part := range m.Parts {
+		if part.ID > maxID {
+			maxID = part.ID
+		}
+	}
+	return maxID
 }
@@ -0,0 +1,176 @@
+package publisher
+
+import (
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+// FormatPost оформляет пост шаблоном для платформы. Текст поста с форматированием
+// передается в шаблон как .Content и выводится для Telegram в HTML, для остальных
+// платформ простым текстом. HTML строится после обрезки текста в шаблоне.
+func FormatPost(post *models.Post, rule *models.ParsingRule, platform models.PlatformType, tmpl string) (string, error) {
+	render := renderPlain
+	if platform == models.PlatformTelegram {
+		render = renderHTML
+	}
+
+	content := models.NewPostContent(post.Content, post.Entities, render)
+	return models.RenderPostTemplate(tmpl, models.NewPostTemplateData(post, rule, content))
+}
+
+// renderHTML преобразует текст с сущностями в HTML для Telegram Bot API.
+// Пересекающиеся сущности разбиваются так, чтобы теги были правильно вложены.
+func renderHTML(text string, entities []models.TextEntity) string {
+	units := utf16.Encode([]rune(text))
+	entities = validEntities(entities, len(units))
+	if len(entities) == 0 {
+		return html.EscapeString(text)
+	}
+
+	// Границы фрагментов, внутри которых набор сущностей не меняется
+	bounds := []int{0, len(units)}
+	for _, e := range entities {
+		bounds = append(bounds, e.Offset, e.End())
+	}
+	sort.Ints(bounds)
+
+	var b strings.Builder
+	var open []int
+	for i := 0; i+1 < len(bounds); i++ {
+		start, end := bounds[i], bounds[i+1]
+		if start == end {
+			continue
+		}
+
+		var active []int
+		for j, e := range entities {
+			if e.Offset <= start && e.End() >= end {
+				active = append(active, j)
+			}
+		}
+
+		// Закрываем теги, которые не совпадают с новым набором, и открываем недостающие
+		common := 0
+		for common < len(open) && common < len(active) && open[common] == active[common] {
+			common++
+		}
+		for j := len(open) - 1; j >= common; j-- {
+			b.WriteString(closeTag(entities[open[j]]))
+		}
+		for _, j := range active[common:] {
+			b.WriteString(openTag(entities[j]))
+		}
+		open = active
+
+		b.WriteString(html.EscapeString(string(utf16.Decode(units[start:end]))))
+	}
+	for j := len(open) - 1; j >= 0; j-- {
+		b.WriteString(closeTag(entities[open[j]]))
+	}
+
+	return b.String()
+}
+
+// renderPlain преобразует текст с сущностями в простой текст для платформ
+// без разметки: форматирование убирается, адреса скрытых ссылок
+// добавляются после текста ссылки.
+func renderPlain(text string, entities []models.TextEntity) string {
+	units := utf16.Encode([]rune(text))
+	entities = validEntities(entities, len(units))
+
+	var b strings.Builder
+	pos := 0
+	for _, e := range entities {
+		if e.Type != models.EntityTextLink || e.End() < pos {
+			continue
+		}
+		linkText := string(utf16.Decode(units[e.Offset:e.End()]))
+		if strings.TrimSpace(linkText) == e.URL {
+			continue
+		}
+
+		b.WriteString(string(utf16.Decode(units[pos:e.End()])))
+		b.WriteString(" (" + e.URL + ")")
+		pos = e.End()
+	}
+	b.WriteString(string(utf16.Decode(units[pos:])))
+
+	return b.String()
+}
+
+// validEntities отбрасывает сущности за пределами текста и сортирует остальные
+func validEntities(entities []models.TextEntity, textLen int) []models.TextEntity {
+	var result []models.TextEntity
+	for _, e := range models.SortEntities(entities) {
+		if e.Offset < 0 || e.Length <= 0 || e.End() > textLen {
+			continue
+		}
+		result = append(result, e)
+	}
+	return result
+}
+
+// openTag возвращает открывающий HTML тег сущности
+func openTag(e models.TextEntity) string {
+	switch e.Type {
+	case models.EntityBold:
+		return "<b>"
+	case models.EntityItalic:
+		return "<i>"
+	case models.EntityUnderline:
+		return "<u>"
+	case models.EntityStrike:
+		return "<s>"
+	case models.EntitySpoiler:
+		return "<tg-spoiler>"
+	case models.EntityCode:
+		return "<code>"
+	case models.EntityPre:
+		if e.Language != "" {
+			return fmt.Sprintf(`<pre><code class="language-%s">`, html.EscapeString(e.Language))
+		}
+		return "<pre>"
+	case models.EntityBlockquote:
+		return "<blockquote>"
+	case models.EntityTextLink:
+		return fmt.Sprintf(`<a href="%s">`, html.EscapeString(e.URL))
+	case models.EntityMentionName:
+		return fmt.Sprintf(`<a href="tg://user?id=%d">`, e.UserID)
+	default:
+		return ""
+	}
+}
+
+// closeTag возвращает закрывающий HTML тег сущности
+func closeTag(e models.TextEntity) string {
+	switch e.Type {
+	case models.EntityBold:
+		return "</b>"
+	case models.EntityItalic:
+		return "</i>"
+	case models.EntityUnderline:
+		return "</u>"
+	case models.EntityStrike:
+		return "</s>"
+	case models.EntitySpoiler:
+		return "</tg-spoiler>"
+	case models.EntityCode:
+		return "</code>"
+	case models.EntityPre:
+		if e.Language != "" {
+			return "</code></pre>"
+		}
+		return "</pre>"
+	case models.EntityBlockquote:
+		return "</blockquote>"
+	case models.EntityTextLink, models.EntityMentionName:
+		return "</a>"
+	default:
+		return ""
+	}
+}
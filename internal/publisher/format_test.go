@@ -0,0 +1,244 @@
+package publisher
+
+import (
+	"testing"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+func entity(typ models.EntityType, offset, length int) models.TextEntity {
+	return models.TextEntity{Type: typ, Offset: offset, Length: length}
+}
+
+func textLink(offset, length int, url string) models.TextEntity {
+	return models.TextEntity{Type: models.EntityTextLink, Offset: offset, Length: length, URL: url}
+}
+
+func TestRenderHTML(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		entities []models.TextEntity
+		want     string
+	}{
+		{
+			name: "экранирование без сущностей",
+			text: `a < b & "c"`,
+			want: "a &lt; b &amp; &#34;c&#34;",
+		},
+		{
+			name:     "одна сущность",
+			text:     "Hello world",
+			entities: []models.TextEntity{entity(models.EntityBold, 0, 5)},
+			want:     "<b>Hello</b> world",
+		},
+		{
+			name: "вложенные сущности",
+			text: "Hello world",
+			entities: []models.TextEntity{
+				entity(models.EntityItalic, 6, 5),
+				entity(models.EntityBold, 0, 11),
+			},
+			want: "<b>Hello <i>world</i></b>",
+		},
+		{
+			name: "сущности с общим началом",
+			text: "Hello world",
+			entities: []models.TextEntity{
+				entity(models.EntityItalic, 0, 5),
+				entity(models.EntityBold, 0, 11),
+			},
+			want: "<b><i>Hello</i> world</b>",
+		},
+		{
+			name: "пересекающиеся сущности",
+			text: "Hello world",
+			entities: []models.TextEntity{
+				entity(models.EntityBold, 0, 7),
+				entity(models.EntityItalic, 6, 5),
+			},
+			want: "<b>Hello <i>w</i></b><i>orld</i>",
+		},
+		{
+			name:     "смещения UTF-16 после эмодзи",
+			text:     "😀 bold 👍 end",
+			entities: []models.TextEntity{entity(models.EntityBold, 3, 4), entity(models.EntityItalic, 8, 2)},
+			want:     "😀 <b>bold</b> <i>👍</i> end",
+		},
+		{
+			name:     "текст внутри сущности экранируется",
+			text:     "x<y>",
+			entities: []models.TextEntity{entity(models.EntityCode, 0, 4)},
+			want:     "<code>x&lt;y&gt;</code>",
+		},
+		{
+			name:     "скрытая ссылка",
+			text:     "read more",
+			entities: []models.TextEntity{textLink(5, 4, "https://e.com/?a=1&b=2")},
+			want:     `read <a href="https://e.com/?a=1&amp;b=2">more</a>`,
+		},
+		{
+			name:     "блок кода с языком",
+			text:     "fmt.Println()",
+			entities: []models.TextEntity{{Type: models.EntityPre, Offset: 0, Length: 13, Language: "go"}},
+			want:     `<pre><code class="language-go">fmt.Println()</code></pre>`,
+		},
+		{
+			name:     "упоминание пользователя",
+			text:     "Иван",
+			entities: []models.TextEntity{{Type: models.EntityMentionName, Offset: 0, Length: 4, UserID: 42}},
+			want:     `<a href="tg://user?id=42">Иван</a>`,
+		},
+		{
+			name: "сущности за пределами текста отбрасываются",
+			text: "short",
+			entities: []models.TextEntity{
+				entity(models.EntityBold, 2, 10),
+				entity(models.EntityItalic, -1, 2),
+				entity(models.EntityUnderline, 0, 0),
+			},
+			want: "short",
+		},
+		{
+			name:     "неизвестная сущность выводит текст",
+			text:     "#news",
+			entities: []models.TextEntity{{Type: "custom_emoji", Offset: 0, Length: 5}},
+			want:     "#news",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderHTML(tt.text, tt.entities); got != tt.want {
+				t.Errorf("renderHTML() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderPlain(t *testing.T) {
+	tests := []struct {
+		name     string
+		text     string
+		entities []models.TextEntity
+		want     string
+	}{
+		{
+			name: "без сущностей",
+			text: "a < b",
+			want: "a < b",
+		},
+		{
+			name:     "форматирование убирается",
+			text:     "Hello world",
+			entities: []models.TextEntity{entity(models.EntityBold, 0, 5), entity(models.EntityItalic, 6, 5)},
+			want:     "Hello world",
+		},
+		{
+			name:     "адрес скрытой ссылки после текста",
+			text:     "Читать тут, дальше",
+			entities: []models.TextEntity{textLink(7, 3, "https://e.com")},
+			want:     "Читать тут (https://e.com), дальше",
+		},
+		{
+			name:     "ссылка с текстом-адресом не дублируется",
+			text:     "see https://e.com",
+			entities: []models.TextEntity{textLink(4, 13, "https://e.com")},
+			want:     "see https://e.com",
+		},
+		{
+			name: "несколько ссылок после эмодзи",
+			text: "🔥 one 👍 two",
+			entities: []models.TextEntity{
+				textLink(3, 3, "https://a.com"),
+				textLink(10, 3, "https://b.com"),
+			},
+			want: "🔥 one (https://a.com) 👍 two (https://b.com)",
+		},
+		{
+			name: "ссылка внутри форматирования",
+			text: "bold link",
+			entities: []models.TextEntity{
+				entity(models.EntityBold, 0, 9),
+				textLink(5, 4, "https://e.com"),
+			},
+			want: "bold link (https://e.com)",
+		},
+		{
+			name:     "ссылка за пределами текста отбрасывается",
+			text:     "short",
+			entities: []models.TextEntity{textLink(3, 10, "https://e.com")},
+			want:     "short",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := renderPlain(tt.text, tt.entities); got != tt.want {
+				t.Errorf("renderPlain() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatPost(t *testing.T) {
+	post := &models.Post{
+		SourceChannel: "@news",
+		Content:       "Первое слово и очень длинное продолжение текста",
+		Entities: []models.TextEntity{
+			entity(models.EntityBold, 0, 12),
+			textLink(15, 5, "https://e.com"),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		platform models.PlatformType
+		tmpl     string
+		want     string
+	}{
+		{
+			name:     "Telegram по умолчанию",
+			platform: models.PlatformTelegram,
+			tmpl:     models.DefaultPostTemplate(models.PlatformTelegram),
+			want:     "<b>Первое слово</b> и <a href=\"https://e.com\">очень</a> длинное продолжение текста\n\n📎 <i>Источник: @news</i>",
+		},
+		{
+			name:     "VK по умолчанию",
+			platform: models.PlatformVK,
+			tmpl:     models.DefaultPostTemplate(models.PlatformVK),
+			want:     "Первое слово и очень (https://e.com) длинное продолжение текста\n\n📎 Источник: @news",
+		},
+		{
+			// Обрезка внутри сущности не разрезает теги
+			name:     "Telegram с обрезкой внутри ссылки",
+			platform: models.PlatformTelegram,
+			tmpl:     "{{.Content | truncate 19}}",
+			want:     "<b>Первое слово</b> и…",
+		},
+		{
+			name:     "Telegram с обрезкой внутри жирного",
+			platform: models.PlatformTelegram,
+			tmpl:     "{{.Content | truncate 10}}",
+			want:     "<b>Первое</b>…",
+		},
+		{
+			name:     "VK с обрезкой после ссылки",
+			platform: models.PlatformVK,
+			tmpl:     "{{.Content | truncate 25}}",
+			want:     "Первое слово и очень (https://e.com)…",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FormatPost(post, nil, tt.platform, tt.tmpl)
+			if err != nil {
+				t.Fatalf("FormatPost: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("FormatPost() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
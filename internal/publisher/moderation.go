@@ -0,0 +1,502 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"go.uber.org/zap"
+)
+
+// ReviewNotifier публикатор, который умеет присылать посты на модерацию
+type ReviewNotifier interface {
+	// SendForReview отправляет пост редакторам с кнопками решения
+	SendForReview(ctx context.Context, post *models.Post, rule *models.ParsingRule) error
+}
+
+var _ ReviewNotifier = (*TelegramPublisher)(nil)
+
+// Действия кнопок карточки модерации. Данные кнопки: "rv:<действие>:<ID поста>"
+const (
+	reviewCallbackPrefix = "rv"
+
+	reviewApprove = "approve"
+	reviewReject  = "reject"
+	reviewEdit    = "edit"
+	reviewOnlyTG  = "tg"
+	reviewOnlyVK  = "vk"
+)
+
+// maxReviewCardText максимальная длина текста поста в карточке модерации,
+// лимит сообщения Telegram - 4096 символов вместе с заголовком
+const maxReviewCardText = 3500
+
+// editPromptRe находит ID поста в сообщении бота с просьбой прислать новый текст
+var editPromptRe = regexp.MustCompile(`#(\d+)`)
+
+// RequestReview отправляет пост на модерацию через публикаторы, которые это умеют.
+// Без настроенного чата редакторов ничего не делает.
+func (p *MultiPublisher) RequestReview(ctx context.Context, post *models.Post, rule *models.ParsingRule) error {
+	var failed []string
+	for _, pub := range p.registry.All() {
+		notifier, ok := pub.(ReviewNotifier)
+		if !ok {
+			continue
+		}
+		if err := notifier.SendForReview(ctx, post, rule); err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", pub.Name(), err))
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("ошибка отправки на модерацию: %s", strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// SendForReview отправляет карточку поста в чат редакторов
+func (p *TelegramPublisher) SendForReview(ctx context.Context, post *models.Post, rule *models.ParsingRule) error {
+	if p.cfg.ModeratorsChat == "" {
+		return nil
+	}
+	chat, err := parseTelegramChat(p.cfg.ModeratorsChat)
+	if err != nil {
+		return err
+	}
+
+	msg := tgbotapi.NewMessage(0, reviewCardText(post, rule, ""))
+	chat.apply(&msg.BaseChat)
+	msg.ParseMode = "HTML"
+	msg.DisableWebPagePreview = true
+	msg.ReplyMarkup = reviewKeyboard(post, rule)
+
+	var sent tgbotapi.Message
+	err = p.send(ctx, chat, func() error {
+		var err error
+		sent, err = p.bot.Send(msg)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ошибка отправки поста %d в чат редакторов: %w", post.ID, err)
+	}
+
+	p.logger.Infof("📝 Пост %d отправлен на модерацию в %s (сообщение %d)", post.ID, chat, sent.MessageID)
+	return nil
+}
+
+// reviewCardText собирает текст карточки модерации. outcome - строка с итогом
+// решения, пусто - пост еще ждет решения.
+func reviewCardText(post *models.Post, rule *models.ParsingRule, outcome string) string {
+	var sb strings.Builder
+
+	fmt.Fprintf(&sb, "📝 <b>Пост #%d</b>\n", post.ID)
+	fmt.Fprintf(&sb, "Источник: %s", html.EscapeString(post.SourceChannel))
+	if rule != nil {
+		fmt.Fprintf(&sb, " · правило «%s»", html.EscapeString(rule.Name))
+	}
+	if link := post.MessageLink(); link != "" {
+		fmt.Fprintf(&sb, " · <a href=\"%s\">оригинал</a>", html.EscapeString(link))
+	}
+	if post.HasMedia() {
+		fmt.Fprintf(&sb, "\nМедиа: %s", post.MediaType)
+		if post.IsAlbum() {
+			fmt.Fprintf(&sb, " (%d файлов)", len(post.Media))
+		}
+	}
+
+	content := post.Content
+	if utf8.RuneCountInString(content) > maxReviewCardText {
+		content = string([]rune(content)[:maxReviewCardText]) + "…"
+	}
+	sb.WriteString("\n\n")
+	sb.WriteString(html.EscapeString(content))
+
+	if outcome != "" {
+		sb.WriteString("\n\n")
+		sb.WriteString(outcome)
+	}
+	return sb.String()
+}
+
+// reviewKeyboard кнопки решения по посту. Кнопки публикации на одну платформу
+// показываются, только если правило публикует на несколько платформ.
+func reviewKeyboard(post *models.Post, rule *models.ParsingRule) tgbotapi.InlineKeyboardMarkup {
+	data := func(action string) string {
+		return fmt.Sprintf("%s:%s:%d", reviewCallbackPrefix, action, post.ID)
+	}
+
+	rows := [][]tgbotapi.InlineKeyboardButton{
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✅ Одобрить", data(reviewApprove)),
+			tgbotapi.NewInlineKeyboardButtonData("❌ Отклонить", data(reviewReject)),
+		),
+		tgbotapi.NewInlineKeyboardRow(
+			tgbotapi.NewInlineKeyboardButtonData("✏️ Изменить", data(reviewEdit)),
+		),
+	}
+
+	if rule != nil && len(rule.TargetPlatforms) > 1 {
+		var row []tgbotapi.InlineKeyboardButton
+		for _, platform := range rule.TargetPlatforms {
+			switch platform {
+			case models.PlatformTelegram:
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData("📣 Только в Telegram", data(reviewOnlyTG)))
+			case models.PlatformVK:
+				row = append(row, tgbotapi.NewInlineKeyboardButtonData("📣 Только в VK", data(reviewOnlyVK)))
+			}
+		}
+		if len(row) > 0 {
+			rows = append(rows, row)
+		}
+	}
+
+	return tgbotapi.NewInlineKeyboardMarkup(rows...)
+}
+
+// reviewOutcome строка итога решения по посту для карточки
+func reviewOutcome(post *models.Post, platforms []models.PlatformType) string {
+	when := ""
+	if post.ReviewedAt != nil {
+		when = ", " + post.ReviewedAt.Local().Format("02.01 15:04")
+	}
+	by := html.EscapeString(post.ReviewedBy)
+
+	switch post.ReviewStatus {
+	case models.ReviewApproved:
+		outcome := fmt.Sprintf("✅ <b>Одобрен</b>: %s%s", by, when)
+		if len(platforms) > 0 {
+			names := make([]string, len(platforms))
+			for i, platform := range platforms {
+				names[i] = string(platform)
+			}
+			outcome = fmt.Sprintf("✅ <b>Одобрен</b> (%s): %s%s", strings.Join(names, ", "), by, when)
+		}
+		if post.ScheduledAt != nil && post.ReleasedAt == nil {
+			outcome += fmt.Sprintf("\n🕒 Публикация не раньше %s", post.ScheduledAt.Local().Format("02.01 15:04"))
+		}
+		return outcome
+	case models.ReviewRejected:
+		return fmt.Sprintf("❌ <b>Отклонен</b>: %s%s", by, when)
+	default:
+		return ""
+	}
+}
+
+// parseReviewCallback разбирает данные кнопки карточки модерации
+func parseReviewCallback(data string) (string, int64, bool) {
+	parts := strings.Split(data, ":")
+	if len(parts) != 3 || parts[0] != reviewCallbackPrefix {
+		return "", 0, false
+	}
+	postID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", 0, false
+	}
+	return parts[1], postID, true
+}
+
+// telegramReviewer имя редактора для журнала модерации
+func telegramReviewer(user *tgbotapi.User) string {
+	if user == nil {
+		return "telegram"
+	}
+	if user.UserName != "" {
+		return "@" + user.UserName + " (telegram)"
+	}
+	name := strings.TrimSpace(user.FirstName + " " + user.LastName)
+	if name == "" {
+		name = strconv.FormatInt(user.ID, 10)
+	}
+	return name + " (telegram)"
+}
+
+// ModerationBot обрабатывает решения редакторов в чате модерации:
+// нажатия кнопок карточек и ответы с новым текстом поста
+type ModerationBot struct {
+	tg         *TelegramPublisher
+	chat       telegramChat
+	postRepo   *storage.PostRepository
+	ruleRepo   *storage.RuleRepository
+	reviewRepo *storage.ReviewRepository
+	logger     *zap.SugaredLogger
+}
+
+// NewModerationBot создает обработчик чата модерации. Чат берется из
+// конфигурации Telegram публикатора.
+func NewModerationBot(
+	tg *TelegramPublisher,
+	postRepo *storage.PostRepository,
+	ruleRepo *storage.RuleRepository,
+	reviewRepo *storage.ReviewRepository,
+	logger *zap.SugaredLogger,
+) (*ModerationBot, error) {
+	if tg.cfg.ModeratorsChat == "" {
+		return nil, fmt.Errorf("moderators_chat не указан в конфигурации")
+	}
+	chat, err := parseTelegramChat(tg.cfg.ModeratorsChat)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ModerationBot{
+		tg:         tg,
+		chat:       chat,
+		postRepo:   postRepo,
+		ruleRepo:   ruleRepo,
+		reviewRepo: reviewRepo,
+		logger:     logger,
+	}, nil
+}
+
+// Run получает обновления бота long polling'ом до отмены контекста
+func (m *ModerationBot) Run(ctx context.Context) {
+	u := tgbotapi.NewUpdate(0)
+	u.Timeout = 30
+	u.AllowedUpdates = []string{"callback_query", "message"}
+
+	updates := m.tg.bot.GetUpdatesChan(u)
+	m.logger.Infof("🛡️ Модерация в чате %s запущена", m.chat)
+
+	for {
+		select {
+		case <-ctx.Done():
+			m.tg.bot.StopReceivingUpdates()
+			m.logger.Info("🛑 Модерация в Telegram остановлена")
+			return
+		case update, ok := <-updates:
+			if !ok {
+				return
+			}
+			switch {
+			case update.CallbackQuery != nil:
+				m.handleCallback(ctx, update.CallbackQuery)
+			case update.Message != nil:
+				m.handleMessage(ctx, update.Message)
+			}
+		}
+	}
+}
+
+// inChat проверяет, что сообщение пришло из чата модерации
+func (m *ModerationBot) inChat(chat *tgbotapi.Chat) bool {
+	if chat == nil {
+		return false
+	}
+	if m.chat.id != 0 {
+		return chat.ID == m.chat.id
+	}
+	return strings.EqualFold("@"+chat.UserName, m.chat.username)
+}
+
+// answer отвечает на нажатие кнопки всплывающим уведомлением
+func (m *ModerationBot) answer(q *tgbotapi.CallbackQuery, text string) {
+	if _, err := m.tg.bot.Request(tgbotapi.NewCallback(q.ID, text)); err != nil {
+		m.logger.Warnf("⚠️ Ошибка ответа на нажатие кнопки: %v", err)
+	}
+}
+
+// handleCallback обрабатывает нажатие кнопки карточки модерации
+func (m *ModerationBot) handleCallback(ctx context.Context, q *tgbotapi.CallbackQuery) {
+	action, postID, ok := parseReviewCallback(q.Data)
+	if !ok {
+		return
+	}
+	if q.Message == nil || !m.inChat(q.Message.Chat) {
+		m.answer(q, "Модерация доступна только в чате редакторов")
+		return
+	}
+
+	post, err := m.postRepo.GetByID(ctx, postID)
+	if err != nil {
+		m.logger.Errorf("❌ Ошибка получения поста %d для модерации: %v", postID, err)
+		m.answer(q, "Ошибка получения поста")
+		return
+	}
+	if post == nil {
+		m.answer(q, fmt.Sprintf("Пост #%d не найден", postID))
+		return
+	}
+
+	rule, err := m.ruleRepo.GetByID(ctx, post.RuleID)
+	if err != nil {
+		m.logger.Errorf("❌ Ошибка получения правила %d: %v", post.RuleID, err)
+		m.answer(q, "Ошибка получения правила")
+		return
+	}
+
+	if action == reviewEdit {
+		m.promptEdit(ctx, q, post)
+		return
+	}
+
+	review := &models.PostReview{PostID: post.ID, Username: telegramReviewer(q.From)}
+	holdUntil := post.HoldUntil(rule, time.Now())
+
+	var platforms []models.PlatformType
+	switch action {
+	case reviewApprove, reviewOnlyTG, reviewOnlyVK:
+		if rule == nil {
+			m.answer(q, "Правило поста не найдено")
+			return
+		}
+		platforms = rule.TargetPlatforms
+		if platform, only := reviewPlatform(action); only {
+			// Кнопка могла остаться в старой карточке после изменения правила
+			if !rule.SupportsPlatform(platform) {
+				m.answer(q, fmt.Sprintf("Правило не публикует в %s", platform))
+				return
+			}
+			platforms = []models.PlatformType{platform}
+		}
+		err = m.reviewRepo.Approve(ctx, review, platforms, holdUntil)
+	case reviewReject:
+		err = m.reviewRepo.Reject(ctx, review)
+	default:
+		m.answer(q, "Неизвестное действие")
+		return
+	}
+
+	if errors.Is(err, storage.ErrNotPendingReview) {
+		m.answer(q, fmt.Sprintf("Пост #%d уже обработан", post.ID))
+		// Решение принято в другом месте, например в веб-интерфейсе
+		m.closeCard(ctx, q.Message, post, rule, nil)
+		return
+	}
+	if err != nil {
+		m.logger.Errorf("❌ Ошибка модерации поста %d: %v", post.ID, err)
+		m.answer(q, "Ошибка сохранения решения")
+		return
+	}
+
+	m.logger.Infof("🛡️ Пост %d: %s (%s)", post.ID, review.Action, review.Username)
+	m.answer(q, "Готово")
+
+	post, err = m.postRepo.GetByID(ctx, post.ID)
+	if err != nil || post == nil {
+		m.logger.Warnf("⚠️ Не удалось обновить карточку поста %d: %v", postID, err)
+		return
+	}
+	m.closeCard(ctx, q.Message, post, rule, platforms)
+}
+
+// reviewPlatform возвращает платформу действия "только в ...".
+// false - действие не ограничено одной платформой.
+func reviewPlatform(action string) (models.PlatformType, bool) {
+	switch action {
+	case reviewOnlyTG:
+		return models.PlatformTelegram, true
+	case reviewOnlyVK:
+		return models.PlatformVK, true
+	default:
+		return "", false
+	}
+}
+
+// closeCard показывает в карточке итог решения и убирает кнопки
+func (m *ModerationBot) closeCard(ctx context.Context, card *tgbotapi.Message, post *models.Post, rule *models.ParsingRule, platforms []models.PlatformType) {
+	edit := tgbotapi.NewEditMessageText(card.Chat.ID, card.MessageID, reviewCardText(post, rule, reviewOutcome(post, platforms)))
+	edit.ParseMode = "HTML"
+	edit.DisableWebPagePreview = true
+
+	err := m.tg.send(ctx, m.chat, func() error {
+		_, err := m.tg.bot.Request(edit)
+		return err
+	})
+	if err != nil && !strings.Contains(err.Error(), "message is not modified") {
+		m.logger.Warnf("⚠️ Ошибка обновления карточки поста %d: %v", post.ID, err)
+	}
+}
+
+// promptEdit просит редактора прислать новый текст ответом на сообщение бота
+func (m *ModerationBot) promptEdit(ctx context.Context, q *tgbotapi.CallbackQuery, post *models.Post) {
+	if !post.IsPendingReview() {
+		m.answer(q, fmt.Sprintf("Пост #%d уже обработан", post.ID))
+		return
+	}
+
+	msg := tgbotapi.NewMessage(q.Message.Chat.ID, fmt.Sprintf("✏️ Пришлите новый текст поста #%d ответом на это сообщение", post.ID))
+	msg.ReplyToMessageID = q.Message.MessageID
+	msg.ReplyMarkup = tgbotapi.ForceReply{ForceReply: true, Selective: true}
+
+	err := m.tg.send(ctx, m.chat, func() error {
+		_, err := m.tg.bot.Send(msg)
+		return err
+	})
+	if err != nil {
+		m.logger.Errorf("❌ Ошибка запроса нового текста поста %d: %v", post.ID, err)
+		m.answer(q, "Ошибка отправки сообщения")
+		return
+	}
+	m.answer(q, "Пришлите новый текст ответом")
+}
+
+// handleMessage принимает новый текст поста, присланный ответом на запрос бота
+func (m *ModerationBot) handleMessage(ctx context.Context, msg *tgbotapi.Message) {
+	if !m.inChat(msg.Chat) || msg.Text == "" {
+		return
+	}
+	prompt := msg.ReplyToMessage
+	if prompt == nil || prompt.From == nil || prompt.From.ID != m.tg.bot.Self.ID {
+		return
+	}
+	match := editPromptRe.FindStringSubmatch(prompt.Text)
+	if match == nil || !strings.HasPrefix(prompt.Text, "✏️") {
+		return
+	}
+	postID, _ := strconv.ParseInt(match[1], 10, 64)
+
+	post, err := m.postRepo.GetByID(ctx, postID)
+	if err != nil || post == nil {
+		m.reply(ctx, msg, fmt.Sprintf("❌ Пост #%d не найден", postID))
+		return
+	}
+
+	post.Content = msg.Text
+	post.Entities = nil
+
+	review := &models.PostReview{Username: telegramReviewer(msg.From)}
+	err = m.reviewRepo.Edit(ctx, post, review)
+	if errors.Is(err, storage.ErrNotPendingReview) {
+		m.reply(ctx, msg, fmt.Sprintf("Пост #%d уже обработан, текст не изменен", postID))
+		return
+	}
+	if err != nil {
+		m.logger.Errorf("❌ Ошибка изменения текста поста %d: %v", postID, err)
+		m.reply(ctx, msg, "❌ Ошибка сохранения текста")
+		return
+	}
+	m.logger.Infof("✏️ Текст поста %d изменен в чате модерации (%s)", postID, review.Username)
+
+	// Новая карточка с измененным текстом и кнопками решения
+	rule, err := m.ruleRepo.GetByID(ctx, post.RuleID)
+	if err != nil {
+		m.logger.Warnf("⚠️ Ошибка получения правила %d: %v", post.RuleID, err)
+	}
+	if err := m.tg.SendForReview(ctx, post, rule); err != nil {
+		m.logger.Errorf("❌ %v", err)
+	}
+}
+
+// reply отвечает на сообщение в чате модерации
+func (m *ModerationBot) reply(ctx context.Context, to *tgbotapi.Message, text string) {
+	msg := tgbotapi.NewMessage(to.Chat.ID, text)
+	msg.ReplyToMessageID = to.MessageID
+
+	err := m.tg.send(ctx, m.chat, func() error {
+		_, err := m.tg.bot.Send(msg)
+		return err
+	})
+	if err != nil {
+		m.logger.Warnf("⚠️ Ошибка ответа в чате модерации: %v", err)
+	}
+}
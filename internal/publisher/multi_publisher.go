@@ -2,134 +2,212 @@ package publisher
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/ratelimit"
 	"github.com/drerr0r/tgparserbot/internal/storage"
 	"go.uber.org/zap"
 )
 
+const (
+	// saveAttempts сколько раз пробовать сохранить сделанную публикацию
+	saveAttempts = 3
+	// saveRetryDelay пауза между попытками сохранения публикации
+	saveRetryDelay = time.Second
+)
+
+// ErrPublicationNotSaved пост опубликован, но копия не записана в БД.
+// Повтор задачи опубликовал бы пост в то же назначение еще раз, поэтому
+// такая задача не повторяется.
+var ErrPublicationNotSaved = errors.New("публикация не сохранена")
+
 // MultiPublisher управляет публикацией в multiple платформы
 type MultiPublisher struct {
-	tgPublisher *TelegramPublisher
-	vkPublisher *VKPublisher
-	postRepo    *storage.PostRepository
-	logger      *zap.SugaredLogger
-	tgChannelID string // ChatID для Telegram канала
+	registry *Registry
+	postRepo *storage.PostRepository
+	pubRepo  *storage.PublicationRepository
+	logger   *zap.SugaredLogger
 }
 
 // NewMultiPublisher создает новый мульти-публикатор
 func NewMultiPublisher(
-	tgPublisher *TelegramPublisher,
-	vkPublisher *VKPublisher,
+	registry *Registry,
 	postRepo *storage.PostRepository,
-	tgChannelID string,
+	pubRepo *storage.PublicationRepository,
 	logger *zap.SugaredLogger,
 ) *MultiPublisher {
 	return &MultiPublisher{
-		tgPublisher: tgPublisher,
-		vkPublisher: vkPublisher,
-		postRepo:    postRepo,
-		tgChannelID: tgChannelID,
-		logger:      logger,
+		registry: registry,
+		postRepo: postRepo,
+		pubRepo:  pubRepo,
+		logger:   logger,
 	}
 }
 
-// containsPlatform проверяет наличие платформы в массиве
-func containsPlatform(platforms []models.PlatformType, platform string) bool {
-	for _, p := range platforms {
-		if string(p) == platform {
-			return true
-		}
+// PublishPlatform публикует пост во все назначения правила на платформе.
+// Назначения, в которые пост уже опубликован, пропускаются, поэтому повторный
+// вызов после частичной ошибки не создает дубликатов.
+func (p *MultiPublisher) PublishPlatform(ctx context.Context, post *models.Post, rule *models.ParsingRule, platform models.PlatformType) error {
+	pub, ok := p.registry.Get(platform)
+	if !ok {
+		return fmt.Errorf("публикатор для платформы %s не зарегистрирован", platform)
 	}
-	return false
-}
-func (p *MultiPublisher) Publish(ctx context.Context, post *models.Post, rule *models.ParsingRule) error {
-	p.logger.Infof("🔄 Начало публикации поста %d на платформы: %v", post.ID, rule.TargetPlatforms)
 
-	var errors []string
-	publishedPlatforms := []string{}
-
-	// Публикация в Telegram
-	if p.tgPublisher != nil && containsPlatform(rule.TargetPlatforms, "telegram") {
-		p.logger.Infof("📤 Публикация поста %d на telegram", post.ID)
-		if err := p.tgPublisher.Publish(ctx, post, p.tgChannelID); err != nil {
-			p.logger.Errorf("❌ Ошибка публикации в Telegram: %v", err)
-			errors = append(errors, fmt.Sprintf("Telegram: %v", err))
-		} else {
-			// Обновляем статус в БД
-			if err := p.postRepo.MarkAsPublishedTelegram(ctx, post.ID); err != nil {
-				p.logger.Errorf("❌ Ошибка обновления статуса Telegram: %v", err)
-			} else {
-				publishedPlatforms = append(publishedPlatforms, "telegram")
-				p.logger.Infof("✅ Успешная публикация поста %d на telegram", post.ID)
-			}
+	targets, err := pub.Targets(rule)
+	if err != nil {
+		return err
+	}
+
+	existing, err := p.pubRepo.GetByPostID(ctx, post.ID)
+	if err != nil {
+		return err
+	}
+	published := make(map[string]*models.Publication)
+	for _, e := range existing {
+		if e.Platform == platform {
+			published[e.Target] = e
 		}
 	}
 
-	// Публикация в VK
-	if p.vkPublisher != nil && containsPlatform(rule.TargetPlatforms, "vk") {
-		p.logger.Infof("📤 Публикация поста %d на vk", post.ID)
-		if err := p.vkPublisher.Publish(ctx, post); err != nil {
-			p.logger.Errorf("❌ Ошибка публикации в VK: %v", err)
-			errors = append(errors, fmt.Sprintf("VK: %v", err))
+	var errs []string
+	for _, target := range targets {
+		var publication *models.Publication
+		var err error
+		if prev := published[target]; prev != nil {
+			resumer, ok := pub.(Resumer)
+			if !prev.Partial || !ok {
+				p.logger.Debugf("⏭️ Пост %d уже опубликован в %s %s", post.ID, platform, target)
+				continue
+			}
+			p.logger.Infof("📤 Продолжение публикации поста %d на %s %s", post.ID, platform, target)
+			publication, err = resumer.Resume(ctx, post, rule, prev)
 		} else {
-			// Обновляем статус в БД
-			if err := p.postRepo.MarkAsPublishedVK(ctx, post.ID); err != nil {
-				p.logger.Errorf("❌ Ошибка обновления статуса VK: %v", err)
-			} else {
-				publishedPlatforms = append(publishedPlatforms, "vk")
-				p.logger.Infof("✅ Успешная публикация поста %d на vk", post.ID)
+			p.logger.Infof("📤 Публикация поста %d на %s %s", post.ID, platform, target)
+			publication, err = pub.Publish(ctx, post, rule, target)
+		}
+
+		// Сохраняем копию сразу, даже отправленную частично, чтобы при
+		// повторе не публиковать ее снова
+		if publication != nil {
+			if saveErr := p.savePublication(publication); saveErr != nil {
+				return fmt.Errorf("%w: пост %d опубликован в %s %s, но не записан: %v",
+					ErrPublicationNotSaved, post.ID, platform, target, saveErr)
 			}
+			published[target] = publication
 		}
-	}
 
-	if len(errors) > 0 {
-		errorMsg := strings.Join(errors, "; ")
-		if err := p.postRepo.MarkAsFailed(ctx, post.ID, errorMsg); err != nil {
-			p.logger.Errorf("❌ Ошибка сохранения ошибки публикации: %v", err)
+		if _, ok := ratelimit.AsWait(err); ok {
+			// Платформа ограничила запросы - остальные назначения тоже подождут
+			return err
+		}
+		if err != nil {
+			p.logger.Errorf("❌ Ошибка публикации в %s: %v", platform, err)
+			errs = append(errs, err.Error())
+			continue
 		}
-		return fmt.Errorf("ошибки публикации: %s", errorMsg)
 	}
 
-	p.logger.Infof("🎉 Пост %d успешно опубликован на платформы: %v", post.ID, publishedPlatforms)
+	if len(errs) > 0 {
+		return fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+
+	// Обновляем статус в БД
+	if err := p.postRepo.MarkAsPublishedOn(ctx, post.ID, platform); err != nil {
+		p.logger.Errorf("❌ Ошибка обновления статуса %s: %v", platform, err)
+	}
+
+	p.logger.Infof("✅ Успешная публикация поста %d на %s", post.ID, platform)
 	return nil
 }
 
-// ProcessUnpublished обрабатывает неопубликованные посты
-func (p *MultiPublisher) ProcessUnpublished(ctx context.Context) error {
-	p.logger.Info("Обработка неопубликованных постов...")
+// savePublication сохраняет сделанную публикацию с несколькими попытками.
+// Пост уже отправлен, поэтому запись не зависит от отмены контекста задачи.
+// Продолженная публикация уже есть в БД и обновляется.
+func (p *MultiPublisher) savePublication(publication *models.Publication) error {
+	var err error
+	for attempt := 1; attempt <= saveAttempts; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if publication.ID != 0 {
+			err = p.pubRepo.Update(ctx, publication)
+		} else {
+			err = p.pubRepo.Create(ctx, publication)
+		}
+		cancel()
+		if err == nil {
+			return nil
+		}
 
-	// Получаем неопубликованные посты
-	posts, err := p.postRepo.GetUnpublishedPosts(ctx, 10) // Ограничиваем 10 постами за раз
+		p.logger.Errorf("❌ Ошибка сохранения публикации поста %d в %s %s (попытка %d): %v",
+			publication.PostID, publication.Platform, publication.Target, attempt, err)
+		if attempt < saveAttempts {
+			time.Sleep(saveRetryDelay)
+		}
+	}
+	return err
+}
+
+// SyncEdit обновляет все опубликованные копии поста после правки исходного сообщения
+func (p *MultiPublisher) SyncEdit(ctx context.Context, post *models.Post, rule *models.ParsingRule) error {
+	publications, err := p.pubRepo.GetByPostID(ctx, post.ID)
 	if err != nil {
-		return fmt.Errorf("ошибка получения неопубликованных постов: %v", err)
+		return err
 	}
 
-	if len(posts) == 0 {
-		p.logger.Info("Нет неопубликованных постов для обработки")
-		return nil
+	var errors []string
+	for _, pub := range publications {
+		publisher, ok := p.registry.Get(pub.Platform)
+		if !ok {
+			continue
+		}
+
+		if err := publisher.Edit(ctx, post, rule, pub); err != nil {
+			p.logger.Errorf("❌ Ошибка обновления копии поста %d в %s %s: %v", post.ID, pub.Platform, pub.Target, err)
+			errors = append(errors, fmt.Sprintf("%s %s: %v", pub.Platform, pub.Target, err))
+		}
+	}
+
+	if len(errors) > 0 {
+		return fmt.Errorf("ошибки обновления: %s", strings.Join(errors, "; "))
 	}
 
-	p.logger.Infof("Найдено %d неопубликованных постов", len(posts))
+	p.logger.Infof("✏️ Копии поста %d обновлены: %d", post.ID, len(publications))
+	return nil
+}
 
-	// Обрабатываем каждый пост
-	for _, post := range posts {
-		// Получаем правило для поста
-		// TODO: Реализовать получение правила по post.RuleID
+// SyncDelete удаляет все опубликованные копии поста после удаления исходного сообщения
+func (p *MultiPublisher) SyncDelete(ctx context.Context, post *models.Post) error {
+	publications, err := p.pubRepo.GetByPostID(ctx, post.ID)
+	if err != nil {
+		return err
+	}
 
-		// Временная заглушка - создаем mock правило
-		rule := &models.ParsingRule{
-			TargetPlatforms: []models.PlatformType{models.PlatformTelegram, models.PlatformVK},
+	var errors []string
+	for _, pub := range publications {
+		publisher, ok := p.registry.Get(pub.Platform)
+		if !ok {
+			continue
 		}
 
-		if err := p.Publish(ctx, post, rule); err != nil {
-			p.logger.Errorf("Ошибка публикации поста %d: %v", post.ID, err)
+		if err := publisher.Delete(ctx, pub); err != nil {
+			p.logger.Errorf("❌ Ошибка удаления копии поста %d в %s %s: %v", post.ID, pub.Platform, pub.Target, err)
+			errors = append(errors, fmt.Sprintf("%s %s: %v", pub.Platform, pub.Target, err))
 			continue
 		}
+
+		if err := p.pubRepo.Delete(ctx, pub.ID); err != nil {
+			p.logger.Errorf("❌ %v", err)
+		}
 	}
 
+	if len(errors) > 0 {
+		return fmt.Errorf("ошибки удаления: %s", strings.Join(errors, "; "))
+	}
+
+	p.logger.Infof("🗑️ Копии поста %d удалены: %d", post.ID, len(publications))
 	return nil
 }
 
@@ -137,18 +215,11 @@ func (p *MultiPublisher) ProcessUnpublished(ctx context.Context) error {
 func (p *MultiPublisher) TestConnections(ctx context.Context) error {
 	p.logger.Info("Проверка подключений к платформам...")
 
-	if p.tgPublisher != nil {
-		if err := p.tgPublisher.TestConnection(ctx); err != nil {
-			return fmt.Errorf("ошибка подключения Telegram: %v", err)
-		}
-		p.logger.Info("✓ Подключение к Telegram OK")
-	}
-
-	if p.vkPublisher != nil {
-		if err := p.vkPublisher.TestConnection(ctx); err != nil {
-			return fmt.Errorf("ошибка подключения VK: %v", err)
+	for _, pub := range p.registry.All() {
+		if err := pub.TestConnection(ctx); err != nil {
+			return fmt.Errorf("ошибка подключения %s: %v", pub.Name(), err)
 		}
-		p.logger.Info("✓ Подключение к VK OK")
+		p.logger.Infof("✓ Подключение к %s OK", pub.Name())
 	}
 
 	p.logger.Info("Все подключения работают")
@@ -0,0 +1,80 @@
+package publisher
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+// Publisher публикатор постов в одну платформу
+type Publisher interface {
+	// Name возвращает платформу, в которую публикует публикатор
+	Name() models.PlatformType
+	// Targets возвращает назначения правила на платформе в том виде,
+	// в котором они сохраняются в публикациях
+	Targets(rule *models.ParsingRule) ([]string, error)
+	// Publish публикует пост в одно назначение и возвращает созданную копию.
+	// Текст поста оформляется шаблоном правила для платформы. Если копия
+	// отправлена частично, она возвращается вместе с ошибкой.
+	Publish(ctx context.Context, post *models.Post, rule *models.ParsingRule, target string) (*models.Publication, error)
+	// Edit обновляет текст опубликованной копии поста
+	Edit(ctx context.Context, post *models.Post, rule *models.ParsingRule, pub *models.Publication) error
+	// Delete удаляет опубликованную копию поста
+	Delete(ctx context.Context, pub *models.Publication) error
+	// TestConnection проверяет подключение к платформе
+	TestConnection(ctx context.Context) error
+}
+
+// Resumer публикатор, который может дослать частично отправленную копию
+// поста, например альбом, часть медиагрупп которого не отправилась
+type Resumer interface {
+	// Resume досылает неотправленную часть копии и возвращает ее с ID всех
+	// отправленных сообщений. При ошибке копия возвращается вместе с ней.
+	Resume(ctx context.Context, post *models.Post, rule *models.ParsingRule, pub *models.Publication) (*models.Publication, error)
+}
+
+// Registry реестр публикаторов по платформам
+type Registry struct {
+	mu         sync.RWMutex
+	publishers map[models.PlatformType]Publisher
+}
+
+// NewRegistry создает пустой реестр публикаторов
+func NewRegistry() *Registry {
+	return &Registry{
+		publishers: make(map[models.PlatformType]Publisher),
+	}
+}
+
+// Register добавляет публикатор в реестр, заменяя ранее зарегистрированный
+// для той же платформы
+func (r *Registry) Register(p Publisher) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.publishers[p.Name()] = p
+}
+
+// Get возвращает публикатор для платформы
+func (r *Registry) Get(platform models.PlatformType) (Publisher, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.publishers[platform]
+	return p, ok
+}
+
+// All возвращает все публикаторы, отсортированные по платформе
+func (r *Registry) All() []Publisher {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	publishers := make([]Publisher, 0, len(r.publishers))
+	for _, p := range r.publishers {
+		publishers = append(publishers, p)
+	}
+	sort.Slice(publishers, func(i, j int) bool {
+		return publishers[i].Name() < publishers[j].Name()
+	})
+	return publishers
+}
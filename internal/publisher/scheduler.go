@@ -0,0 +1,154 @@
+package publisher
+
+import (
+	"context"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+	"go.uber.org/zap"
+)
+
+// releaseHistory за какой период учитываются отпущенные посты правила:
+// с запасом на дневной лимит в любом часовом поясе
+const releaseHistory = 48 * time.Hour
+
+// Scheduler отпускает отложенные посты воркерам публикации по расписанию правил.
+// Посты правила отпускаются по порядку обработки, с назначенным вручную
+// временем - ровно в это время.
+type Scheduler struct {
+	jobRepo  *storage.JobRepository
+	ruleRepo *storage.RuleRepository
+	interval time.Duration
+	logger   *zap.SugaredLogger
+}
+
+// NewScheduler создает планировщик публикации. Интервал проверки тот же,
+// что у воркеров очереди.
+func NewScheduler(jobRepo *storage.JobRepository, ruleRepo *storage.RuleRepository, cfg models.PublishConfig, logger *zap.SugaredLogger) *Scheduler {
+	interval := time.Duration(cfg.PollSeconds) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &Scheduler{
+		jobRepo:  jobRepo,
+		ruleRepo: ruleRepo,
+		interval: interval,
+		logger:   logger,
+	}
+}
+
+// Run проверяет отложенные посты до отмены контекста
+func (s *Scheduler) Run(ctx context.Context) {
+	s.logger.Info("🕒 Планировщик публикации запущен")
+
+	for {
+		if err := s.tick(ctx, time.Now()); err != nil {
+			s.logger.Errorf("❌ Ошибка планировщика публикации: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			s.logger.Info("🛑 Планировщик публикации остановлен")
+			return
+		case <-time.After(s.interval):
+		}
+	}
+}
+
+// tick отпускает посты, время которых пришло, и обновляет ожидаемое время остальных
+func (s *Scheduler) tick(ctx context.Context, now time.Time) error {
+	posts, err := s.jobRepo.HeldPosts(ctx)
+	if err != nil {
+		return err
+	}
+
+	byRule := make(map[int64][]*models.Post)
+	var ruleOrder []int64
+	for _, post := range posts {
+		if post.ScheduleManual && post.ScheduledAt != nil {
+			if !post.ScheduledAt.After(now) {
+				s.release(ctx, post, "назначенное время")
+			}
+			continue
+		}
+		if _, ok := byRule[post.RuleID]; !ok {
+			ruleOrder = append(ruleOrder, post.RuleID)
+		}
+		byRule[post.RuleID] = append(byRule[post.RuleID], post)
+	}
+
+	for _, ruleID := range ruleOrder {
+		if err := s.scheduleRule(ctx, ruleID, byRule[ruleID], now); err != nil {
+			s.logger.Errorf("❌ Ошибка расписания правила %d: %v", ruleID, err)
+		}
+	}
+	return nil
+}
+
+// scheduleRule отпускает посты правила, пока расписание это позволяет,
+// а оставшимся назначает ожидаемое время публикации
+func (s *Scheduler) scheduleRule(ctx context.Context, ruleID int64, posts []*models.Post, now time.Time) error {
+	rule, err := s.ruleRepo.GetByID(ctx, ruleID)
+	if err != nil {
+		return err
+	}
+
+	// Расписание сняли с правила или правило удалено - ждать нечего
+	if rule == nil || rule.Schedule == nil {
+		for _, post := range posts {
+			s.release(ctx, post, "без расписания")
+		}
+		return nil
+	}
+
+	released, err := s.jobRepo.ReleaseTimes(ctx, ruleID, now.Add(-releaseHistory))
+	if err != nil {
+		return err
+	}
+
+	// Следующие посты встают за предыдущими, поэтому ожидаемое время
+	// считается с учетом уже запланированных
+	after := now
+	for _, post := range posts {
+		slot := rule.Schedule.NextSlot(after, released)
+		if !slot.After(now) {
+			if s.release(ctx, post, "расписание правила") {
+				released = append(released, now)
+			}
+			continue
+		}
+		slot = ceilSecond(slot)
+
+		if post.ScheduledAt == nil || !post.ScheduledAt.Equal(slot) {
+			if err := s.jobRepo.Reschedule(ctx, post.ID, slot); err != nil {
+				return err
+			}
+		}
+		released = append(released, slot)
+		after = slot
+	}
+	return nil
+}
+
+// release отпускает пост воркерам публикации
+func (s *Scheduler) release(ctx context.Context, post *models.Post, reason string) bool {
+	ok, err := s.jobRepo.Release(ctx, post.ID)
+	if err != nil {
+		s.logger.Errorf("❌ %v", err)
+		return false
+	}
+	if ok {
+		s.logger.Infof("🕒 Пост %d отправлен на публикацию (%s)", post.ID, reason)
+	}
+	return ok
+}
+
+// ceilSecond округляет время вверх до секунды, чтобы сохраненное время
+// не оказалось раньше разрешенного
+func ceilSecond(t time.Time) time.Time {
+	if truncated := t.Truncate(time.Second); truncated.Before(t) {
+		return truncated.Add(time.Second)
+	}
+	return t
+}
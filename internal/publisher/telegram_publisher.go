@@ -2,11 +2,17 @@ package publisher
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/ratelimit"
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"go.uber.org/zap"
@@ -14,11 +20,15 @@ import (
 
 // TelegramPublisher публикатор в Telegram
 type TelegramPublisher struct {
-	bot    *tgbotapi.BotAPI
-	logger *zap.SugaredLogger
-	cfg    *models.TelegramConfig
+	bot     *tgbotapi.BotAPI
+	logger  *zap.SugaredLogger
+	cfg     *models.TelegramConfig
+	limiter *ratelimit.Limiter // общий лимит бота
+	chats   *ratelimit.Group   // лимит на каждый чат
 }
 
+var _ Publisher = (*TelegramPublisher)(nil)
+
 // NewTelegramPublisher создает новый публикатор для Telegram
 func NewTelegramPublisher(cfg *models.TelegramConfig, logger *zap.SugaredLogger) (*TelegramPublisher, error) {
 	if cfg.BotToken == "" {
@@ -36,121 +46,490 @@ func NewTelegramPublisher(cfg *models.TelegramConfig, logger *zap.SugaredLogger)
 		bot:    bot,
 		logger: logger,
 		cfg:    cfg,
+		// Bot API: не больше 30 сообщений в секунду в целом
+		// и 20 сообщений в минуту в одну группу или канал
+		limiter: ratelimit.New("telegram_bot", 30, 30),
+		chats:   ratelimit.NewGroup("telegram_bot_chat", 20.0/60, 3),
 	}, nil
 }
 
-// Publish публикует пост в Telegram канал
-func (p *TelegramPublisher) Publish(ctx context.Context, post *models.Post, targetChannel string) error {
-	p.logger.Infof("Публикация поста %d в канал %s", post.ID, targetChannel)
+// Name возвращает платформу публикатора
+func (p *TelegramPublisher) Name() models.PlatformType {
+	return models.PlatformTelegram
+}
+
+// telegramChat чат назначения: числовой ID или @username
+type telegramChat struct {
+	id       int64
+	username string
+}
 
-	// Всегда используем target_channel из конфига, игнорируя переданный параметр
-	if p.cfg.TargetChannel == "" {
-		return fmt.Errorf("target_channel не указан в конфигурации")
+// parseTelegramChat разбирает chat ID или @username
+func parseTelegramChat(target string) (telegramChat, error) {
+	target = strings.TrimSpace(target)
+	if strings.HasPrefix(target, "@") {
+		return telegramChat{username: target}, nil
 	}
 
-	// Парсим ChatID из конфига
-	chatID, err := strconv.ParseInt(p.cfg.TargetChannel, 10, 64)
+	chatID, err := strconv.ParseInt(target, 10, 64)
 	if err != nil {
-		return fmt.Errorf("ошибка парсинга ChatID из конфига %s: %v", p.cfg.TargetChannel, err)
+		return telegramChat{}, fmt.Errorf("ошибка парсинга ChatID %s: %v", target, err)
+	}
+	return telegramChat{id: chatID}, nil
+}
+
+// apply заполняет адресата в базовой конфигурации сообщения
+func (c telegramChat) apply(base *tgbotapi.BaseChat) {
+	base.ChatID = c.id
+	base.ChannelUsername = c.username
+}
+
+// String возвращает читаемое имя чата
+func (c telegramChat) String() string {
+	if c.username != "" {
+		return c.username
+	}
+	return strconv.FormatInt(c.id, 10)
+}
+
+// Targets возвращает чаты назначения правила, а если они не заданы - канал из конфига
+func (p *TelegramPublisher) Targets(rule *models.ParsingRule) ([]string, error) {
+	var targets []string
+	for _, d := range rule.DestinationsFor(models.PlatformTelegram) {
+		targets = append(targets, d.Target)
+	}
+	if len(targets) == 0 {
+		if p.cfg.TargetChannel == "" {
+			return nil, fmt.Errorf("target_channel не указан ни в правиле, ни в конфигурации")
+		}
+		targets = []string{p.cfg.TargetChannel}
 	}
 
-	// Подготавливаем контент
-	content := p.prepareContent(post)
+	// Приводим к виду, который сохраняется в публикациях
+	for i, target := range targets {
+		chat, err := parseTelegramChat(target)
+		if err != nil {
+			return nil, err
+		}
+		targets[i] = chat.String()
+	}
+	return targets, nil
+}
 
-	// Если есть медиа, публикуем с медиа
-	if post.MediaURL != "" {
-		return p.publishWithMedia(post, chatID, content)
+// Publish публикует пост в один Telegram чат
+func (p *TelegramPublisher) Publish(ctx context.Context, post *models.Post, rule *models.ParsingRule, target string) (*models.Publication, error) {
+	chat, err := parseTelegramChat(target)
+	if err != nil {
+		return nil, err
 	}
 
-	// Публикуем текстовое сообщение
-	return p.publishText(chatID, content)
+	p.logger.Infof("Публикация поста %d в канал %s", post.ID, chat)
+	messageIDs, err := p.publishTo(ctx, post, chat, p.prepareContent(post, rule))
+	if err != nil && len(messageIDs) == 0 {
+		return nil, fmt.Errorf("%s: %w", chat, err)
+	}
+
+	// Часть медиагрупп альбома уже отправлена - копия сохраняется, чтобы
+	// повтор дослал только остальные
+	publication := &models.Publication{
+		PostID:     post.ID,
+		Platform:   models.PlatformTelegram,
+		Target:     chat.String(),
+		MessageIDs: messageIDs,
+		Partial:    err != nil,
+	}
+	if err != nil {
+		return publication, fmt.Errorf("%s: %w", chat, err)
+	}
+	return publication, nil
+}
+
+// Resume досылает медиагруппы альбома, которые не отправились при
+// публикации. Каждое сообщение медиагруппы - один файл альбома, поэтому
+// отправленные файлы считаются по ID сообщений.
+func (p *TelegramPublisher) Resume(ctx context.Context, post *models.Post, rule *models.ParsingRule, pub *models.Publication) (*models.Publication, error) {
+	chat, err := parseTelegramChat(pub.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	resumed := *pub
+	resumed.MessageIDs = append([]int64(nil), pub.MessageIDs...)
+	if !post.IsAlbum() {
+		resumed.Partial = false
+		return &resumed, nil
+	}
+
+	p.logger.Infof("Продолжение публикации альбома %d в канал %s с файла %d", post.ID, chat, len(pub.MessageIDs)+1)
+	messageIDs, err := p.publishAlbum(ctx, post, chat, p.prepareContent(post, rule), len(pub.MessageIDs))
+	resumed.MessageIDs = append(resumed.MessageIDs, messageIDs...)
+	resumed.Partial = err != nil
+	if err != nil {
+		return &resumed, fmt.Errorf("%s: %w", chat, err)
+	}
+	return &resumed, nil
+}
+
+// publishTo публикует пост в один чат и возвращает ID отправленных сообщений
+func (p *TelegramPublisher) publishTo(ctx context.Context, post *models.Post, chat telegramChat, content string) ([]int64, error) {
+	// Альбом отправляем одной медиагруппой
+	if post.IsAlbum() {
+		return p.publishAlbum(ctx, post, chat, content, 0)
+	}
+
+	var messageID int
+	var err error
+	if hasCaption(post) {
+		// Если есть медиа, публикуем с медиа
+		messageID, err = p.publishWithMedia(ctx, post, chat, content)
+	} else {
+		// Публикуем текстовое сообщение
+		messageID, err = p.publishText(ctx, chat, content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return []int64{int64(messageID)}, nil
+}
+
+// hasCaption проверяет, публикуется ли пост как медиа с подписью
+func hasCaption(post *models.Post) bool {
+	if post.IsAlbum() {
+		return true
+	}
+	if !post.HasMedia() {
+		return false
+	}
+	switch post.MediaType {
+	case models.MediaPhoto, models.MediaVideo, models.MediaDocument:
+		return true
+	default:
+		return false
+	}
 }
 
 // publishWithMedia публикует пост с медиа
-func (p *TelegramPublisher) publishWithMedia(post *models.Post, chatID int64, content string) error {
+func (p *TelegramPublisher) publishWithMedia(ctx context.Context, post *models.Post, chat telegramChat, content string) (int, error) {
 	switch post.MediaType {
 	case models.MediaPhoto:
-		return p.publishPhoto(post, chatID, content)
+		return p.publishPhoto(ctx, post, chat, content)
 	case models.MediaVideo:
-		return p.publishVideo(post, chatID, content)
+		return p.publishVideo(ctx, post, chat, content)
 	case models.MediaDocument:
-		return p.publishDocument(post, chatID, content)
+		return p.publishDocument(ctx, post, chat, content)
 	default:
-		return p.publishText(chatID, content)
+		return p.publishText(ctx, chat, content)
 	}
 }
 
+// mediaFile возвращает файл медиа: из локального кэша, если он загружен, иначе по URL.
+// Возвращаемую функцию нужно вызвать после отправки.
+func (p *TelegramPublisher) mediaFile(post *models.Post) (tgbotapi.RequestFileData, func(), error) {
+	if post.MediaFile == "" {
+		return tgbotapi.FileURL(post.MediaURL), func() {}, nil
+	}
+
+	file, err := os.Open(post.MediaFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ошибка открытия файла медиа: %v", err)
+	}
+
+	data := tgbotapi.FileReader{
+		Name:   filepath.Base(post.MediaFile),
+		Reader: file,
+	}
+	return data, func() { file.Close() }, nil
+}
+
 // publishPhoto публикует фото
-func (p *TelegramPublisher) publishPhoto(post *models.Post, chatID int64, caption string) error {
-	// Создаем конфиг для фото с URL
-	photo := tgbotapi.NewPhoto(chatID, tgbotapi.FileURL(post.MediaURL))
-	photo.Caption = caption
-	photo.ParseMode = "HTML"
+func (p *TelegramPublisher) publishPhoto(ctx context.Context, post *models.Post, chat telegramChat, caption string) (int, error) {
+	var msg tgbotapi.Message
+	err := p.send(ctx, chat, func() error {
+		// Файл открываем заново при каждой попытке - прочитанный поток не переотправить
+		file, closeFile, err := p.mediaFile(post)
+		if err != nil {
+			return err
+		}
+		defer closeFile()
+
+		photo := tgbotapi.NewPhoto(0, file)
+		chat.apply(&photo.BaseChat)
+		photo.Caption = caption
+		photo.ParseMode = "HTML"
 
-	_, err := p.bot.Send(photo)
+		msg, err = p.bot.Send(photo)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("ошибка публикации фото: %v", err)
+		return 0, fmt.Errorf("ошибка публикации фото: %w", err)
 	}
-	return nil
+	return msg.MessageID, nil
 }
 
 // publishVideo публикует видео
-func (p *TelegramPublisher) publishVideo(post *models.Post, chatID int64, caption string) error {
-	// Создаем конфиг для видео с URL
-	video := tgbotapi.NewVideo(chatID, tgbotapi.FileURL(post.MediaURL))
-	video.Caption = caption
-	video.ParseMode = "HTML"
+func (p *TelegramPublisher) publishVideo(ctx context.Context, post *models.Post, chat telegramChat, caption string) (int, error) {
+	var msg tgbotapi.Message
+	err := p.send(ctx, chat, func() error {
+		// Файл открываем заново при каждой попытке - прочитанный поток не переотправить
+		file, closeFile, err := p.mediaFile(post)
+		if err != nil {
+			return err
+		}
+		defer closeFile()
+
+		video := tgbotapi.NewVideo(0, file)
+		chat.apply(&video.BaseChat)
+		video.Caption = caption
+		video.ParseMode = "HTML"
 
-	_, err := p.bot.Send(video)
+		msg, err = p.bot.Send(video)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("ошибка публикации видео: %v", err)
+		return 0, fmt.Errorf("ошибка публикации видео: %w", err)
 	}
-	return nil
+	return msg.MessageID, nil
 }
 
 // publishDocument публикует документ
-func (p *TelegramPublisher) publishDocument(post *models.Post, chatID int64, caption string) error {
-	// Создаем конфиг для документа с URL
-	document := tgbotapi.NewDocument(chatID, tgbotapi.FileURL(post.MediaURL))
-	document.Caption = caption
-	document.ParseMode = "HTML"
+func (p *TelegramPublisher) publishDocument(ctx context.Context, post *models.Post, chat telegramChat, caption string) (int, error) {
+	var msg tgbotapi.Message
+	err := p.send(ctx, chat, func() error {
+		// Файл открываем заново при каждой попытке - прочитанный поток не переотправить
+		file, closeFile, err := p.mediaFile(post)
+		if err != nil {
+			return err
+		}
+		defer closeFile()
+
+		document := tgbotapi.NewDocument(0, file)
+		chat.apply(&document.BaseChat)
+		document.Caption = caption
+		document.ParseMode = "HTML"
 
-	_, err := p.bot.Send(document)
+		msg, err = p.bot.Send(document)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("ошибка публикации документа: %v", err)
+		return 0, fmt.Errorf("ошибка публикации документа: %w", err)
 	}
-	return nil
+	return msg.MessageID, nil
+}
+
+// maxAlbumSize максимальное количество файлов в одной медиагруппе Telegram
+const maxAlbumSize = 10
+
+// publishAlbum публикует альбом медиагруппами, начиная с файла from.
+// Подпись добавляется к первому файлу первой группы, как это делает сам
+// Telegram. При ошибке возвращаются ID уже отправленных групп.
+func (p *TelegramPublisher) publishAlbum(ctx context.Context, post *models.Post, chat telegramChat, caption string, from int) ([]int64, error) {
+	if from > 0 {
+		caption = ""
+	}
+
+	var messageIDs []int64
+	for start := from; start < len(post.Media); start += maxAlbumSize {
+		end := start + maxAlbumSize
+		if end > len(post.Media) {
+			end = len(post.Media)
+		}
+
+		ids, err := p.publishMediaGroup(ctx, post, post.Media[start:end], chat, caption)
+		if err != nil {
+			return messageIDs, err
+		}
+		messageIDs = append(messageIDs, ids...)
+		caption = ""
+	}
+	return messageIDs, nil
+}
+
+// publishMediaGroup отправляет одну медиагруппу
+func (p *TelegramPublisher) publishMediaGroup(ctx context.Context, post *models.Post, items []models.PostMedia, chat telegramChat, caption string) ([]int64, error) {
+	var messages []tgbotapi.Message
+	err := p.send(ctx, chat, func() error {
+		var files []interface{}
+		for i, item := range items {
+			itemPost := *post
+			itemPost.MediaType = item.MediaType
+			itemPost.MediaURL = item.MediaURL
+			itemPost.MediaFile = item.MediaFile
+
+			file, closeFile, err := p.mediaFile(&itemPost)
+			if err != nil {
+				return err
+			}
+			defer closeFile()
+
+			itemCaption := ""
+			if i == 0 {
+				itemCaption = caption
+			}
+
+			switch item.MediaType {
+			case models.MediaVideo:
+				media := tgbotapi.NewInputMediaVideo(file)
+				media.Caption = itemCaption
+				media.ParseMode = "HTML"
+				files = append(files, media)
+			case models.MediaDocument:
+				media := tgbotapi.NewInputMediaDocument(file)
+				media.Caption = itemCaption
+				media.ParseMode = "HTML"
+				files = append(files, media)
+			default:
+				media := tgbotapi.NewInputMediaPhoto(file)
+				media.Caption = itemCaption
+				media.ParseMode = "HTML"
+				files = append(files, media)
+			}
+		}
+
+		group := tgbotapi.NewMediaGroup(chat.id, files)
+		group.ChannelUsername = chat.username
+
+		var err error
+		messages, err = p.bot.SendMediaGroup(group)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ошибка публикации альбома: %w", err)
+	}
+
+	messageIDs := make([]int64, 0, len(messages))
+	for _, msg := range messages {
+		messageIDs = append(messageIDs, int64(msg.MessageID))
+	}
+	return messageIDs, nil
 }
 
 // publishText публикует текстовое сообщение
-func (p *TelegramPublisher) publishText(chatID int64, content string) error {
-	msg := tgbotapi.NewMessage(chatID, content)
+func (p *TelegramPublisher) publishText(ctx context.Context, chat telegramChat, content string) (int, error) {
+	msg := tgbotapi.NewMessage(0, content)
+	chat.apply(&msg.BaseChat)
 	msg.ParseMode = "HTML"
 
-	_, err := p.bot.Send(msg)
+	var sent tgbotapi.Message
+	err := p.send(ctx, chat, func() error {
+		var err error
+		sent, err = p.bot.Send(msg)
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("ошибка публикации текста: %v", err)
+		return 0, fmt.Errorf("ошибка публикации текста: %w", err)
 	}
+	return sent.MessageID, nil
+}
+
+// send выполняет запрос к Bot API с учетом общего лимита бота и лимита чата.
+// При ответе 429 запрос повторяется после retry_after.
+func (p *TelegramPublisher) send(ctx context.Context, chat telegramChat, fn func() error) error {
+	return p.chats.Get(chat.String()).Do(ctx, retryAfter, func() error {
+		if err := p.limiter.Wait(ctx); err != nil {
+			return err
+		}
+
+		err := fn()
+		if wait, ok := retryAfter(err); ok {
+			p.logger.Warnf("⏳ Bot API ограничил запросы в %s: ожидание %v", chat, wait)
+		}
+		return err
+	})
+}
+
+// retryAfter распознает ответ 429 Bot API и возвращает время ожидания
+func retryAfter(err error) (time.Duration, bool) {
+	var apiErr *tgbotapi.Error
+	if errors.As(err, &apiErr) && apiErr.Code == http.StatusTooManyRequests {
+		wait := time.Duration(apiErr.RetryAfter) * time.Second
+		if wait <= 0 {
+			wait = time.Second
+		}
+		return wait, true
+	}
+	return 0, false
+}
+
+// Edit обновляет текст или подпись опубликованного сообщения. У альбома
+// подпись хранится в первом сообщении.
+func (p *TelegramPublisher) Edit(ctx context.Context, post *models.Post, rule *models.ParsingRule, pub *models.Publication) error {
+	chat, err := parseTelegramChat(pub.Target)
+	if err != nil {
+		return err
+	}
+	if len(pub.MessageIDs) == 0 {
+		return fmt.Errorf("у публикации %d нет ID сообщений", pub.ID)
+	}
+	messageID := int(pub.MessageIDs[0])
+	content := p.prepareContent(post, rule)
+
+	var edit tgbotapi.Chattable
+	if hasCaption(post) {
+		cfg := tgbotapi.NewEditMessageCaption(chat.id, messageID, content)
+		cfg.ChannelUsername = chat.username
+		cfg.ParseMode = "HTML"
+		edit = cfg
+	} else {
+		cfg := tgbotapi.NewEditMessageText(chat.id, messageID, content)
+		cfg.ChannelUsername = chat.username
+		cfg.ParseMode = "HTML"
+		edit = cfg
+	}
+
+	err = p.send(ctx, chat, func() error {
+		_, err := p.bot.Request(edit)
+		return err
+	})
+	if err != nil {
+		// Текст не изменился - например, правка затронула только часть, отброшенную правилом
+		if strings.Contains(err.Error(), "message is not modified") {
+			return nil
+		}
+		return fmt.Errorf("ошибка редактирования сообщения %d в %s: %w", messageID, chat, err)
+	}
+
+	p.logger.Infof("Сообщение %d в канале %s обновлено", messageID, chat)
 	return nil
 }
 
-// prepareContent подготавливает контент для публикации
-func (p *TelegramPublisher) prepareContent(post *models.Post) string {
-	var content strings.Builder
+// Delete удаляет все сообщения публикации
+func (p *TelegramPublisher) Delete(ctx context.Context, pub *models.Publication) error {
+	chat, err := parseTelegramChat(pub.Target)
+	if err != nil {
+		return err
+	}
 
-	// Экранируем HTML-символы
-	escapedContent := strings.ReplaceAll(post.Content, "&", "&amp;")
-	escapedContent = strings.ReplaceAll(escapedContent, "<", "&lt;")
-	escapedContent = strings.ReplaceAll(escapedContent, ">", "&gt;")
+	var failed []string
+	for _, id := range pub.MessageIDs {
+		cfg := tgbotapi.NewDeleteMessage(chat.id, int(id))
+		cfg.ChannelUsername = chat.username
 
-	content.WriteString(escapedContent)
+		err := p.send(ctx, chat, func() error {
+			_, err := p.bot.Request(cfg)
+			return err
+		})
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("сообщение %d: %v", id, err))
+		}
+	}
 
-	// Добавляем информацию об источнике
-	if post.SourceChannel != "" {
-		content.WriteString(fmt.Sprintf("\n\n📎 <i>Источник: %s</i>", post.SourceChannel))
+	if len(failed) > 0 {
+		return fmt.Errorf("ошибка удаления в %s: %s", chat, strings.Join(failed, "; "))
 	}
 
-	return content.String()
+	p.logger.Infof("Сообщения %v в канале %s удалены", pub.MessageIDs, chat)
+	return nil
+}
+
+// prepareContent подготавливает контент для публикации по шаблону правила.
+// Форматирование исходного сообщения переносится в HTML, остальной текст экранируется.
+func (p *TelegramPublisher) prepareContent(post *models.Post, rule *models.ParsingRule) string {
+	content, err := FormatPost(post, rule, models.PlatformTelegram, rule.TemplateFor(models.PlatformTelegram))
+	if err != nil {
+		p.logger.Warnf("⚠️ Ошибка шаблона Telegram для поста %d, используется шаблон по умолчанию: %v", post.ID, err)
+		content, _ = FormatPost(post, rule, models.PlatformTelegram, models.DefaultPostTemplate(models.PlatformTelegram))
+	}
+	return content
 }
 
 // TestConnection проверяет подключение к Telegram
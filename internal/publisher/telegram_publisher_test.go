@@ -0,0 +1,54 @@
+package publisher
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantWait time.Duration
+		wantOK   bool
+	}{
+		{name: "nil", err: nil},
+		{name: "обычная ошибка", err: errors.New("boom")},
+		{
+			name: "другая ошибка API",
+			err:  &tgbotapi.Error{Code: http.StatusBadRequest, Message: "Bad Request"},
+		},
+		{
+			name:     "429 с retry_after",
+			err:      &tgbotapi.Error{Code: http.StatusTooManyRequests, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 17}},
+			wantWait: 17 * time.Second,
+			wantOK:   true,
+		},
+		{
+			name:     "429 без retry_after",
+			err:      &tgbotapi.Error{Code: http.StatusTooManyRequests},
+			wantWait: time.Second,
+			wantOK:   true,
+		},
+		{
+			name:     "обернутая 429",
+			err:      fmt.Errorf("ошибка отправки: %w", &tgbotapi.Error{Code: http.StatusTooManyRequests, ResponseParameters: tgbotapi.ResponseParameters{RetryAfter: 3}}),
+			wantWait: 3 * time.Second,
+			wantOK:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, ok := retryAfter(tt.err)
+			if ok != tt.wantOK || wait != tt.wantWait {
+				t.Errorf("retryAfter() = (%v, %v), want (%v, %v)", wait, ok, tt.wantWait, tt.wantOK)
+			}
+		})
+	}
+}
@@ -4,13 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/ratelimit"
 
 	"github.com/SevereCloud/vksdk/v2/api"
 	"github.com/SevereCloud/vksdk/v2/api/params"
@@ -24,10 +30,29 @@ type VKPublisher struct {
 	logger  *zap.SugaredLogger
 }
 
+var _ Publisher = (*VKPublisher)(nil)
+
 // NewVKPublisher создает новый публикатор для VK
 func NewVKPublisher(accessToken string, groupID int, logger *zap.SugaredLogger) (*VKPublisher, error) {
 	vk := api.NewVK(accessToken)
 
+	// VK API: не больше 3 запросов в секунду с пользовательским токеном.
+	// Встроенный лимит vksdk заменяем своим, чтобы ошибка 6 ставила запросы на паузу.
+	limiter := ratelimit.New("vk_api", 3, 3)
+	vk.Limit = 0
+	vk.Handler = func(method string, params ...api.Params) (api.Response, error) {
+		var resp api.Response
+		err := limiter.Do(requestContext(params), tooManyRequests, func() error {
+			var err error
+			resp, err = vk.DefaultHandler(method, params...)
+			if _, ok := tooManyRequests(err); ok {
+				logger.Warnf("⏳ VK ограничил запросы (%s)", method)
+			}
+			return err
+		})
+		return resp, err
+	}
+
 	// Более простая проверка токена - запрос информации о пользователе
 	_, err := vk.UsersGet(api.Params{
 		"user_ids": 1, // Запрашиваем информацию о пользователе с ID 1
@@ -58,26 +83,125 @@ func NewVKPublisher(accessToken string, groupID int, logger *zap.SugaredLogger)
 	}, nil
 }
 
-// Publish публикует пост в VK группу
-func (p *VKPublisher) Publish(ctx context.Context, post *models.Post) error {
-	p.logger.Infof("Публикация поста %d в VK группу %d", post.ID, p.groupID)
+// requestContext возвращает контекст запроса VK, переданный через
+// Params.WithContext, иначе фоновый контекст
+func requestContext(params []api.Params) context.Context {
+	for _, p := range params {
+		if ctx, ok := p[":context"].(context.Context); ok {
+			return ctx
+		}
+	}
+	return context.Background()
+}
+
+// tooManyRequests распознает ошибку VK 6 "слишком много запросов в секунду".
+// VK не сообщает время ожидания, поэтому ждем секунду.
+func tooManyRequests(err error) (time.Duration, bool) {
+	if errors.Is(err, api.ErrTooMany) {
+		return time.Second, true
+	}
+	return 0, false
+}
+
+// Name возвращает платформу публикатора
+func (p *VKPublisher) Name() models.PlatformType {
+	return models.PlatformVK
+}
+
+// groups возвращает ID групп назначения правила, а если они не заданы - группу из конфига
+func (p *VKPublisher) groups(rule *models.ParsingRule) ([]int, error) {
+	var groups []int
+	for _, d := range rule.DestinationsFor(models.PlatformVK) {
+		groupID, err := parseGroupID(d.Target)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, groupID)
+	}
+	if len(groups) > 0 {
+		return groups, nil
+	}
+
+	if p.groupID == 0 {
+		return nil, fmt.Errorf("группа VK не указана ни в правиле, ни в конфигурации")
+	}
+	return []int{p.groupID}, nil
+}
+
+// parseGroupID разбирает ID группы VK. Допускаем ID группы как с минусом (owner_id), так и без.
+func parseGroupID(target string) (int, error) {
+	groupID, err := strconv.Atoi(strings.TrimSpace(target))
+	if err != nil {
+		return 0, fmt.Errorf("неверный ID группы VK %s: %v", target, err)
+	}
+	if groupID < 0 {
+		groupID = -groupID
+	}
+	return groupID, nil
+}
+
+// Targets возвращает ID групп назначения правила
+func (p *VKPublisher) Targets(rule *models.ParsingRule) ([]string, error) {
+	groups, err := p.groups(rule)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]string, 0, len(groups))
+	for _, groupID := range groups {
+		targets = append(targets, strconv.Itoa(groupID))
+	}
+	return targets, nil
+}
+
+// Publish публикует пост в одну VK группу
+func (p *VKPublisher) Publish(ctx context.Context, post *models.Post, rule *models.ParsingRule, target string) (*models.Publication, error) {
+	groupID, err := parseGroupID(target)
+	if err != nil {
+		return nil, err
+	}
+
+	postID, err := p.publishToGroup(ctx, post, rule, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("группа %d: %w", groupID, err)
+	}
+
+	return &models.Publication{
+		PostID:     post.ID,
+		Platform:   models.PlatformVK,
+		Target:     strconv.Itoa(groupID),
+		MessageIDs: []int64{int64(postID)},
+	}, nil
+}
+
+// publishToGroup публикует пост в одну VK группу и возвращает ID записи
+func (p *VKPublisher) publishToGroup(ctx context.Context, post *models.Post, rule *models.ParsingRule, groupID int) (int, error) {
+	p.logger.Infof("Публикация поста %d в VK группу %d", post.ID, groupID)
 
 	// Подготавливаем контент
-	content := p.prepareContent(post)
+	content := p.prepareContent(post, rule)
 
 	// Создаем параметры для поста
 	b := params.NewWallPostBuilder()
 
-	b.OwnerID(-p.groupID) // Для групп используем отрицательный ID
+	b.OwnerID(-groupID) // Для групп используем отрицательный ID
 	b.Message(content)
 	b.FromGroup(true)
 
 	// Если есть медиа, добавляем его
-	if post.MediaURL != "" {
+	if post.IsAlbum() {
+		attachments, err := p.uploadAlbum(ctx, post, groupID)
+		if err != nil {
+			return 0, fmt.Errorf("ошибка загрузки медиа: %w", err)
+		}
+		if len(attachments) > 0 {
+			b.Attachments(strings.Join(attachments, ","))
+		}
+	} else if post.HasMedia() {
 		// Загружаем медиа и получаем attachment
-		attachment, err := p.uploadMedia(post)
+		attachment, err := p.uploadMedia(ctx, post, groupID)
 		if err != nil {
-			return fmt.Errorf("ошибка загрузки медиа: %v", err)
+			return 0, fmt.Errorf("ошибка загрузки медиа: %w", err)
 		}
 		if attachment != "" {
 			b.Attachments(attachment)
@@ -85,12 +209,99 @@ func (p *VKPublisher) Publish(ctx context.Context, post *models.Post) error {
 	}
 
 	// Публикуем пост
-	_, err := p.vk.WallPost(b.Params)
+	resp, err := p.vk.WallPost(b.Params.WithContext(ctx))
 	if err != nil {
-		return fmt.Errorf("ошибка публикации в VK: %v", err)
+		return 0, fmt.Errorf("ошибка публикации в VK: %w", err)
 	}
 
 	p.logger.Infof("Пост %d успешно опубликован в VK", post.ID)
+	return resp.PostID, nil
+}
+
+// publicationTarget возвращает группу и запись VK публикации
+func publicationTarget(pub *models.Publication) (int, int, error) {
+	groupID, err := parseGroupID(pub.Target)
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(pub.MessageIDs) == 0 {
+		return 0, 0, fmt.Errorf("у публикации %d нет ID записи VK", pub.ID)
+	}
+	return groupID, int(pub.MessageIDs[0]), nil
+}
+
+// Edit обновляет текст записи VK. Вложения записи сохраняются.
+func (p *VKPublisher) Edit(ctx context.Context, post *models.Post, rule *models.ParsingRule, pub *models.Publication) error {
+	groupID, postID, err := publicationTarget(pub)
+	if err != nil {
+		return err
+	}
+
+	// wall.edit заменяет вложения целиком, поэтому передаем текущие
+	attachments, err := p.wallAttachments(ctx, groupID, postID)
+	if err != nil {
+		return err
+	}
+
+	params := api.Params{
+		"owner_id": -groupID,
+		"post_id":  postID,
+		"message":  p.prepareContent(post, rule),
+	}
+	if len(attachments) > 0 {
+		params["attachments"] = strings.Join(attachments, ",")
+	}
+
+	if _, err := p.vk.WallEdit(params.WithContext(ctx)); err != nil {
+		return fmt.Errorf("ошибка редактирования записи VK: %w", err)
+	}
+
+	p.logger.Infof("Запись %d в VK группе %d обновлена", postID, groupID)
+	return nil
+}
+
+// wallAttachments возвращает вложения записи в формате attachments
+func (p *VKPublisher) wallAttachments(ctx context.Context, groupID, postID int) ([]string, error) {
+	resp, err := p.vk.WallGetByID(api.Params{
+		"posts": fmt.Sprintf("-%d_%d", groupID, postID),
+	}.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения записи VK: %w", err)
+	}
+	if len(resp) == 0 {
+		return nil, fmt.Errorf("запись VK -%d_%d не найдена", groupID, postID)
+	}
+
+	var attachments []string
+	for _, a := range resp[0].Attachments {
+		switch a.Type {
+		case "photo":
+			attachments = append(attachments, a.Photo.ToAttachment())
+		case "video":
+			attachments = append(attachments, a.Video.ToAttachment())
+		case "doc":
+			attachments = append(attachments, a.Doc.ToAttachment())
+		}
+	}
+	return attachments, nil
+}
+
+// Delete удаляет запись VK
+func (p *VKPublisher) Delete(ctx context.Context, pub *models.Publication) error {
+	groupID, postID, err := publicationTarget(pub)
+	if err != nil {
+		return err
+	}
+
+	_, err = p.vk.WallDelete(api.Params{
+		"owner_id": -groupID,
+		"post_id":  postID,
+	}.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("ошибка удаления записи VK: %w", err)
+	}
+
+	p.logger.Infof("Запись %d в VK группе %d удалена", postID, groupID)
 	return nil
 }
 
@@ -98,38 +309,66 @@ func (p *VKPublisher) Publish(ctx context.Context, post *models.Post) error {
 func (p *VKPublisher) TestConnection(ctx context.Context) error {
 	_, err := p.vk.GroupsGetByID(api.Params{
 		"group_ids": p.groupID,
-	})
+	}.WithContext(ctx))
 	if err != nil {
 		return fmt.Errorf("ошибка проверки подключения к VK: %v", err)
 	}
 	return nil
 }
 
-// prepareContent подготавливает контент для публикации
-func (p *VKPublisher) prepareContent(post *models.Post) string {
-	var content strings.Builder
+// prepareContent подготавливает контент для публикации по шаблону правила.
+// VK не поддерживает разметку в записях - оставляем текст и адреса ссылок.
+func (p *VKPublisher) prepareContent(post *models.Post, rule *models.ParsingRule) string {
+	content, err := FormatPost(post, rule, models.PlatformVK, rule.TemplateFor(models.PlatformVK))
+	if err != nil {
+		p.logger.Warnf("Ошибка шаблона VK для поста %d, используется шаблон по умолчанию: %v", post.ID, err)
+		content, _ = FormatPost(post, rule, models.PlatformVK, models.DefaultPostTemplate(models.PlatformVK))
+	}
+	return content
+}
 
-	content.WriteString(post.Content)
+// maxVKAttachments максимальное количество вложений в записи на стене VK
+const maxVKAttachments = 10
 
-	// Добавляем информацию об источнике
-	if post.SourceChannel != "" {
-		content.WriteString(fmt.Sprintf("\n\n📎 Источник: %s", post.SourceChannel))
+// uploadAlbum загружает файлы альбома и возвращает список attachment
+func (p *VKPublisher) uploadAlbum(ctx context.Context, post *models.Post, groupID int) ([]string, error) {
+	items := post.Media
+	if len(items) > maxVKAttachments {
+		p.logger.Warnf("В альбоме поста %d %d файлов, в VK будут опубликованы первые %d",
+			post.ID, len(items), maxVKAttachments)
+		items = items[:maxVKAttachments]
 	}
 
-	return content.String()
+	var attachments []string
+	for _, item := range items {
+		itemPost := *post
+		itemPost.MediaType = item.MediaType
+		itemPost.MediaURL = item.MediaURL
+		itemPost.MediaFile = item.MediaFile
+
+		attachment, err := p.uploadMedia(ctx, &itemPost, groupID)
+		if err != nil {
+			return nil, err
+		}
+		if attachment != "" {
+			attachments = append(attachments, attachment)
+		}
+	}
+
+	return attachments, nil
 }
 
 // uploadMedia загружает медиа файл и возвращает attachment
-func (p *VKPublisher) uploadMedia(post *models.Post) (string, error) {
-	p.logger.Infof("Загрузка медиа в VK: %s (тип: %s)", post.MediaURL, post.MediaType)
+func (p *VKPublisher) uploadMedia(ctx context.Context, post *models.Post, groupID int) (string, error) {
+	p.logger.Infof("Загрузка медиа в VK: %s%s (тип: %s)", post.MediaFile, post.MediaURL, post.MediaType)
 
 	switch post.MediaType {
 	case models.MediaPhoto:
-		return p.uploadPhoto(post)
+		return p.uploadPhoto(ctx, post, groupID)
 	case models.MediaVideo:
-		return p.uploadVideo(post)
+		return p.uploadVideo(ctx, post, groupID)
 	case models.MediaDocument:
-		return p.uploadDocument(post)
+		return p.uploadDocument(ctx, post, groupID)
 	default:
 		p.logger.Warnf("Неподдерживаемый тип медиа для VK: %s", post.MediaType)
 		return "", nil
@@ -137,30 +376,30 @@ func (p *VKPublisher) uploadMedia(post *models.Post) (string, error) {
 }
 
 // uploadPhoto загружает фото и возвращает attachment
-func (p *VKPublisher) uploadPhoto(post *models.Post) (string, error) {
+func (p *VKPublisher) uploadPhoto(ctx context.Context, post *models.Post, groupID int) (string, error) {
 	// 1. Получаем URL для загрузки
 	uploadServer, err := p.vk.PhotosGetWallUploadServer(api.Params{
-		"group_id": p.groupID,
-	})
+		"group_id": groupID,
+	}.WithContext(ctx))
 	if err != nil {
-		return "", fmt.Errorf("ошибка получения upload server: %v", err)
+		return "", fmt.Errorf("ошибка получения upload server: %w", err)
 	}
 
 	// 2. Загружаем файл на сервер
-	photoData, err := p.uploadFileToVK(uploadServer.UploadURL, post.MediaURL, "photo")
+	photoData, err := p.uploadFileToVK(ctx, uploadServer.UploadURL, post, "photo")
 	if err != nil {
 		return "", fmt.Errorf("ошибка загрузки фото: %v", err)
 	}
 
 	// 3. Сохраняем фото в альбом группы
 	savedPhoto, err := p.vk.PhotosSaveWallPhoto(api.Params{
-		"group_id": p.groupID,
+		"group_id": groupID,
 		"photo":    photoData.Photo,
 		"server":   photoData.Server,
 		"hash":     photoData.Hash,
-	})
+	}.WithContext(ctx))
 	if err != nil {
-		return "", fmt.Errorf("ошибка сохранения фото: %v", err)
+		return "", fmt.Errorf("ошибка сохранения фото: %w", err)
 	}
 
 	if len(savedPhoto) == 0 {
@@ -173,19 +412,19 @@ func (p *VKPublisher) uploadPhoto(post *models.Post) (string, error) {
 }
 
 // uploadVideo загружает видео и возвращает attachment
-func (p *VKPublisher) uploadVideo(post *models.Post) (string, error) {
+func (p *VKPublisher) uploadVideo(ctx context.Context, post *models.Post, groupID int) (string, error) {
 	// 1. Получаем URL для загрузки
 	uploadServer, err := p.vk.VideoSave(api.Params{
-		"group_id":    p.groupID,
+		"group_id":    groupID,
 		"name":        "Video from parser",
 		"description": fmt.Sprintf("Source: %s", post.SourceChannel),
-	})
+	}.WithContext(ctx))
 	if err != nil {
-		return "", fmt.Errorf("ошибка получения upload server для видео: %v", err)
+		return "", fmt.Errorf("ошибка получения upload server для видео: %w", err)
 	}
 
 	// 2. Загружаем файл на сервер
-	_, err = p.uploadFileToVK(uploadServer.UploadURL, post.MediaURL, "video_file")
+	_, err = p.uploadFileToVK(ctx, uploadServer.UploadURL, post, "video_file")
 	if err != nil {
 		return "", fmt.Errorf("ошибка загрузки видео: %v", err)
 	}
@@ -196,17 +435,17 @@ func (p *VKPublisher) uploadVideo(post *models.Post) (string, error) {
 }
 
 // uploadDocument загружает документ и возвращает attachment
-func (p *VKPublisher) uploadDocument(post *models.Post) (string, error) {
+func (p *VKPublisher) uploadDocument(ctx context.Context, post *models.Post, groupID int) (string, error) {
 	// 1. Получаем URL для загрузки
 	uploadServer, err := p.vk.DocsGetWallUploadServer(api.Params{
-		"group_id": p.groupID,
-	})
+		"group_id": groupID,
+	}.WithContext(ctx))
 	if err != nil {
-		return "", fmt.Errorf("ошибка получения upload server для документа: %v", err)
+		return "", fmt.Errorf("ошибка получения upload server для документа: %w", err)
 	}
 
 	// 2. Загружаем файл на сервер
-	docData, err := p.uploadFileToVK(uploadServer.UploadURL, post.MediaURL, "file")
+	docData, err := p.uploadFileToVK(ctx, uploadServer.UploadURL, post, "file")
 	if err != nil {
 		return "", fmt.Errorf("ошибка загрузки документа: %v", err)
 	}
@@ -215,9 +454,9 @@ func (p *VKPublisher) uploadDocument(post *models.Post) (string, error) {
 	savedDoc, err := p.vk.DocsSave(api.Params{
 		"file":  docData.File,
 		"title": "Document from parser",
-	})
+	}.WithContext(ctx))
 	if err != nil {
-		return "", fmt.Errorf("ошибка сохранения документа: %v", err)
+		return "", fmt.Errorf("ошибка сохранения документа: %w", err)
 	}
 
 	// Проверяем что документ сохранен
@@ -229,39 +468,72 @@ func (p *VKPublisher) uploadDocument(post *models.Post) (string, error) {
 	return fmt.Sprintf("doc%d_%d", savedDoc.Doc.OwnerID, savedDoc.Doc.ID), nil
 }
 
-// uploadFileToVK загружает файл по URL на сервер VK
-func (p *VKPublisher) uploadFileToVK(uploadURL, fileURL, fieldName string) (*VKUploadResponse, error) {
-	// 1. Скачиваем файл по URL
-	downloadResp, err := http.Get(fileURL)
+// maxVKFileSize максимальный размер файла для загрузки в VK
+const maxVKFileSize = 50 * 1024 * 1024
+
+// readMedia читает файл медиа: с диска из кэша, если он загружен, иначе по URL
+func (p *VKPublisher) readMedia(ctx context.Context, post *models.Post) ([]byte, string, error) {
+	if post.MediaFile != "" {
+		info, err := os.Stat(post.MediaFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("ошибка открытия файла медиа: %v", err)
+		}
+		if info.Size() > maxVKFileSize {
+			return nil, "", fmt.Errorf("файл слишком большой: %d bytes", info.Size())
+		}
+
+		fileData, err := os.ReadFile(post.MediaFile)
+		if err != nil {
+			return nil, "", fmt.Errorf("ошибка чтения файла: %v", err)
+		}
+		return fileData, filepath.Base(post.MediaFile), nil
+	}
+
+	// Скачиваем файл по URL
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, post.MediaURL, nil)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка скачивания файла: %v", err)
+		return nil, "", fmt.Errorf("ошибка создания запроса: %v", err)
+	}
+	downloadResp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", fmt.Errorf("ошибка скачивания файла: %v", err)
 	}
 	defer downloadResp.Body.Close()
 
 	if downloadResp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("ошибка HTTP при скачивании: %s", downloadResp.Status)
+		return nil, "", fmt.Errorf("ошибка HTTP при скачивании: %s", downloadResp.Status)
+	}
+
+	// Читаем не больше лимита + 1 байт, чтобы отличить слишком большой файл
+	fileData, err := io.ReadAll(io.LimitReader(downloadResp.Body, maxVKFileSize+1))
+	if err != nil {
+		return nil, "", fmt.Errorf("ошибка чтения файла: %v", err)
 	}
+	return fileData, "file", nil
+}
 
-	// 2. Читаем содержимое файла
-	fileData, err := io.ReadAll(downloadResp.Body)
+// uploadFileToVK загружает файл медиа поста на сервер VK
+func (p *VKPublisher) uploadFileToVK(ctx context.Context, uploadURL string, post *models.Post, fieldName string) (*VKUploadResponse, error) {
+	// 1. Получаем содержимое файла
+	fileData, fileName, err := p.readMedia(ctx, post)
 	if err != nil {
-		return nil, fmt.Errorf("ошибка чтения файла: %v", err)
+		return nil, err
 	}
 
-	// 3. Проверяем размер файла
+	// 2. Проверяем размер файла
 	if len(fileData) == 0 {
 		return nil, fmt.Errorf("файл пустой или не загружен")
 	}
-	if len(fileData) > 50*1024*1024 { // 50MB limit
+	if len(fileData) > maxVKFileSize {
 		return nil, fmt.Errorf("файл слишком большой: %d bytes", len(fileData))
 	}
 
-	// 4. Создаем multipart форму
+	// 3. Создаем multipart форму
 	var requestBody bytes.Buffer
 	writer := multipart.NewWriter(&requestBody)
 
 	// Создаем поле для файла
-	part, err := writer.CreateFormFile(fieldName, "file")
+	part, err := writer.CreateFormFile(fieldName, fileName)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка создания формы: %v", err)
 	}
@@ -276,8 +548,8 @@ func (p *VKPublisher) uploadFileToVK(uploadURL, fileURL, fieldName string) (*VKU
 		return nil, fmt.Errorf("ошибка закрытия формы: %v", err)
 	}
 
-	// 5. Отправляем файл на сервер VK
-	req, err := http.NewRequest("POST", uploadURL, &requestBody)
+	// 4. Отправляем файл на сервер VK
+	req, err := http.NewRequestWithContext(ctx, "POST", uploadURL, &requestBody)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка создания запроса: %v", err)
 	}
@@ -291,13 +563,13 @@ func (p *VKPublisher) uploadFileToVK(uploadURL, fileURL, fieldName string) (*VKU
 	}
 	defer uploadResp.Body.Close() // Закрываем Body ответа
 
-	// 6. Читаем ответ от сервера VK
+	// 5. Читаем ответ от сервера VK
 	responseData, err := io.ReadAll(uploadResp.Body)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка чтения ответа: %v", err)
 	}
 
-	// 7. Парсим ответ
+	// 6. Парсим ответ
 	var uploadResponse VKUploadResponse
 	if err := json.Unmarshal(responseData, &uploadResponse); err != nil {
 		return nil, fmt.Errorf("ошибка парсинга ответа: %v", err)
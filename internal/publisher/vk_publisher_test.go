@@ -0,0 +1,58 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SevereCloud/vksdk/v2/api"
+)
+
+func TestTooManyRequests(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		wantWait time.Duration
+		wantOK   bool
+	}{
+		{name: "nil", err: nil},
+		{name: "обычная ошибка", err: errors.New("boom")},
+		{name: "другая ошибка VK", err: &api.Error{Code: api.ErrAccess}},
+		{name: "ошибка 6", err: &api.Error{Code: api.ErrTooMany}, wantWait: time.Second, wantOK: true},
+		{name: "код ошибки 6", err: api.ErrTooMany, wantWait: time.Second, wantOK: true},
+		{
+			name:     "обернутая ошибка 6",
+			err:      fmt.Errorf("ошибка публикации: %w", &api.Error{Code: api.ErrTooMany}),
+			wantWait: time.Second,
+			wantOK:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			wait, ok := tooManyRequests(tt.err)
+			if ok != tt.wantOK || wait != tt.wantWait {
+				t.Errorf("tooManyRequests() = (%v, %v), want (%v, %v)", wait, ok, tt.wantWait, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "request")
+
+	if got := requestContext(nil); got != context.Background() {
+		t.Errorf("без параметров = %v, want context.Background()", got)
+	}
+	if got := requestContext([]api.Params{{"group_id": 1}}); got != context.Background() {
+		t.Errorf("без контекста = %v, want context.Background()", got)
+	}
+
+	params := api.Params{"group_id": 1}.WithContext(ctx)
+	if got := requestContext([]api.Params{params}); got.Value(key{}) != "request" {
+		t.Error("контекст запроса не передан лимитеру")
+	}
+}
@@ -0,0 +1,182 @@
+package publisher
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/drerr0r/tgparserbot/internal/ratelimit"
+	"github.com/drerr0r/tgparserbot/internal/storage"
+	"go.uber.org/zap"
+)
+
+// jobLockTimeout время, после которого задача упавшего воркера снова
+// становится доступной. С запасом на загрузку больших видео.
+const jobLockTimeout = 10 * time.Minute
+
+// Worker воркеры очереди публикации
+type Worker struct {
+	jobRepo  *storage.JobRepository
+	postRepo *storage.PostRepository
+	ruleRepo *storage.RuleRepository
+	multi    *MultiPublisher
+	cfg      models.PublishConfig
+	logger   *zap.SugaredLogger
+}
+
+// NewWorker создает воркеры очереди публикации
+func NewWorker(
+	jobRepo *storage.JobRepository,
+	postRepo *storage.PostRepository,
+	ruleRepo *storage.RuleRepository,
+	multi *MultiPublisher,
+	cfg models.PublishConfig,
+	logger *zap.SugaredLogger,
+) *Worker {
+	return &Worker{
+		jobRepo:  jobRepo,
+		postRepo: postRepo,
+		ruleRepo: ruleRepo,
+		multi:    multi,
+		cfg:      cfg,
+		logger:   logger,
+	}
+}
+
+// Run запускает cfg.Workers воркеров и ждет их завершения после отмены контекста
+func (w *Worker) Run(ctx context.Context) {
+	workers := w.cfg.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	w.logger.Infof("🚀 Запуск очереди публикации: %d воркеров", workers)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.loop(ctx)
+		}()
+	}
+	wg.Wait()
+
+	w.logger.Info("🛑 Очередь публикации остановлена")
+}
+
+// loop выбирает задачи, пока они есть, затем ждет следующей проверки
+func (w *Worker) loop(ctx context.Context) {
+	interval := time.Duration(w.cfg.PollSeconds) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	for {
+		jobs, err := w.jobRepo.Claim(ctx, 1, jobLockTimeout)
+		if err != nil {
+			w.logger.Errorf("❌ %v", err)
+		}
+
+		if len(jobs) > 0 {
+			w.process(ctx, jobs[0])
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// process публикует пост задачи и обновляет ее статус
+func (w *Worker) process(ctx context.Context, job *models.PublishJob) {
+	err := w.publish(ctx, job)
+	if err == nil {
+		if err := w.jobRepo.Complete(ctx, job.ID); err != nil {
+			w.logger.Errorf("❌ %v", err)
+		}
+		return
+	}
+
+	// Ограничение частоты запросов - не ошибка, откладываем задачу без траты попытки
+	if waitErr, ok := ratelimit.AsWait(err); ok {
+		w.logger.Warnf("⏳ Задача %d (пост %d, %s) отложена на %v: %v",
+			job.ID, job.PostID, job.Platform, waitErr.Wait, err)
+		if err := w.jobRepo.Postpone(ctx, job.ID, time.Now().Add(waitErr.Wait), err.Error()); err != nil {
+			w.logger.Errorf("❌ %v", err)
+		}
+		return
+	}
+
+	// Повтор опубликовал бы пост в то же назначение второй раз. Задача
+	// останавливается и при отмене контекста, иначе ее подберут снова.
+	if errors.Is(err, ErrPublicationNotSaved) {
+		w.logger.Errorf("💀 Задача %d (пост %d, %s) остановлена без повтора: %v",
+			job.ID, job.PostID, job.Platform, err)
+		killCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := w.jobRepo.Kill(killCtx, job.ID, err.Error()); err != nil {
+			w.logger.Errorf("❌ %v", err)
+		}
+		return
+	}
+
+	if job.Attempts >= w.cfg.MaxAttempts {
+		w.logger.Errorf("💀 Задача %d (пост %d, %s) исчерпала %d попыток: %v",
+			job.ID, job.PostID, job.Platform, job.Attempts, err)
+		if err := w.jobRepo.Kill(ctx, job.ID, err.Error()); err != nil {
+			w.logger.Errorf("❌ %v", err)
+		}
+		return
+	}
+
+	delay := w.backoff(job.Attempts)
+	w.logger.Warnf("⚠️ Задача %d (пост %d, %s), попытка %d: %v. Повтор через %v",
+		job.ID, job.PostID, job.Platform, job.Attempts, err, delay)
+	if err := w.jobRepo.Retry(ctx, job.ID, time.Now().Add(delay), err.Error()); err != nil {
+		w.logger.Errorf("❌ %v", err)
+	}
+}
+
+// publish загружает пост и правило задачи и публикует пост на платформу
+func (w *Worker) publish(ctx context.Context, job *models.PublishJob) error {
+	post, err := w.postRepo.GetByID(ctx, job.PostID)
+	if err != nil {
+		return err
+	}
+	if post == nil {
+		return fmt.Errorf("пост %d не найден", job.PostID)
+	}
+
+	rule, err := w.ruleRepo.GetByID(ctx, post.RuleID)
+	if err != nil {
+		return err
+	}
+	if rule == nil {
+		return fmt.Errorf("правило %d поста %d не найдено", post.RuleID, post.ID)
+	}
+
+	return w.multi.PublishPlatform(ctx, post, rule, job.Platform)
+}
+
+// backoff возвращает задержку перед следующей попыткой: базовая задержка,
+// удваиваемая с каждой попыткой, но не больше максимальной
+func (w *Worker) backoff(attempts int) time.Duration {
+	base := time.Duration(w.cfg.RetryBaseSeconds) * time.Second
+	maxDelay := time.Duration(w.cfg.RetryMaxMinutes) * time.Minute
+
+	delay := base
+	for i := 1; i < attempts && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
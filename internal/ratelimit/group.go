@@ -0,0 +1,70 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+)
+
+// Group набор лимитеров с одинаковыми параметрами по ключу, например
+// отдельный лимит на каждый чат
+type Group struct {
+	name  string
+	rate  float64
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*Limiter
+}
+
+// NewGroup создает группу лимитеров и регистрирует ее в метриках
+func NewGroup(name string, rate float64, burst int) *Group {
+	g := &Group{
+		name:     name,
+		rate:     rate,
+		burst:    burst,
+		limiters: make(map[string]*Limiter),
+	}
+	register(g)
+	return g
+}
+
+// Get возвращает лимитер для ключа, создавая его при первом обращении
+func (g *Group) Get(key string) *Limiter {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	l, ok := g.limiters[key]
+	if !ok {
+		l = newLimiter(g.name+":"+key, g.rate, g.burst)
+		g.limiters[key] = l
+	}
+	return l
+}
+
+// Wait ждет свободный токен для ключа
+func (g *Group) Wait(ctx context.Context, key string) error {
+	return g.Get(key).Wait(ctx)
+}
+
+// Stats возвращает суммарное состояние лимитеров группы
+func (g *Group) Stats() Stats {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	stats := Stats{
+		Name:  g.name,
+		Rate:  g.rate,
+		Burst: g.burst,
+		Keys:  len(g.limiters),
+	}
+	for _, l := range g.limiters {
+		s := l.Stats()
+		stats.Waits += s.Waits
+		stats.Throttled += s.Throttled
+		stats.WaitedSec += s.WaitedSec
+		if s.PausedUntil.After(stats.PausedUntil) {
+			stats.PausedUntil = s.PausedUntil
+		}
+	}
+	return stats
+}
@@ -0,0 +1,224 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// MaxInlineWait максимальное время ожидания, которое выдерживается на месте.
+// При более долгом ограничении от сервера работа откладывается через WaitError.
+const MaxInlineWait = time.Minute
+
+// maxRetries сколько раз повторяется запрос после ограничения от сервера
+const maxRetries = 5
+
+// WaitError сервер ограничил запросы дольше MaxInlineWait. Работу нужно
+// отложить на Wait, а не считать ошибкой.
+type WaitError struct {
+	Limiter string
+	Wait    time.Duration
+	Err     error
+}
+
+func (e *WaitError) Error() string {
+	return fmt.Sprintf("превышен лимит запросов %s, ожидание %v: %v", e.Limiter, e.Wait, e.Err)
+}
+
+func (e *WaitError) Unwrap() error {
+	return e.Err
+}
+
+// AsWait проверяет, является ли ошибка ограничением по частоте запросов
+func AsWait(err error) (*WaitError, bool) {
+	var waitErr *WaitError
+	if errors.As(err, &waitErr) {
+		return waitErr, true
+	}
+	return nil, false
+}
+
+// Classifier возвращает время ожидания, если ошибка означает превышение лимита
+type Classifier func(err error) (time.Duration, bool)
+
+// clock источник времени лимитера, подменяется в тестах
+type clock interface {
+	Now() time.Time
+	Sleep(ctx context.Context, d time.Duration) error
+}
+
+// realClock системное время
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+// Sleep ждет d или отмену контекста
+func (realClock) Sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Limiter token bucket с паузой на время, указанное сервером
+type Limiter struct {
+	name  string
+	rate  float64 // токенов в секунду
+	burst float64
+	clock clock
+
+	mu          sync.Mutex
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+	waits       int64
+	throttled   int64
+	waited      time.Duration
+}
+
+// New создает лимитер на rate запросов в секунду с запасом burst и
+// регистрирует его в метриках
+func New(name string, rate float64, burst int) *Limiter {
+	l := newLimiter(name, rate, burst)
+	register(l)
+	return l
+}
+
+func newLimiter(name string, rate float64, burst int) *Limiter {
+	return newLimiterWithClock(name, rate, burst, realClock{})
+}
+
+func newLimiterWithClock(name string, rate float64, burst int, c clock) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &Limiter{
+		name:   name,
+		rate:   rate,
+		burst:  float64(burst),
+		clock:  c,
+		tokens: float64(burst),
+		last:   c.Now(),
+	}
+}
+
+// Wait ждет свободный токен или отмену контекста
+func (l *Limiter) Wait(ctx context.Context) error {
+	for {
+		delay := l.reserve()
+		if delay <= 0 {
+			return nil
+		}
+
+		if err := l.clock.Sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// reserve забирает токен, если он есть, иначе возвращает время до его появления
+func (l *Limiter) reserve() time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock.Now()
+	if now.Before(l.pausedUntil) {
+		delay := l.pausedUntil.Sub(now)
+		l.waits++
+		l.waited += delay
+		return delay
+	}
+
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.burst {
+		l.tokens = l.burst
+	}
+	l.last = now
+
+	if l.tokens >= 1 {
+		l.tokens--
+		return 0
+	}
+
+	delay := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+	l.waits++
+	l.waited += delay
+	return delay
+}
+
+// Pause останавливает выдачу токенов на время, указанное сервером
+func (l *Limiter) Pause(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.throttled++
+	l.tokens = 0
+	if until := l.clock.Now().Add(d); until.After(l.pausedUntil) {
+		l.pausedUntil = until
+	}
+}
+
+// Do выполняет fn, дожидаясь токена. Если classify распознает в ошибке
+// ограничение сервера, лимитер встает на паузу и запрос повторяется.
+// Ожидание дольше MaxInlineWait возвращается как WaitError.
+func (l *Limiter) Do(ctx context.Context, classify Classifier, fn func() error) error {
+	for attempt := 0; ; attempt++ {
+		if err := l.Wait(ctx); err != nil {
+			return err
+		}
+
+		err := fn()
+		if err == nil {
+			return nil
+		}
+
+		wait, ok := classify(err)
+		if !ok {
+			return err
+		}
+
+		l.Pause(wait)
+		if wait > MaxInlineWait || attempt >= maxRetries {
+			return &WaitError{Limiter: l.name, Wait: wait, Err: err}
+		}
+	}
+}
+
+// Stats состояние лимитера для метрик
+type Stats struct {
+	Name        string    `json:"name"`
+	Rate        float64   `json:"rate"`
+	Burst       int       `json:"burst"`
+	Tokens      float64   `json:"tokens"`
+	Keys        int       `json:"keys,omitempty"`
+	Waits       int64     `json:"waits"`        // сколько раз пришлось ждать токен
+	Throttled   int64     `json:"throttled"`    // сколько раз сервер вернул ограничение
+	WaitedSec   float64   `json:"waited_sec"`   // суммарное время ожидания
+	PausedUntil time.Time `json:"paused_until"` // пауза по ограничению сервера
+}
+
+// Stats возвращает текущее состояние лимитера
+func (l *Limiter) Stats() Stats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return Stats{
+		Name:        l.name,
+		Rate:        l.rate,
+		Burst:       int(l.burst),
+		Tokens:      l.tokens,
+		Waits:       l.waits,
+		Throttled:   l.throttled,
+		WaitedSec:   l.waited.Seconds(),
+		PausedUntil: l.pausedUntil,
+	}
+}
@@ -0,0 +1,303 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeClock время, которое сдвигается только ожиданием лимитера
+type fakeClock struct {
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.now = c.now.Add(d)
+}
+
+func (c *fakeClock) slept() time.Duration {
+	var total time.Duration
+	for _, d := range c.sleeps {
+		total += d
+	}
+	return total
+}
+
+func TestLimiterReserve(t *testing.T) {
+	tests := []struct {
+		name    string
+		rate    float64
+		burst   int
+		taken   int           // токенов забрано до проверки
+		elapsed time.Duration // прошло времени после этого
+		want    time.Duration
+	}{
+		{name: "запас burst", rate: 1, burst: 3, taken: 2, want: 0},
+		{name: "запас исчерпан", rate: 1, burst: 3, taken: 3, want: time.Second},
+		{name: "частота 4 в секунду", rate: 4, burst: 1, taken: 1, want: 250 * time.Millisecond},
+		{name: "частичное пополнение", rate: 1, burst: 1, taken: 1, elapsed: 400 * time.Millisecond, want: 600 * time.Millisecond},
+		{name: "полное пополнение", rate: 1, burst: 1, taken: 1, elapsed: time.Second, want: 0},
+		{name: "пополнение не выше burst", rate: 10, burst: 2, taken: 2, elapsed: time.Hour, want: 0},
+		{name: "burst не меньше 1", rate: 1, burst: 0, taken: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClock()
+			l := newLimiterWithClock("test", tt.rate, tt.burst, c)
+			for i := 0; i < tt.taken; i++ {
+				if delay := l.reserve(); delay != 0 {
+					t.Fatalf("токен %d: ожидание %v, ожидался запас", i, delay)
+				}
+			}
+			c.Advance(tt.elapsed)
+
+			if got := l.reserve(); got != tt.want {
+				t.Errorf("reserve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterBurstCap(t *testing.T) {
+	c := newFakeClock()
+	l := newLimiterWithClock("test", 10, 2, c)
+	c.Advance(time.Hour)
+
+	for i := 0; i < 2; i++ {
+		if delay := l.reserve(); delay != 0 {
+			t.Fatalf("токен %d: ожидание %v", i, delay)
+		}
+	}
+	if delay := l.reserve(); delay != 100*time.Millisecond {
+		t.Errorf("после burst ожидание %v, want 100ms", delay)
+	}
+}
+
+func TestLimiterWait(t *testing.T) {
+	c := newFakeClock()
+	l := newLimiterWithClock("test", 2, 1, c)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := l.Wait(ctx); err != nil {
+			t.Fatalf("Wait: %v", err)
+		}
+	}
+	if got, want := c.slept(), time.Second; got != want {
+		t.Errorf("ожидание %v, want %v", got, want)
+	}
+
+	stats := l.Stats()
+	if stats.Waits != 2 {
+		t.Errorf("Waits = %d, want 2", stats.Waits)
+	}
+}
+
+func TestLimiterWaitCanceled(t *testing.T) {
+	c := newFakeClock()
+	l := newLimiterWithClock("test", 1, 1, c)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Свободный токен выдается и при отмененном контексте
+	if err := l.Wait(ctx); err != nil {
+		t.Fatalf("Wait с запасом: %v", err)
+	}
+	if err := l.Wait(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("Wait без токена = %v, want context.Canceled", err)
+	}
+}
+
+func TestLimiterPause(t *testing.T) {
+	c := newFakeClock()
+	l := newLimiterWithClock("test", 100, 10, c)
+
+	l.Pause(5 * time.Second)
+	// Более короткая пауза не сокращает уже назначенную
+	l.Pause(time.Second)
+
+	if got := l.reserve(); got != 5*time.Second {
+		t.Errorf("reserve() на паузе = %v, want 5s", got)
+	}
+	c.Advance(5 * time.Second)
+	if got := l.reserve(); got != 0 {
+		t.Errorf("reserve() после паузы = %v, want 0", got)
+	}
+
+	stats := l.Stats()
+	if stats.Throttled != 2 {
+		t.Errorf("Throttled = %d, want 2", stats.Throttled)
+	}
+}
+
+var errLimited = errors.New("limited")
+
+// classifyLimited распознает errLimited как ограничение на wait
+func classifyLimited(wait time.Duration) Classifier {
+	return func(err error) (time.Duration, bool) {
+		if errors.Is(err, errLimited) {
+			return wait, true
+		}
+		return 0, false
+	}
+}
+
+func TestLimiterDo(t *testing.T) {
+	errOther := errors.New("other")
+
+	tests := []struct {
+		name      string
+		wait      time.Duration
+		results   []error // ответы fn по попыткам, дальше nil
+		wantCalls int
+		wantErr   error
+		wantWait  bool          // ошибка должна быть WaitError
+		wantSlept time.Duration // сколько лимитер ждал на месте
+	}{
+		{
+			name:      "успех с первой попытки",
+			wantCalls: 1,
+		},
+		{
+			name:      "обычная ошибка не повторяется",
+			results:   []error{errOther},
+			wantCalls: 1,
+			wantErr:   errOther,
+		},
+		{
+			name:      "повтор после ограничения",
+			wait:      3 * time.Second,
+			results:   []error{errLimited, errLimited},
+			wantCalls: 3,
+			wantSlept: 6 * time.Second,
+		},
+		{
+			name:      "ограничение ровно MaxInlineWait ждется на месте",
+			wait:      MaxInlineWait,
+			results:   []error{errLimited},
+			wantCalls: 2,
+			wantSlept: MaxInlineWait,
+		},
+		{
+			name:      "ограничение дольше MaxInlineWait",
+			wait:      MaxInlineWait + time.Second,
+			results:   []error{errLimited},
+			wantCalls: 1,
+			wantErr:   errLimited,
+			wantWait:  true,
+		},
+		{
+			name:      "попытки закончились",
+			wait:      time.Second,
+			results:   []error{errLimited, errLimited, errLimited, errLimited, errLimited, errLimited, errLimited},
+			wantCalls: maxRetries + 1,
+			wantErr:   errLimited,
+			wantWait:  true,
+			wantSlept: maxRetries * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newFakeClock()
+			l := newLimiterWithClock("test", 100, 100, c)
+
+			calls := 0
+			err := l.Do(context.Background(), classifyLimited(tt.wait), func() error {
+				calls++
+				if calls <= len(tt.results) {
+					return tt.results[calls-1]
+				}
+				return nil
+			})
+
+			if calls != tt.wantCalls {
+				t.Errorf("вызовов fn = %d, want %d", calls, tt.wantCalls)
+			}
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("Do() = %v, want %v", err, tt.wantErr)
+			}
+			waitErr, ok := AsWait(err)
+			if ok != tt.wantWait {
+				t.Fatalf("AsWait() = %v, want %v", ok, tt.wantWait)
+			}
+			if ok && (waitErr.Wait != tt.wait || waitErr.Limiter != "test") {
+				t.Errorf("WaitError = %+v, want wait %v", waitErr, tt.wait)
+			}
+			if got := c.slept(); got != tt.wantSlept {
+				t.Errorf("ожидание %v, want %v", got, tt.wantSlept)
+			}
+		})
+	}
+}
+
+func TestLimiterDoCanceledDuringPause(t *testing.T) {
+	c := newFakeClock()
+	l := newLimiterWithClock("test", 100, 100, c)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	calls := 0
+	err := l.Do(ctx, classifyLimited(time.Second), func() error {
+		calls++
+		cancel()
+		return errLimited
+	})
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Do() = %v, want context.Canceled", err)
+	}
+	if calls != 1 {
+		t.Errorf("вызовов fn = %d, want 1", calls)
+	}
+}
+
+func TestAsWait(t *testing.T) {
+	waitErr := &WaitError{Limiter: "test", Wait: time.Minute, Err: errLimited}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "nil", err: nil, want: false},
+		{name: "обычная ошибка", err: errLimited, want: false},
+		{name: "WaitError", err: waitErr, want: true},
+		{name: "обернутая WaitError", err: errors.Join(errors.New("publish"), waitErr), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := AsWait(tt.err)
+			if ok != tt.want {
+				t.Fatalf("AsWait() = %v, want %v", ok, tt.want)
+			}
+			if ok && got != waitErr {
+				t.Errorf("AsWait() вернул %v", got)
+			}
+		})
+	}
+
+	if !errors.Is(waitErr, errLimited) {
+		t.Error("WaitError должна раскрываться в исходную ошибку")
+	}
+}
@@ -0,0 +1,47 @@
+package ratelimit
+
+import (
+	"expvar"
+	"sort"
+	"sync"
+)
+
+// statser источник метрик лимитера
+type statser interface {
+	Stats() Stats
+}
+
+var (
+	registryMu sync.Mutex
+	registry   []statser
+)
+
+func init() {
+	// Состояние лимитеров доступно в /debug/vars под ключом ratelimit
+	expvar.Publish("ratelimit", expvar.Func(func() interface{} {
+		return Snapshot()
+	}))
+}
+
+// register добавляет лимитер в метрики
+func register(s statser) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, s)
+}
+
+// Snapshot возвращает состояние всех лимитеров, отсортированное по имени
+func Snapshot() []Stats {
+	registryMu.Lock()
+	sources := append([]statser(nil), registry...)
+	registryMu.Unlock()
+
+	stats := make([]Stats, 0, len(sources))
+	for _, s := range sources {
+		stats = append(stats, s.Stats())
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
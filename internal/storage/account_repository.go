@@ -0,0 +1,192 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// accountColumns список колонок аккаунта в порядке сканирования scanAccount
+const accountColumns = `id, phone, session_file, status, flood_wait_until, last_error, is_active, created_at, updated_at`
+
+// AccountRepository репозиторий аккаунтов Telegram и назначенных им каналов
+type AccountRepository struct {
+	db *DB
+}
+
+// NewAccountRepository создает новый репозиторий аккаунтов
+func NewAccountRepository(db *DB) *AccountRepository {
+	return &AccountRepository{db: db}
+}
+
+// scanAccount сканирует строку результата в аккаунт
+func scanAccount(row pgx.Row) (*models.TelegramAccount, error) {
+	var account models.TelegramAccount
+	err := row.Scan(
+		&account.ID,
+		&account.Phone,
+		&account.SessionFile,
+		&account.Status,
+		&account.FloodWaitUntil,
+		&account.LastError,
+		&account.IsActive,
+		&account.CreatedAt,
+		&account.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &account, nil
+}
+
+// List возвращает все аккаунты в порядке добавления
+func (r *AccountRepository) List(ctx context.Context) ([]*models.TelegramAccount, error) {
+	query := `SELECT ` + accountColumns + ` FROM telegram_accounts ORDER BY id`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения аккаунтов: %v", err)
+	}
+	defer rows.Close()
+
+	var accounts []*models.TelegramAccount
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения аккаунта: %v", err)
+		}
+		accounts = append(accounts, account)
+	}
+	return accounts, rows.Err()
+}
+
+// GetByID возвращает аккаунт по ID, nil - аккаунт не найден
+func (r *AccountRepository) GetByID(ctx context.Context, id int64) (*models.TelegramAccount, error) {
+	query := `SELECT ` + accountColumns + ` FROM telegram_accounts WHERE id = $1`
+
+	account, err := scanAccount(r.db.Pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения аккаунта %d: %v", id, err)
+	}
+	return account, nil
+}
+
+// Create добавляет аккаунт в пул
+func (r *AccountRepository) Create(ctx context.Context, account *models.TelegramAccount) error {
+	query := `
+		INSERT INTO telegram_accounts (phone, session_file, is_active)
+		VALUES ($1, $2, $3)
+		RETURNING ` + accountColumns
+
+	created, err := scanAccount(r.db.Pool.QueryRow(ctx, query, account.Phone, account.SessionFile, account.IsActive))
+	if err != nil {
+		return fmt.Errorf("ошибка создания аккаунта: %v", err)
+	}
+	*account = *created
+	return nil
+}
+
+// Ensure возвращает аккаунт с этим номером, добавляя его в пул при
+// первом запуске. Так аккаунт из конфигурации попадает в пул.
+func (r *AccountRepository) Ensure(ctx context.Context, phone, sessionFile string) (*models.TelegramAccount, error) {
+	query := `
+		INSERT INTO telegram_accounts (phone, session_file)
+		VALUES ($1, $2)
+		ON CONFLICT (phone) DO UPDATE SET phone = EXCLUDED.phone
+		RETURNING ` + accountColumns
+
+	account, err := scanAccount(r.db.Pool.QueryRow(ctx, query, phone, sessionFile))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка добавления аккаунта %s: %v", phone, err)
+	}
+	return account, nil
+}
+
+// SetActive включает или выключает аккаунт. Включение сбрасывает состояние,
+// чтобы пул снова попробовал запустить аккаунт. Возвращает false, если
+// аккаунт не найден.
+func (r *AccountRepository) SetActive(ctx context.Context, id int64, active bool) (bool, error) {
+	query := `
+		UPDATE telegram_accounts
+		SET is_active = $2,
+			status = CASE WHEN $2 THEN 'active' ELSE status END,
+			flood_wait_until = CASE WHEN $2 THEN NULL ELSE flood_wait_until END,
+			updated_at = NOW()
+		WHERE id = $1
+	`
+
+	result, err := r.db.Pool.Exec(ctx, query, id, active)
+	if err != nil {
+		return false, fmt.Errorf("ошибка изменения аккаунта %d: %v", id, err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// SetStatus сохраняет состояние аккаунта. floodWaitUntil учитывается
+// только для статуса flood_wait.
+func (r *AccountRepository) SetStatus(ctx context.Context, id int64, status models.AccountStatus, floodWaitUntil *time.Time, lastError string) error {
+	query := `
+		UPDATE telegram_accounts
+		SET status = $2, flood_wait_until = $3, last_error = $4, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if status != models.AccountFloodWait {
+		floodWaitUntil = nil
+	}
+	if _, err := r.db.Pool.Exec(ctx, query, id, status, floodWaitUntil, lastError); err != nil {
+		return fmt.Errorf("ошибка сохранения состояния аккаунта %d: %v", id, err)
+	}
+	return nil
+}
+
+// Delete удаляет аккаунт из пула вместе с его кэшем каналов и назначениями
+func (r *AccountRepository) Delete(ctx context.Context, id int64) (bool, error) {
+	result, err := r.db.Pool.Exec(ctx, `DELETE FROM telegram_accounts WHERE id = $1`, id)
+	if err != nil {
+		return false, fmt.Errorf("ошибка удаления аккаунта %d: %v", id, err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// GetAssignments возвращает назначения каналов: канонический вид канала -> ID аккаунта
+func (r *AccountRepository) GetAssignments(ctx context.Context) (map[string]int64, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT source, account_id FROM channel_assignments`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения назначений каналов: %v", err)
+	}
+	defer rows.Close()
+
+	assignments := make(map[string]int64)
+	for rows.Next() {
+		var source string
+		var accountID int64
+		if err := rows.Scan(&source, &accountID); err != nil {
+			return nil, fmt.Errorf("ошибка чтения назначения канала: %v", err)
+		}
+		assignments[source] = accountID
+	}
+	return assignments, rows.Err()
+}
+
+// Assign закрепляет канал за аккаунтом
+func (r *AccountRepository) Assign(ctx context.Context, source string, accountID int64) error {
+	query := `
+		INSERT INTO channel_assignments (source, account_id)
+		VALUES ($1, $2)
+		ON CONFLICT (source) DO UPDATE
+		SET account_id = EXCLUDED.account_id, updated_at = NOW()
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, source, accountID); err != nil {
+		return fmt.Errorf("ошибка назначения канала %s аккаунту %d: %v", source, accountID, err)
+	}
+	return nil
+}
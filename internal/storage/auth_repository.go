@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"context"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// authColumns список колонок состояния входа в порядке сканирования scanAuth
+const authColumns = `account_id, state, method, code_type, password_hint, qr_url, qr_expires_at, last_error, updated_at`
+
+// ErrPasswordLoginDisabled пароль двухэтапной проверки нельзя передать
+// клиенту, потому что не задан ключ шифрования сессий
+var ErrPasswordLoginDisabled = errors.New("вход с паролем недоступен: не задан ключ шифрования сессий (TG_SESSION_KEY)")
+
+// AuthRepository репозиторий состояния входа аккаунтов в Telegram. Через
+// него веб-API передает код и пароль клиенту парсера, даже если они
+// работают в разных процессах.
+type AuthRepository struct {
+	db   *DB
+	aead cipher.AEAD // шифр пароля, nil - ключ не задан и вход с паролем недоступен
+}
+
+// NewAuthRepository создает новый репозиторий состояния входа. Пароль
+// двухэтапной проверки шифруется тем же ключом, что и сессии, без ключа
+// вход с паролем недоступен.
+func NewAuthRepository(db *DB, key string) (*AuthRepository, error) {
+	repo := &AuthRepository{db: db}
+	if key != "" {
+		aead, err := newSessionCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		repo.aead = aead
+	}
+	return repo, nil
+}
+
+// scanAuth сканирует строку результата в состояние входа
+func scanAuth(row pgx.Row) (*models.TelegramAuth, error) {
+	var auth models.TelegramAuth
+	err := row.Scan(
+		&auth.AccountID,
+		&auth.State,
+		&auth.Method,
+		&auth.CodeType,
+		&auth.PasswordHint,
+		&auth.QRURL,
+		&auth.QRExpiresAt,
+		&auth.LastError,
+		&auth.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &auth, nil
+}
+
+// Get возвращает состояние входа аккаунта, nil - клиент аккаунта еще не запускался
+func (r *AuthRepository) Get(ctx context.Context, accountID int64) (*models.TelegramAuth, error) {
+	query := `SELECT ` + authColumns + ` FROM telegram_auth WHERE account_id = $1`
+
+	auth, err := scanAuth(r.db.Pool.QueryRow(ctx, query, accountID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения состояния входа аккаунта %d: %v", accountID, err)
+	}
+	return auth, nil
+}
+
+// RequestLogin начинает вход заново выбранным способом. Клиент аккаунта
+// замечает запрос и отправляет код или показывает QR-код.
+func (r *AuthRepository) RequestLogin(ctx context.Context, accountID int64, method models.AuthMethod) (*models.TelegramAuth, error) {
+	query := `
+		INSERT INTO telegram_auth (account_id, state, method)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (account_id) DO UPDATE
+		SET state = EXCLUDED.state, method = EXCLUDED.method,
+			code = '', password = '', code_type = '', password_hint = '',
+			qr_url = '', qr_expires_at = NULL, last_error = '', updated_at = NOW()
+		RETURNING ` + authColumns
+
+	auth, err := scanAuth(r.db.Pool.QueryRow(ctx, query, accountID, models.AuthRequested, method))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка начала входа аккаунта %d: %v", accountID, err)
+	}
+	return auth, nil
+}
+
+// SetWaiting сообщает, что у аккаунта нет сессии и клиент ждет начала
+// входа. Уже начатый вход и ошибка прошлого входа не затираются.
+func (r *AuthRepository) SetWaiting(ctx context.Context, accountID int64) error {
+	query := `
+		INSERT INTO telegram_auth (account_id, state)
+		VALUES ($1, $2)
+		ON CONFLICT (account_id) DO UPDATE
+		SET state = EXCLUDED.state, qr_url = '', qr_expires_at = NULL, updated_at = NOW()
+		WHERE telegram_auth.state NOT IN ($3, $4)
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, accountID, models.AuthWaiting, models.AuthRequested, models.AuthFailed); err != nil {
+		return fmt.Errorf("ошибка сохранения состояния входа аккаунта %d: %v", accountID, err)
+	}
+	return nil
+}
+
+// Report сохраняет этап входа, о котором сообщает клиент аккаунта.
+// После завершения входа переданные код и пароль стираются.
+func (r *AuthRepository) Report(ctx context.Context, auth *models.TelegramAuth) error {
+	query := `
+		INSERT INTO telegram_auth (account_id, state, code_type, password_hint, qr_url, qr_expires_at, last_error)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (account_id) DO UPDATE
+		SET state = EXCLUDED.state,
+			code_type = EXCLUDED.code_type,
+			password_hint = EXCLUDED.password_hint,
+			qr_url = EXCLUDED.qr_url,
+			qr_expires_at = EXCLUDED.qr_expires_at,
+			last_error = EXCLUDED.last_error,
+			code = CASE WHEN EXCLUDED.state IN ($8, $9) THEN '' ELSE telegram_auth.code END,
+			password = CASE WHEN EXCLUDED.state IN ($8, $9) THEN '' ELSE telegram_auth.password END,
+			updated_at = NOW()
+	`
+
+	_, err := r.db.Pool.Exec(ctx, query,
+		auth.AccountID, auth.State, auth.CodeType, auth.PasswordHint, auth.QRURL, auth.QRExpiresAt, auth.LastError,
+		models.AuthAuthorized, models.AuthFailed,
+	)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения состояния входа аккаунта %d: %v", auth.AccountID, err)
+	}
+	return nil
+}
+
+// SubmitCode передает клиенту код входа. Возвращает false, если клиент
+// сейчас не ждет код.
+func (r *AuthRepository) SubmitCode(ctx context.Context, accountID int64, code string) (bool, error) {
+	query := `
+		UPDATE telegram_auth SET code = $2, last_error = '', updated_at = NOW()
+		WHERE account_id = $1 AND state = $3
+	`
+
+	result, err := r.db.Pool.Exec(ctx, query, accountID, code, models.AuthAwaitingCode)
+	if err != nil {
+		return false, fmt.Errorf("ошибка передачи кода аккаунта %d: %v", accountID, err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// SubmitPassword передает клиенту пароль двухэтапной проверки, в БД
+// пароль хранится зашифрованным. Возвращает false, если клиент сейчас не
+// ждет пароль, и ErrPasswordLoginDisabled, если не задан ключ шифрования.
+func (r *AuthRepository) SubmitPassword(ctx context.Context, accountID int64, password string) (bool, error) {
+	if r.aead == nil {
+		return false, ErrPasswordLoginDisabled
+	}
+
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return false, fmt.Errorf("ошибка шифрования пароля аккаунта %d: %v", accountID, err)
+	}
+	sealed := r.aead.Seal(nonce, nonce, []byte(password), passwordAAD(accountID))
+
+	query := `
+		UPDATE telegram_auth SET password = $2, last_error = '', updated_at = NOW()
+		WHERE account_id = $1 AND state = $3
+	`
+
+	result, err := r.db.Pool.Exec(ctx, query, accountID, base64.StdEncoding.EncodeToString(sealed), models.AuthAwaitingPassword)
+	if err != nil {
+		return false, fmt.Errorf("ошибка передачи пароля аккаунта %d: %v", accountID, err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// TakeCode забирает переданный код и стирает его, "" - кода еще нет
+func (r *AuthRepository) TakeCode(ctx context.Context, accountID int64) (string, error) {
+	query := `
+		UPDATE telegram_auth a SET code = ''
+		FROM (SELECT account_id, code FROM telegram_auth WHERE account_id = $1 FOR UPDATE) old
+		WHERE a.account_id = old.account_id AND old.code <> ''
+		RETURNING old.code
+	`
+
+	code, err := r.take(ctx, query, accountID)
+	if err != nil {
+		return "", fmt.Errorf("ошибка получения кода аккаунта %d: %v", accountID, err)
+	}
+	return code, nil
+}
+
+// TakePassword забирает переданный пароль и стирает его, "" - пароля еще нет
+func (r *AuthRepository) TakePassword(ctx context.Context, accountID int64) (string, error) {
+	query := `
+		UPDATE telegram_auth a SET password = ''
+		FROM (SELECT account_id, password FROM telegram_auth WHERE account_id = $1 FOR UPDATE) old
+		WHERE a.account_id = old.account_id AND old.password <> ''
+		RETURNING old.password
+	`
+
+	sealed, err := r.take(ctx, query, accountID)
+	if err != nil {
+		return "", fmt.Errorf("ошибка получения пароля аккаунта %d: %v", accountID, err)
+	}
+	if sealed == "" {
+		return "", nil
+	}
+	if r.aead == nil {
+		return "", ErrPasswordLoginDisabled
+	}
+
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	size := r.aead.NonceSize()
+	if err != nil || len(data) < size {
+		return "", fmt.Errorf("пароль аккаунта %d поврежден", accountID)
+	}
+	plain, err := r.aead.Open(nil, data[:size], data[size:], passwordAAD(accountID))
+	if err != nil {
+		return "", fmt.Errorf("ошибка расшифровки пароля аккаунта %d, проверьте ключ: %v", accountID, err)
+	}
+	return string(plain), nil
+}
+
+// passwordAAD привязывает шифротекст пароля к аккаунту
+func passwordAAD(accountID int64) []byte {
+	return []byte(fmt.Sprintf("telegram_password:%d", accountID))
+}
+
+// take выполняет запрос, забирающий секрет, "" - секрета еще нет
+func (r *AuthRepository) take(ctx context.Context, query string, accountID int64) (string, error) {
+	var value string
+	err := r.db.Pool.QueryRow(ctx, query, accountID).Scan(&value)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", nil
+		}
+		return "", err
+	}
+	return value, nil
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// backfillColumns список колонок импорта в порядке сканирования scanBackfill
+const backfillColumns = `id, rule_id, from_date, to_date, mode, status, cursor_id, fetched, imported,
+	last_error, created_by, created_at, updated_at, finished_at`
+
+// BackfillRepository репозиторий импортов истории каналов
+type BackfillRepository struct {
+	db *DB
+}
+
+// NewBackfillRepository создает новый репозиторий импортов истории
+func NewBackfillRepository(db *DB) *BackfillRepository {
+	return &BackfillRepository{db: db}
+}
+
+// scanBackfill сканирует строку результата в импорт
+func scanBackfill(row pgx.Row) (*models.BackfillJob, error) {
+	var job models.BackfillJob
+	err := row.Scan(
+		&job.ID,
+		&job.RuleID,
+		&job.From,
+		&job.To,
+		&job.Mode,
+		&job.Status,
+		&job.CursorID,
+		&job.Fetched,
+		&job.Imported,
+		&job.LastError,
+		&job.CreatedBy,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+		&job.FinishedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// queryOne выполняет запрос, возвращающий не больше одного импорта.
+// Пустой результат - nil без ошибки.
+func (r *BackfillRepository) queryOne(ctx context.Context, query string, args ...interface{}) (*models.BackfillJob, error) {
+	job, err := scanBackfill(r.db.Pool.QueryRow(ctx, query, args...))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	return job, err
+}
+
+// Create сохраняет новый импорт в статусе pending
+func (r *BackfillRepository) Create(ctx context.Context, job *models.BackfillJob) error {
+	query := `
+		INSERT INTO backfill_jobs (rule_id, from_date, to_date, mode, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING ` + backfillColumns
+
+	created, err := scanBackfill(r.db.Pool.QueryRow(ctx, query,
+		job.RuleID,
+		job.From,
+		job.To,
+		job.Mode,
+		job.CreatedBy,
+	))
+	if err != nil {
+		return fmt.Errorf("ошибка создания импорта истории: %v", err)
+	}
+
+	*job = *created
+	return nil
+}
+
+// GetByID возвращает импорт по ID, nil - импорт не найден
+func (r *BackfillRepository) GetByID(ctx context.Context, id int64) (*models.BackfillJob, error) {
+	query := `SELECT ` + backfillColumns + ` FROM backfill_jobs WHERE id = $1`
+
+	job, err := r.queryOne(ctx, query, id)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения импорта истории: %v", err)
+	}
+	return job, nil
+}
+
+// List возвращает последние импорты, начиная с новых
+func (r *BackfillRepository) List(ctx context.Context, limit int) ([]*models.BackfillJob, error) {
+	query := `SELECT ` + backfillColumns + ` FROM backfill_jobs ORDER BY id DESC LIMIT $1`
+
+	rows, err := r.db.Pool.Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения импортов истории: %v", err)
+	}
+	defer rows.Close()
+
+	var jobs []*models.BackfillJob
+	for rows.Next() {
+		job, err := scanBackfill(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования импорта истории: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// Claim переводит импорт в running. Занять можно ожидающий импорт,
+// остановленный ошибкой - он продолжится с сообщения, на котором
+// остановился, - или выполняемый, по которому нет отметок дольше
+// staleAfter: процесс, который его выполнял, остановился.
+// nil - импорт занят или завершен.
+func (r *BackfillRepository) Claim(ctx context.Context, id int64, staleAfter time.Duration) (*models.BackfillJob, error) {
+	query := `
+		UPDATE backfill_jobs
+		SET status = 'running', last_error = '', finished_at = NULL, updated_at = NOW()
+		WHERE id = $1 AND (status IN ('pending', 'failed')
+			OR (status = 'running' AND updated_at < NOW() - $2 * INTERVAL '1 second'))
+		RETURNING ` + backfillColumns
+
+	job, err := r.queryOne(ctx, query, id, staleAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запуска импорта истории: %v", err)
+	}
+	return job, nil
+}
+
+// ClaimNext занимает самый старый импорт, который можно выполнить.
+// nil - выполнять нечего.
+func (r *BackfillRepository) ClaimNext(ctx context.Context, staleAfter time.Duration) (*models.BackfillJob, error) {
+	query := `
+		UPDATE backfill_jobs
+		SET status = 'running', updated_at = NOW()
+		WHERE id = (
+			SELECT id FROM backfill_jobs
+			WHERE status = 'pending'
+				OR (status = 'running' AND updated_at < NOW() - $1 * INTERVAL '1 second')
+			ORDER BY id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + backfillColumns
+
+	job, err := r.queryOne(ctx, query, staleAfter.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения импорта истории: %v", err)
+	}
+	return job, nil
+}
+
+// Checkpoint сохраняет прогресс выполняемого импорта. false - импорт больше
+// не выполняется этим процессом, например отменен.
+func (r *BackfillRepository) Checkpoint(ctx context.Context, job *models.BackfillJob) (bool, error) {
+	query := `
+		UPDATE backfill_jobs
+		SET cursor_id = $2, fetched = $3, imported = $4, updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`
+
+	tag, err := r.db.Pool.Exec(ctx, query, job.ID, job.CursorID, job.Fetched, job.Imported)
+	if err != nil {
+		return false, fmt.Errorf("ошибка сохранения прогресса импорта %d: %v", job.ID, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Finish завершает выполняемый импорт со статусом done или failed
+func (r *BackfillRepository) Finish(ctx context.Context, id int64, status models.BackfillStatus, lastError string) error {
+	query := `
+		UPDATE backfill_jobs
+		SET status = $2, last_error = $3, updated_at = NOW(), finished_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, id, status, lastError); err != nil {
+		return fmt.Errorf("ошибка завершения импорта %d: %v", id, err)
+	}
+	return nil
+}
+
+// Suspend возвращает выполняемый импорт в pending, чтобы его продолжил
+// следующий запуск
+func (r *BackfillRepository) Suspend(ctx context.Context, id int64) error {
+	query := `
+		UPDATE backfill_jobs
+		SET status = 'pending', updated_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("ошибка приостановки импорта %d: %v", id, err)
+	}
+	return nil
+}
+
+// Cancel отменяет незавершенный импорт. false - импорт не найден или уже завершен.
+func (r *BackfillRepository) Cancel(ctx context.Context, id int64) (bool, error) {
+	query := `
+		UPDATE backfill_jobs
+		SET status = 'cancelled', updated_at = NOW(), finished_at = NOW()
+		WHERE id = $1 AND status IN ('pending', 'running')
+	`
+
+	tag, err := r.db.Pool.Exec(ctx, query, id)
+	if err != nil {
+		return false, fmt.Errorf("ошибка отмены импорта %d: %v", id, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// cursorColumns список колонок курсора в порядке сканирования scanCursor
+const cursorColumns = `rule_id, source_channel, last_message_id, pts, last_checked_at, last_error,
+	last_success_at, messages_seen, posts_created, updated_at`
+
+// CursorRepository репозиторий курсоров чтения каналов. Курсор только
+// сдвигается вперед, поэтому параллельные обновления из опроса и потока
+// обновлений не откатывают его назад.
+type CursorRepository struct {
+	db *DB
+}
+
+// NewCursorRepository создает новый репозиторий курсоров
+func NewCursorRepository(db *DB) *CursorRepository {
+	return &CursorRepository{db: db}
+}
+
+// scanCursor сканирует строку результата в курсор
+func scanCursor(row pgx.Row) (*models.ChannelCursor, error) {
+	var cursor models.ChannelCursor
+	err := row.Scan(
+		&cursor.RuleID,
+		&cursor.SourceChannel,
+		&cursor.LastMessageID,
+		&cursor.Pts,
+		&cursor.LastCheckedAt,
+		&cursor.LastError,
+		&cursor.LastSuccessAt,
+		&cursor.MessagesSeen,
+		&cursor.PostsCreated,
+		&cursor.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// Get возвращает курсор канала правила, nil - канал еще не читался
+func (r *CursorRepository) Get(ctx context.Context, ruleID int64, channel string) (*models.ChannelCursor, error) {
+	query := `SELECT ` + cursorColumns + ` FROM channel_cursors WHERE rule_id = $1 AND source_channel = $2`
+
+	cursor, err := scanCursor(r.db.Pool.QueryRow(ctx, query, ruleID, channel))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения курсора канала %s: %v", channel, err)
+	}
+	return cursor, nil
+}
+
+// Advance сдвигает курсор до messageID и pts, если они больше сохраненных.
+// pts = 0 - значение не меняется.
+func (r *CursorRepository) Advance(ctx context.Context, ruleID int64, channel string, messageID int64, pts int) error {
+	query := `
+		INSERT INTO channel_cursors (rule_id, source_channel, last_message_id, pts)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (rule_id, source_channel) DO UPDATE
+		SET last_message_id = GREATEST(channel_cursors.last_message_id, EXCLUDED.last_message_id),
+			pts = GREATEST(channel_cursors.pts, EXCLUDED.pts),
+			updated_at = NOW()
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, ruleID, channel, messageID, pts); err != nil {
+		return fmt.Errorf("ошибка сдвига курсора канала %s: %v", channel, err)
+	}
+	return nil
+}
+
+// MarkChecked записывает время и результат опроса истории канала.
+// Пустой lastError - опрос прошел успешно.
+func (r *CursorRepository) MarkChecked(ctx context.Context, ruleID int64, channel string, lastError string) error {
+	query := `
+		INSERT INTO channel_cursors (rule_id, source_channel, last_checked_at, last_error, last_success_at)
+		VALUES ($1, $2, NOW(), $3, CASE WHEN $3 = '' THEN NOW() END)
+		ON CONFLICT (rule_id, source_channel) DO UPDATE
+		SET last_checked_at = NOW(), last_error = EXCLUDED.last_error,
+			last_success_at = COALESCE(EXCLUDED.last_success_at, channel_cursors.last_success_at),
+			updated_at = NOW()
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, ruleID, channel, lastError); err != nil {
+		return fmt.Errorf("ошибка сохранения проверки канала %s: %v", channel, err)
+	}
+	return nil
+}
+
+// Count прибавляет к счетчикам канала правила прочитанные сообщения и созданные посты
+func (r *CursorRepository) Count(ctx context.Context, ruleID int64, channel string, seen, created int) error {
+	query := `
+		INSERT INTO channel_cursors (rule_id, source_channel, messages_seen, posts_created)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (rule_id, source_channel) DO UPDATE
+		SET messages_seen = channel_cursors.messages_seen + EXCLUDED.messages_seen,
+			posts_created = channel_cursors.posts_created + EXCLUDED.posts_created,
+			updated_at = NOW()
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, ruleID, channel, seen, created); err != nil {
+		return fmt.Errorf("ошибка обновления счетчиков канала %s: %v", channel, err)
+	}
+	return nil
+}
+
+// List возвращает курсоры всех каналов
+func (r *CursorRepository) List(ctx context.Context) ([]*models.ChannelCursor, error) {
+	query := `SELECT ` + cursorColumns + ` FROM channel_cursors ORDER BY rule_id, source_channel`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения курсоров каналов: %v", err)
+	}
+	defer rows.Close()
+
+	var cursors []*models.ChannelCursor
+	for rows.Next() {
+		cursor, err := scanCursor(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения курсора канала: %v", err)
+		}
+		cursors = append(cursors, cursor)
+	}
+	return cursors, rows.Err()
+}
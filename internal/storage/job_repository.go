@@ -0,0 +1,447 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// jobColumns список колонок задачи в порядке сканирования scanJob
+const jobColumns = `id, post_id, platform, status, attempts, next_attempt_at, last_error, created_at, updated_at`
+
+// JobRepository репозиторий очереди публикации
+type JobRepository struct {
+	db *DB
+}
+
+// NewJobRepository создает новый репозиторий задач публикации
+func NewJobRepository(db *DB) *JobRepository {
+	return &JobRepository{db: db}
+}
+
+// scanJob сканирует строку результата в задачу
+func scanJob(row pgx.Row) (*models.PublishJob, error) {
+	var job models.PublishJob
+	err := row.Scan(
+		&job.ID,
+		&job.PostID,
+		&job.Platform,
+		&job.Status,
+		&job.Attempts,
+		&job.NextAttemptAt,
+		&job.LastError,
+		&job.CreatedAt,
+		&job.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+// queryJobs выполняет запрос и сканирует все задачи
+func (r *JobRepository) queryJobs(ctx context.Context, query string, args ...interface{}) ([]*models.PublishJob, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*models.PublishJob
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования задачи: %v", err)
+		}
+		jobs = append(jobs, job)
+	}
+
+	return jobs, rows.Err()
+}
+
+// enqueueJobs ставит пост в очередь на платформы. Повторная постановка
+// той же пары (пост, платформа) ничего не делает. С holdUntil задачи ждут
+// в статусе scheduled, пока планировщик не отпустит пост.
+func enqueueJobs(ctx context.Context, tx pgx.Tx, postID int64, platforms []models.PlatformType, holdUntil *time.Time) error {
+	if len(platforms) == 0 {
+		return nil
+	}
+
+	status, nextAttemptAt := models.JobPending, time.Now()
+	if holdUntil != nil {
+		status, nextAttemptAt = models.JobScheduled, *holdUntil
+	}
+
+	query := `
+		INSERT INTO publish_jobs (post_id, platform, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (post_id, platform) DO NOTHING
+	`
+
+	for _, platform := range platforms {
+		if _, err := tx.Exec(ctx, query, postID, platform, status, nextAttemptAt); err != nil {
+			return fmt.Errorf("ошибка постановки поста %d в очередь %s: %v", postID, platform, err)
+		}
+	}
+
+	postQuery := `UPDATE posts SET released_at = NOW() WHERE id = $1`
+	args := []interface{}{postID}
+	if holdUntil != nil {
+		postQuery = `UPDATE posts SET scheduled_at = $2, released_at = NULL WHERE id = $1`
+		args = append(args, *holdUntil)
+	}
+	if _, err := tx.Exec(ctx, postQuery, args...); err != nil {
+		return fmt.Errorf("ошибка обновления расписания поста %d: %v", postID, err)
+	}
+	return nil
+}
+
+// Claim забирает до limit готовых к выполнению задач и помечает их running.
+// Задачи, заблокированные другими воркерами, пропускаются. Задача running
+// с истекшим lockTimeout (воркер упал) снова становится доступной.
+func (r *JobRepository) Claim(ctx context.Context, limit int, lockTimeout time.Duration) ([]*models.PublishJob, error) {
+	query := `
+		UPDATE publish_jobs
+		SET status = 'running', attempts = attempts + 1,
+			locked_until = NOW() + $2 * INTERVAL '1 second', updated_at = NOW()
+		WHERE id IN (
+			SELECT id FROM publish_jobs
+			WHERE (status = 'pending' AND next_attempt_at <= NOW())
+				OR (status = 'running' AND locked_until < NOW())
+			ORDER BY next_attempt_at
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + jobColumns
+
+	jobs, err := r.queryJobs(ctx, query, limit, lockTimeout.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения задач из очереди: %v", err)
+	}
+
+	return jobs, nil
+}
+
+// Complete помечает задачу выполненной
+func (r *JobRepository) Complete(ctx context.Context, id int64) error {
+	query := `
+		UPDATE publish_jobs
+		SET status = 'done', last_error = '', locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, id); err != nil {
+		return fmt.Errorf("ошибка завершения задачи: %v", err)
+	}
+	return nil
+}
+
+// Retry возвращает задачу в очередь с новой попыткой в nextAttemptAt
+func (r *JobRepository) Retry(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string) error {
+	query := `
+		UPDATE publish_jobs
+		SET status = 'pending', next_attempt_at = $2, last_error = $3, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, id, nextAttemptAt, lastError); err != nil {
+		return fmt.Errorf("ошибка переноса задачи: %v", err)
+	}
+	return nil
+}
+
+// Postpone откладывает задачу до nextAttemptAt, не засчитывая попытку.
+// Используется, когда платформа ограничила частоту запросов.
+func (r *JobRepository) Postpone(ctx context.Context, id int64, nextAttemptAt time.Time, lastError string) error {
+	query := `
+		UPDATE publish_jobs
+		SET status = 'pending', attempts = GREATEST(attempts - 1, 0), next_attempt_at = $2,
+			last_error = $3, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, id, nextAttemptAt, lastError); err != nil {
+		return fmt.Errorf("ошибка откладывания задачи: %v", err)
+	}
+	return nil
+}
+
+// Kill переводит задачу в dead после исчерпания попыток
+func (r *JobRepository) Kill(ctx context.Context, id int64, lastError string) error {
+	query := `
+		UPDATE publish_jobs
+		SET status = 'dead', last_error = $2, locked_until = NULL, updated_at = NOW()
+		WHERE id = $1
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, id, lastError); err != nil {
+		return fmt.Errorf("ошибка перевода задачи в dead: %v", err)
+	}
+	return nil
+}
+
+// Requeue сбрасывает попытки задачи и ставит ее на немедленное выполнение.
+// Выполненные и выполняющиеся задачи не меняются.
+func (r *JobRepository) Requeue(ctx context.Context, id int64) (*models.PublishJob, error) {
+	query := `
+		UPDATE publish_jobs
+		SET status = 'pending', attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status IN ('pending', 'dead')
+		RETURNING ` + jobColumns
+
+	job, err := scanJob(r.db.Pool.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка повторной постановки задачи: %v", err)
+	}
+
+	return job, nil
+}
+
+// List возвращает задачи с фильтром по статусу (пустой - все)
+func (r *JobRepository) List(ctx context.Context, status models.JobStatus, limit, offset int) ([]*models.PublishJob, error) {
+	query := `
+		SELECT ` + jobColumns + `
+		FROM publish_jobs
+		WHERE $1 = '' OR status = $1
+		ORDER BY updated_at DESC
+		LIMIT $2 OFFSET $3
+	`
+
+	jobs, err := r.queryJobs(ctx, query, string(status), limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса задач: %v", err)
+	}
+
+	return jobs, nil
+}
+
+// AttachToPosts загружает задачи публикации для списка постов
+func (r *JobRepository) AttachToPosts(ctx context.Context, posts []*models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	ids := make([]int64, 0, len(posts))
+	byID := make(map[int64]*models.Post, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+		byID[post.ID] = post
+	}
+
+	query := `SELECT ` + jobColumns + ` FROM publish_jobs WHERE post_id = ANY($1) ORDER BY platform`
+
+	jobs, err := r.queryJobs(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("ошибка загрузки задач постов: %v", err)
+	}
+
+	for _, job := range jobs {
+		if post, ok := byID[job.PostID]; ok {
+			post.Jobs = append(post.Jobs, job)
+		}
+	}
+
+	return nil
+}
+
+// CountByStatus возвращает количество задач по статусам
+func (r *JobRepository) CountByStatus(ctx context.Context) (map[models.JobStatus]int, error) {
+	rows, err := r.db.Pool.Query(ctx, `SELECT status, COUNT(*) FROM publish_jobs GROUP BY status`)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка подсчета задач: %v", err)
+	}
+	defer rows.Close()
+
+	counts := make(map[models.JobStatus]int)
+	for rows.Next() {
+		var status models.JobStatus
+		var count int
+		if err := rows.Scan(&status, &count); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования статистики задач: %v", err)
+		}
+		counts[status] = count
+	}
+
+	return counts, rows.Err()
+}
+
+// ErrNotSchedulable возвращается при назначении времени посту, который уже публикуется
+// или опубликован
+var ErrNotSchedulable = errors.New("пост уже публикуется или опубликован")
+
+// HeldPosts возвращает посты, задачи которых ждут планировщика, в порядке обработки
+func (r *JobRepository) HeldPosts(ctx context.Context) ([]*models.Post, error) {
+	query := `
+		SELECT ` + postColumns + `
+		FROM posts p
+		WHERE EXISTS (SELECT 1 FROM publish_jobs j WHERE j.post_id = p.id AND j.status = 'scheduled')
+		ORDER BY id
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса отложенных постов: %v", err)
+	}
+	defer rows.Close()
+
+	var posts []*models.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования поста: %v", err)
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// ReleaseTimes возвращает время отправки постов правила воркерам начиная с since
+func (r *JobRepository) ReleaseTimes(ctx context.Context, ruleID int64, since time.Time) ([]time.Time, error) {
+	query := `
+		SELECT released_at FROM posts
+		WHERE rule_id = $1 AND released_at >= $2
+		ORDER BY released_at
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, ruleID, since)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса истории публикаций правила: %v", err)
+	}
+	defer rows.Close()
+
+	var times []time.Time
+	for rows.Next() {
+		var t time.Time
+		if err := rows.Scan(&t); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования времени публикации: %v", err)
+		}
+		times = append(times, t)
+	}
+
+	return times, rows.Err()
+}
+
+// Release отпускает отложенный пост воркерам: задачи scheduled становятся pending.
+// Возвращает false, если у поста не было отложенных задач.
+func (r *JobRepository) Release(ctx context.Context, postID int64) (bool, error) {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE publish_jobs
+		SET status = 'pending', next_attempt_at = NOW(), updated_at = NOW()
+		WHERE post_id = $1 AND status = 'scheduled'
+	`
+
+	result, err := tx.Exec(ctx, query, postID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка отправки поста %d воркерам: %v", postID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE posts SET released_at = NOW() WHERE id = $1`, postID); err != nil {
+		return false, fmt.Errorf("ошибка обновления поста %d: %v", postID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+	return true, nil
+}
+
+// Reschedule переносит ожидаемое время публикации отложенного поста
+func (r *JobRepository) Reschedule(ctx context.Context, postID int64, at time.Time) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := tx.Exec(ctx, `UPDATE posts SET scheduled_at = $2 WHERE id = $1`, postID, at); err != nil {
+		return fmt.Errorf("ошибка переноса поста %d: %v", postID, err)
+	}
+
+	query := `
+		UPDATE publish_jobs SET next_attempt_at = $2, updated_at = NOW()
+		WHERE post_id = $1 AND status = 'scheduled'
+	`
+	if _, err := tx.Exec(ctx, query, postID, at); err != nil {
+		return fmt.Errorf("ошибка переноса задач поста %d: %v", postID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+	return nil
+}
+
+// SchedulePost назначает посту время публикации вручную: окна и лимиты правила
+// на него не действуют. at = nil возвращает пост под расписание правила.
+// Пост, ожидающий модерации, получит это время при одобрении.
+func (r *JobRepository) SchedulePost(ctx context.Context, postID int64, at *time.Time) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var reviewStatus models.ReviewStatus
+	err = tx.QueryRow(ctx, `SELECT review_status FROM posts WHERE id = $1 FOR UPDATE`, postID).Scan(&reviewStatus)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrNotSchedulable
+		}
+		return fmt.Errorf("ошибка получения поста %d: %v", postID, err)
+	}
+
+	if at == nil {
+		query := `
+			UPDATE posts
+			SET schedule_manual = FALSE,
+				scheduled_at = CASE WHEN EXISTS (
+					SELECT 1 FROM publish_jobs WHERE post_id = $1 AND status = 'scheduled'
+				) THEN NOW() ELSE NULL END
+			WHERE id = $1
+		`
+		if _, err := tx.Exec(ctx, query, postID); err != nil {
+			return fmt.Errorf("ошибка сброса времени публикации поста %d: %v", postID, err)
+		}
+	} else {
+		// Откладываем задачи, которые воркеры еще не взяли
+		query := `
+			UPDATE publish_jobs
+			SET status = 'scheduled', next_attempt_at = $2, updated_at = NOW()
+			WHERE post_id = $1 AND status IN ('scheduled', 'pending')
+		`
+		result, err := tx.Exec(ctx, query, postID, *at)
+		if err != nil {
+			return fmt.Errorf("ошибка переноса задач поста %d: %v", postID, err)
+		}
+		if result.RowsAffected() == 0 && reviewStatus != models.ReviewPending {
+			return ErrNotSchedulable
+		}
+
+		query = `UPDATE posts SET scheduled_at = $2, schedule_manual = TRUE, released_at = NULL WHERE id = $1`
+		if _, err := tx.Exec(ctx, query, postID, *at); err != nil {
+			return fmt.Errorf("ошибка назначения времени публикации поста %d: %v", postID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+// ParserStatusRepository репозиторий состояния запущенных процессов парсера
+type ParserStatusRepository struct {
+	db *DB
+}
+
+// NewParserStatusRepository создает новый репозиторий состояния парсера
+func NewParserStatusRepository(db *DB) *ParserStatusRepository {
+	return &ParserStatusRepository{db: db}
+}
+
+// Save сохраняет состояние процесса парсера и время последнего отчета
+func (r *ParserStatusRepository) Save(ctx context.Context, instance *models.ParserInstance) error {
+	monitors := instance.Monitors
+	if monitors == nil {
+		monitors = []models.RuleMonitorStatus{}
+	}
+	monitorsJSON, err := json.Marshal(monitors)
+	if err != nil {
+		return fmt.Errorf("ошибка маршалинга мониторингов: %v", err)
+	}
+
+	query := `
+		INSERT INTO parser_instances (id, hostname, accounts_ready, monitors, started_at, heartbeat_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (id) DO UPDATE
+		SET accounts_ready = EXCLUDED.accounts_ready, monitors = EXCLUDED.monitors, heartbeat_at = NOW()
+	`
+
+	_, err = r.db.Pool.Exec(ctx, query, instance.ID, instance.Hostname, instance.AccountsReady, monitorsJSON, instance.StartedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения состояния парсера: %v", err)
+	}
+	return nil
+}
+
+// Delete удаляет запись остановленного процесса парсера
+func (r *ParserStatusRepository) Delete(ctx context.Context, id string) error {
+	if _, err := r.db.Pool.Exec(ctx, `DELETE FROM parser_instances WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("ошибка удаления состояния парсера: %v", err)
+	}
+	return nil
+}
+
+// ListAlive возвращает процессы парсера, приславшие отчет после since.
+// Записи процессов, давно не приславших отчет, удаляются.
+func (r *ParserStatusRepository) ListAlive(ctx context.Context, since time.Time) ([]*models.ParserInstance, error) {
+	if _, err := r.db.Pool.Exec(ctx, `DELETE FROM parser_instances WHERE heartbeat_at < NOW() - INTERVAL '1 day'`); err != nil {
+		return nil, fmt.Errorf("ошибка очистки состояния парсера: %v", err)
+	}
+
+	query := `
+		SELECT id, hostname, accounts_ready, monitors, started_at, heartbeat_at
+		FROM parser_instances
+		WHERE heartbeat_at >= $1
+		ORDER BY started_at
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения состояния парсера: %v", err)
+	}
+	defer rows.Close()
+
+	var instances []*models.ParserInstance
+	for rows.Next() {
+		var instance models.ParserInstance
+		var monitorsJSON []byte
+		err := rows.Scan(
+			&instance.ID,
+			&instance.Hostname,
+			&instance.AccountsReady,
+			&monitorsJSON,
+			&instance.StartedAt,
+			&instance.HeartbeatAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка чтения состояния парсера: %v", err)
+		}
+		if err := json.Unmarshal(monitorsJSON, &instance.Monitors); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга monitors: %v", err)
+		}
+		instances = append(instances, &instance)
+	}
+	return instances, rows.Err()
+}
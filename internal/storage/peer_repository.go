@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// peerColumns список колонок найденного канала в порядке сканирования scanPeer
+const peerColumns = `account_id, source, channel_id, access_hash, username, title, resolved_at, updated_at`
+
+// PeerRepository репозиторий кэша найденных каналов-источников
+type PeerRepository struct {
+	db *DB
+}
+
+// NewPeerRepository создает новый репозиторий найденных каналов
+func NewPeerRepository(db *DB) *PeerRepository {
+	return &PeerRepository{db: db}
+}
+
+// scanPeer сканирует строку результата в найденный канал
+func scanPeer(row pgx.Row) (*models.ResolvedPeer, error) {
+	var peer models.ResolvedPeer
+	err := row.Scan(
+		&peer.AccountID,
+		&peer.Source,
+		&peer.ChannelID,
+		&peer.AccessHash,
+		&peer.Username,
+		&peer.Title,
+		&peer.ResolvedAt,
+		&peer.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &peer, nil
+}
+
+// Get возвращает канал, найденный аккаунтом по каноническому виду источника,
+// nil - аккаунт канал еще не искал
+func (r *PeerRepository) Get(ctx context.Context, accountID int64, source string) (*models.ResolvedPeer, error) {
+	query := `SELECT ` + peerColumns + ` FROM resolved_peers WHERE account_id = $1 AND source = $2`
+
+	peer, err := scanPeer(r.db.Pool.QueryRow(ctx, query, accountID, source))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения канала %s из кэша: %v", source, err)
+	}
+	return peer, nil
+}
+
+// GetByChannelID возвращает любой найденный аккаунтом источник с этим ID
+// канала, nil - аккаунту канал еще не встречался
+func (r *PeerRepository) GetByChannelID(ctx context.Context, accountID, channelID int64) (*models.ResolvedPeer, error) {
+	query := `SELECT ` + peerColumns + ` FROM resolved_peers WHERE account_id = $1 AND channel_id = $2 ORDER BY updated_at DESC LIMIT 1`
+
+	peer, err := scanPeer(r.db.Pool.QueryRow(ctx, query, accountID, channelID))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения канала %d из кэша: %v", channelID, err)
+	}
+	return peer, nil
+}
+
+// Save сохраняет найденный канал, перезаписывая прежний результат поиска
+func (r *PeerRepository) Save(ctx context.Context, peer *models.ResolvedPeer) error {
+	query := `
+		INSERT INTO resolved_peers (account_id, source, channel_id, access_hash, username, title)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (account_id, source) DO UPDATE
+		SET channel_id = EXCLUDED.channel_id,
+			access_hash = EXCLUDED.access_hash,
+			username = EXCLUDED.username,
+			title = EXCLUDED.title,
+			resolved_at = NOW(),
+			updated_at = NOW()
+		RETURNING resolved_at, updated_at
+	`
+
+	err := r.db.Pool.QueryRow(ctx, query,
+		peer.AccountID, peer.Source, peer.ChannelID, peer.AccessHash, peer.Username, peer.Title,
+	).Scan(&peer.ResolvedAt, &peer.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения канала %s в кэш: %v", peer.Source, err)
+	}
+	return nil
+}
+
+// UpdateInfo обновляет username и название канала во всех источниках
+// всех аккаунтов, которые на него указывают
+func (r *PeerRepository) UpdateInfo(ctx context.Context, channelID int64, username, title string) error {
+	query := `
+		UPDATE resolved_peers
+		SET username = $2, title = $3, updated_at = NOW()
+		WHERE channel_id = $1 AND (username <> $2 OR title <> $3)
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, channelID, username, title); err != nil {
+		return fmt.Errorf("ошибка обновления канала %d в кэше: %v", channelID, err)
+	}
+	return nil
+}
+
+// DeleteChannel удаляет канал из кэша аккаунта под всеми источниками,
+// чтобы при следующем чтении он был найден заново
+func (r *PeerRepository) DeleteChannel(ctx context.Context, accountID, channelID int64) error {
+	if _, err := r.db.Pool.Exec(ctx, `DELETE FROM resolved_peers WHERE account_id = $1 AND channel_id = $2`, accountID, channelID); err != nil {
+		return fmt.Errorf("ошибка удаления канала %d из кэша: %v", channelID, err)
+	}
+	return nil
+}
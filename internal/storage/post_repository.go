@@ -2,14 +2,20 @@ package storage
 
 import (
 	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"time"
 
+	"github.com/drerr0r/tgparserbot/internal/dedup"
 	"github.com/drerr0r/tgparserbot/internal/models"
 	"github.com/jackc/pgx/v5"
 )
 
+// postColumns список колонок поста в порядке сканирования scanPost
+const postColumns = `id, rule_id, message_id, source_channel, content, media_type,
+	media_url, media_file, grouped_id, entities, text_hash, simhash, image_hash, duplicate_of, archived,
+	review_status, reviewed_by, reviewed_at, scheduled_at, schedule_manual, released_at, posted_at, parsed_at, published_telegram, published_vk, publish_error`
+
 // PostRepository репозиторий для работы с постами
 type PostRepository struct {
 	db *DB
@@ -20,61 +26,358 @@ func NewPostRepository(db *DB) *PostRepository {
 	return &PostRepository{db: db}
 }
 
+// scanPost сканирует строку результата в пост
+func scanPost(row pgx.Row) (*models.Post, error) {
+	var post models.Post
+	var entitiesJSON []byte
+	err := row.Scan(
+		&post.ID,
+		&post.RuleID,
+		&post.MessageID,
+		&post.SourceChannel,
+		&post.Content,
+		&post.MediaType,
+		&post.MediaURL,
+		&post.MediaFile,
+		&post.GroupedID,
+		&entitiesJSON,
+		&post.TextHash,
+		&post.SimHash,
+		&post.ImageHash,
+		&post.DuplicateOf,
+		&post.Archived,
+		&post.ReviewStatus,
+		&post.ReviewedBy,
+		&post.ReviewedAt,
+		&post.ScheduledAt,
+		&post.ScheduleManual,
+		&post.ReleasedAt,
+		&post.PostedAt,
+		&post.ParsedAt,
+		&post.PublishedTelegram,
+		&post.PublishedVK,
+		&post.PublishError,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(entitiesJSON) > 0 {
+		if err := json.Unmarshal(entitiesJSON, &post.Entities); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга entities: %v", err)
+		}
+	}
+
+	return &post, nil
+}
+
+// marshalEntities преобразует сущности в JSON, пустой список сохраняется как []
+func marshalEntities(entities []models.TextEntity) ([]byte, error) {
+	if entities == nil {
+		entities = []models.TextEntity{}
+	}
+	data, err := json.Marshal(entities)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка маршалинга entities: %v", err)
+	}
+	return data, nil
+}
+
+// queryPosts выполняет запрос и сканирует все посты
+func (r *PostRepository) queryPosts(ctx context.Context, query string, args ...interface{}) ([]*models.Post, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var posts []*models.Post
+	for rows.Next() {
+		post, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования поста: %v", err)
+		}
+		posts = append(posts, post)
+	}
+
+	return posts, rows.Err()
+}
+
+// Create сохраняет новый пост вместе с элементами медиа
 func (r *PostRepository) Create(ctx context.Context, post *models.Post) error {
+	return r.CreateWithJobs(ctx, post, nil)
+}
+
+// CreateWithJobs сохраняет новый пост и в той же транзакции ставит его
+// в очередь публикации на платформы. Пост с ScheduledAt ждет планировщика.
+func (r *PostRepository) CreateWithJobs(ctx context.Context, post *models.Post, platforms []models.PlatformType) error {
 	query := `
         INSERT INTO posts (
             rule_id, message_id, source_channel, content, media_type,
-            media_url, posted_at, parsed_at, published_telegram, published_vk, publish_error
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
-        RETURNING id, parsed_at
+            media_url, media_file, grouped_id, entities, text_hash, simhash, image_hash, duplicate_of, archived,
+            review_status, scheduled_at, schedule_manual, posted_at, parsed_at, published_telegram, published_vk, publish_error
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, COALESCE(NULLIF($15, ''), 'none'), $16, $17, $18, $19, $20, $21, $22)
+        RETURNING id, review_status, parsed_at
     `
 
-	err := r.db.Pool.QueryRow(ctx, query,
+	entitiesJSON, err := marshalEntities(post.Entities)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	err = tx.QueryRow(ctx, query,
 		post.RuleID,
 		post.MessageID,
 		post.SourceChannel,
 		post.Content,
 		post.MediaType,
 		post.MediaURL,
+		post.MediaFile,
+		post.GroupedID,
+		entitiesJSON,
+		post.TextHash,
+		post.SimHash,
+		post.ImageHash,
+		post.DuplicateOf,
+		post.Archived,
+		post.ReviewStatus,
+		post.ScheduledAt,
+		post.ScheduleManual,
 		post.PostedAt,
 		post.ParsedAt,
 		post.PublishedTelegram, // новое поле
 		post.PublishedVK,       // новое поле
 		post.PublishError,
-	).Scan(&post.ID, &post.ParsedAt)
+	).Scan(&post.ID, &post.ReviewStatus, &post.ParsedAt)
 
 	if err != nil {
 		return fmt.Errorf("ошибка создания поста: %v", err)
 	}
 
+	mediaQuery := `
+        INSERT INTO post_media (post_id, position, media_type, media_url, media_file)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id
+    `
+
+	for i := range post.Media {
+		item := &post.Media[i]
+		item.PostID = post.ID
+		item.Position = i
+
+		err := tx.QueryRow(ctx, mediaQuery,
+			item.PostID,
+			item.Position,
+			item.MediaType,
+			item.MediaURL,
+			item.MediaFile,
+		).Scan(&item.ID)
+		if err != nil {
+			return fmt.Errorf("ошибка сохранения медиа поста: %v", err)
+		}
+	}
+
+	if err := enqueueJobs(ctx, tx, post.ID, platforms, post.ScheduledAt); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+
 	return nil
 }
 
-// GetByID возвращает пост по ID
-func (r *PostRepository) GetByID(ctx context.Context, id int64) (*models.Post, error) {
-	query := `
-    SELECT id, rule_id, message_id, source_channel, content, media_type,
-           media_url, posted_at, parsed_at, published_telegram, published_vk, publish_error
-    FROM posts
-    WHERE id = $1
+// relatedRulesCTE выбирает правила, у которых есть общее с правилом $1
+// назначение публикации. Правило без назначений на платформе публикует в
+// назначение по умолчанию из конфигурации, оно обозначается пустой строкой.
+const relatedRulesCTE = `
+	WITH rule_targets AS (
+		SELECT r.id AS rule_id, pl.platform, COALESCE(d.target, '') AS target
+		FROM parsing_rules r
+		CROSS JOIN LATERAL unnest(r.target_platforms) AS pl(platform)
+		LEFT JOIN LATERAL (
+			SELECT lower(trim(e->>'target')) AS target
+			FROM jsonb_array_elements(r.destinations) e
+			WHERE e->>'platform' = pl.platform
+		) d ON TRUE
+	),
+	related_rules AS (
+		SELECT DISTINCT o.rule_id
+		FROM rule_targets c
+		JOIN rule_targets o ON o.platform = c.platform AND o.target = c.target
+		WHERE c.rule_id = $1
+	)
 `
 
-	var post models.Post
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
-		&post.ID,
-		&post.RuleID,
-		&post.MessageID,
-		&post.SourceChannel,
-		&post.Content,
-		&post.MediaType,
-		&post.MediaURL,
-		&post.PostedAt,
-		&post.ParsedAt,
-		&post.PublishedTelegram,
-		&post.PublishedVK,
-		&post.PublishError,
-	)
+// FindByTextHash ищет оригинальный пост с тем же хэшем текста, сохраненный
+// после since правилом с общим назначением публикации. 0 - пост не найден.
+func (r *PostRepository) FindByTextHash(ctx context.Context, ruleID int64, textHash string, since time.Time) (int64, error) {
+	query := relatedRulesCTE + `
+		SELECT p.id
+		FROM posts p
+		WHERE p.text_hash = $2 AND p.parsed_at >= $3 AND p.duplicate_of IS NULL
+		  AND p.review_status <> 'rejected'
+		  AND p.rule_id IN (SELECT rule_id FROM related_rules)
+		ORDER BY p.parsed_at DESC
+		LIMIT 1
+	`
+
+	var id int64
+	err := r.db.Pool.QueryRow(ctx, query, ruleID, textHash, since).Scan(&id)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("ошибка поиска поста по хэшу текста: %v", err)
+	}
+	return id, nil
+}
 
+// GetSimilarCandidates возвращает SimHash и хэши изображений оригинальных
+// постов, сохраненных после since правилами с общим назначением публикации,
+// начиная с самых новых. Нужны для поиска похожих, а не одинаковых постов.
+func (r *PostRepository) GetSimilarCandidates(ctx context.Context, ruleID int64, since time.Time) ([]dedup.Candidate, error) {
+	query := relatedRulesCTE + `
+		SELECT p.id, p.text_hash, p.simhash, p.image_hash
+		FROM posts p
+		WHERE p.parsed_at >= $2 AND p.duplicate_of IS NULL
+		  AND p.review_status <> 'rejected'
+		  AND (p.simhash <> 0 OR p.image_hash <> 0)
+		  AND p.rule_id IN (SELECT rule_id FROM related_rules)
+		ORDER BY p.parsed_at DESC
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, ruleID, since)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса отпечатков постов: %v", err)
+	}
+	defer rows.Close()
+
+	var candidates []dedup.Candidate
+	for rows.Next() {
+		var c dedup.Candidate
+		var simHash, imageHash int64
+		if err := rows.Scan(&c.PostID, &c.TextHash, &simHash, &imageHash); err != nil {
+			return nil, fmt.Errorf("ошибка сканирования отпечатка поста: %v", err)
+		}
+		c.SimHash = uint64(simHash)
+		c.ImageHash = uint64(imageHash)
+		candidates = append(candidates, c)
+	}
+
+	return candidates, rows.Err()
+}
+
+// HeldMediaFiles возвращает файлы медиа постов, которые еще будут
+// опубликованы: ожидающих модерации или с незавершенными задачами публикации,
+// в том числе отложенными по расписанию
+func (r *PostRepository) HeldMediaFiles(ctx context.Context) ([]string, error) {
+	query := `
+		WITH held AS (
+			SELECT p.id, p.media_file
+			FROM posts p
+			WHERE p.review_status = 'pending_review'
+			   OR EXISTS (
+				SELECT 1 FROM publish_jobs j
+				WHERE j.post_id = p.id AND j.status NOT IN ('done', 'dead')
+			   )
+		)
+		SELECT media_file FROM held WHERE media_file <> ''
+		UNION
+		SELECT m.media_file FROM post_media m JOIN held ON held.id = m.post_id WHERE m.media_file <> ''
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка получения файлов неопубликованных постов: %v", err)
+	}
+	defer rows.Close()
+
+	var files []string
+	for rows.Next() {
+		var file string
+		if err := rows.Scan(&file); err != nil {
+			return nil, fmt.Errorf("ошибка чтения файла поста: %v", err)
+		}
+		files = append(files, file)
+	}
+	return files, rows.Err()
+}
+
+// loadMedia загружает элементы медиа для списка постов
+func (r *PostRepository) loadMedia(ctx context.Context, posts []*models.Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+
+	byID := make(map[int64]*models.Post, len(posts))
+	ids := make([]int64, 0, len(posts))
+	for _, post := range posts {
+		byID[post.ID] = post
+		ids = append(ids, post.ID)
+	}
+
+	query := `
+        SELECT id, post_id, position, media_type, media_url, media_file
+        FROM post_media
+        WHERE post_id = ANY($1)
+        ORDER BY post_id, position
+    `
+
+	rows, err := r.db.Pool.Query(ctx, query, ids)
+	if err != nil {
+		return fmt.Errorf("ошибка запроса медиа постов: %v", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var item models.PostMedia
+		if err := rows.Scan(
+			&item.ID,
+			&item.PostID,
+			&item.Position,
+			&item.MediaType,
+			&item.MediaURL,
+			&item.MediaFile,
+		); err != nil {
+			return fmt.Errorf("ошибка сканирования медиа поста: %v", err)
+		}
+
+		if post, ok := byID[item.PostID]; ok {
+			post.Media = append(post.Media, item)
+		}
+	}
+
+	return rows.Err()
+}
+
+// GetByGroupedID возвращает пост, созданный из альбома Telegram
+func (r *PostRepository) GetByGroupedID(ctx context.Context, sourceChannel string, groupedID int64) (*models.Post, error) {
+	query := `SELECT ` + postColumns + ` FROM posts WHERE source_channel = $1 AND grouped_id = $2 LIMIT 1`
+
+	post, err := scanPost(r.db.Pool.QueryRow(ctx, query, sourceChannel, groupedID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения поста альбома: %v", err)
+	}
+
+	return post, nil
+}
+
+// GetByID возвращает пост по ID
+func (r *PostRepository) GetByID(ctx context.Context, id int64) (*models.Post, error) {
+	query := `SELECT ` + postColumns + ` FROM posts WHERE id = $1`
+
+	post, err := scanPost(r.db.Pool.QueryRow(ctx, query, id))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -82,34 +385,18 @@ func (r *PostRepository) GetByID(ctx context.Context, id int64) (*models.Post, e
 		return nil, fmt.Errorf("ошибка получения поста: %v", err)
 	}
 
-	return &post, nil
+	if err := r.loadMedia(ctx, []*models.Post{post}); err != nil {
+		return nil, err
+	}
+
+	return post, nil
 }
 
 // GetByMessageID возвращает пост по ID сообщения и каналу
 func (r *PostRepository) GetByMessageID(ctx context.Context, sourceChannel string, messageID int64) (*models.Post, error) {
-	query := `
-    SELECT id, rule_id, message_id, source_channel, content, media_type,
-           media_url, posted_at, parsed_at, published_telegram, published_vk, publish_error
-    FROM posts
-    WHERE source_channel = $1 AND message_id = $2
-`
-
-	var post models.Post
-	err := r.db.Pool.QueryRow(ctx, query, sourceChannel, messageID).Scan(
-		&post.ID,
-		&post.RuleID,
-		&post.MessageID,
-		&post.SourceChannel,
-		&post.Content,
-		&post.MediaType,
-		&post.MediaURL,
-		&post.PostedAt,
-		&post.ParsedAt,
-		&post.PublishedTelegram,
-		&post.PublishedVK,
-		&post.PublishError,
-	)
+	query := `SELECT ` + postColumns + ` FROM posts WHERE source_channel = $1 AND message_id = $2`
 
+	post, err := scanPost(r.db.Pool.QueryRow(ctx, query, sourceChannel, messageID))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -117,7 +404,28 @@ func (r *PostRepository) GetByMessageID(ctx context.Context, sourceChannel strin
 		return nil, fmt.Errorf("ошибка получения поста: %v", err)
 	}
 
-	return &post, nil
+	return post, nil
+}
+
+// UpdateContent обновляет текст и форматирование поста после правки
+// исходного сообщения. Текст, который редактор уже изменил, и текст поста
+// на модерации не заменяются. Возвращает false, если пост не обновлен.
+func (r *PostRepository) UpdateContent(ctx context.Context, post *models.Post) (bool, error) {
+	entitiesJSON, err := marshalEntities(post.Entities)
+	if err != nil {
+		return false, err
+	}
+
+	query := `
+		UPDATE posts SET content = $1, entities = $2
+		WHERE id = $3 AND review_status <> 'pending_review'
+		  AND NOT EXISTS (SELECT 1 FROM post_reviews WHERE post_id = posts.id AND action = 'edit')
+	`
+	result, err := r.db.Pool.Exec(ctx, query, post.Content, entitiesJSON, post.ID)
+	if err != nil {
+		return false, fmt.Errorf("ошибка обновления текста поста: %v", err)
+	}
+	return result.RowsAffected() > 0, nil
 }
 
 // MarkAsPublished помечает пост как опубликованный
@@ -139,44 +447,21 @@ func (r *PostRepository) MarkAsPublished(ctx context.Context, id int64) error {
 // GetUnpublishedPosts возвращает неопубликованные посты
 func (r *PostRepository) GetUnpublishedPosts(ctx context.Context, limit int) ([]*models.Post, error) {
 	query := `
-    SELECT id, rule_id, message_id, source_channel, content, media_type,
-           media_url, posted_at, parsed_at, published_telegram, published_vk, publish_error
+    SELECT ` + postColumns + `
     FROM posts
     WHERE (published_telegram = FALSE OR published_vk = FALSE) AND publish_error = ''
+      AND review_status IN ('none', 'approved') AND duplicate_of IS NULL AND NOT archived
     ORDER BY parsed_at ASC
     LIMIT $1
 `
 
-	rows, err := r.db.Pool.Query(ctx, query, limit)
+	posts, err := r.queryPosts(ctx, query, limit)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка запроса неопубликованных постов: %v", err)
 	}
-	defer rows.Close()
 
-	var posts []*models.Post
-	for rows.Next() {
-		var post models.Post
-
-		err := rows.Scan(
-			&post.ID,
-			&post.RuleID,
-			&post.MessageID,
-			&post.SourceChannel,
-			&post.Content,
-			&post.MediaType,
-			&post.MediaURL,
-			&post.PostedAt,
-			&post.ParsedAt,
-			&post.PublishedTelegram,
-			&post.PublishedVK,
-			&post.PublishError,
-		)
-
-		if err != nil {
-			return nil, fmt.Errorf("ошибка сканирования поста: %v", err)
-		}
-
-		posts = append(posts, &post)
+	if err := r.loadMedia(ctx, posts); err != nil {
+		return nil, err
 	}
 
 	return posts, nil
@@ -184,48 +469,49 @@ func (r *PostRepository) GetUnpublishedPosts(ctx context.Context, limit int) ([]
 
 // GetPosts возвращает все посты с пагинацией
 func (r *PostRepository) GetPosts(ctx context.Context, limit, offset int) ([]*models.Post, error) {
-	// Если rule_id = 0, возвращаем все посты
 	query := `
-    SELECT id, rule_id, message_id, source_channel, content, media_type,
-           media_url, posted_at, parsed_at, published_telegram, published_vk, publish_error
+    SELECT ` + postColumns + `
     FROM posts
     ORDER BY parsed_at DESC
     LIMIT $1 OFFSET $2
 `
 
-	rows, err := r.db.Pool.Query(ctx, query, limit, offset)
+	posts, err := r.queryPosts(ctx, query, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка запроса постов: %v", err)
 	}
-	defer rows.Close()
 
-	var posts []*models.Post
-	for rows.Next() {
-		var post models.Post
-
-		err := rows.Scan(
-			&post.ID,
-			&post.RuleID,
-			&post.MessageID,
-			&post.SourceChannel,
-			&post.Content,
-			&post.MediaType,
-			&post.MediaURL,
-			&post.PostedAt,
-			&post.ParsedAt,
-			&post.PublishedTelegram,
-			&post.PublishedVK,
-			&post.PublishError,
-		)
+	if err := r.loadMedia(ctx, posts); err != nil {
+		return nil, err
+	}
 
-		if err != nil {
-			return nil, fmt.Errorf("ошибка сканирования поста: %v", err)
-		}
+	// Всегда возвращаем массив (даже пустой) вместо nil
+	if posts == nil {
+		posts = []*models.Post{}
+	}
+
+	return posts, nil
+}
 
-		posts = append(posts, &post)
+// GetPostsByReviewStatus возвращает посты в состоянии модерации, старые - первыми
+func (r *PostRepository) GetPostsByReviewStatus(ctx context.Context, status models.ReviewStatus, limit, offset int) ([]*models.Post, error) {
+	query := `
+		SELECT ` + postColumns + `
+		FROM posts
+		WHERE review_status = $1
+		ORDER BY parsed_at ASC
+		LIMIT $2 OFFSET $3
+	`
+
+	posts, err := r.queryPosts(ctx, query, status, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса постов на модерации: %v", err)
+	}
+
+	if err := r.loadMedia(ctx, posts); err != nil {
+		return nil, err
 	}
 
-	// Всегда возвращаем массив (даже пустой) вместо nil
 	if posts == nil {
 		posts = []*models.Post{}
 	}
@@ -236,44 +522,20 @@ func (r *PostRepository) GetPosts(ctx context.Context, limit, offset int) ([]*mo
 // GetPostsByRule возвращает посты по правилу
 func (r *PostRepository) GetPostsByRule(ctx context.Context, ruleID int64, limit, offset int) ([]*models.Post, error) {
 	query := `
-    SELECT id, rule_id, message_id, source_channel, content, media_type,
-           media_url, posted_at, parsed_at, published_telegram, published_vk, publish_error
+    SELECT ` + postColumns + `
     FROM posts
     WHERE rule_id = $1
     ORDER BY parsed_at DESC
     LIMIT $2 OFFSET $3
 `
 
-	rows, err := r.db.Pool.Query(ctx, query, ruleID, limit, offset)
+	posts, err := r.queryPosts(ctx, query, ruleID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка запроса постов по правилу: %v", err)
 	}
-	defer rows.Close()
-
-	var posts []*models.Post
-	for rows.Next() {
-		var post models.Post
-
-		err := rows.Scan(
-			&post.ID,
-			&post.RuleID,
-			&post.MessageID,
-			&post.SourceChannel,
-			&post.Content,
-			&post.MediaType,
-			&post.MediaURL,
-			&post.PostedAt,
-			&post.ParsedAt,
-			&post.PublishedTelegram,
-			&post.PublishedVK,
-			&post.PublishError,
-		)
-
-		if err != nil {
-			return nil, fmt.Errorf("ошибка сканирования поста: %v", err)
-		}
 
-		posts = append(posts, &post)
+	if err := r.loadMedia(ctx, posts); err != nil {
+		return nil, err
 	}
 
 	return posts, nil
@@ -344,6 +606,19 @@ func (r *PostRepository) MarkAsPublishedVK(ctx context.Context, id int64) error
 	return nil
 }
 
+// MarkAsPublishedOn помечает пост как опубликованный на платформе.
+// Для платформ без отдельной колонки статуса ничего не делает.
+func (r *PostRepository) MarkAsPublishedOn(ctx context.Context, id int64, platform models.PlatformType) error {
+	switch platform {
+	case models.PlatformTelegram:
+		return r.MarkAsPublishedTelegram(ctx, id)
+	case models.PlatformVK:
+		return r.MarkAsPublishedVK(ctx, id)
+	default:
+		return nil
+	}
+}
+
 // MarkAsFailed помечает пост как неопубликованный с ошибкой
 func (r *PostRepository) MarkAsFailed(ctx context.Context, id int64, errorMsg string) error {
 	query := `UPDATE posts SET published_telegram = FALSE, published_vk = FALSE, publish_error = $1 WHERE id = $2`
@@ -364,32 +639,3 @@ type PostStats struct {
 	PendingPosts   int64 `json:"pending_posts"`
 	FailedPosts    int64 `json:"failed_posts"`
 }
-
-// GetLastMessageID возвращает последний обработанный MessageID для канала
-func (r *PostRepository) GetLastMessageID(ctx context.Context, sourceChannel string) (*models.Post, error) {
-	query := `
-    SELECT id, rule_id, message_id, source_channel, content, media_type, media_url, 
-           posted_at, parsed_at, published_telegram, published_vk, publish_error
-    FROM posts 
-    WHERE source_channel = $1 
-    ORDER BY message_id DESC 
-    LIMIT 1
-`
-
-	var post models.Post
-	err := r.db.Pool.QueryRow(ctx, query, sourceChannel).Scan(
-		&post.ID, &post.RuleID, &post.MessageID, &post.SourceChannel, &post.Content,
-		&post.MediaType, &post.MediaURL, &post.PostedAt, &post.ParsedAt,
-		&post.PublishedTelegram,
-		&post.PublishedVK, &post.PublishError,
-	)
-
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return nil, nil
-		}
-		return nil, fmt.Errorf("ошибка получения последнего сообщения: %v", err)
-	}
-
-	return &post, nil
-}
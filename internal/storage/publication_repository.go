@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+)
+
+// PublicationRepository репозиторий опубликованных копий постов
+type PublicationRepository struct {
+	db *DB
+}
+
+// NewPublicationRepository создает новый репозиторий публикаций
+func NewPublicationRepository(db *DB) *PublicationRepository {
+	return &PublicationRepository{db: db}
+}
+
+// Create сохраняет публикацию поста
+func (r *PublicationRepository) Create(ctx context.Context, pub *models.Publication) error {
+	query := `
+		INSERT INTO post_publications (post_id, platform, target, message_ids, partial)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := r.db.Pool.QueryRow(ctx, query,
+		pub.PostID,
+		pub.Platform,
+		pub.Target,
+		pub.MessageIDs,
+		pub.Partial,
+	).Scan(&pub.ID, &pub.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка сохранения публикации: %v", err)
+	}
+
+	return nil
+}
+
+// Update сохраняет сообщения, отправленные при продолжении публикации
+func (r *PublicationRepository) Update(ctx context.Context, pub *models.Publication) error {
+	query := `UPDATE post_publications SET message_ids = $2, partial = $3 WHERE id = $1`
+
+	if _, err := r.db.Pool.Exec(ctx, query, pub.ID, pub.MessageIDs, pub.Partial); err != nil {
+		return fmt.Errorf("ошибка обновления публикации: %v", err)
+	}
+	return nil
+}
+
+// GetByPostID возвращает все публикации поста
+func (r *PublicationRepository) GetByPostID(ctx context.Context, postID int64) ([]*models.Publication, error) {
+	query := `
+		SELECT id, post_id, platform, target, message_ids, partial, created_at
+		FROM post_publications
+		WHERE post_id = $1
+		ORDER BY id
+	`
+
+	rows, err := r.db.Pool.Query(ctx, query, postID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса публикаций: %v", err)
+	}
+	defer rows.Close()
+
+	var publications []*models.Publication
+	for rows.Next() {
+		var pub models.Publication
+		err := rows.Scan(
+			&pub.ID,
+			&pub.PostID,
+			&pub.Platform,
+			&pub.Target,
+			&pub.MessageIDs,
+			&pub.Partial,
+			&pub.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования публикации: %v", err)
+		}
+		publications = append(publications, &pub)
+	}
+
+	return publications, rows.Err()
+}
+
+// Delete удаляет публикацию
+func (r *PublicationRepository) Delete(ctx context.Context, id int64) error {
+	_, err := r.db.Pool.Exec(ctx, `DELETE FROM post_publications WHERE id = $1`, id)
+	if err != nil {
+		return fmt.Errorf("ошибка удаления публикации: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/drerr0r/tgparserbot/internal/models"
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNotPendingReview возвращается при решении по посту, который не ждет модерации
+var ErrNotPendingReview = errors.New("пост не ожидает модерации")
+
+// reviewColumns список колонок записи модерации в порядке сканирования scanReview
+const reviewColumns = `id, post_id, action, COALESCE(user_id, 0), username, comment, created_at`
+
+// ReviewRepository репозиторий решений модерации постов
+type ReviewRepository struct {
+	db *DB
+}
+
+// NewReviewRepository создает новый репозиторий модерации
+func NewReviewRepository(db *DB) *ReviewRepository {
+	return &ReviewRepository{db: db}
+}
+
+// scanReview сканирует строку результата в запись модерации
+func scanReview(row pgx.Row) (*models.PostReview, error) {
+	var review models.PostReview
+	err := row.Scan(
+		&review.ID,
+		&review.PostID,
+		&review.Action,
+		&review.UserID,
+		&review.Username,
+		&review.Comment,
+		&review.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &review, nil
+}
+
+// logReview записывает решение в журнал модерации
+func logReview(ctx context.Context, tx pgx.Tx, review *models.PostReview) error {
+	query := `
+		INSERT INTO post_reviews (post_id, action, user_id, username, comment)
+		VALUES ($1, $2, NULLIF($3, 0), $4, $5)
+		RETURNING id, created_at
+	`
+
+	err := tx.QueryRow(ctx, query,
+		review.PostID,
+		review.Action,
+		review.UserID,
+		review.Username,
+		review.Comment,
+	).Scan(&review.ID, &review.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("ошибка записи решения модерации: %v", err)
+	}
+	return nil
+}
+
+// decide переводит пост из pending_review в новое состояние и записывает решение
+func (r *ReviewRepository) decide(ctx context.Context, review *models.PostReview, status models.ReviewStatus, platforms []models.PlatformType, holdUntil *time.Time) error {
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE posts
+		SET review_status = $2, reviewed_by = $3, reviewed_at = NOW()
+		WHERE id = $1 AND review_status = 'pending_review'
+	`
+
+	result, err := tx.Exec(ctx, query, review.PostID, status, review.Username)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления статуса модерации: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotPendingReview
+	}
+
+	if err := logReview(ctx, tx, review); err != nil {
+		return err
+	}
+
+	if err := enqueueJobs(ctx, tx, review.PostID, platforms, holdUntil); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+	return nil
+}
+
+// Approve одобряет пост и ставит его в очередь публикации на платформы.
+// С holdUntil пост ждет планировщика, как и при обработке.
+func (r *ReviewRepository) Approve(ctx context.Context, review *models.PostReview, platforms []models.PlatformType, holdUntil *time.Time) error {
+	review.Action = models.ReviewActionApprove
+	return r.decide(ctx, review, models.ReviewApproved, platforms, holdUntil)
+}
+
+// Reject отклоняет пост, он не будет опубликован
+func (r *ReviewRepository) Reject(ctx context.Context, review *models.PostReview) error {
+	review.Action = models.ReviewActionReject
+	return r.decide(ctx, review, models.ReviewRejected, nil, nil)
+}
+
+// Edit меняет текст поста, ожидающего модерации
+func (r *ReviewRepository) Edit(ctx context.Context, post *models.Post, review *models.PostReview) error {
+	entitiesJSON, err := marshalEntities(post.Entities)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		UPDATE posts
+		SET content = $2, entities = $3
+		WHERE id = $1 AND review_status = 'pending_review'
+	`
+
+	result, err := tx.Exec(ctx, query, post.ID, post.Content, entitiesJSON)
+	if err != nil {
+		return fmt.Errorf("ошибка обновления текста поста: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotPendingReview
+	}
+
+	review.PostID = post.ID
+	review.Action = models.ReviewActionEdit
+	if err := logReview(ctx, tx, review); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка коммита транзакции: %v", err)
+	}
+	return nil
+}
+
+// ListByPost возвращает журнал модерации поста по времени
+func (r *ReviewRepository) ListByPost(ctx context.Context, postID int64) ([]*models.PostReview, error) {
+	query := `SELECT ` + reviewColumns + ` FROM post_reviews WHERE post_id = $1 ORDER BY created_at, id`
+
+	rows, err := r.db.Pool.Query(ctx, query, postID)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса журнала модерации: %v", err)
+	}
+	defer rows.Close()
+
+	var reviews []*models.PostReview
+	for rows.Next() {
+		review, err := scanReview(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования записи модерации: %v", err)
+		}
+		reviews = append(reviews, review)
+	}
+
+	return reviews, rows.Err()
+}
+
+// CountPending возвращает количество постов, ожидающих модерации
+func (r *ReviewRepository) CountPending(ctx context.Context) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM posts WHERE review_status = 'pending_review'`
+	if err := r.db.Pool.QueryRow(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("ошибка подсчета постов на модерации: %v", err)
+	}
+	return count, nil
+}
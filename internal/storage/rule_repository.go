@@ -4,11 +4,26 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
 
 	"github.com/drerr0r/tgparserbot/internal/models"
 	"github.com/jackc/pgx/v5"
 )
 
+// RuleChangesChannel канал LISTEN/NOTIFY, в который отправляется ID
+// созданного, измененного или удаленного правила
+const RuleChangesChannel = "parsing_rules_changed"
+
+// RuleCheckChannel канал LISTEN/NOTIFY, в который отправляется ID правила,
+// канал которого нужно проверить, не дожидаясь очередного опроса
+const RuleCheckChannel = "parsing_rules_check"
+
+// ruleColumns список колонок правила в порядке сканирования scanRule
+const ruleColumns = `id, name, source_channel, keywords, exclude_words, media_types,
+	min_text_length, max_text_length, transformations, add_prefix,
+	add_suffix, target_platforms, destinations, check_interval, fetch_mode, sync_edits, sync_deletes,
+	requires_approval, filter, templates, schedule, is_active, is_paused, created_at, updated_at`
+
 // RuleRepository репозиторий для работы с правилами парсинга
 type RuleRepository struct {
 	db *DB
@@ -19,24 +34,181 @@ func NewRuleRepository(db *DB) *RuleRepository {
 	return &RuleRepository{db: db}
 }
 
+// scanRule сканирует строку результата в правило
+func scanRule(row pgx.Row) (*models.ParsingRule, error) {
+	var rule models.ParsingRule
+	var transformationsJSON, destinationsJSON, templatesJSON, scheduleJSON []byte
+
+	err := row.Scan(
+		&rule.ID,
+		&rule.Name,
+		&rule.SourceChannel,
+		&rule.Keywords,
+		&rule.ExcludeWords,
+		&rule.MediaTypes,
+		&rule.MinTextLength,
+		&rule.MaxTextLength,
+		&transformationsJSON,
+		&rule.AddPrefix,
+		&rule.AddSuffix,
+		&rule.TargetPlatforms,
+		&destinationsJSON,
+		&rule.CheckInterval,
+		&rule.FetchMode,
+		&rule.SyncEdits,
+		&rule.SyncDeletes,
+		&rule.RequiresApproval,
+		&rule.Filter,
+		&templatesJSON,
+		&scheduleJSON,
+		&rule.IsActive,
+		&rule.IsPaused,
+		&rule.CreatedAt,
+		&rule.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	// Парсим JSON шагов преобразования
+	if len(transformationsJSON) > 0 {
+		if err := json.Unmarshal(transformationsJSON, &rule.Transformations); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга transformations: %v", err)
+		}
+	}
+
+	if len(destinationsJSON) > 0 {
+		if err := json.Unmarshal(destinationsJSON, &rule.Destinations); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга destinations: %v", err)
+		}
+	}
+
+	if len(templatesJSON) > 0 {
+		if err := json.Unmarshal(templatesJSON, &rule.Templates); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга templates: %v", err)
+		}
+	}
+
+	if len(scheduleJSON) > 0 {
+		if err := json.Unmarshal(scheduleJSON, &rule.Schedule); err != nil {
+			return nil, fmt.Errorf("ошибка парсинга schedule: %v", err)
+		}
+	}
+
+	// Выражение фильтра компилируется один раз при загрузке правила.
+	// Некорректное выражение не ломает загрузку - такое правило ничего не пропустит.
+	_ = rule.CompileFilter()
+
+	return &rule, nil
+}
+
+// marshalDestinations преобразует назначения в JSON, пустой список сохраняется как []
+func marshalDestinations(destinations []models.Destination) ([]byte, error) {
+	if destinations == nil {
+		destinations = []models.Destination{}
+	}
+	data, err := json.Marshal(destinations)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка маршалинга destinations: %v", err)
+	}
+	return data, nil
+}
+
+// marshalTransformations преобразует шаги преобразования в JSON, пустой список сохраняется как []
+func marshalTransformations(steps []models.Transformation) ([]byte, error) {
+	if steps == nil {
+		steps = []models.Transformation{}
+	}
+	data, err := json.Marshal(steps)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка маршалинга transformations: %v", err)
+	}
+	return data, nil
+}
+
+// marshalTemplates преобразует шаблоны в JSON, пустой набор сохраняется как {}
+func marshalTemplates(templates map[models.PlatformType]string) ([]byte, error) {
+	if templates == nil {
+		templates = map[models.PlatformType]string{}
+	}
+	data, err := json.Marshal(templates)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка маршалинга templates: %v", err)
+	}
+	return data, nil
+}
+
+// marshalSchedule преобразует расписание в JSON, без расписания сохраняется NULL
+func marshalSchedule(schedule *models.PostingSchedule) ([]byte, error) {
+	if schedule == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(schedule)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка маршалинга schedule: %v", err)
+	}
+	return data, nil
+}
+
+// queryRules выполняет запрос и сканирует все правила
+func (r *RuleRepository) queryRules(ctx context.Context, query string, args ...interface{}) ([]*models.ParsingRule, error) {
+	rows, err := r.db.Pool.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*models.ParsingRule
+	for rows.Next() {
+		rule, err := scanRule(rows)
+		if err != nil {
+			return nil, fmt.Errorf("ошибка сканирования правила: %v", err)
+		}
+		rules = append(rules, rule)
+	}
+
+	return rules, rows.Err()
+}
+
 // Create создает новое правило
 func (r *RuleRepository) Create(ctx context.Context, rule *models.ParsingRule) error {
 	query := `
         INSERT INTO parsing_rules (
             name, source_channel, keywords, exclude_words, media_types,
-            min_text_length, max_text_length, text_replacements, add_prefix,
-            add_suffix, target_platforms, check_interval, is_active, created_at, updated_at
-        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-        RETURNING id, created_at, updated_at
+            min_text_length, max_text_length, transformations, add_prefix,
+            add_suffix, target_platforms, destinations, check_interval, fetch_mode, sync_edits, sync_deletes,
+            requires_approval, filter, templates, schedule, is_active, created_at, updated_at
+        ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, COALESCE(NULLIF($14, ''), 'stream'), $15, $16, $17, $18, $19, $20, $21, $22, $23)
+        RETURNING id, fetch_mode, created_at, updated_at
     `
 
-	// Преобразуем текстовые замены в JSON
-	replacementsJSON, err := json.Marshal(rule.TextReplacements)
+	transformationsJSON, err := marshalTransformations(rule.Transformations)
+	if err != nil {
+		return err
+	}
+
+	templatesJSON, err := marshalTemplates(rule.Templates)
+	if err != nil {
+		return err
+	}
+
+	scheduleJSON, err := marshalSchedule(rule.Schedule)
+	if err != nil {
+		return err
+	}
+
+	destinationsJSON, err := marshalDestinations(rule.Destinations)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("ошибка маршалинга text_replacements: %v", err)
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
 	}
+	defer tx.Rollback(ctx)
 
-	err = r.db.Pool.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		rule.Name,
 		rule.SourceChannel,
 		rule.Keywords,
@@ -44,55 +216,44 @@ func (r *RuleRepository) Create(ctx context.Context, rule *models.ParsingRule) e
 		rule.MediaTypes,
 		rule.MinTextLength,
 		rule.MaxTextLength,
-		replacementsJSON,
+		transformationsJSON,
 		rule.AddPrefix,
 		rule.AddSuffix,
 		rule.TargetPlatforms,
+		destinationsJSON,
 		rule.CheckInterval,
+		rule.FetchMode,
+		rule.SyncEdits,
+		rule.SyncDeletes,
+		rule.RequiresApproval,
+		rule.Filter,
+		templatesJSON,
+		scheduleJSON,
 		rule.IsActive,
 		rule.CreatedAt,
 		rule.UpdatedAt,
-	).Scan(&rule.ID, &rule.CreatedAt, &rule.UpdatedAt)
+	).Scan(&rule.ID, &rule.FetchMode, &rule.CreatedAt, &rule.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("ошибка создания правила: %v", err)
 	}
 
+	if err := notifyRuleChanged(ctx, tx, rule.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка создания правила: %v", err)
+	}
+
 	return nil
 }
 
 // GetByID возвращает правило по ID
 func (r *RuleRepository) GetByID(ctx context.Context, id int64) (*models.ParsingRule, error) {
-	query := `
-		SELECT id, name, source_channel, keywords, exclude_words, media_types,
-			   min_text_length, max_text_length, text_replacements, add_prefix,
-			   add_suffix, target_platforms, check_interval, is_active, created_at, updated_at
-		FROM parsing_rules
-		WHERE id = $1
-	`
-
-	var rule models.ParsingRule
-	var replacementsJSON []byte
-
-	err := r.db.Pool.QueryRow(ctx, query, id).Scan(
-		&rule.ID,
-		&rule.Name,
-		&rule.SourceChannel,
-		&rule.Keywords,
-		&rule.ExcludeWords,
-		&rule.MediaTypes,
-		&rule.MinTextLength,
-		&rule.MaxTextLength,
-		&replacementsJSON,
-		&rule.AddPrefix,
-		&rule.AddSuffix,
-		&rule.TargetPlatforms,
-		&rule.CheckInterval,
-		&rule.IsActive,
-		&rule.CreatedAt,
-		&rule.UpdatedAt,
-	)
+	query := `SELECT ` + ruleColumns + ` FROM parsing_rules WHERE id = $1`
 
+	rule, err := scanRule(r.db.Pool.QueryRow(ctx, query, id))
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, nil
@@ -100,66 +261,22 @@ func (r *RuleRepository) GetByID(ctx context.Context, id int64) (*models.Parsing
 		return nil, fmt.Errorf("ошибка получения правила: %v", err)
 	}
 
-	// Парсим JSON замен текста
-	if err := json.Unmarshal(replacementsJSON, &rule.TextReplacements); err != nil {
-		return nil, fmt.Errorf("ошибка парсинга text_replacements: %v", err)
-	}
-
-	return &rule, nil
+	return rule, nil
 }
 
-// GetActiveRules возвращает все активные правила
+// GetActiveRules возвращает все активные правила, кроме приостановленных
 func (r *RuleRepository) GetActiveRules(ctx context.Context) ([]*models.ParsingRule, error) {
 	query := `
-        SELECT id, name, source_channel, keywords, exclude_words, media_types,
-               min_text_length, max_text_length, text_replacements, add_prefix,
-               add_suffix, target_platforms, check_interval, is_active, created_at, updated_at
-        FROM parsing_rules 
-        WHERE is_active = TRUE
+        SELECT ` + ruleColumns + `
+        FROM parsing_rules
+        WHERE is_active = TRUE AND is_paused = FALSE
         ORDER BY created_at DESC
     `
 
-	rows, err := r.db.Pool.Query(ctx, query)
+	rules, err := r.queryRules(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка запроса активных правил: %v", err)
 	}
-	defer rows.Close()
-
-	var rules []*models.ParsingRule
-	for rows.Next() {
-		var rule models.ParsingRule
-		var replacementsJSON []byte
-
-		err := rows.Scan(
-			&rule.ID,
-			&rule.Name,
-			&rule.SourceChannel,
-			&rule.Keywords,
-			&rule.ExcludeWords,
-			&rule.MediaTypes,
-			&rule.MinTextLength,
-			&rule.MaxTextLength,
-			&replacementsJSON,
-			&rule.AddPrefix,
-			&rule.AddSuffix,
-			&rule.TargetPlatforms,
-			&rule.CheckInterval,
-			&rule.IsActive,
-			&rule.CreatedAt,
-			&rule.UpdatedAt,
-		)
-
-		if err != nil {
-			return nil, fmt.Errorf("ошибка сканирования правила: %v", err)
-		}
-
-		// Парсим JSON замен текста
-		if err := json.Unmarshal(replacementsJSON, &rule.TextReplacements); err != nil {
-			return nil, fmt.Errorf("ошибка парсинга text_replacements: %v", err)
-		}
-
-		rules = append(rules, &rule)
-	}
 
 	return rules, nil
 }
@@ -167,55 +284,16 @@ func (r *RuleRepository) GetActiveRules(ctx context.Context) ([]*models.ParsingR
 // GetBySourceChannel возвращает правила для канала
 func (r *RuleRepository) GetBySourceChannel(ctx context.Context, sourceChannel string) ([]*models.ParsingRule, error) {
 	query := `
-		SELECT id, name, source_channel, keywords, exclude_words, media_types,
-			   min_text_length, max_text_length, text_replacements, add_prefix,
-			   add_suffix, target_platforms, check_interval, is_active, created_at, updated_at
+		SELECT ` + ruleColumns + `
 		FROM parsing_rules
 		WHERE source_channel = $1 AND is_active = TRUE
 		ORDER BY created_at DESC
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query, sourceChannel)
+	rules, err := r.queryRules(ctx, query, sourceChannel)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка запроса правил по каналу: %v", err)
 	}
-	defer rows.Close()
-
-	var rules []*models.ParsingRule
-	for rows.Next() {
-		var rule models.ParsingRule
-		var replacementsJSON []byte
-
-		err := rows.Scan(
-			&rule.ID,
-			&rule.Name,
-			&rule.SourceChannel,
-			&rule.Keywords,
-			&rule.ExcludeWords,
-			&rule.MediaTypes,
-			&rule.MinTextLength,
-			&rule.MaxTextLength,
-			&replacementsJSON,
-			&rule.AddPrefix,
-			&rule.AddSuffix,
-			&rule.TargetPlatforms,
-			&rule.CheckInterval,
-			&rule.IsActive,
-			&rule.CreatedAt,
-			&rule.UpdatedAt,
-		)
-
-		if err != nil {
-			return nil, fmt.Errorf("ошибка сканирования правила: %v", err)
-		}
-
-		// Парсим JSON замен текста
-		if err := json.Unmarshal(replacementsJSON, &rule.TextReplacements); err != nil {
-			return nil, fmt.Errorf("ошибка парсинга text_replacements: %v", err)
-		}
-
-		rules = append(rules, &rule)
-	}
 
 	return rules, nil
 }
@@ -223,22 +301,45 @@ func (r *RuleRepository) GetBySourceChannel(ctx context.Context, sourceChannel s
 // Update обновляет правило
 func (r *RuleRepository) Update(ctx context.Context, rule *models.ParsingRule) error {
 	query := `
-		UPDATE parsing_rules 
+		UPDATE parsing_rules
 		SET name = $1, source_channel = $2, keywords = $3, exclude_words = $4,
 			media_types = $5, min_text_length = $6, max_text_length = $7,
-			text_replacements = $8, add_prefix = $9, add_suffix = $10,
-			target_platforms = $11, check_interval = $12, is_active = $13, 
-			updated_at = CURRENT_TIMESTAMP
-		WHERE id = $14
-		RETURNING updated_at
+			transformations = $8, add_prefix = $9, add_suffix = $10,
+			target_platforms = $11, destinations = $12, check_interval = $13,
+			fetch_mode = COALESCE(NULLIF($14, ''), fetch_mode), sync_edits = $15,
+			sync_deletes = $16, requires_approval = $17, filter = $18, templates = $19,
+			schedule = $20, is_active = $21, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $22
+		RETURNING fetch_mode, updated_at
 	`
 
-	replacementsJSON, err := json.Marshal(rule.TextReplacements)
+	transformationsJSON, err := marshalTransformations(rule.Transformations)
+	if err != nil {
+		return err
+	}
+
+	templatesJSON, err := marshalTemplates(rule.Templates)
+	if err != nil {
+		return err
+	}
+
+	scheduleJSON, err := marshalSchedule(rule.Schedule)
+	if err != nil {
+		return err
+	}
+
+	destinationsJSON, err := marshalDestinations(rule.Destinations)
+	if err != nil {
+		return err
+	}
+
+	tx, err := r.db.Pool.Begin(ctx)
 	if err != nil {
-		return fmt.Errorf("ошибка маршалинга text_replacements: %v", err)
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
 	}
+	defer tx.Rollback(ctx)
 
-	err = r.db.Pool.QueryRow(ctx, query,
+	err = tx.QueryRow(ctx, query,
 		rule.Name,
 		rule.SourceChannel,
 		rule.Keywords,
@@ -246,19 +347,35 @@ func (r *RuleRepository) Update(ctx context.Context, rule *models.ParsingRule) e
 		rule.MediaTypes,
 		rule.MinTextLength,
 		rule.MaxTextLength,
-		replacementsJSON,
+		transformationsJSON,
 		rule.AddPrefix,
 		rule.AddSuffix,
 		rule.TargetPlatforms,
+		destinationsJSON,
 		rule.CheckInterval,
+		rule.FetchMode,
+		rule.SyncEdits,
+		rule.SyncDeletes,
+		rule.RequiresApproval,
+		rule.Filter,
+		templatesJSON,
+		scheduleJSON,
 		rule.IsActive,
 		rule.ID,
-	).Scan(&rule.UpdatedAt)
+	).Scan(&rule.FetchMode, &rule.UpdatedAt)
 
 	if err != nil {
 		return fmt.Errorf("ошибка обновления правила: %v", err)
 	}
 
+	if err := notifyRuleChanged(ctx, tx, rule.ID); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка обновления правила: %v", err)
+	}
+
 	return nil
 }
 
@@ -266,7 +383,13 @@ func (r *RuleRepository) Update(ctx context.Context, rule *models.ParsingRule) e
 func (r *RuleRepository) Delete(ctx context.Context, id int64) error {
 	query := `DELETE FROM parsing_rules WHERE id = $1`
 
-	result, err := r.db.Pool.Exec(ctx, query, id)
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, query, id)
 	if err != nil {
 		return fmt.Errorf("ошибка удаления правила: %v", err)
 	}
@@ -275,58 +398,131 @@ func (r *RuleRepository) Delete(ctx context.Context, id int64) error {
 		return fmt.Errorf("правило с ID %d не найдено", id)
 	}
 
+	if err := notifyRuleChanged(ctx, tx, id); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("ошибка удаления правила: %v", err)
+	}
+
 	return nil
 }
 
-// List возвращает все правила с пагинацией
-func (r *RuleRepository) List(ctx context.Context, limit, offset int) ([]*models.ParsingRule, error) {
+// SetPaused приостанавливает или возобновляет чтение канала правилом.
+// Возвращает false, если правило не найдено.
+func (r *RuleRepository) SetPaused(ctx context.Context, id int64, paused bool) (bool, error) {
+	query := `UPDATE parsing_rules SET is_paused = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`
+
+	tx, err := r.db.Pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("ошибка начала транзакции: %v", err)
+	}
+	defer tx.Rollback(ctx)
+
+	result, err := tx.Exec(ctx, query, id, paused)
+	if err != nil {
+		return false, fmt.Errorf("ошибка приостановки правила %d: %v", id, err)
+	}
+	if result.RowsAffected() == 0 {
+		return false, nil
+	}
+
+	if err := notifyRuleChanged(ctx, tx, id); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("ошибка приостановки правила %d: %v", id, err)
+	}
+	return true, nil
+}
+
+// RequestCheck просит парсер проверить канал правила вне расписания.
+// Запрос не сохраняется: если парсер не запущен, он потеряется.
+func (r *RuleRepository) RequestCheck(ctx context.Context, id int64) error {
+	if _, err := r.db.Pool.Exec(ctx, `SELECT pg_notify($1, $2)`, RuleCheckChannel, strconv.FormatInt(id, 10)); err != nil {
+		return fmt.Errorf("ошибка запроса проверки правила %d: %v", id, err)
+	}
+	return nil
+}
+
+// notifyRuleChanged сообщает парсерам об изменении правила. Уведомление
+// доставляется только после коммита транзакции.
+func notifyRuleChanged(ctx context.Context, tx pgx.Tx, id int64) error {
+	if _, err := tx.Exec(ctx, `SELECT pg_notify($1, $2)`, RuleChangesChannel, strconv.FormatInt(id, 10)); err != nil {
+		return fmt.Errorf("ошибка уведомления об изменении правила %d: %v", id, err)
+	}
+	return nil
+}
+
+// ListenChanges подписывается на изменения правил и запросы проверки
+// каналов. На каждое уведомление вызывается onChange или onCheck с ID
+// правила. Блокируется до отмены контекста или потери соединения с БД.
+func (r *RuleRepository) ListenChanges(ctx context.Context, onChange, onCheck func(ruleID int64)) error {
+	conn, err := r.db.Pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("ошибка получения соединения с БД: %v", err)
+	}
+	defer conn.Release()
+
+	channels := []string{RuleChangesChannel, RuleCheckChannel}
+	for _, channel := range channels {
+		if _, err := conn.Exec(ctx, `LISTEN `+channel); err != nil {
+			return fmt.Errorf("ошибка подписки на изменения правил: %v", err)
+		}
+	}
+	defer func() {
+		// Соединение возвращается в пул, подписка на нем не нужна
+		_, _ = conn.Exec(context.Background(), `UNLISTEN *`)
+	}()
+
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("ошибка ожидания изменений правил: %v", err)
+		}
+
+		id, err := strconv.ParseInt(notification.Payload, 10, 64)
+		if err != nil {
+			continue
+		}
+		if notification.Channel == RuleCheckChannel {
+			onCheck(id)
+		} else {
+			onChange(id)
+		}
+	}
+}
+
+// ListAll возвращает все правила без пагинации
+func (r *RuleRepository) ListAll(ctx context.Context) ([]*models.ParsingRule, error) {
 	query := `
-		SELECT id, name, source_channel, keywords, exclude_words, media_types,
-			   min_text_length, max_text_length, text_replacements, add_prefix,
-			   add_suffix, target_platforms, is_active, created_at, updated_at
+		SELECT ` + ruleColumns + `
 		FROM parsing_rules
 		ORDER BY created_at DESC
-		LIMIT $1 OFFSET $2
 	`
 
-	rows, err := r.db.Pool.Query(ctx, query, limit, offset)
+	rules, err := r.queryRules(ctx, query)
 	if err != nil {
 		return nil, fmt.Errorf("ошибка запроса списка правил: %v", err)
 	}
-	defer rows.Close()
 
-	var rules []*models.ParsingRule
-	for rows.Next() {
-		var rule models.ParsingRule
-		var replacementsJSON []byte
-
-		err := rows.Scan(
-			&rule.ID,
-			&rule.Name,
-			&rule.SourceChannel,
-			&rule.Keywords,
-			&rule.ExcludeWords,
-			&rule.MediaTypes,
-			&rule.MinTextLength,
-			&rule.MaxTextLength,
-			&replacementsJSON,
-			&rule.AddPrefix,
-			&rule.AddSuffix,
-			&rule.TargetPlatforms,
-			&rule.IsActive,
-			&rule.CreatedAt,
-			&rule.UpdatedAt,
-		)
-
-		if err != nil {
-			return nil, fmt.Errorf("ошибка сканирования правила: %v", err)
-		}
+	return rules, nil
+}
 
-		if err := json.Unmarshal(replacementsJSON, &rule.TextReplacements); err != nil {
-			return nil, fmt.Errorf("ошибка парсинга text_replacements: %v", err)
-		}
+// List возвращает все правила с пагинацией
+func (r *RuleRepository) List(ctx context.Context, limit, offset int) ([]*models.ParsingRule, error) {
+	query := `
+		SELECT ` + ruleColumns + `
+		FROM parsing_rules
+		ORDER BY created_at DESC
+		LIMIT $1 OFFSET $2
+	`
 
-		rules = append(rules, &rule)
+	rules, err := r.queryRules(ctx, query, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка запроса списка правил: %v", err)
 	}
 
 	return rules, nil
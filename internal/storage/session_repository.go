@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+
+	"github.com/gotd/td/session"
+	"github.com/jackc/pgx/v5"
+)
+
+// SessionRepository репозиторий сессий MTProto. Сессия дает полный доступ
+// к аккаунту, поэтому в БД она хранится зашифрованной AES-256-GCM.
+type SessionRepository struct {
+	db   *DB
+	aead cipher.AEAD
+}
+
+// NewSessionRepository создает репозиторий сессий. Ключ шифрования
+// получается из key через SHA-256, поэтому подходит строка любой длины.
+func NewSessionRepository(db *DB, key string) (*SessionRepository, error) {
+	if key == "" {
+		return nil, fmt.Errorf("не задан ключ шифрования сессий")
+	}
+
+	aead, err := newSessionCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionRepository{db: db, aead: aead}, nil
+}
+
+// newSessionCipher создает шифр AES-256-GCM с ключом, полученным из key
+// через SHA-256. Тем же шифром защищены и другие секреты аккаунтов.
+func newSessionCipher(key string) (cipher.AEAD, error) {
+	sum := sha256.Sum256([]byte(key))
+	block, err := aes.NewCipher(sum[:])
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания шифра сессий: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("ошибка создания шифра сессий: %v", err)
+	}
+	return aead, nil
+}
+
+// Storage возвращает хранилище сессии аккаунта для клиента Telegram.
+// legacyFile - файл сессии, которым аккаунт пользовался раньше: если
+// в БД сессии еще нет, она один раз переносится из файла.
+func (r *SessionRepository) Storage(accountID int64, legacyFile string) session.Storage {
+	return &accountSession{repo: r, accountID: accountID, legacyFile: legacyFile}
+}
+
+// Load возвращает расшифрованную сессию аккаунта, nil - сессии нет
+func (r *SessionRepository) Load(ctx context.Context, accountID int64) ([]byte, error) {
+	var data []byte
+	err := r.db.Pool.QueryRow(ctx, `SELECT data FROM telegram_sessions WHERE account_id = $1`, accountID).Scan(&data)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("ошибка получения сессии аккаунта %d: %v", accountID, err)
+	}
+
+	size := r.aead.NonceSize()
+	if len(data) < size {
+		return nil, fmt.Errorf("сессия аккаунта %d повреждена", accountID)
+	}
+	plain, err := r.aead.Open(nil, data[:size], data[size:], sessionAAD(accountID))
+	if err != nil {
+		return nil, fmt.Errorf("ошибка расшифровки сессии аккаунта %d, проверьте ключ: %v", accountID, err)
+	}
+	return plain, nil
+}
+
+// Save шифрует и сохраняет сессию аккаунта
+func (r *SessionRepository) Save(ctx context.Context, accountID int64, data []byte) error {
+	nonce := make([]byte, r.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("ошибка шифрования сессии аккаунта %d: %v", accountID, err)
+	}
+	sealed := r.aead.Seal(nonce, nonce, data, sessionAAD(accountID))
+
+	query := `
+		INSERT INTO telegram_sessions (account_id, data)
+		VALUES ($1, $2)
+		ON CONFLICT (account_id) DO UPDATE
+		SET data = EXCLUDED.data, updated_at = NOW()
+	`
+
+	if _, err := r.db.Pool.Exec(ctx, query, accountID, sealed); err != nil {
+		return fmt.Errorf("ошибка сохранения сессии аккаунта %d: %v", accountID, err)
+	}
+	return nil
+}
+
+// Delete удаляет сессию аккаунта, следующий запуск потребует входа заново
+func (r *SessionRepository) Delete(ctx context.Context, accountID int64) error {
+	if _, err := r.db.Pool.Exec(ctx, `DELETE FROM telegram_sessions WHERE account_id = $1`, accountID); err != nil {
+		return fmt.Errorf("ошибка удаления сессии аккаунта %d: %v", accountID, err)
+	}
+	return nil
+}
+
+// sessionAAD привязывает шифротекст к аккаунту, чтобы сессию одного
+// аккаунта нельзя было подложить другому
+func sessionAAD(accountID int64) []byte {
+	return []byte(fmt.Sprintf("telegram_session:%d", accountID))
+}
+
+// accountSession хранилище сессии одного аккаунта, реализует session.Storage
+type accountSession struct {
+	repo       *SessionRepository
+	accountID  int64
+	legacyFile string
+}
+
+// LoadSession загружает сессию из БД, при первом запуске переносит ее из файла
+func (s *accountSession) LoadSession(ctx context.Context) ([]byte, error) {
+	data, err := s.repo.Load(ctx, s.accountID)
+	if err != nil {
+		return nil, err
+	}
+	if data != nil {
+		return data, nil
+	}
+
+	if s.legacyFile == "" {
+		return nil, session.ErrNotFound
+	}
+	file := &session.FileStorage{Path: s.legacyFile}
+	data, err = file.LoadSession(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.Save(ctx, s.accountID, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// StoreSession сохраняет сессию в БД
+func (s *accountSession) StoreSession(ctx context.Context, data []byte) error {
+	return s.repo.Save(ctx, s.accountID, data)
+}
@@ -0,0 +1,4 @@
+-- Режим получения сообщений: поток обновлений MTProto или опрос истории
+ALTER TABLE parsing_rules ADD COLUMN IF NOT EXISTS fetch_mode VARCHAR(20) NOT NULL DEFAULT 'stream';
+
+UPDATE parsing_rules SET fetch_mode = 'stream' WHERE fetch_mode IS NULL OR fetch_mode = '';
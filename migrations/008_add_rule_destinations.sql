@@ -0,0 +1,3 @@
+-- Назначения публикации правила: [{"platform": "telegram", "target": "@channel"}, {"platform": "vk", "target": "123"}]
+-- Пустой список означает публикацию в каналы из конфигурации
+ALTER TABLE parsing_rules ADD COLUMN IF NOT EXISTS destinations JSONB NOT NULL DEFAULT '[]'::jsonb;
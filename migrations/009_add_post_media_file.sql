@@ -0,0 +1,2 @@
+-- Путь к загруженному из Telegram медиафайлу в локальном кэше
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS media_file TEXT NOT NULL DEFAULT '';
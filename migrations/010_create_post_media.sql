@@ -0,0 +1,17 @@
+-- Элементы медиа постов: альбом из Telegram публикуется одним постом с несколькими файлами
+CREATE TABLE IF NOT EXISTS post_media (
+    id BIGSERIAL PRIMARY KEY,
+    post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    position INTEGER NOT NULL DEFAULT 0,
+    media_type VARCHAR(50) NOT NULL,
+    media_url TEXT NOT NULL DEFAULT '',
+    media_file TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_media_post_id ON post_media(post_id, position);
+
+-- ID альбома Telegram, чтобы не создавать второй пост из оставшихся частей альбома
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS grouped_id BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS idx_posts_grouped_id ON posts(source_channel, grouped_id) WHERE grouped_id <> 0;
@@ -0,0 +1,2 @@
+-- Форматирование текста поста (сущности Telegram: жирный, ссылки, спойлеры и т.д.)
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS entities JSONB NOT NULL DEFAULT '[]';
@@ -0,0 +1,16 @@
+-- Опубликованные копии постов: ID сообщений в Telegram и записей в VK
+-- для синхронизации правок и удалений исходных сообщений
+CREATE TABLE IF NOT EXISTS post_publications (
+    id BIGSERIAL PRIMARY KEY,
+    post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    platform VARCHAR(50) NOT NULL,
+    target VARCHAR(255) NOT NULL,
+    message_ids BIGINT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_publications_post_id ON post_publications(post_id);
+
+-- Синхронизация правок и удалений включается отдельно для каждого правила
+ALTER TABLE parsing_rules ADD COLUMN IF NOT EXISTS sync_edits BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE parsing_rules ADD COLUMN IF NOT EXISTS sync_deletes BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,32 @@
+-- Очередь публикации: одна задача на пару (пост, платформа)
+CREATE TABLE IF NOT EXISTS publish_jobs (
+    id BIGSERIAL PRIMARY KEY,
+    post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    platform VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- pending, running, done, dead
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP WITH TIME ZONE, -- задача running с истекшей блокировкой снова доступна воркерам
+    last_error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (post_id, platform)
+);
+
+CREATE INDEX IF NOT EXISTS idx_publish_jobs_due ON publish_jobs(next_attempt_at) WHERE status IN ('pending', 'running');
+CREATE INDEX IF NOT EXISTS idx_publish_jobs_status ON publish_jobs(status);
+
+-- Ставим в очередь посты, которые еще не опубликованы на платформах своих правил
+INSERT INTO publish_jobs (post_id, platform)
+SELECT p.id, 'telegram'
+FROM posts p
+JOIN parsing_rules r ON r.id = p.rule_id
+WHERE p.published_telegram = FALSE AND 'telegram' = ANY(r.target_platforms)
+ON CONFLICT (post_id, platform) DO NOTHING;
+
+INSERT INTO publish_jobs (post_id, platform)
+SELECT p.id, 'vk'
+FROM posts p
+JOIN parsing_rules r ON r.id = p.rule_id
+WHERE p.published_vk = FALSE AND 'vk' = ANY(r.target_platforms)
+ON CONFLICT (post_id, platform) DO NOTHING;
@@ -0,0 +1,3 @@
+-- Выражение фильтра правила: AND/OR/NOT, скобки, слова целиком, регулярные
+-- выражения и условия по полям сообщения. Пусто - фильтр не используется
+ALTER TABLE parsing_rules ADD COLUMN IF NOT EXISTS filter TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,13 @@
+-- Упорядоченный список шагов преобразования текста вместо словаря замен,
+-- порядок применения которого не определен
+ALTER TABLE parsing_rules ADD COLUMN IF NOT EXISTS transformations JSONB NOT NULL DEFAULT '[]';
+
+-- Переносим существующие замены в шаги replace в алфавитном порядке
+UPDATE parsing_rules
+SET transformations = (
+    SELECT COALESCE(jsonb_agg(jsonb_build_object('type', 'replace', 'find', key, 'replace', value) ORDER BY key), '[]'::jsonb)
+    FROM jsonb_each_text(text_replacements)
+)
+WHERE jsonb_typeof(text_replacements) = 'object';
+
+ALTER TABLE parsing_rules DROP COLUMN IF EXISTS text_replacements;
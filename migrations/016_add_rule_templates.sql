@@ -0,0 +1,3 @@
+-- Шаблоны поста правила по платформам: {"telegram": "...", "vk": "..."}.
+-- Для платформы без шаблона используется шаблон по умолчанию
+ALTER TABLE parsing_rules ADD COLUMN IF NOT EXISTS templates JSONB NOT NULL DEFAULT '{}';
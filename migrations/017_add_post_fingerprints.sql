@@ -0,0 +1,11 @@
+-- Отпечатки содержимого постов для поиска дубликатов из разных каналов:
+-- хэш нормализованного текста, SimHash текста и перцептивный хэш изображения
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS text_hash VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS simhash BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS image_hash BIGINT NOT NULL DEFAULT 0;
+
+-- Подавленный дубликат ссылается на опубликованный оригинал
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS duplicate_of BIGINT REFERENCES posts(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_parsed_at_original ON posts(parsed_at) WHERE duplicate_of IS NULL;
+CREATE INDEX IF NOT EXISTS idx_posts_duplicate_of ON posts(duplicate_of);
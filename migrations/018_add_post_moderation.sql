@@ -0,0 +1,22 @@
+-- Модерация постов: правило может требовать одобрения редактора перед публикацией
+ALTER TABLE parsing_rules ADD COLUMN IF NOT EXISTS requires_approval BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS review_status VARCHAR(20) NOT NULL DEFAULT 'none'
+    CHECK (review_status IN ('none', 'pending_review', 'approved', 'rejected'));
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS reviewed_by VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS reviewed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_posts_review_pending ON posts(parsed_at) WHERE review_status = 'pending_review';
+
+-- Журнал решений редакторов: кто и когда одобрил, отклонил или изменил пост
+CREATE TABLE IF NOT EXISTS post_reviews (
+    id BIGSERIAL PRIMARY KEY,
+    post_id BIGINT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    action VARCHAR(20) NOT NULL,
+    user_id BIGINT,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    comment TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_reviews_post_id ON post_reviews(post_id);
@@ -0,0 +1,16 @@
+-- Расписание публикации правила: окна, минимальный интервал и лимиты.
+-- NULL - посты публикуются сразу после обработки
+ALTER TABLE parsing_rules ADD COLUMN IF NOT EXISTS schedule JSONB;
+
+-- Отложенная публикация поста: задачи ждут в статусе scheduled,
+-- пока планировщик не отпустит пост воркерам
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS scheduled_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS schedule_manual BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS released_at TIMESTAMP WITH TIME ZONE;
+
+-- Уже поставленные в очередь посты считаются отпущенными в момент обработки
+UPDATE posts p SET released_at = p.parsed_at
+WHERE p.released_at IS NULL AND EXISTS (SELECT 1 FROM publish_jobs j WHERE j.post_id = p.id);
+
+CREATE INDEX IF NOT EXISTS idx_posts_released_at ON posts(rule_id, released_at) WHERE released_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_publish_jobs_scheduled ON publish_jobs(post_id) WHERE status = 'scheduled';
@@ -0,0 +1,21 @@
+-- Курсор чтения канала правилом: последнее просмотренное сообщение,
+-- в том числе не прошедшее фильтры, pts потока обновлений и результат
+-- последней проверки. Смена канала в правиле начинает новый курсор.
+CREATE TABLE IF NOT EXISTS channel_cursors (
+    rule_id BIGINT NOT NULL REFERENCES parsing_rules(id) ON DELETE CASCADE,
+    source_channel VARCHAR(255) NOT NULL,
+    last_message_id BIGINT NOT NULL DEFAULT 0,
+    pts INTEGER NOT NULL DEFAULT 0,
+    last_checked_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (rule_id, source_channel)
+);
+
+-- Раньше позиция восстанавливалась по последнему сохраненному посту
+INSERT INTO channel_cursors (rule_id, source_channel, last_message_id)
+SELECT r.id, r.source_channel, MAX(p.message_id)
+FROM parsing_rules r
+JOIN posts p ON p.rule_id = r.id AND p.source_channel = r.source_channel
+GROUP BY r.id, r.source_channel
+ON CONFLICT (rule_id, source_channel) DO NOTHING;
@@ -0,0 +1,14 @@
+-- Кэш найденных каналов-источников. Ключ - канонический вид канала из
+-- правила (@username, +hash приглашения или -100<id>), access hash позволяет
+-- читать канал без повторного поиска после смены username.
+CREATE TABLE IF NOT EXISTS resolved_peers (
+    source VARCHAR(255) PRIMARY KEY,
+    channel_id BIGINT NOT NULL,
+    access_hash BIGINT NOT NULL,
+    username VARCHAR(255) NOT NULL DEFAULT '',
+    title TEXT NOT NULL DEFAULT '',
+    resolved_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_resolved_peers_channel_id ON resolved_peers(channel_id);
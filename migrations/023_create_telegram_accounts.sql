@@ -0,0 +1,29 @@
+-- Пул аккаунтов Telegram, от имени которых читаются каналы
+CREATE TABLE IF NOT EXISTS telegram_accounts (
+    id BIGSERIAL PRIMARY KEY,
+    phone VARCHAR(32) NOT NULL UNIQUE,
+    session_file VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'active',
+    flood_wait_until TIMESTAMP WITH TIME ZONE,
+    last_error TEXT NOT NULL DEFAULT '',
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Аккаунт, который сейчас читает канал. Канал остается за аккаунтом,
+-- пока тот доступен, и переходит к другому при сбое.
+CREATE TABLE IF NOT EXISTS channel_assignments (
+    source VARCHAR(255) PRIMARY KEY,
+    account_id BIGINT NOT NULL REFERENCES telegram_accounts(id) ON DELETE CASCADE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Access hash канала свой у каждого аккаунта. Кэш без привязки
+-- к аккаунту сбрасывается, каналы будут найдены заново.
+TRUNCATE resolved_peers;
+ALTER TABLE resolved_peers DROP CONSTRAINT IF EXISTS resolved_peers_pkey;
+DROP INDEX IF EXISTS idx_resolved_peers_channel_id;
+ALTER TABLE resolved_peers ADD COLUMN IF NOT EXISTS account_id BIGINT NOT NULL REFERENCES telegram_accounts(id) ON DELETE CASCADE;
+ALTER TABLE resolved_peers ADD PRIMARY KEY (account_id, source);
+CREATE INDEX IF NOT EXISTS idx_resolved_peers_account_channel ON resolved_peers(account_id, channel_id);
@@ -0,0 +1,7 @@
+-- Сессии MTProto аккаунтов. Данные зашифрованы ключом из конфигурации,
+-- поэтому сессия переживает передеплой без тома и общая для всех реплик.
+CREATE TABLE IF NOT EXISTS telegram_sessions (
+    account_id BIGINT PRIMARY KEY REFERENCES telegram_accounts(id) ON DELETE CASCADE,
+    data BYTEA NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,16 @@
+-- Состояние входа аккаунтов в Telegram. Клиент парсера сообщает этап
+-- входа, администратор передает через API код и пароль, клиент забирает
+-- их и сразу стирает.
+CREATE TABLE IF NOT EXISTS telegram_auth (
+    account_id BIGINT PRIMARY KEY REFERENCES telegram_accounts(id) ON DELETE CASCADE,
+    state VARCHAR(20) NOT NULL,
+    method VARCHAR(10) NOT NULL DEFAULT '',
+    code VARCHAR(16) NOT NULL DEFAULT '',
+    password TEXT NOT NULL DEFAULT '',
+    code_type VARCHAR(32) NOT NULL DEFAULT '',
+    password_hint TEXT NOT NULL DEFAULT '',
+    qr_url TEXT NOT NULL DEFAULT '',
+    qr_expires_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT NOT NULL DEFAULT '',
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
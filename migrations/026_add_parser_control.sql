@@ -0,0 +1,19 @@
+-- Пауза правила: правило остается включенным, но парсер не читает канал,
+-- пока его не возобновят
+ALTER TABLE parsing_rules ADD COLUMN IF NOT EXISTS is_paused BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Здоровье чтения канала правилом: последний успешный опрос и счетчики
+ALTER TABLE channel_cursors ADD COLUMN IF NOT EXISTS last_success_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE channel_cursors ADD COLUMN IF NOT EXISTS messages_seen BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE channel_cursors ADD COLUMN IF NOT EXISTS posts_created BIGINT NOT NULL DEFAULT 0;
+
+-- Запущенные процессы парсера. Процесс периодически обновляет свою
+-- запись, по ней веб-интерфейс видит, идет ли парсинг.
+CREATE TABLE IF NOT EXISTS parser_instances (
+    id VARCHAR(255) PRIMARY KEY,
+    hostname VARCHAR(255) NOT NULL,
+    accounts_ready BOOLEAN NOT NULL DEFAULT FALSE,
+    monitors JSONB NOT NULL DEFAULT '[]',
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    heartbeat_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
@@ -0,0 +1,3 @@
+-- Точное совпадение текста ищется по индексу, без перебора всех постов окна
+CREATE INDEX IF NOT EXISTS idx_posts_text_hash ON posts(text_hash, parsed_at)
+    WHERE duplicate_of IS NULL AND text_hash <> '';
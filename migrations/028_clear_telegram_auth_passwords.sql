@@ -0,0 +1,4 @@
+-- Пароль двухэтапной проверки теперь хранится зашифрованным ключом сессий.
+-- Стираем пароли, сохраненные раньше открытым текстом: клиент, который
+-- ждет пароль, попросит его снова.
+UPDATE telegram_auth SET password = '' WHERE password <> '';
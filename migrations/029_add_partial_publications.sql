@@ -0,0 +1,3 @@
+-- Альбом, отправленный не целиком: часть медиагрупп дошла до назначения,
+-- повтор задачи дошлет остальные, не повторяя уже отправленные
+ALTER TABLE post_publications ADD COLUMN IF NOT EXISTS partial BOOLEAN NOT NULL DEFAULT FALSE;
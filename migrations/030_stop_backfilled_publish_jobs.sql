@@ -0,0 +1,13 @@
+-- Миграция 013 поставила в очередь все неопубликованные посты, в том
+-- числе посты с ошибкой публикации и старые посты. Как и прежний отбор
+-- неопубликованных постов, такие посты не публикуются задним числом.
+-- Задачи 013 созданы в одной транзакции с ее отметкой в schema_migrations,
+-- поэтому время их создания совпадает со временем применения миграции.
+UPDATE publish_jobs j
+SET status = 'dead', last_error = 'пост не публикуется задним числом', updated_at = NOW()
+FROM posts p, schema_migrations m
+WHERE j.post_id = p.id
+  AND m.version = '013_create_publish_jobs'
+  AND j.created_at = m.applied_at
+  AND j.status = 'pending'
+  AND (COALESCE(p.publish_error, '') <> '' OR p.parsed_at <= m.applied_at - INTERVAL '1 day');
@@ -95,11 +95,114 @@ export const rulesService = {
 
 // Posts service
 export const postsService = {
-  async getPosts(limit = 50, offset = 0) {
+  async getPosts(limit = 50, offset = 0, reviewStatus = '') {
     const response = await api.get('/posts', {
-      params: { limit, offset }
+      params: { limit, offset, review_status: reviewStatus }
     })
     return response.data
+  },
+
+  // scheduledAt = null возвращает пост под расписание правила
+  async schedule(id, scheduledAt) {
+    const response = await api.put(`/posts/${id}/schedule`, { scheduled_at: scheduledAt })
+    return response.data
+  }
+}
+
+// Moderation service
+export const reviewService = {
+  async approve(id, comment = '') {
+    const response = await api.post(`/posts/${id}/approve`, { comment })
+    return response.data
+  },
+
+  async reject(id, comment = '') {
+    const response = await api.post(`/posts/${id}/reject`, { comment })
+    return response.data
+  },
+
+  async edit(id, content, approve = false) {
+    const response = await api.post(`/posts/${id}/edit`, { content, approve })
+    return response.data
+  },
+
+  async bulk(ids, action) {
+    const response = await api.post('/posts/bulk', { ids, action })
+    return response.data
+  },
+
+  async getReviews(id) {
+    const response = await api.get(`/posts/${id}/reviews`)
+    return response.data
+  }
+}
+
+// Publish queue service
+export const jobsService = {
+  async getJobs(params = {}) {
+    const response = await api.get('/jobs', {
+      params: {
+        status: params.status || '',
+        limit: params.limit || 50,
+        offset: params.offset || 0
+      }
+    })
+    return response.data
+  },
+
+  async retryJob(id) {
+    const response = await api.post(`/jobs/${id}/retry`)
+    return response.data
+  }
+}
+
+// History import service
+export const backfillService = {
+  async list(limit = 50) {
+    const response = await api.get('/backfills', { params: { limit } })
+    return response.data
+  },
+
+  // to = null - до текущего момента
+  async create(ruleId, from, to, mode) {
+    const response = await api.post('/backfills', { rule_id: ruleId, from, to, mode })
+    return response.data
+  },
+
+  async cancel(id) {
+    const response = await api.post(`/backfills/${id}/cancel`)
+    return response.data
+  }
+}
+
+// Parser control service
+export const parserService = {
+  async status() {
+    const response = await api.get('/parser/status')
+    return response.data
+  },
+
+  async pause(ruleId) {
+    const response = await api.post(`/parser/rules/${ruleId}/pause`)
+    return response.data
+  },
+
+  async resume(ruleId) {
+    const response = await api.post(`/parser/rules/${ruleId}/resume`)
+    return response.data
+  },
+
+  async checkNow(ruleId) {
+    const response = await api.post(`/parser/rules/${ruleId}/check-now`)
+    return response.data
+  }
+}
+
+// Templates service
+export const templatesService = {
+  async preview(data) {
+    const response = await api.post('/templates/preview', data)
+    return response.data
   }
 }
 
@@ -121,10 +121,10 @@ export default createStore({
     },
 
     // Posts actions 
-    async fetchPosts({ commit }) {
+    async fetchPosts({ commit }, params = {}) {
       commit('SET_LOADING', true)
       try {
-        const posts = await postsService.getPosts()
+        const posts = await postsService.getPosts(params.limit, params.offset, params.reviewStatus)
         commit('SET_POSTS', posts)
       } catch (error) {
         console.error('Error fetching posts:', error)